@@ -1,34 +1,63 @@
 package bbnclient
 
 import (
+	"context"
+	"fmt"
 	"math"
+	"time"
 
 	"github.com/babylonlabs-io/babylon/client/query"
 	bbntypes "github.com/babylonlabs-io/babylon/x/btcstaking/types"
 	sdkquerytypes "github.com/cosmos/cosmos-sdk/types/query"
 )
 
+// statusTimeout is the timeout of the Babylon node status query
+const statusTimeout = 20 * time.Second
+
 type BabylonClient struct {
 	*query.QueryClient
+	powerCache  *powerCache
+	paramsCache *paramsCache
 }
 
 //////////////////////////////
 // CONSTRUCTOR
 //////////////////////////////
 
-func NewBabylonClient(queryClient *query.QueryClient) *BabylonClient {
+func NewBabylonClient(queryClient *query.QueryClient, cacheCfg *CacheConfig) (*BabylonClient, error) {
+	powerCache, err := newPowerCache(cacheCfg.PowerCacheSize, cacheCfg.PowerCacheTTL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create voting power cache: %w", err)
+	}
+
 	return &BabylonClient{
 		QueryClient: queryClient,
-	}
+		powerCache:  powerCache,
+		paramsCache: newParamsCache(cacheCfg.ParamsCacheTTL),
+	}, nil
 }
 
 //////////////////////////////
 // METHODS
 //////////////////////////////
 
-func (bbnClient *BabylonClient) QueryAllFpBtcPubKeys(consumerId string) ([]string, error) {
+// QueryLatestHeight returns the latest Babylon height, which queries can be pinned to. Queries pinned to the
+// height are sent to the Babylon node that returned it, if the client is an RPCPool.
+func (bbnClient *BabylonClient) QueryLatestHeight() (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), statusTimeout)
+	defer cancel()
+	status, err := bbnClient.QueryClient.RPCClient.Status(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return status.SyncInfo.LatestBlockHeight, nil
+}
+
+// QueryAllFpBtcPubKeys returns the BTC public keys of the FPs of the consumer chain at the given Babylon
+// height, or at the latest height if 0
+func (bbnClient *BabylonClient) QueryAllFpBtcPubKeys(consumerId string, babylonHeight int64) ([]string, error) {
 	pagination := &sdkquerytypes.PageRequest{}
-	resp, err := bbnClient.QueryClient.QueryConsumerFinalityProviders(consumerId, pagination)
+	resp, err := bbnClient.atHeight(babylonHeight).QueryConsumerFinalityProviders(consumerId, pagination)
 	if err != nil {
 		return nil, err
 	}
@@ -41,45 +70,31 @@ func (bbnClient *BabylonClient) QueryAllFpBtcPubKeys(consumerId string) ([]strin
 	return pkArr, nil
 }
 
-func (bbnClient *BabylonClient) QueryFpPower(fpPubkeyHex string, btcHeight uint64) (uint64, error) {
-	totalPower := uint64(0)
-	pagination := &sdkquerytypes.PageRequest{}
-	// queries the BTCStaking module for all delegations of a finality provider
-	resp, err := bbnClient.QueryClient.FinalityProviderDelegations(fpPubkeyHex, pagination)
+// QueryFpPower returns the voting power of the FP at the given BTC height, as of the given Babylon
+// height or the latest height if 0. Results are cached per (FP, BTC height, Babylon height) as
+// consecutive L2 blocks usually map to the same BTC height, for a limited time at the latest height.
+func (bbnClient *BabylonClient) QueryFpPower(fpPubkeyHex string, btcHeight uint64, babylonHeight int64) (uint64, error) {
+	if power, ok := bbnClient.powerCache.get(fpPubkeyHex, btcHeight, babylonHeight); ok {
+		return power, nil
+	}
+
+	power, err := bbnClient.queryFpPower(fpPubkeyHex, btcHeight, babylonHeight)
 	if err != nil {
 		return 0, err
 	}
-	for {
-		// btcDels contains all the queried BTC delegations
-		for _, btcDels := range resp.BtcDelegatorDelegations {
-			for _, btcDel := range btcDels.Dels {
-				// check whether the delegation is active
-				isActive, err := bbnClient.isDelegationActive(btcDel, btcHeight)
-				if err != nil {
-					return 0, err
-				}
-				if isActive {
-					totalPower += btcDel.TotalSat
-				}
-			}
-		}
-		if resp.Pagination == nil || resp.Pagination.NextKey == nil {
-			break
-		}
-		pagination.Key = resp.Pagination.NextKey
-	}
-
-	return totalPower, nil
+	bbnClient.powerCache.add(fpPubkeyHex, btcHeight, babylonHeight, power)
+	return power, nil
 }
 
 func (bbnClient *BabylonClient) QueryMultiFpPower(
 	fpPubkeyHexList []string,
 	btcHeight uint64,
+	babylonHeight int64,
 ) (map[string]uint64, error) {
 	fpPowerMap := make(map[string]uint64)
 
 	for _, fpPubkeyHex := range fpPubkeyHexList {
-		fpPower, err := bbnClient.QueryFpPower(fpPubkeyHex, btcHeight)
+		fpPower, err := bbnClient.QueryFpPower(fpPubkeyHex, btcHeight, babylonHeight)
 		if err != nil {
 			return nil, err
 		}
@@ -89,12 +104,13 @@ func (bbnClient *BabylonClient) QueryMultiFpPower(
 	return fpPowerMap, nil
 }
 
-// QueryEarliestActiveDelBtcHeight returns the earliest active BTC staking height
-func (bbnClient *BabylonClient) QueryEarliestActiveDelBtcHeight(fpPkHexList []string) (uint64, error) {
+// QueryEarliestActiveDelBtcHeight returns the earliest active BTC staking height at the given Babylon
+// height, or at the latest height if 0
+func (bbnClient *BabylonClient) QueryEarliestActiveDelBtcHeight(fpPkHexList []string, babylonHeight int64) (uint64, error) {
 	allFpEarliestDelBtcHeight := uint64(math.MaxUint64)
 
 	for _, fpPkHex := range fpPkHexList {
-		fpEarliestDelBtcHeight, err := bbnClient.QueryFpEarliestActiveDelBtcHeight(fpPkHex)
+		fpEarliestDelBtcHeight, err := bbnClient.QueryFpEarliestActiveDelBtcHeight(fpPkHex, babylonHeight)
 		if err != nil {
 			return math.MaxUint64, err
 		}
@@ -106,37 +122,32 @@ func (bbnClient *BabylonClient) QueryEarliestActiveDelBtcHeight(fpPkHexList []st
 	return allFpEarliestDelBtcHeight, nil
 }
 
-func (bbnClient *BabylonClient) QueryFpEarliestActiveDelBtcHeight(fpPubkeyHex string) (uint64, error) {
+func (bbnClient *BabylonClient) QueryFpEarliestActiveDelBtcHeight(fpPubkeyHex string, babylonHeight int64) (uint64, error) {
+	queryClient := bbnClient.atHeight(babylonHeight)
 	pagination := &sdkquerytypes.PageRequest{
 		Limit: 100,
 	}
 
 	// queries the BTCStaking module for all delegations of a finality provider
-	resp, err := bbnClient.QueryClient.FinalityProviderDelegations(fpPubkeyHex, pagination)
+	resp, err := queryClient.FinalityProviderDelegations(fpPubkeyHex, pagination)
 	if err != nil {
 		return math.MaxUint64, err
 	}
 
 	// queries BtcConfirmationDepth, CovenantQuorum, and the latest BTC header
-	btccheckpointParams, err := bbnClient.QueryClient.BTCCheckpointParams()
-	if err != nil {
-		return math.MaxUint64, err
-	}
-
-	// get the BTC staking params
-	btcstakingParams, err := bbnClient.QueryClient.BTCStakingParams()
+	params, err := bbnClient.queryStakingParams(babylonHeight)
 	if err != nil {
 		return math.MaxUint64, err
 	}
 
 	// get the latest BTC header
-	btcHeader, err := bbnClient.QueryClient.BTCHeaderChainTip()
+	btcHeader, err := queryClient.BTCHeaderChainTip()
 	if err != nil {
 		return math.MaxUint64, err
 	}
 
-	kValue := btccheckpointParams.GetParams().BtcConfirmationDepth
-	covQuorum := btcstakingParams.GetParams().CovenantQuorum
+	kValue := params.kValue
+	covQuorum := params.covQuorum
 	latestBtcHeight := btcHeader.GetHeader().Height
 
 	earliestBtcHeight := uint64(math.MaxUint64)
@@ -158,31 +169,104 @@ func (bbnClient *BabylonClient) QueryFpEarliestActiveDelBtcHeight(fpPubkeyHex st
 	return earliestBtcHeight, nil
 }
 
+// CacheStats returns the hit/miss counters of the voting power and params caches
+func (bbnClient *BabylonClient) CacheStats() CacheStats {
+	return CacheStats{
+		PowerHits:    bbnClient.powerCache.hits.Load(),
+		PowerMisses:  bbnClient.powerCache.misses.Load(),
+		ParamsHits:   bbnClient.paramsCache.hits.Load(),
+		ParamsMisses: bbnClient.paramsCache.misses.Load(),
+	}
+}
+
+// InvalidateCache clears the voting power and params caches, e.g. after a params change
+func (bbnClient *BabylonClient) InvalidateCache() {
+	bbnClient.powerCache.purge()
+	bbnClient.paramsCache.invalidate()
+}
+
 //////////////////////////////
 // INTERNAL
 //////////////////////////////
 
-// we implemented exact logic as in GetStatus
-// https://github.com/babylonlabs-io/babylon-private/blob/3d8f190c9b0c0795f6546806e3b8582de716cd60/x/btcstaking/types/btc_delegation.go#L90-L111
-func (bbnClient *BabylonClient) isDelegationActive(
-	btcDel *bbntypes.BTCDelegationResponse,
-	btcHeight uint64,
-) (bool, error) {
-	btccheckpointParams, err := bbnClient.QueryClient.BTCCheckpointParams()
+func (bbnClient *BabylonClient) queryFpPower(fpPubkeyHex string, btcHeight uint64, babylonHeight int64) (uint64, error) {
+	totalPower := uint64(0)
+	// the params are the same for all delegations, so fetch them once
+	params, err := bbnClient.queryStakingParams(babylonHeight)
 	if err != nil {
-		return false, err
+		return 0, err
 	}
-	btcstakingParams, err := bbnClient.QueryClient.BTCStakingParams()
+	pagination := &sdkquerytypes.PageRequest{}
+	// queries the BTCStaking module for all delegations of a finality provider
+	resp, err := bbnClient.atHeight(babylonHeight).FinalityProviderDelegations(fpPubkeyHex, pagination)
 	if err != nil {
-		return false, err
+		return 0, err
 	}
-	kValue := btccheckpointParams.GetParams().BtcConfirmationDepth
-	wValue := btccheckpointParams.GetParams().CheckpointFinalizationTimeout
-	covQuorum := btcstakingParams.GetParams().CovenantQuorum
+	for {
+		// btcDels contains all the queried BTC delegations
+		for _, btcDels := range resp.BtcDelegatorDelegations {
+			for _, btcDel := range btcDels.Dels {
+				// check whether the delegation is active
+				if isDelegationActive(btcDel, btcHeight, params) {
+					totalPower += btcDel.TotalSat
+				}
+			}
+		}
+		if resp.Pagination == nil || resp.Pagination.NextKey == nil {
+			break
+		}
+		pagination.Key = resp.Pagination.NextKey
+	}
+
+	return totalPower, nil
+}
+
+// queryStakingParams returns the BTC checkpoint and staking params at the given Babylon height, served
+// from the params cache while it is fresh
+func (bbnClient *BabylonClient) queryStakingParams(babylonHeight int64) (*stakingParams, error) {
+	return bbnClient.paramsCache.get(babylonHeight, func() (*stakingParams, error) {
+		queryClient := bbnClient.atHeight(babylonHeight)
+		btccheckpointParams, err := queryClient.BTCCheckpointParams()
+		if err != nil {
+			return nil, err
+		}
+		btcstakingParams, err := queryClient.BTCStakingParams()
+		if err != nil {
+			return nil, err
+		}
+		return &stakingParams{
+			kValue:    btccheckpointParams.GetParams().BtcConfirmationDepth,
+			wValue:    btccheckpointParams.GetParams().CheckpointFinalizationTimeout,
+			covQuorum: btcstakingParams.GetParams().CovenantQuorum,
+		}, nil
+	})
+}
+
+// atHeight returns a query client pinned to the given Babylon height, or the latest height if 0. The
+// Babylon query client doesn't take the query height, so it is set on the ABCI queries it sends.
+func (bbnClient *BabylonClient) atHeight(babylonHeight int64) *query.QueryClient {
+	if babylonHeight <= 0 {
+		return bbnClient.QueryClient
+	}
+	queryClient := *bbnClient.QueryClient
+	queryClient.RPCClient = &heightPinnedClient{Client: queryClient.RPCClient, height: babylonHeight}
+	return &queryClient
+}
+
+// we implemented exact logic as in GetStatus
+// https://github.com/babylonlabs-io/babylon-private/blob/3d8f190c9b0c0795f6546806e3b8582de716cd60/x/btcstaking/types/btc_delegation.go#L90-L111
+func isDelegationActive(
+	btcDel *bbntypes.BTCDelegationResponse,
+	btcHeight uint64,
+	params *stakingParams,
+) bool {
+	kValue := params.kValue
+	wValue := params.wValue
+	covQuorum := params.covQuorum
 	ud := btcDel.UndelegationResponse
 
 	if len(ud.GetDelegatorUnbondingSigHex()) > 0 {
-		return false, nil
+		return false
 	}
 
 	// k is not involved in the `GetStatus` logic as Babylon will accept a BTC delegation request
@@ -197,20 +281,20 @@ func (bbnClient *BabylonClient) isDelegationActive(
 	//
 	// So in our case, we need to check both to ensure the delegation is active
 	if btcHeight < btcDel.StartHeight+kValue || btcHeight+wValue > btcDel.EndHeight {
-		return false, nil
+		return false
 	}
 
 	if len(btcDel.CovenantSigs) < int(covQuorum) {
-		return false, nil
+		return false
 	}
 	if len(ud.CovenantUnbondingSigList) < int(covQuorum) {
-		return false, nil
+		return false
 	}
 	if len(ud.CovenantSlashingSigs) < int(covQuorum) {
-		return false, nil
+		return false
 	}
 
-	return true, nil
+	return true
 }
 
 // The active delegation needs to satisfy:
@@ -0,0 +1,202 @@
+package bbnclient
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+)
+
+const (
+	// DefaultPowerCacheSize is the number of (FP, BTC height) voting power entries kept in memory
+	DefaultPowerCacheSize = 10000
+	// DefaultPowerCacheTTL is how long the voting power queried at the latest Babylon height is cached for
+	DefaultPowerCacheTTL = time.Minute
+	// DefaultParamsCacheTTL is how long the BTC checkpoint and staking params are cached for
+	DefaultParamsCacheTTL = 10 * time.Minute
+)
+
+type CacheConfig struct {
+	// PowerCacheSize is the max number of (FP, BTC height) voting power entries to cache
+	PowerCacheSize int
+	// PowerCacheTTL is how long the voting power queried at the latest Babylon height is cached for, the
+	// voting power queried at a pinned Babylon height never changes so it isn't expired
+	PowerCacheTTL time.Duration
+	// ParamsCacheTTL is how long the BTC checkpoint and staking params are cached for
+	ParamsCacheTTL time.Duration
+}
+
+func DefaultCacheConfig() *CacheConfig {
+	return &CacheConfig{
+		PowerCacheSize: DefaultPowerCacheSize,
+		PowerCacheTTL:  DefaultPowerCacheTTL,
+		ParamsCacheTTL: DefaultParamsCacheTTL,
+	}
+}
+
+// CacheStats holds the hit/miss counters of the Babylon client caches
+type CacheStats struct {
+	PowerHits    uint64
+	PowerMisses  uint64
+	ParamsHits   uint64
+	ParamsMisses uint64
+}
+
+// powerCache is a bounded LRU cache of FP voting power keyed by (FP pubkey, BTC height, Babylon height).
+// The voting power at a pinned Babylon height never changes, while the voting power at the latest Babylon
+// height changes as delegations are slashed or unbonded, so it is cached for a fixed TTL. The voting power
+// at the unbounded BTC height math.MaxUint64 changes with every new BTC block, so it is never cached.
+type powerCache struct {
+	cache  *lru.Cache[powerCacheKey, powerCacheEntry]
+	now    func() time.Time
+	ttl    time.Duration
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+type powerCacheKey struct {
+	fpPubkeyHex string
+	btcHeight   uint64
+	// babylonHeight is the Babylon height the power was queried at, 0 for the latest state
+	babylonHeight int64
+}
+
+type powerCacheEntry struct {
+	fetchedAt time.Time
+	power     uint64
+}
+
+// stakingParams are the BTC checkpoint and staking params used to check if a delegation is active
+type stakingParams struct {
+	kValue    uint64
+	wValue    uint64
+	covQuorum uint32
+}
+
+// paramsCache caches the latest staking params for a fixed TTL. The params only change through
+// governance, so refreshing them periodically is enough. The params at a pinned Babylon height never
+// change, so the params at the last pinned height are cached until invalidated.
+type paramsCache struct {
+	params       *stakingParams
+	pinnedParams *stakingParams
+	fetchedAt    time.Time
+	now          func() time.Time
+	ttl          time.Duration
+	pinnedHeight int64
+	hits         atomic.Uint64
+	misses       atomic.Uint64
+	mutex        sync.Mutex
+}
+
+//////////////////////////////
+// CONSTRUCTOR
+//////////////////////////////
+
+func newPowerCache(size int, ttl time.Duration) (*powerCache, error) {
+	cache, err := lru.New[powerCacheKey, powerCacheEntry](size)
+	if err != nil {
+		return nil, err
+	}
+	return &powerCache{
+		cache: cache,
+		ttl:   ttl,
+		now:   time.Now,
+	}, nil
+}
+
+func newParamsCache(ttl time.Duration) *paramsCache {
+	return &paramsCache{
+		ttl: ttl,
+		now: time.Now,
+	}
+}
+
+//////////////////////////////
+// METHODS
+//////////////////////////////
+
+// get returns the cached voting power, unless it is missing, expired or not cacheable
+func (c *powerCache) get(fpPubkeyHex string, btcHeight uint64, babylonHeight int64) (uint64, bool) {
+	if btcHeight == math.MaxUint64 {
+		return 0, false
+	}
+	key := powerCacheKey{fpPubkeyHex: fpPubkeyHex, btcHeight: btcHeight, babylonHeight: babylonHeight}
+	entry, ok := c.cache.Get(key)
+	if ok && babylonHeight == 0 && c.now().Sub(entry.fetchedAt) >= c.ttl {
+		c.cache.Remove(key)
+		ok = false
+	}
+	if ok {
+		c.hits.Add(1)
+	} else {
+		c.misses.Add(1)
+	}
+	return entry.power, ok
+}
+
+func (c *powerCache) add(fpPubkeyHex string, btcHeight uint64, babylonHeight int64, power uint64) {
+	if btcHeight == math.MaxUint64 {
+		return
+	}
+	key := powerCacheKey{fpPubkeyHex: fpPubkeyHex, btcHeight: btcHeight, babylonHeight: babylonHeight}
+	c.cache.Add(key, powerCacheEntry{power: power, fetchedAt: c.now()})
+}
+
+func (c *powerCache) purge() {
+	c.cache.Purge()
+}
+
+// get returns the cached params at the given Babylon height, 0 for the latest params, or fetches and
+// caches them if they are missing or expired
+func (c *paramsCache) get(babylonHeight int64, fetch func() (*stakingParams, error)) (*stakingParams, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if babylonHeight > 0 {
+		return c.getPinned(babylonHeight, fetch)
+	}
+	if c.params != nil && c.now().Sub(c.fetchedAt) < c.ttl {
+		c.hits.Add(1)
+		return c.params, nil
+	}
+	c.misses.Add(1)
+
+	params, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+	c.params = params
+	c.fetchedAt = c.now()
+	return params, nil
+}
+
+func (c *paramsCache) invalidate() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.params = nil
+	c.pinnedParams = nil
+}
+
+//////////////////////////////
+// INTERNAL
+//////////////////////////////
+
+// getPinned returns the cached params at the pinned Babylon height, or fetches and caches them if the
+// height differs from the last pinned height. The caller must hold the mutex.
+func (c *paramsCache) getPinned(babylonHeight int64, fetch func() (*stakingParams, error)) (*stakingParams, error) {
+	if c.pinnedParams != nil && c.pinnedHeight == babylonHeight {
+		c.hits.Add(1)
+		return c.pinnedParams, nil
+	}
+	c.misses.Add(1)
+
+	params, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+	c.pinnedParams = params
+	c.pinnedHeight = babylonHeight
+	return params, nil
+}
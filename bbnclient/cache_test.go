@@ -0,0 +1,189 @@
+package bbnclient
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPowerCache(t *testing.T) {
+	cache, err := newPowerCache(2, time.Minute)
+	require.NoError(t, err)
+
+	// miss on empty cache
+	_, ok := cache.get("pk1", 100, 0)
+	require.False(t, ok)
+
+	cache.add("pk1", 100, 0, 1000)
+	cache.add("pk2", 100, 0, 2000)
+
+	// hit on cached entries, keyed by both FP and BTC height
+	power, ok := cache.get("pk1", 100, 0)
+	require.True(t, ok)
+	require.Equal(t, uint64(1000), power)
+	_, ok = cache.get("pk1", 101, 0)
+	require.False(t, ok)
+
+	// adding a third entry evicts the least recently used one (pk2)
+	cache.add("pk3", 100, 0, 3000)
+	_, ok = cache.get("pk2", 100, 0)
+	require.False(t, ok)
+	power, ok = cache.get("pk1", 100, 0)
+	require.True(t, ok)
+	require.Equal(t, uint64(1000), power)
+
+	require.Equal(t, uint64(2), cache.hits.Load())
+	require.Equal(t, uint64(3), cache.misses.Load())
+
+	// entries are also keyed by the Babylon height they were queried at
+	cache.add("pk1", 100, 4242, 900)
+	power, ok = cache.get("pk1", 100, 4242)
+	require.True(t, ok)
+	require.Equal(t, uint64(900), power)
+	power, ok = cache.get("pk1", 100, 0)
+	require.True(t, ok)
+	require.Equal(t, uint64(1000), power)
+	require.Equal(t, uint64(4), cache.hits.Load())
+
+	// purge clears all entries
+	cache.purge()
+	_, ok = cache.get("pk1", 100, 0)
+	require.False(t, ok)
+}
+
+func TestPowerCacheExpiresLatestHeight(t *testing.T) {
+	now := time.Unix(1000, 0)
+	cache, err := newPowerCache(10, time.Minute)
+	require.NoError(t, err)
+	cache.now = func() time.Time { return now }
+
+	cache.add("pk1", 100, 0, 1000)
+	cache.add("pk1", 100, 4242, 900)
+
+	// the voting power at the latest Babylon height is served from the cache within the TTL
+	now = now.Add(59 * time.Second)
+	power, ok := cache.get("pk1", 100, 0)
+	require.True(t, ok)
+	require.Equal(t, uint64(1000), power)
+
+	// then it expires, while the voting power at a pinned Babylon height never changes
+	now = now.Add(time.Second)
+	_, ok = cache.get("pk1", 100, 0)
+	require.False(t, ok)
+	power, ok = cache.get("pk1", 100, 4242)
+	require.True(t, ok)
+	require.Equal(t, uint64(900), power)
+
+	// re-adding the voting power at the latest height refreshes it
+	cache.add("pk1", 100, 0, 1100)
+	power, ok = cache.get("pk1", 100, 0)
+	require.True(t, ok)
+	require.Equal(t, uint64(1100), power)
+}
+
+func TestPowerCacheSkipsUnboundedBtcHeight(t *testing.T) {
+	cache, err := newPowerCache(10, time.Minute)
+	require.NoError(t, err)
+
+	// the voting power at the unbounded BTC height is never cached, even at a pinned Babylon height
+	cache.add("pk1", math.MaxUint64, 0, 1000)
+	cache.add("pk1", math.MaxUint64, 4242, 1000)
+	_, ok := cache.get("pk1", math.MaxUint64, 0)
+	require.False(t, ok)
+	_, ok = cache.get("pk1", math.MaxUint64, 4242)
+	require.False(t, ok)
+	require.Zero(t, cache.cache.Len())
+}
+
+func TestParamsCache(t *testing.T) {
+	now := time.Unix(1000, 0)
+	cache := newParamsCache(time.Minute)
+	cache.now = func() time.Time { return now }
+
+	fetchCount := 0
+	fetch := func() (*stakingParams, error) {
+		fetchCount++
+		return &stakingParams{kValue: uint64(fetchCount), wValue: 100, covQuorum: 3}, nil
+	}
+
+	// first call fetches the params
+	params, err := cache.get(0, fetch)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), params.kValue)
+
+	// calls within the TTL are served from the cache
+	now = now.Add(59 * time.Second)
+	params, err = cache.get(0, fetch)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), params.kValue)
+	require.Equal(t, 1, fetchCount)
+
+	// params are re-fetched once the TTL expires
+	now = now.Add(time.Second)
+	params, err = cache.get(0, fetch)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), params.kValue)
+
+	// params are re-fetched after invalidation
+	cache.invalidate()
+	params, err = cache.get(0, fetch)
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), params.kValue)
+
+	require.Equal(t, uint64(1), cache.hits.Load())
+	require.Equal(t, uint64(3), cache.misses.Load())
+}
+
+func TestParamsCachePinnedHeight(t *testing.T) {
+	cache := newParamsCache(time.Minute)
+	fetchCount := 0
+	fetch := func() (*stakingParams, error) {
+		fetchCount++
+		return &stakingParams{kValue: uint64(fetchCount)}, nil
+	}
+
+	// the params at a pinned height are cached separately from the latest params
+	params, err := cache.get(0, fetch)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), params.kValue)
+	params, err = cache.get(100, fetch)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), params.kValue)
+	params, err = cache.get(100, fetch)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), params.kValue)
+	params, err = cache.get(0, fetch)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), params.kValue)
+
+	// params are re-fetched for another pinned height, regardless of the TTL
+	params, err = cache.get(101, fetch)
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), params.kValue)
+
+	// and after invalidation
+	cache.invalidate()
+	params, err = cache.get(101, fetch)
+	require.NoError(t, err)
+	require.Equal(t, uint64(4), params.kValue)
+}
+
+func TestParamsCacheFetchError(t *testing.T) {
+	cache := newParamsCache(time.Minute)
+	expectedErr := errors.New("rpc error")
+
+	// errors are returned and not cached
+	_, err := cache.get(0, func() (*stakingParams, error) {
+		return nil, expectedErr
+	})
+	require.ErrorIs(t, err, expectedErr)
+
+	params, err := cache.get(0, func() (*stakingParams, error) {
+		return &stakingParams{kValue: 6}, nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, uint64(6), params.kValue)
+}
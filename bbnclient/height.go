@@ -0,0 +1,29 @@
+package bbnclient
+
+import (
+	"context"
+
+	"github.com/cometbft/cometbft/libs/bytes"
+	rpcclient "github.com/cometbft/cometbft/rpc/client"
+	ctypes "github.com/cometbft/cometbft/rpc/core/types"
+)
+
+// heightPinnedClient is a CometBFT RPC client sending ABCI queries at a pinned Babylon height, unless the query
+// sets its own height
+type heightPinnedClient struct {
+	rpcclient.Client
+	height int64
+}
+
+var _ rpcclient.Client = &heightPinnedClient{}
+
+func (c *heightPinnedClient) ABCIQuery(ctx context.Context, path string, data bytes.HexBytes) (*ctypes.ResultABCIQuery, error) {
+	return c.ABCIQueryWithOptions(ctx, path, data, rpcclient.DefaultABCIQueryOptions)
+}
+
+func (c *heightPinnedClient) ABCIQueryWithOptions(ctx context.Context, path string, data bytes.HexBytes, opts rpcclient.ABCIQueryOptions) (*ctypes.ResultABCIQuery, error) {
+	if opts.Height == 0 {
+		opts.Height = c.height
+	}
+	return c.Client.ABCIQueryWithOptions(ctx, path, data, opts)
+}
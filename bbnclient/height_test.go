@@ -0,0 +1,57 @@
+package bbnclient
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/babylonlabs-io/babylon/client/query"
+	"github.com/cometbft/cometbft/libs/bytes"
+	rpcclient "github.com/cometbft/cometbft/rpc/client"
+	ctypes "github.com/cometbft/cometbft/rpc/core/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeightPinnedClient(t *testing.T) {
+	node := &heightRecordingNode{}
+	client := &heightPinnedClient{Client: node, height: 100}
+
+	// queries at the latest height are pinned
+	_, err := client.ABCIQuery(context.Background(), "/path", nil)
+	require.NoError(t, err)
+	require.Equal(t, int64(100), node.height)
+
+	// queries setting their own height are not
+	_, err = client.ABCIQueryWithOptions(context.Background(), "/path", nil, rpcclient.ABCIQueryOptions{Height: 99})
+	require.NoError(t, err)
+	require.Equal(t, int64(99), node.height)
+}
+
+func TestAtHeight(t *testing.T) {
+	node := &heightRecordingNode{}
+	queryClient, err := query.NewWithClient(node, time.Second)
+	require.NoError(t, err)
+	bbnClient, err := NewBabylonClient(queryClient, DefaultCacheConfig())
+	require.NoError(t, err)
+
+	// the latest height is queried with the unpinned query client
+	require.Same(t, queryClient, bbnClient.atHeight(0))
+
+	// the pinned query client sends its queries at the pinned height
+	pinned := bbnClient.atHeight(100)
+	_, err = pinned.RPCClient.ABCIQuery(context.Background(), "/path", nil)
+	require.NoError(t, err)
+	require.Equal(t, int64(100), node.height)
+	require.Same(t, node, queryClient.RPCClient)
+}
+
+// heightRecordingNode records the height of the last ABCI query
+type heightRecordingNode struct {
+	rpcclient.Client
+	height int64
+}
+
+func (n *heightRecordingNode) ABCIQueryWithOptions(_ context.Context, _ string, _ bytes.HexBytes, opts rpcclient.ABCIQueryOptions) (*ctypes.ResultABCIQuery, error) {
+	n.height = opts.Height
+	return &ctypes.ResultABCIQuery{}, nil
+}
@@ -0,0 +1,257 @@
+package bbnclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cometbft/cometbft/libs/bytes"
+	rpcclient "github.com/cometbft/cometbft/rpc/client"
+	rpchttp "github.com/cometbft/cometbft/rpc/client/http"
+	ctypes "github.com/cometbft/cometbft/rpc/core/types"
+	lru "github.com/hashicorp/golang-lru/v2"
+	"go.uber.org/zap"
+)
+
+const (
+	// endpointBaseCooldown is how long a failing endpoint is skipped after its first failure, doubling with each
+	// consecutive failure up to endpointMaxCooldown
+	endpointBaseCooldown = 5 * time.Second
+	endpointMaxCooldown  = time.Minute
+	// stickyHeightsSize is the number of query heights for which the endpoint that served them is remembered
+	stickyHeightsSize = 1024
+)
+
+// RPCPool is a CometBFT RPC client failing over between Babylon nodes. The Babylon and CosmWasm queries are all sent
+// as ABCI queries, which go to the first healthy endpoint in order of preference and fail over to the next one on
+// error, as does Status. Endpoints are skipped for a cooldown after failing. Other RPC calls are sent to the first
+// endpoint.
+//
+// Queries at a given Babylon height stick to the endpoint that last served that height, so that all the queries
+// made to evaluate a block see the state of the same node.
+type RPCPool struct {
+	// Client is the first endpoint, serving the calls other than ABCI queries and Status
+	rpcclient.Client
+
+	logger *zap.Logger
+	// stickyHeights maps query heights to the endpoint that last served them
+	stickyHeights *lru.Cache[int64, *rpcEndpoint]
+	endpoints     []*rpcEndpoint
+}
+
+var _ rpcclient.Client = &RPCPool{}
+
+// rpcEndpoint is a Babylon node of the pool and its health
+type rpcEndpoint struct {
+	// unhealthyUntil is the time until which the endpoint is only queried if all healthy endpoints failed
+	unhealthyUntil time.Time
+	client         rpcclient.Client
+	// name identifies the endpoint in logs, without the path and query of its address as they may contain API keys
+	name     string
+	failures int
+	mutex    sync.Mutex
+}
+
+//////////////////////////////
+// CONSTRUCTOR
+//////////////////////////////
+
+// NewRPCPool creates a pool of the Babylon nodes at the given RPC addresses, in order of preference
+func NewRPCPool(rpcAddrs []string, timeout time.Duration, logger *zap.Logger) (*RPCPool, error) {
+	clients := make([]rpcclient.Client, len(rpcAddrs))
+	for i, addr := range rpcAddrs {
+		client, err := rpchttp.NewWithTimeout(addr, "/websocket", uint(timeout.Seconds()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Babylon RPC client for %s: %w", endpointName(addr), err)
+		}
+		clients[i] = client
+	}
+	return newRPCPool(rpcAddrs, clients, logger)
+}
+
+//////////////////////////////
+// METHODS
+//////////////////////////////
+
+// Clients returns the RPC clients of the endpoints, in order of preference
+func (p *RPCPool) Clients() []rpcclient.Client {
+	clients := make([]rpcclient.Client, len(p.endpoints))
+	for i, e := range p.endpoints {
+		clients[i] = e.client
+	}
+	return clients
+}
+
+func (p *RPCPool) ABCIQuery(ctx context.Context, path string, data bytes.HexBytes) (*ctypes.ResultABCIQuery, error) {
+	return p.ABCIQueryWithOptions(ctx, path, data, rpcclient.DefaultABCIQueryOptions)
+}
+
+/* ABCIQueryWithOptions sends the query to the endpoints in order of preference until one of them answers
+ *
+ * - queries at a given height are first sent to the endpoint that last served that height
+ * - then to the healthy endpoints in the configured order, then to the unhealthy ones, soonest to recover first
+ * - an endpoint failing to answer is marked unhealthy. queries failing on the node, e.g. for a missing contract
+ *   state, are answered with an error code, and are not failed over
+ * - if all endpoints fail, their errors are returned
+ */
+func (p *RPCPool) ABCIQueryWithOptions(ctx context.Context, path string, data bytes.HexBytes, opts rpcclient.ABCIQueryOptions) (*ctypes.ResultABCIQuery, error) {
+	var res *ctypes.ResultABCIQuery
+	e, err := p.withFailover(ctx, opts.Height, func(c rpcclient.Client) error {
+		var err error
+		res, err = c.ABCIQueryWithOptions(ctx, path, data, opts)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if opts.Height > 0 {
+		p.stickyHeights.Add(opts.Height, e)
+	}
+	return res, nil
+}
+
+// Status returns the status of the first endpoint to answer. Queries at the latest height of the status stick to
+// that endpoint, so that queries pinned to the height are not sent to nodes which haven't reached it yet.
+func (p *RPCPool) Status(ctx context.Context) (*ctypes.ResultStatus, error) {
+	var status *ctypes.ResultStatus
+	e, err := p.withFailover(ctx, 0, func(c rpcclient.Client) error {
+		var err error
+		status, err = c.Status(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	p.stickyHeights.Add(status.SyncInfo.LatestBlockHeight, e)
+	return status, nil
+}
+
+//////////////////////////////
+// INTERNAL
+//////////////////////////////
+
+func newRPCPool(rpcAddrs []string, clients []rpcclient.Client, logger *zap.Logger) (*RPCPool, error) {
+	if len(clients) == 0 {
+		return nil, errors.New("no Babylon endpoints")
+	}
+	stickyHeights, err := lru.New[int64, *rpcEndpoint](stickyHeightsSize)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := &RPCPool{
+		Client:        clients[0],
+		logger:        logger,
+		endpoints:     make([]*rpcEndpoint, len(clients)),
+		stickyHeights: stickyHeights,
+	}
+	names := make(map[string]bool)
+	for i, client := range clients {
+		name := endpointName(rpcAddrs[i])
+		if names[name] {
+			name = fmt.Sprintf("%s#%d", name, i)
+		}
+		names[name] = true
+		pool.endpoints[i] = &rpcEndpoint{client: client, name: name}
+	}
+	return pool, nil
+}
+
+// withFailover calls the endpoints in order of preference for the given height until one of them succeeds, and
+// returns that endpoint
+func (p *RPCPool) withFailover(ctx context.Context, height int64, call func(c rpcclient.Client) error) (*rpcEndpoint, error) {
+	var errs []error
+	for _, e := range p.byPreference(height) {
+		err := call(e.client)
+		if err == nil {
+			p.recordSuccess(e)
+			return e, nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		p.recordFailure(e, err)
+		errs = append(errs, fmt.Errorf("babylon endpoint %s: %w", e.name, err))
+	}
+	return nil, errors.Join(errs...)
+}
+
+// byPreference returns the endpoints in the order they should be queried: the endpoint that last served the height
+// if it is healthy, the healthy endpoints in the configured order, then the unhealthy endpoints, soonest to recover
+// first
+func (p *RPCPool) byPreference(height int64) []*rpcEndpoint {
+	now := time.Now()
+	healthy := make([]*rpcEndpoint, 0, len(p.endpoints))
+	var unhealthy []*rpcEndpoint
+	unhealthyUntil := make(map[*rpcEndpoint]time.Time)
+	for _, e := range p.endpoints {
+		e.mutex.Lock()
+		until := e.unhealthyUntil
+		e.mutex.Unlock()
+		if now.Before(until) {
+			unhealthy = append(unhealthy, e)
+			unhealthyUntil[e] = until
+			continue
+		}
+		healthy = append(healthy, e)
+	}
+	sort.SliceStable(unhealthy, func(i, j int) bool {
+		return unhealthyUntil[unhealthy[i]].Before(unhealthyUntil[unhealthy[j]])
+	})
+
+	if sticky, ok := p.stickyHeights.Get(height); ok && height > 0 {
+		for i, e := range healthy {
+			if e == sticky {
+				healthy = append([]*rpcEndpoint{e}, append(healthy[:i:i], healthy[i+1:]...)...)
+				break
+			}
+		}
+	}
+	return append(healthy, unhealthy...)
+}
+
+// recordFailure marks the endpoint unhealthy for a cooldown doubling with each consecutive failure
+func (p *RPCPool) recordFailure(e *rpcEndpoint, err error) {
+	e.mutex.Lock()
+	e.failures++
+	cooldown := endpointMaxCooldown
+	if e.failures < 32 && endpointBaseCooldown<<(e.failures-1) < endpointMaxCooldown {
+		cooldown = endpointBaseCooldown << (e.failures - 1)
+	}
+	e.unhealthyUntil = time.Now().Add(cooldown)
+	failures := e.failures
+	e.mutex.Unlock()
+
+	p.logger.Warn("Babylon endpoint failed, skipping it",
+		zap.String("endpoint", e.name),
+		zap.Int("consecutive_failures", failures),
+		zap.Duration("cooldown", cooldown),
+		zap.Error(err),
+	)
+}
+
+// recordSuccess marks the endpoint healthy
+func (p *RPCPool) recordSuccess(e *rpcEndpoint) {
+	e.mutex.Lock()
+	recovered := e.failures > 0
+	e.failures = 0
+	e.unhealthyUntil = time.Time{}
+	e.mutex.Unlock()
+
+	if recovered {
+		p.logger.Info("Babylon endpoint recovered", zap.String("endpoint", e.name))
+	}
+}
+
+// endpointName returns the scheme and host of the endpoint address, or the address if it can't be parsed
+func endpointName(addr string) string {
+	u, err := url.Parse(addr)
+	if err != nil || u.Host == "" {
+		return addr
+	}
+	return u.Scheme + "://" + u.Host
+}
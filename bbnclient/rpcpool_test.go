@@ -0,0 +1,153 @@
+package bbnclient
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+	"github.com/cometbft/cometbft/libs/bytes"
+	rpcclient "github.com/cometbft/cometbft/rpc/client"
+	ctypes "github.com/cometbft/cometbft/rpc/core/types"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestRPCPoolFailover(t *testing.T) {
+	primary := &fakeRPCNode{value: []byte("primary")}
+	fallback := &fakeRPCNode{value: []byte("fallback")}
+	pool := newTestRPCPool(t, primary, fallback)
+
+	// the primary endpoint is preferred
+	res, err := pool.ABCIQuery(context.Background(), "/path", nil)
+	require.NoError(t, err)
+	require.Equal(t, []byte("primary"), res.Response.Value)
+
+	// queries fail over to the fallback endpoint while the primary endpoint is down
+	primary.failing.Store(true)
+	res, err = pool.ABCIQuery(context.Background(), "/path", nil)
+	require.NoError(t, err)
+	require.Equal(t, []byte("fallback"), res.Response.Value)
+	require.Equal(t, int32(2), primary.requests.Load())
+
+	// the failed endpoint is skipped during its cooldown
+	_, err = pool.ABCIQuery(context.Background(), "/path", nil)
+	require.NoError(t, err)
+	require.Equal(t, int32(2), primary.requests.Load())
+	require.Equal(t, []*rpcEndpoint{pool.endpoints[1], pool.endpoints[0]}, pool.byPreference(0))
+
+	// and tried again as a last resort
+	fallback.failing.Store(true)
+	_, err = pool.ABCIQuery(context.Background(), "/path", nil)
+	require.ErrorContains(t, err, "babylon endpoint http://fallback:26657: connection refused")
+	require.ErrorContains(t, err, "babylon endpoint http://primary:26657: connection refused")
+	require.Equal(t, 2, pool.endpoints[0].failures)
+
+	// endpoints are healthy again once they succeed
+	primary.failing.Store(false)
+	pool.endpoints[0].unhealthyUntil = pool.endpoints[0].unhealthyUntil.Add(-endpointMaxCooldown)
+	_, err = pool.ABCIQuery(context.Background(), "/path", nil)
+	require.NoError(t, err)
+	require.Equal(t, 0, pool.endpoints[0].failures)
+	require.Equal(t, pool.endpoints[0], pool.byPreference(0)[0])
+}
+
+func TestRPCPoolStickyHeight(t *testing.T) {
+	primary := &fakeRPCNode{value: []byte("primary")}
+	fallback := &fakeRPCNode{value: []byte("fallback")}
+	pool := newTestRPCPool(t, primary, fallback)
+
+	// height 10 is served by the fallback endpoint while the primary endpoint is down
+	primary.failing.Store(true)
+	res, err := pool.ABCIQueryWithOptions(context.Background(), "/path", nil, rpcclient.ABCIQueryOptions{Height: 10})
+	require.NoError(t, err)
+	require.Equal(t, []byte("fallback"), res.Response.Value)
+
+	// once the primary endpoint recovered, queries at height 10 stick to the fallback endpoint
+	primary.failing.Store(false)
+	pool.endpoints[0].unhealthyUntil = pool.endpoints[0].unhealthyUntil.Add(-endpointMaxCooldown)
+	res, err = pool.ABCIQueryWithOptions(context.Background(), "/path", nil, rpcclient.ABCIQueryOptions{Height: 10})
+	require.NoError(t, err)
+	require.Equal(t, []byte("fallback"), res.Response.Value)
+
+	// other heights and latest queries go to the primary endpoint
+	res, err = pool.ABCIQueryWithOptions(context.Background(), "/path", nil, rpcclient.ABCIQueryOptions{Height: 11})
+	require.NoError(t, err)
+	require.Equal(t, []byte("primary"), res.Response.Value)
+	res, err = pool.ABCIQuery(context.Background(), "/path", nil)
+	require.NoError(t, err)
+	require.Equal(t, []byte("primary"), res.Response.Value)
+
+	// the sticky endpoint is not preferred while it is unhealthy
+	fallback.failing.Store(true)
+	res, err = pool.ABCIQueryWithOptions(context.Background(), "/path", nil, rpcclient.ABCIQueryOptions{Height: 10})
+	require.NoError(t, err)
+	require.Equal(t, []byte("primary"), res.Response.Value)
+	require.Equal(t, pool.endpoints[0], pool.byPreference(10)[0])
+}
+
+func TestRPCPoolStatus(t *testing.T) {
+	primary := &fakeRPCNode{value: []byte("primary"), latestHeight: 10}
+	fallback := &fakeRPCNode{value: []byte("fallback"), latestHeight: 12}
+	pool := newTestRPCPool(t, primary, fallback)
+
+	// the status fails over like queries
+	primary.failing.Store(true)
+	status, err := pool.Status(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, int64(12), status.SyncInfo.LatestBlockHeight)
+
+	// queries at the returned height stick to the endpoint that returned it, which has reached the height
+	primary.failing.Store(false)
+	pool.endpoints[0].unhealthyUntil = pool.endpoints[0].unhealthyUntil.Add(-endpointMaxCooldown)
+	res, err := pool.ABCIQueryWithOptions(context.Background(), "/path", nil, rpcclient.ABCIQueryOptions{Height: 12})
+	require.NoError(t, err)
+	require.Equal(t, []byte("fallback"), res.Response.Value)
+}
+
+func TestNewRPCPool(t *testing.T) {
+	_, err := NewRPCPool(nil, 0, zap.NewNop())
+	require.EqualError(t, err, "no Babylon endpoints")
+
+	// endpoint names don't leak the API keys in the address path
+	pool, err := NewRPCPool([]string{"https://rpc.example.com/key1", "https://rpc.example.com/key2"}, 0, zap.NewNop())
+	require.NoError(t, err)
+	require.Equal(t, "https://rpc.example.com", pool.endpoints[0].name)
+	require.Equal(t, "https://rpc.example.com#1", pool.endpoints[1].name)
+	require.Len(t, pool.Clients(), 2)
+}
+
+func newTestRPCPool(t *testing.T, primary, fallback *fakeRPCNode) *RPCPool {
+	pool, err := newRPCPool(
+		[]string{"http://primary:26657", "http://fallback:26657"},
+		[]rpcclient.Client{primary, fallback},
+		zap.NewNop(),
+	)
+	require.NoError(t, err)
+	return pool
+}
+
+// fakeRPCNode answers ABCI queries with a fixed value and its status with a fixed height, or fails if failing is set
+type fakeRPCNode struct {
+	rpcclient.Client
+	value        []byte
+	latestHeight int64
+	requests     atomic.Int32
+	failing      atomic.Bool
+}
+
+func (n *fakeRPCNode) Status(context.Context) (*ctypes.ResultStatus, error) {
+	if n.failing.Load() {
+		return nil, errors.New("connection refused")
+	}
+	return &ctypes.ResultStatus{SyncInfo: ctypes.SyncInfo{LatestBlockHeight: n.latestHeight}}, nil
+}
+
+func (n *fakeRPCNode) ABCIQueryWithOptions(context.Context, string, bytes.HexBytes, rpcclient.ABCIQueryOptions) (*ctypes.ResultABCIQuery, error) {
+	n.requests.Add(1)
+	if n.failing.Load() {
+		return nil, errors.New("connection refused")
+	}
+	return &ctypes.ResultABCIQuery{Response: abci.ResponseQuery{Value: n.value}}, nil
+}
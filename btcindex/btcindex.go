@@ -0,0 +1,432 @@
+package btcindex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/babylonlabs-io/finality-gadget/db"
+	"github.com/babylonlabs-io/finality-gadget/types"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultIndexDepth is how many blocks below the BTC tip a fresh index starts at if no start
+	// height is configured (~2 weeks of blocks). Timestamps before the index are served by the RPC.
+	DefaultIndexDepth = 2016
+	// medianTimeSpan is the number of blocks used to compute the median time past
+	medianTimeSpan = 11
+	// syncBatchSize is the number of headers fetched before they are written to the db
+	syncBatchSize = 500
+)
+
+type IBitcoinClient interface {
+	GetBlockCount() (uint64, error)
+	GetBlockHashByHeight(height uint64) (*chainhash.Hash, error)
+	GetBlockHeaderByHash(blockHash *chainhash.Hash) (*wire.BlockHeader, error)
+	GetBlockHeightByTimestamp(targetTimestamp uint64) (uint64, error)
+	GetBlockTimestampByHeight(height uint64) (uint64, error)
+}
+
+// BtcHeaderIndex maintains a persistent index of BTC headers in the db, so that timestamp to
+// height lookups are answered locally instead of binary searching the chain over RPC.
+// It implements the same interface as the BTC client it wraps.
+type BtcHeaderIndex struct {
+	btcClient IBitcoinClient
+	db        db.IDatabaseHandler
+	logger    *zap.Logger
+
+	// mapping is how timestamps are mapped to BTC heights
+	mapping types.BtcTimestampMapping
+
+	// startHeight is the height a fresh index starts at, 0 to start DefaultIndexDepth below the tip
+	startHeight  uint64
+	pollInterval time.Duration
+	// mutex serializes the syncs of the index. Lookups only read the index and don't take it.
+	mutex sync.Mutex
+}
+
+//////////////////////////////
+// CONSTRUCTOR
+//////////////////////////////
+
+func NewBtcHeaderIndex(
+	btcClient IBitcoinClient,
+	db db.IDatabaseHandler,
+	mapping types.BtcTimestampMapping,
+	startHeight uint64,
+	pollInterval time.Duration,
+	logger *zap.Logger,
+) *BtcHeaderIndex {
+	return &BtcHeaderIndex{
+		btcClient:    btcClient,
+		db:           db,
+		logger:       logger,
+		mapping:      mapping,
+		startHeight:  startHeight,
+		pollInterval: pollInterval,
+	}
+}
+
+//////////////////////////////
+// METHODS
+//////////////////////////////
+
+// Run keeps the index in sync with the BTC chain until the context is cancelled
+func (idx *BtcHeaderIndex) Run(ctx context.Context) {
+	ticker := time.NewTicker(idx.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := idx.Sync(ctx); err != nil {
+			idx.logger.Error("Failed to sync BTC header index", zap.Error(err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+/* Sync indexes all BTC headers up to the current tip
+ *
+ * - if the index is empty, start at the configured start height
+ * - else, check the latest indexed header is still on the BTC chain. if not, walk back to the
+ *   fork point and roll the index back
+ * - fetch the headers above the latest indexed header, check each links to the previous one and
+ *   compute its median time past, and store them in batches
+ * - if a fetched header doesn't link to the previous one, the chain reorged while syncing, so we
+ *   stop and pick it up on the next sync
+ */
+func (idx *BtcHeaderIndex) Sync(ctx context.Context) error {
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+	return idx.sync(ctx)
+}
+
+func (idx *BtcHeaderIndex) GetBlockCount() (uint64, error) {
+	return idx.btcClient.GetBlockCount()
+}
+
+func (idx *BtcHeaderIndex) GetBlockHashByHeight(height uint64) (*chainhash.Hash, error) {
+	return idx.btcClient.GetBlockHashByHeight(height)
+}
+
+func (idx *BtcHeaderIndex) GetBlockHeaderByHash(blockHash *chainhash.Hash) (*wire.BlockHeader, error) {
+	return idx.btcClient.GetBlockHeaderByHash(blockHash)
+}
+
+// GetBlockTimestampByHeight returns the timestamp of the BTC block at the given height, from
+// the index if the height is indexed and from the RPC otherwise
+func (idx *BtcHeaderIndex) GetBlockTimestampByHeight(height uint64) (uint64, error) {
+	header, err := idx.db.GetBtcHeaderByHeight(height)
+	if err != nil {
+		if errors.Is(err, types.ErrBtcHeaderNotFound) {
+			return idx.btcClient.GetBlockTimestampByHeight(height)
+		}
+		return 0, err
+	}
+	return header.Timestamp, nil
+}
+
+// GetBlockHeightByTimestamp maps the target timestamp to a BTC height with the configured mapping. Lookups
+// only read the index, which is synced by Run, so timestamps after the latest indexed header are mapped as
+// if the header was the BTC tip. Returns types.ErrBtcHeaderNotIndexed until the index is first synced.
+func (idx *BtcHeaderIndex) GetBlockHeightByTimestamp(targetTimestamp uint64) (uint64, error) {
+	if idx.mapping == types.BtcTimestampMappingMedianTimePast {
+		return idx.heightByMedianTimePast(targetTimestamp)
+	}
+	return idx.heightByBlockTime(targetTimestamp)
+}
+
+//////////////////////////////
+// INTERNAL
+//////////////////////////////
+
+/* heightByBlockTime returns the height of the last BTC block with a timestamp at or before the
+ * target timestamp, with the same semantics as the BTC client binary search
+ *
+ * - if the index is empty, return types.ErrBtcHeaderNotIndexed so that the lookup is retried
+ * - if the target is before the earliest indexed header, fall back to the RPC
+ * - binary search the indexed headers
+ * - return math.MaxUint64 if the target is after the latest indexed header
+ */
+func (idx *BtcHeaderIndex) heightByBlockTime(targetTimestamp uint64) (uint64, error) {
+	earliest, latest, err := idx.indexRange()
+	if err != nil {
+		return 0, err
+	}
+	if latest == nil {
+		return 0, types.ErrBtcHeaderNotIndexed
+	}
+
+	if targetTimestamp < earliest.Timestamp {
+		idx.logger.Debug("Timestamp is not indexed, querying BTC RPC", zap.Uint64("timestamp", targetTimestamp))
+		return idx.btcClient.GetBlockHeightByTimestamp(targetTimestamp)
+	}
+
+	return searchHeight(earliest.Height, latest.Height, targetTimestamp, func(height uint64) (uint64, error) {
+		header, err := idx.indexedHeader(height)
+		if err != nil {
+			return 0, err
+		}
+		return header.Timestamp, nil
+	})
+}
+
+func (idx *BtcHeaderIndex) sync(ctx context.Context) error {
+	tip, err := idx.btcClient.GetBlockCount()
+	if err != nil {
+		return fmt.Errorf("error fetching BTC tip: %w", err)
+	}
+
+	nextHeight, err := idx.nextHeightToIndex(tip)
+	if err != nil {
+		return err
+	}
+	if nextHeight > tip {
+		return nil
+	}
+
+	idx.logger.Info("Syncing BTC header index", zap.Uint64("from_height", nextHeight), zap.Uint64("to_height", tip))
+
+	// seed the previous header and median time window from the headers below the next height
+	var prev *types.BtcHeader
+	if nextHeight > 0 {
+		prev, err = idx.db.GetBtcHeaderByHeight(nextHeight - 1)
+		if err != nil && !errors.Is(err, types.ErrBtcHeaderNotFound) {
+			return fmt.Errorf("error fetching BTC header %d from db: %w", nextHeight-1, err)
+		}
+	}
+	window, err := idx.timestampWindow(nextHeight)
+	if err != nil {
+		return err
+	}
+
+	batch := make([]*types.BtcHeader, 0, syncBatchSize)
+	for height := nextHeight; height <= tip; height++ {
+		if ctx.Err() != nil {
+			break
+		}
+
+		header, err := idx.fetchHeader(height)
+		if err != nil {
+			return err
+		}
+		if prev != nil && header.PrevHash != prev.Hash {
+			idx.logger.Warn("BTC chain reorged while syncing header index", zap.Uint64("height", height))
+			break
+		}
+
+		window = append(window, header.Timestamp)
+		if len(window) > medianTimeSpan {
+			window = window[1:]
+		}
+		header.MedianTimePast = medianTime(window)
+
+		batch = append(batch, header)
+		prev = header
+		if len(batch) == syncBatchSize {
+			if err := idx.db.InsertBtcHeaders(batch); err != nil {
+				return fmt.Errorf("error storing BTC headers: %w", err)
+			}
+			batch = make([]*types.BtcHeader, 0, syncBatchSize)
+		}
+	}
+
+	if err := idx.db.InsertBtcHeaders(batch); err != nil {
+		return fmt.Errorf("error storing BTC headers: %w", err)
+	}
+	return nil
+}
+
+// nextHeightToIndex returns the height to continue indexing from, rolling back the index if the
+// latest indexed headers are no longer on the BTC chain
+func (idx *BtcHeaderIndex) nextHeightToIndex(tip uint64) (uint64, error) {
+	earliest, latest, err := idx.indexRange()
+	if err != nil {
+		return 0, err
+	}
+
+	// fresh index
+	if latest == nil {
+		if idx.startHeight > 0 {
+			return idx.startHeight, nil
+		}
+		if tip < DefaultIndexDepth {
+			return 0, nil
+		}
+		return tip - DefaultIndexDepth, nil
+	}
+
+	// walk back from the latest indexed header (or the tip if the chain got shorter) until the
+	// indexed header matches the BTC chain
+	height := latest.Height
+	if tip < height {
+		height = tip
+	}
+	for {
+		header, err := idx.db.GetBtcHeaderByHeight(height)
+		if err != nil {
+			return 0, fmt.Errorf("error fetching BTC header %d from db: %w", height, err)
+		}
+		hash, err := idx.btcClient.GetBlockHashByHeight(height)
+		if err != nil {
+			return 0, fmt.Errorf("error fetching BTC block hash %d: %w", height, err)
+		}
+		if hash.String() == header.Hash {
+			break
+		}
+		if height == earliest.Height {
+			// no indexed header is on the BTC chain anymore, re-index from the earliest height
+			idx.logger.Warn("BTC header index diverged from the BTC chain, re-indexing", zap.Uint64("from_height", earliest.Height))
+			if err := idx.rollback(latest.Height, earliest.Height, true); err != nil {
+				return 0, err
+			}
+			return earliest.Height, nil
+		}
+		height--
+	}
+
+	if height < latest.Height {
+		if err := idx.rollback(latest.Height, height, false); err != nil {
+			return 0, err
+		}
+	}
+	return height + 1, nil
+}
+
+func (idx *BtcHeaderIndex) rollback(latestHeight, height uint64, all bool) error {
+	idx.logger.Warn("BTC reorg detected, rolling back header index",
+		zap.Uint64("old_tip_height", latestHeight),
+		zap.Uint64("fork_height", height),
+	)
+	rollbackHeight := height
+	if all {
+		if height == 0 {
+			// height 0 is never reorged, but guard against underflow
+			return fmt.Errorf("BTC header index diverged at genesis")
+		}
+		rollbackHeight = height - 1
+	}
+	if err := idx.db.RollbackBtcHeadersToHeight(rollbackHeight); err != nil {
+		return fmt.Errorf("error rolling back BTC header index: %w", err)
+	}
+	return nil
+}
+
+func (idx *BtcHeaderIndex) indexRange() (*types.BtcHeader, *types.BtcHeader, error) {
+	earliest, err := idx.db.QueryEarliestBtcHeader()
+	if err != nil {
+		return nil, nil, fmt.Errorf("error fetching earliest BTC header from db: %w", err)
+	}
+	latest, err := idx.db.QueryLatestBtcHeader()
+	if err != nil {
+		return nil, nil, fmt.Errorf("error fetching latest BTC header from db: %w", err)
+	}
+	return earliest, latest, nil
+}
+
+// indexedHeader returns the indexed header at the given height. As lookups don't lock the index, the header
+// may have been rolled back by a concurrent sync, in which case types.ErrBtcHeaderNotIndexed is returned.
+func (idx *BtcHeaderIndex) indexedHeader(height uint64) (*types.BtcHeader, error) {
+	header, err := idx.db.GetBtcHeaderByHeight(height)
+	if errors.Is(err, types.ErrBtcHeaderNotFound) {
+		return nil, fmt.Errorf("%w: BTC header %d was rolled back", types.ErrBtcHeaderNotIndexed, height)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching BTC header %d from db: %w", height, err)
+	}
+	return header, nil
+}
+
+func (idx *BtcHeaderIndex) fetchHeader(height uint64) (*types.BtcHeader, error) {
+	hash, err := idx.btcClient.GetBlockHashByHeight(height)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching BTC block hash %d: %w", height, err)
+	}
+	header, err := idx.btcClient.GetBlockHeaderByHash(hash)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching BTC block header %d: %w", height, err)
+	}
+	timestamp := header.Timestamp.Unix()
+	if timestamp < 0 {
+		return nil, fmt.Errorf("negative timestamp encountered: %d", timestamp)
+	}
+	return &types.BtcHeader{
+		Hash:      hash.String(),
+		PrevHash:  header.PrevBlock.String(),
+		Height:    height,
+		Timestamp: uint64(timestamp),
+	}, nil
+}
+
+// timestampWindow returns the timestamps of the (up to) 10 blocks below the given height, used
+// to compute the median time past of the block at that height
+func (idx *BtcHeaderIndex) timestampWindow(height uint64) ([]uint64, error) {
+	from := uint64(0)
+	if height > medianTimeSpan-1 {
+		from = height - (medianTimeSpan - 1)
+	}
+	window := make([]uint64, 0, medianTimeSpan)
+	for h := from; h < height; h++ {
+		timestamp, err := idx.GetBlockTimestampByHeight(h)
+		if err != nil {
+			return nil, fmt.Errorf("error fetching BTC block timestamp %d: %w", h, err)
+		}
+		window = append(window, timestamp)
+	}
+	return window, nil
+}
+
+// searchHeight binary searches [lowerBound, upperBound] for the last height with a time at or
+// before the target, where the time is given by `timeAt`, like the BTC client binary search.
+// Returns math.MaxUint64 if the target is after the times probed.
+func searchHeight(
+	lowerBound, upperBound, target uint64,
+	timeAt func(height uint64) (uint64, error),
+) (uint64, error) {
+	maxHeight := upperBound
+	for lowerBound <= upperBound {
+		midHeight := lowerBound + (upperBound-lowerBound)/2
+
+		headerTime, err := timeAt(midHeight)
+		if err != nil {
+			return 0, err
+		}
+
+		if headerTime < target {
+			lowerBound = midHeight + 1
+		} else if headerTime > target {
+			if midHeight == 0 {
+				break
+			}
+			upperBound = midHeight - 1
+		} else {
+			return midHeight, nil
+		}
+	}
+
+	// timestamp is in the future (not in the most-work fully-validated chain)
+	// we return the max uint64 to indicate this
+	if lowerBound > maxHeight {
+		return math.MaxUint64, nil
+	}
+
+	return lowerBound - 1, nil
+}
+
+// medianTime returns the median of the given timestamps, as computed by bitcoin core
+func medianTime(timestamps []uint64) uint64 {
+	sorted := make([]uint64, len(timestamps))
+	copy(sorted, timestamps)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[len(sorted)/2]
+}
@@ -0,0 +1,301 @@
+package btcindex
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/babylonlabs-io/finality-gadget/db"
+	"github.com/babylonlabs-io/finality-gadget/types"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestSyncAndLookup(t *testing.T) {
+	chain := newFakeBtcChain(t, monotonicTimestamps(50))
+	idx := newTestIndex(t, chain, types.BtcTimestampMappingBlockTime, 10)
+
+	err := idx.Sync(context.Background())
+	require.NoError(t, err)
+
+	// verify headers are indexed from the start height with their median time past
+	earliest, err := idx.db.QueryEarliestBtcHeader()
+	require.NoError(t, err)
+	require.Equal(t, uint64(10), earliest.Height)
+	for height := uint64(10); height < 50; height++ {
+		header, err := idx.db.GetBtcHeaderByHeight(height)
+		require.NoError(t, err)
+		require.Equal(t, chain.headers[height].BlockHash().String(), header.Hash)
+		require.Equal(t, chain.timestamps[height], header.Timestamp)
+		require.Equal(t, chain.medianTimePast(height), header.MedianTimePast)
+	}
+
+	// lookups within the index are served locally
+	chain.calls = 0
+	height, err := idx.GetBlockHeightByTimestamp(chain.timestamps[20])
+	require.NoError(t, err)
+	require.Equal(t, uint64(20), height)
+	height, err = idx.GetBlockHeightByTimestamp(chain.timestamps[20] + 1)
+	require.NoError(t, err)
+	require.Equal(t, uint64(20), height)
+	require.Zero(t, chain.calls)
+
+	// lookups after the latest indexed header return math.MaxUint64 without querying the tip
+	height, err = idx.GetBlockHeightByTimestamp(chain.timestamps[49] + 1)
+	require.NoError(t, err)
+	require.Equal(t, uint64(math.MaxUint64), height)
+	require.Zero(t, chain.calls)
+
+	// lookups before the index fall back to the RPC
+	height, err = idx.GetBlockHeightByTimestamp(chain.timestamps[5])
+	require.NoError(t, err)
+	require.Equal(t, uint64(5), height)
+	require.Equal(t, 1, chain.fallbackCalls)
+
+	// timestamps by height are served from the index if indexed
+	timestamp, err := idx.GetBlockTimestampByHeight(30)
+	require.NoError(t, err)
+	require.Equal(t, chain.timestamps[30], timestamp)
+}
+
+func TestLookupMatchesRPCBinarySearch(t *testing.T) {
+	// BTC block timestamps are only loosely ordered, so include some going backwards
+	timestamps := monotonicTimestamps(100)
+	timestamps[40] = timestamps[38] - 1
+	timestamps[41] = timestamps[39] + 1
+	timestamps[70] = timestamps[66]
+
+	// with the same search range, the index returns exactly what the RPC binary search returns
+	chain := newFakeBtcChain(t, timestamps)
+	idx := newTestIndex(t, chain, types.BtcTimestampMappingBlockTime, 0)
+	err := idx.Sync(context.Background())
+	require.NoError(t, err)
+
+	for target := timestamps[0]; target <= timestamps[99]+10; target += 7 {
+		expected, err := chain.GetBlockHeightByTimestamp(target)
+		require.NoError(t, err)
+		height, err := idx.GetBlockHeightByTimestamp(target)
+		require.NoError(t, err)
+		require.Equal(t, expected, height, "timestamp %d", target)
+	}
+}
+
+func TestLookupDoesNotSync(t *testing.T) {
+	chain := newFakeBtcChain(t, monotonicTimestamps(20))
+	idx := newTestIndex(t, chain, types.BtcTimestampMappingBlockTime, 0)
+
+	// lookups on an empty index are retried once it is synced
+	for _, mapping := range []types.BtcTimestampMapping{types.BtcTimestampMappingBlockTime, types.BtcTimestampMappingMedianTimePast} {
+		idx.mapping = mapping
+		_, err := idx.GetBlockHeightByTimestamp(chain.timestamps[15])
+		require.ErrorIs(t, err, types.ErrBtcHeaderNotIndexed)
+	}
+	require.Zero(t, chain.calls)
+	idx.mapping = types.BtcTimestampMappingBlockTime
+
+	require.NoError(t, idx.Sync(context.Background()))
+	height, err := idx.GetBlockHeightByTimestamp(chain.timestamps[15])
+	require.NoError(t, err)
+	require.Equal(t, uint64(15), height)
+
+	// new blocks are after the tip until the index is synced
+	chain.extend(t, 5)
+	chain.calls = 0
+	height, err = idx.GetBlockHeightByTimestamp(chain.timestamps[22])
+	require.NoError(t, err)
+	require.Equal(t, uint64(math.MaxUint64), height)
+	require.Zero(t, chain.calls)
+	latest, err := idx.db.QueryLatestBtcHeader()
+	require.NoError(t, err)
+	require.Equal(t, uint64(19), latest.Height)
+
+	require.NoError(t, idx.Sync(context.Background()))
+	height, err = idx.GetBlockHeightByTimestamp(chain.timestamps[22])
+	require.NoError(t, err)
+	require.Equal(t, uint64(22), height)
+	latest, err = idx.db.QueryLatestBtcHeader()
+	require.NoError(t, err)
+	require.Equal(t, uint64(24), latest.Height)
+	require.Equal(t, chain.medianTimePast(24), latest.MedianTimePast)
+}
+
+func TestSyncHandlesReorg(t *testing.T) {
+	testCases := []struct {
+		name       string
+		forkHeight uint64
+		newLength  int
+	}{
+		{name: "reorg of the latest block", forkHeight: 28, newLength: 30},
+		{name: "reorg to a longer chain", forkHeight: 25, newLength: 33},
+		{name: "reorg to a shorter chain", forkHeight: 25, newLength: 28},
+		{name: "reorg of all indexed blocks", forkHeight: 5, newLength: 30},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			chain := newFakeBtcChain(t, monotonicTimestamps(30))
+			idx := newTestIndex(t, chain, types.BtcTimestampMappingBlockTime, 10)
+			err := idx.Sync(context.Background())
+			require.NoError(t, err)
+
+			chain.reorg(t, tc.forkHeight, tc.newLength)
+			err = idx.Sync(context.Background())
+			require.NoError(t, err)
+
+			earliest, err := idx.db.QueryEarliestBtcHeader()
+			require.NoError(t, err)
+			require.Equal(t, uint64(10), earliest.Height)
+			latest, err := idx.db.QueryLatestBtcHeader()
+			require.NoError(t, err)
+			require.Equal(t, uint64(tc.newLength-1), latest.Height)
+			for height := uint64(10); height < uint64(tc.newLength); height++ {
+				header, err := idx.db.GetBtcHeaderByHeight(height)
+				require.NoError(t, err)
+				require.Equal(t, chain.headers[height].BlockHash().String(), header.Hash)
+				require.Equal(t, chain.medianTimePast(height), header.MedianTimePast)
+			}
+		})
+	}
+}
+
+func TestMedianTime(t *testing.T) {
+	require.Equal(t, uint64(5), medianTime([]uint64{5}))
+	require.Equal(t, uint64(2), medianTime([]uint64{1, 3, 2}))
+	// for an even number of timestamps, bitcoin core takes the upper median
+	require.Equal(t, uint64(3), medianTime([]uint64{4, 1, 3, 2}))
+	require.Equal(t, uint64(6), medianTime([]uint64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}))
+}
+
+// fakeBtcChain is an in-memory BTC chain implementing IBitcoinClient
+type fakeBtcChain struct {
+	headers    []*wire.BlockHeader
+	timestamps []uint64
+	// calls counts the RPC calls to the chain, fallbackCalls the timestamp lookups
+	calls         int
+	fallbackCalls int
+	// nonce makes headers of different forks hash differently
+	nonce uint32
+}
+
+func newFakeBtcChain(t *testing.T, timestamps []uint64) *fakeBtcChain {
+	chain := &fakeBtcChain{}
+	for _, timestamp := range timestamps {
+		chain.append(t, timestamp)
+	}
+	return chain
+}
+
+func (c *fakeBtcChain) append(t *testing.T, timestamp uint64) {
+	var prevHash chainhash.Hash
+	if len(c.headers) > 0 {
+		prevHash = c.headers[len(c.headers)-1].BlockHash()
+	}
+	require.LessOrEqual(t, timestamp, uint64(math.MaxInt64))
+	c.headers = append(c.headers, &wire.BlockHeader{
+		PrevBlock: prevHash,
+		Timestamp: time.Unix(int64(timestamp), 0),
+		Nonce:     c.nonce,
+	})
+	c.timestamps = append(c.timestamps, timestamp)
+}
+
+func (c *fakeBtcChain) extend(t *testing.T, n int) {
+	for i := 0; i < n; i++ {
+		c.append(t, c.timestamps[len(c.timestamps)-1]+600)
+	}
+}
+
+// reorg replaces all blocks above forkHeight with a new fork, so the chain has newLength blocks
+func (c *fakeBtcChain) reorg(t *testing.T, forkHeight uint64, newLength int) {
+	c.headers = c.headers[:forkHeight+1]
+	c.timestamps = c.timestamps[:forkHeight+1]
+	c.nonce++
+	c.extend(t, newLength-len(c.headers))
+}
+
+func (c *fakeBtcChain) medianTimePast(height uint64) uint64 {
+	from := 0
+	if int(height) >= medianTimeSpan {
+		from = int(height) - medianTimeSpan + 1
+	}
+	return medianTime(c.timestamps[from : height+1])
+}
+
+func (c *fakeBtcChain) GetBlockCount() (uint64, error) {
+	c.calls++
+	return uint64(len(c.headers) - 1), nil
+}
+
+func (c *fakeBtcChain) GetBlockHashByHeight(height uint64) (*chainhash.Hash, error) {
+	c.calls++
+	if height >= uint64(len(c.headers)) {
+		return nil, fmt.Errorf("block height %d out of range", height)
+	}
+	hash := c.headers[height].BlockHash()
+	return &hash, nil
+}
+
+func (c *fakeBtcChain) GetBlockHeaderByHash(blockHash *chainhash.Hash) (*wire.BlockHeader, error) {
+	c.calls++
+	for _, header := range c.headers {
+		if header.BlockHash() == *blockHash {
+			return header, nil
+		}
+	}
+	return nil, fmt.Errorf("block %s not found", blockHash)
+}
+
+// GetBlockHeightByTimestamp mirrors the binary search of the BTC client
+func (c *fakeBtcChain) GetBlockHeightByTimestamp(targetTimestamp uint64) (uint64, error) {
+	c.fallbackCalls++
+	lowerBound := uint64(0)
+	upperBound := uint64(len(c.headers) - 1)
+	for lowerBound <= upperBound {
+		midHeight := (lowerBound + upperBound) / 2
+		blockTimestamp := c.timestamps[midHeight]
+		if blockTimestamp < targetTimestamp {
+			lowerBound = midHeight + 1
+		} else if blockTimestamp > targetTimestamp {
+			upperBound = midHeight - 1
+		} else {
+			return midHeight, nil
+		}
+	}
+	if lowerBound > uint64(len(c.headers)-1) {
+		return math.MaxUint64, nil
+	}
+	return lowerBound - 1, nil
+}
+
+func (c *fakeBtcChain) GetBlockTimestampByHeight(height uint64) (uint64, error) {
+	c.calls++
+	if height >= uint64(len(c.timestamps)) {
+		return 0, fmt.Errorf("block height %d out of range", height)
+	}
+	return c.timestamps[height], nil
+}
+
+func monotonicTimestamps(n int) []uint64 {
+	timestamps := make([]uint64, n)
+	for i := range timestamps {
+		timestamps[i] = 1718839311 + uint64(i)*600
+	}
+	return timestamps
+}
+
+func newTestIndex(t *testing.T, chain *fakeBtcChain, mapping types.BtcTimestampMapping, startHeight uint64) *BtcHeaderIndex {
+	logger := zap.NewNop()
+	handler, err := db.NewBBoltHandler(filepath.Join(t.TempDir(), "test.db"), logger)
+	require.NoError(t, err)
+	t.Cleanup(func() { handler.Close() })
+	require.NoError(t, handler.CreateInitialSchema())
+
+	return NewBtcHeaderIndex(chain, handler, mapping, startHeight, time.Second, logger)
+}
+
+var _ IBitcoinClient = &fakeBtcChain{}
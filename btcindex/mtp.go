@@ -0,0 +1,106 @@
+package btcindex
+
+import (
+	"fmt"
+
+	"github.com/babylonlabs-io/finality-gadget/types"
+	"go.uber.org/zap"
+)
+
+/* heightByMedianTimePast returns the height of the last BTC block with a median time past at or
+ * before the target timestamp
+ *
+ * The median time past never decreases with height, so the mapping is monotonic: a later timestamp
+ * never maps to a lower height. The height is also final once the BTC chain has a block with a
+ * median time past after the target, as new blocks can't change it (barring a deep BTC reorg).
+ * The median time past lags ~1 hour behind the block timestamps, so recent timestamps take longer
+ * to map to a final height than with the block timestamp mapping.
+ *
+ * - if the index is empty, return types.ErrBtcHeaderNotIndexed so that the lookup is retried
+ * - return types.ErrBtcHeightNotFinal if the target is at or after the median time past of the latest
+ *   indexed header, as the height is not final yet
+ * - if the target is before the earliest indexed header, binary search the blocks below the index
+ *   over RPC
+ * - else, binary search the indexed headers
+ */
+func (idx *BtcHeaderIndex) heightByMedianTimePast(targetTimestamp uint64) (uint64, error) {
+	earliest, latest, err := idx.indexRange()
+	if err != nil {
+		return 0, err
+	}
+	if latest == nil {
+		return 0, types.ErrBtcHeaderNotIndexed
+	}
+
+	if targetTimestamp >= latest.MedianTimePast {
+		return 0, fmt.Errorf("%w: timestamp %d is at or after the median time past %d of BTC block %d",
+			types.ErrBtcHeightNotFinal, targetTimestamp, latest.MedianTimePast, latest.Height)
+	}
+	if targetTimestamp >= earliest.MedianTimePast {
+		return searchMedianTimePast(earliest.Height, latest.Height, targetTimestamp, idx.indexedMedianTimePast)
+	}
+	if earliest.Height == 0 {
+		return 0, fmt.Errorf("timestamp %d is before the median time past of the BTC genesis block", targetTimestamp)
+	}
+
+	idx.logger.Debug("Timestamp is not indexed, querying BTC RPC", zap.Uint64("timestamp", targetTimestamp))
+	return searchMedianTimePast(0, earliest.Height-1, targetTimestamp, idx.medianTimePastAt)
+}
+
+// indexedMedianTimePast returns the median time past of an indexed header
+func (idx *BtcHeaderIndex) indexedMedianTimePast(height uint64) (uint64, error) {
+	header, err := idx.indexedHeader(height)
+	if err != nil {
+		return 0, err
+	}
+	return header.MedianTimePast, nil
+}
+
+// medianTimePastAt computes the median time past of the block at the given height from the block
+// timestamps, read from the index if indexed and from the RPC otherwise
+func (idx *BtcHeaderIndex) medianTimePastAt(height uint64) (uint64, error) {
+	window, err := idx.timestampWindow(height)
+	if err != nil {
+		return 0, err
+	}
+	timestamp, err := idx.GetBlockTimestampByHeight(height)
+	if err != nil {
+		return 0, fmt.Errorf("error fetching BTC block timestamp %d: %w", height, err)
+	}
+	return medianTime(append(window, timestamp)), nil
+}
+
+// searchMedianTimePast binary searches [lowerBound, upperBound] for the last height with a median
+// time past at or before the target, where the median time past is given by `medianTimePastAt`.
+// Unlike searchHeight, it relies on the median time past never decreasing with height, so it
+// returns the last such height even if several blocks have the same median time past.
+func searchMedianTimePast(
+	lowerBound, upperBound, target uint64,
+	medianTimePastAt func(height uint64) (uint64, error),
+) (uint64, error) {
+	lowerTime, err := medianTimePastAt(lowerBound)
+	if err != nil {
+		return 0, err
+	}
+	if lowerTime > target {
+		return 0, fmt.Errorf("timestamp %d is before the median time past of BTC block %d", target, lowerBound)
+	}
+
+	// invariant: the median time past at lowerBound is at or before the target
+	for lowerBound < upperBound {
+		midHeight := lowerBound + (upperBound-lowerBound+1)/2
+
+		midTime, err := medianTimePastAt(midHeight)
+		if err != nil {
+			return 0, err
+		}
+
+		if midTime <= target {
+			lowerBound = midHeight
+		} else {
+			upperBound = midHeight - 1
+		}
+	}
+
+	return lowerBound, nil
+}
@@ -0,0 +1,233 @@
+package btcindex
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/babylonlabs-io/finality-gadget/types"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	numTestChains   = 10
+	testChainLength = 200
+)
+
+// TestMedianTimePastMappingConsistency checks on synthetic chains with non-monotonic block
+// timestamps that the median time past mapping matches a linear scan of the chain, is monotonic,
+// and doesn't depend on which heights are indexed
+func TestMedianTimePastMappingConsistency(t *testing.T) {
+	for seed := int64(1); seed <= numTestChains; seed++ {
+		r := rand.New(rand.NewSource(seed))
+		chain := newFakeBtcChain(t, randomBtcTimestamps(r, testChainLength))
+		targets := testTargets(chain)
+
+		// index from genesis, and from the middle of the chain so that early targets are searched over RPC
+		for _, startHeight := range []uint64{0, testChainLength / 2} {
+			idx := newTestIndex(t, chain, types.BtcTimestampMappingMedianTimePast, startHeight)
+			err := idx.Sync(context.Background())
+			require.NoError(t, err)
+
+			prevHeight := uint64(0)
+			for _, target := range targets {
+				height, err := idx.GetBlockHeightByTimestamp(target)
+				expected, expectedErr := expectedMedianTimePastHeight(chain, target)
+				if expectedErr != nil {
+					require.Error(t, err, "seed %d, timestamp %d", seed, target)
+					if errors.Is(expectedErr, types.ErrBtcHeightNotFinal) {
+						require.ErrorIs(t, err, types.ErrBtcHeightNotFinal, "seed %d, timestamp %d", seed, target)
+					}
+					continue
+				}
+				require.NoError(t, err)
+				require.Equal(t, expected, height, "seed %d, start height %d, timestamp %d", seed, startHeight, target)
+				require.GreaterOrEqual(t, height, prevHeight, "mapping is not monotonic at timestamp %d", target)
+				prevHeight = height
+			}
+		}
+	}
+}
+
+// TestMedianTimePastMappingIsFinal checks that a timestamp mapped to a height keeps mapping to it
+// as new blocks are added to the chain
+func TestMedianTimePastMappingIsFinal(t *testing.T) {
+	for seed := int64(1); seed <= numTestChains; seed++ {
+		r := rand.New(rand.NewSource(seed))
+		timestamps := randomBtcTimestamps(r, testChainLength)
+		chain := newFakeBtcChain(t, timestamps[:testChainLength/2])
+		idx := newTestIndex(t, chain, types.BtcTimestampMappingMedianTimePast, 0)
+		require.NoError(t, idx.Sync(context.Background()))
+
+		targets := testTargets(newFakeBtcChain(t, timestamps))
+		mapped := make(map[uint64]uint64)
+		for _, target := range targets {
+			height, err := idx.GetBlockHeightByTimestamp(target)
+			if err == nil {
+				mapped[target] = height
+			}
+		}
+
+		for _, timestamp := range timestamps[testChainLength/2:] {
+			chain.append(t, timestamp)
+		}
+		require.NoError(t, idx.Sync(context.Background()))
+		for target, prevHeight := range mapped {
+			height, err := idx.GetBlockHeightByTimestamp(target)
+			require.NoError(t, err)
+			require.Equal(t, prevHeight, height, "seed %d, timestamp %d", seed, target)
+		}
+	}
+}
+
+// TestBlockTimeMappingIsNotFinal documents why the median time past mapping exists: on the same
+// chains, the block timestamp mapping maps a timestamp to a different height as new blocks are
+// added, since the binary search probes different blocks with non-monotonic timestamps
+func TestBlockTimeMappingIsNotFinal(t *testing.T) {
+	foundChange := false
+	for seed := int64(1); seed <= numTestChains && !foundChange; seed++ {
+		r := rand.New(rand.NewSource(seed))
+		timestamps := randomBtcTimestamps(r, testChainLength)
+		chain := newFakeBtcChain(t, timestamps[:testChainLength/2])
+		idx := newTestIndex(t, chain, types.BtcTimestampMappingBlockTime, 0)
+		require.NoError(t, idx.Sync(context.Background()))
+
+		mapped := make(map[uint64]uint64)
+		for _, target := range testTargets(chain) {
+			// the BTC client binary search doesn't support timestamps before the genesis block
+			if target < chain.timestamps[0] {
+				continue
+			}
+			height, err := idx.GetBlockHeightByTimestamp(target)
+			require.NoError(t, err)
+			mapped[target] = height
+		}
+
+		for _, timestamp := range timestamps[testChainLength/2:] {
+			chain.append(t, timestamp)
+		}
+		require.NoError(t, idx.Sync(context.Background()))
+		for target, prevHeight := range mapped {
+			height, err := idx.GetBlockHeightByTimestamp(target)
+			require.NoError(t, err)
+			if prevHeight != math.MaxUint64 && height != prevHeight {
+				foundChange = true
+				break
+			}
+		}
+	}
+	require.True(t, foundChange)
+}
+
+func TestMedianTimePastMappingNearTip(t *testing.T) {
+	chain := newFakeBtcChain(t, monotonicTimestamps(30))
+	idx := newTestIndex(t, chain, types.BtcTimestampMappingMedianTimePast, 0)
+	require.NoError(t, idx.Sync(context.Background()))
+
+	// a timestamp at or after the median time past of the tip is not mapped to a height until a block with a
+	// later median time past is indexed
+	tipMedianTimePast := chain.medianTimePast(29)
+	for _, target := range []uint64{tipMedianTimePast, chain.timestamps[29], chain.timestamps[29] + 3600} {
+		height, err := idx.GetBlockHeightByTimestamp(target)
+		require.ErrorIs(t, err, types.ErrBtcHeightNotFinal)
+		require.Zero(t, height)
+	}
+	height, err := idx.GetBlockHeightByTimestamp(tipMedianTimePast - 1)
+	require.NoError(t, err)
+	require.Equal(t, uint64(28), height)
+
+	chain.extend(t, 1)
+	require.NoError(t, idx.Sync(context.Background()))
+	height, err = idx.GetBlockHeightByTimestamp(tipMedianTimePast)
+	require.NoError(t, err)
+	require.Equal(t, uint64(29), height)
+}
+
+func TestSearchMedianTimePast(t *testing.T) {
+	// median times past with repeated values
+	times := []uint64{10, 20, 20, 20, 30, 40, 40}
+	timeAt := func(height uint64) (uint64, error) { return times[height], nil }
+
+	testCases := []struct {
+		target    uint64
+		expected  uint64
+		expectErr bool
+	}{
+		{target: 9, expectErr: true},
+		{target: 10, expected: 0},
+		{target: 19, expected: 0},
+		{target: 20, expected: 3},
+		{target: 25, expected: 3},
+		{target: 30, expected: 4},
+		{target: 40, expected: 6},
+		{target: 100, expected: 6},
+	}
+	for _, tc := range testCases {
+		height, err := searchMedianTimePast(0, uint64(len(times)-1), tc.target, timeAt)
+		if tc.expectErr {
+			require.Error(t, err)
+			continue
+		}
+		require.NoError(t, err)
+		require.Equal(t, tc.expected, height, "target %d", tc.target)
+	}
+}
+
+// randomBtcTimestamps generates n BTC block timestamps that are not monotonic, but follow the
+// consensus rule that each timestamp is after the median time past of the previous block
+func randomBtcTimestamps(r *rand.Rand, n int) []uint64 {
+	timestamps := make([]uint64, 0, n)
+	for height := 0; height < n; height++ {
+		// blocks are ~10 minutes apart, and miners may set timestamps up to 2 hours off
+		timestamp := uint64(1718839311 + height*600 + r.Intn(4*3600) - 2*3600)
+		// repeat timestamps from time to time, to get repeated median times past
+		if height > 0 && r.Intn(5) == 0 {
+			timestamp = timestamps[height-1]
+		}
+		if height > 0 {
+			from := 0
+			if height > medianTimeSpan {
+				from = height - medianTimeSpan
+			}
+			if prevMedianTimePast := medianTime(timestamps[from:height]); timestamp <= prevMedianTimePast {
+				timestamp = prevMedianTimePast + 1 + uint64(r.Intn(600))
+			}
+		}
+		timestamps = append(timestamps, timestamp)
+	}
+	return timestamps
+}
+
+// testTargets returns sorted timestamps around all block timestamps and median times past of the chain
+func testTargets(chain *fakeBtcChain) []uint64 {
+	targets := make([]uint64, 0, 6*len(chain.timestamps))
+	for height, timestamp := range chain.timestamps {
+		medianTimePast := chain.medianTimePast(uint64(height))
+		targets = append(targets, timestamp-1, timestamp, timestamp+1, medianTimePast-1, medianTimePast, medianTimePast+1)
+	}
+	sort.Slice(targets, func(i, j int) bool { return targets[i] < targets[j] })
+	return targets
+}
+
+// expectedMedianTimePastHeight scans the chain for the last block with a median time past at or
+// before the target. Returns types.ErrBtcHeightNotFinal if the target is at or after the median time
+// past of the tip, and an error if it is before the genesis block.
+func expectedMedianTimePastHeight(chain *fakeBtcChain, target uint64) (uint64, error) {
+	tip := uint64(len(chain.timestamps) - 1)
+	if chain.medianTimePast(tip) <= target {
+		return 0, types.ErrBtcHeightNotFinal
+	}
+	if chain.medianTimePast(0) > target {
+		return 0, errors.New("timestamp is before the genesis block")
+	}
+	height := uint64(0)
+	for h := uint64(0); h <= tip; h++ {
+		if chain.medianTimePast(h) <= target {
+			height = h
+		}
+	}
+	return height, nil
+}
@@ -120,13 +120,195 @@ func (c *FinalityGadgetGrpcClient) QueryLatestFinalizedBlock() (*types.Block, er
 		return nil, err
 	}
 
-	return &types.Block{
-		BlockHash:      res.Block.BlockHash,
-		BlockHeight:    res.Block.BlockHeight,
-		BlockTimestamp: res.Block.BlockTimestamp,
+	return fromBlockInfo(res.Block), nil
+}
+
+func (c *FinalityGadgetGrpcClient) QueryBlockByHeight(height uint64) (*types.Block, error) {
+	req := &proto.QueryBlockByHeightRequest{
+		BlockHeight: height,
+	}
+
+	res, err := c.client.QueryBlockByHeight(context.Background(), req)
+	if err != nil {
+		return nil, err
+	}
+
+	return fromBlockInfo(res.Block), nil
+}
+
+func (c *FinalityGadgetGrpcClient) QueryBlockFinalityEvidence(height uint64) (*types.FinalityEvidence, error) {
+	req := &proto.QueryBlockFinalityEvidenceRequest{
+		BlockHeight: height,
+	}
+
+	res, err := c.client.QueryBlockFinalityEvidence(context.Background(), req)
+	if err != nil {
+		return nil, err
+	}
+
+	voters := make([]*types.VoterPower, 0, len(res.Evidence.Voters))
+	for _, voter := range res.Evidence.Voters {
+		voters = append(voters, &types.VoterPower{
+			FpBtcPkHex: voter.FpBtcPkHex,
+			Power:      voter.Power,
+		})
+	}
+
+	return &types.FinalityEvidence{
+		BlockHash:     res.Evidence.BlockHash,
+		BlockHeight:   res.Evidence.BlockHeight,
+		BtcHeight:     res.Evidence.BtcHeight,
+		BabylonHeight: res.Evidence.BabylonHeight,
+		TotalPower:    res.Evidence.TotalPower,
+		VotedPower:    res.Evidence.VotedPower,
+		Voters:        voters,
+		QuorumThreshold: types.QuorumThreshold{
+			Numerator:   res.Evidence.QuorumNumerator,
+			Denominator: res.Evidence.QuorumDenominator,
+		},
+	}, nil
+}
+
+// FinalizedBlocksSubscription receives the blocks streamed by SubscribeFinalizedBlocks
+type FinalizedBlocksSubscription struct {
+	stream proto.FinalityGadget_SubscribeFinalizedBlocksClient
+}
+
+// Recv blocks until the next finalized block is received. It returns an error once the stream ends,
+// e.g. with code RESOURCE_EXHAUSTED if the client fell behind, in which case the client should
+// resubscribe from the height after the last received block
+func (s *FinalizedBlocksSubscription) Recv() (*types.Block, error) {
+	res, err := s.stream.Recv()
+	if err != nil {
+		return nil, err
+	}
+	return fromBlockInfo(res.Block), nil
+}
+
+// SubscribeFinalizedBlocks streams blocks as they are finalized until the context is cancelled. If fromHeight
+// is not 0, the stored blocks from that height are streamed first.
+func (c *FinalityGadgetGrpcClient) SubscribeFinalizedBlocks(ctx context.Context, fromHeight uint64) (*FinalizedBlocksSubscription, error) {
+	req := &proto.SubscribeFinalizedBlocksRequest{
+		FromHeight: fromHeight,
+	}
+
+	stream, err := c.client.SubscribeFinalizedBlocks(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FinalizedBlocksSubscription{stream: stream}, nil
+}
+
+// WatchTransaction registers a callback url notified when the finality status of the transaction changes, and
+// returns the id of the watch
+func (c *FinalityGadgetGrpcClient) WatchTransaction(txHash string, callbackUrl string) (string, error) {
+	req := &proto.WatchTransactionRequest{
+		TxHash:      txHash,
+		CallbackUrl: callbackUrl,
+	}
+
+	res, err := c.client.WatchTransaction(context.Background(), req)
+	if err != nil {
+		return "", err
+	}
+
+	return res.WatchId, nil
+}
+
+// QueryTransactionsStatus returns the finality status of multiple transactions, in the order of the given hashes
+func (c *FinalityGadgetGrpcClient) QueryTransactionsStatus(txHashes []string) ([]*types.TransactionStatusResult, error) {
+	req := &proto.QueryTransactionsStatusRequest{
+		TxHashes: txHashes,
+	}
+
+	res, err := c.client.QueryTransactionsStatus(context.Background(), req)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*types.TransactionStatusResult, 0, len(res.Results))
+	for _, result := range res.Results {
+		txResult := &types.TransactionStatusResult{
+			TxHash: result.TxHash,
+			Found:  result.Found,
+			Error:  result.Error,
+		}
+		if result.Transaction != nil {
+			txResult.Transaction = fromTransactionInfo(result.Transaction)
+		}
+		results = append(results, txResult)
+	}
+	return results, nil
+}
+
+func (c *FinalityGadgetGrpcClient) QueryTransactionStatus(txHash string) (*types.TransactionInfo, error) {
+	req := &proto.QueryTransactionStatusRequest{
+		TxHash: txHash,
+	}
+
+	res, err := c.client.QueryTransactionStatus(context.Background(), req)
+	if err != nil {
+		return nil, err
+	}
+
+	return fromTransactionInfo(res.Transaction), nil
+}
+
+func (c *FinalityGadgetGrpcClient) QueryChainSyncStatus() (*types.ChainSyncStatus, error) {
+	res, err := c.client.QueryChainSyncStatus(context.Background(), &proto.QueryChainSyncStatusRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.ChainSyncStatus{
+		LatestBlockHeight:               res.LatestBlockHeight,
+		LatestBtcFinalizedBlockHeight:   res.LatestBtcFinalizedBlockHeight,
+		EarliestBtcFinalizedBlockHeight: res.EarliestBtcFinalizedBlockHeight,
+		LatestEthFinalizedBlockHeight:   res.LatestEthFinalizedBlockHeight,
 	}, nil
 }
 
 func (c *FinalityGadgetGrpcClient) Close() error {
 	return c.conn.Close()
 }
+
+func fromBlockInfo(block *proto.BlockInfo) *types.Block {
+	return &types.Block{
+		BlockHash:      block.BlockHash,
+		BlockHeight:    block.BlockHeight,
+		BlockTimestamp: block.BlockTimestamp,
+		ParentHash:     block.ParentHash,
+		StateRoot:      block.StateRoot,
+		L1OriginNumber: block.L1OriginNumber,
+		L1OriginHash:   block.L1OriginHash,
+	}
+}
+
+func fromTransactionInfo(txInfo *proto.TransactionInfo) *types.TransactionInfo {
+	return &types.TransactionInfo{
+		TxHash:           txInfo.TxHash,
+		BlockHash:        txInfo.BlockHash,
+		BlockHeight:      txInfo.BlockHeight,
+		BlockTimestamp:   txInfo.BlockTimestamp,
+		Status:           fromFinalityStatus(txInfo.Status),
+		BabylonFinalized: txInfo.BabylonFinalized,
+	}
+}
+
+func fromFinalityStatus(status proto.FinalityStatus) types.FinalityStatus {
+	switch status {
+	case proto.FinalityStatus_FINALITY_STATUS_PENDING:
+		return types.FinalityStatusPending
+	case proto.FinalityStatus_FINALITY_STATUS_UNSAFE:
+		return types.FinalityStatusUnsafe
+	case proto.FinalityStatus_FINALITY_STATUS_SAFE:
+		return types.FinalityStatusSafe
+	case proto.FinalityStatus_FINALITY_STATUS_BTC_FINALIZED:
+		return types.FinalityStatusBitcoinFinalized
+	case proto.FinalityStatus_FINALITY_STATUS_FINALIZED:
+		return types.FinalityStatusFinalized
+	default:
+		return ""
+	}
+}
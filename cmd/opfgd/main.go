@@ -1,12 +1,15 @@
 package main
 
 import (
+	"errors"
 	"log"
 	"os"
 
 	"github.com/cosmos/cosmos-sdk/client"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+
+	"github.com/babylonlabs-io/finality-gadget/supervisor"
 )
 
 func NewRootCmd() *cobra.Command {
@@ -24,6 +27,7 @@ func main() {
 	cmd := NewRootCmd()
 
 	cmd.AddCommand(CommandStart())
+	cmd.AddCommand(CommandMigrateDb())
 
 	cmd.PersistentFlags().String("cfg", "config.toml", "config file")
 	if err := viper.BindPFlag("cfg", cmd.PersistentFlags().Lookup("cfg")); err != nil {
@@ -32,8 +36,33 @@ func main() {
 	}
 
 	if err := cmd.Execute(); err != nil {
-		log.Fatalf("Error executing your opfgd daemon: %s", err)
-		os.Exit(1)
+		log.Printf("Error executing your opfgd daemon: %s", err)
+		os.Exit(exitCode(err))
+	}
+}
+
+// exit codes of the daemon, 0 if it shut down cleanly
+const (
+	// exitCodeFailure is returned if a component failed while running, or the daemon couldn't shut down cleanly
+	exitCodeFailure = 1
+	// exitCodeStartupFailure is returned if the daemon failed to start, e.g. the config is invalid, the db
+	// can't be opened, or a component failed to start
+	exitCodeStartupFailure = 2
+)
+
+// exitCode returns the exit code of the daemon for the error it stopped with
+func exitCode(err error) int {
+	var serviceErr *supervisor.ServiceError
+	switch {
+	case errors.Is(err, supervisor.ErrDrainTimeout):
+		return exitCodeFailure
+	case errors.As(err, &serviceErr):
+		if serviceErr.Phase == supervisor.PhaseStart {
+			return exitCodeStartupFailure
+		}
+		return exitCodeFailure
+	default:
+		return exitCodeStartupFailure
 	}
 }
 
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/babylonlabs-io/finality-gadget/config"
+	"github.com/babylonlabs-io/finality-gadget/db"
+	"github.com/babylonlabs-io/finality-gadget/finalitygadget"
+	"github.com/babylonlabs-io/finality-gadget/log"
+	"github.com/babylonlabs-io/finality-gadget/metrics"
+)
+
+const (
+	compactToFlag = "compact-to"
+	dryRunFlag    = "dry-run"
+)
+
+// CommandMigrateDb returns the migrate-db command of the op finality gadget daemon.
+func CommandMigrateDb() *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "migrate-db",
+		Short: "Migrate the DB created by an older version of the op finality gadget",
+		Long: `Apply the pending DB migrations, including the offline ones that are not applied at startup, such as
+re-encoding the blocks stored as JSON by older versions with the compact binary encoding. The daemon must be
+stopped while the DB is migrated. bbolt and pebble DBs are backed up next to the DB before being migrated.
+bbolt DBs don't shrink by themselves, so pass --compact-to to also copy the migrated DB to a compacted file,
+then point db-file-path to it.`,
+		Example: `opfgd migrate-db --cfg config.toml --compact-to compacted.db`,
+		Args:    cobra.NoArgs,
+		RunE:    runMigrateDbCmd,
+	}
+	cmd.Flags().String(compactToFlag, "", "path to copy the migrated bbolt DB to, compacted")
+	cmd.Flags().Bool(dryRunFlag, false, "only log the pending migrations, without applying them")
+	return cmd
+}
+
+func runMigrateDbCmd(cmd *cobra.Command, args []string) error {
+	// Parse configs
+	cfgPath, err := cmd.Flags().GetString(cfgFlag)
+	if err != nil {
+		return err
+	}
+	compactTo, err := cmd.Flags().GetString(compactToFlag)
+	if err != nil {
+		return err
+	}
+	dryRun, err := cmd.Flags().GetBool(dryRunFlag)
+	if err != nil {
+		return err
+	}
+	cfg, err := config.Load(cfgPath)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if cfg.ReadOnly {
+		return fmt.Errorf("can't migrate the DB with a read-only configuration")
+	}
+	if compactTo != "" && cfg.DatabaseBackend() != db.BackendBBolt {
+		return fmt.Errorf("--%s is only supported by the bbolt db-backend", compactToFlag)
+	}
+
+	// Create logger
+	logLevel, err := zapcore.ParseLevel(cfg.LogLevel)
+	if err != nil {
+		return fmt.Errorf("invalid log level: %w", err)
+	}
+	logger, err := log.NewRootLogger("console", logLevel)
+	if err != nil {
+		return fmt.Errorf("failed to create logger: %w", err)
+	}
+
+	// errors from here on are not usage errors, so the usage is not printed
+	cmd.SilenceUsage = true
+
+	if err := migrateDb(cmd.Context(), cfg, dryRun, logger); err != nil {
+		return err
+	}
+
+	if compactTo == "" || dryRun {
+		return nil
+	}
+	if err := db.CompactBBolt(cfg.DBFilePath, compactTo, logger); err != nil {
+		return fmt.Errorf("failed to compact DB: %w", err)
+	}
+	logger.Info("Compacted DB, set db-file-path to the compacted DB before starting the daemon", zap.String("db_file_path", compactTo))
+	return nil
+}
+
+/* migrateDb applies the pending DB migrations
+ *
+ * - the finality gadget, which connects to the L2, Babylon and BTC nodes of the config, is only created if an
+ *   online migration, such as the block metadata backfill from the L2 RPC, is pending
+ * - the offline migrations and dry runs only open the DB
+ */
+func migrateDb(ctx context.Context, cfg *config.Config, dryRun bool, logger *zap.Logger) error {
+	handler, err := db.NewDatabaseHandler(cfg.DatabaseBackend(), cfg.DatabasePath(), logger)
+	if err != nil {
+		return fmt.Errorf("failed to create DB handler: %w", err)
+	}
+	defer func() {
+		if err := handler.Close(); err != nil {
+			logger.Error("Error closing DB", zap.Error(err))
+		}
+	}()
+	if err := handler.CreateInitialSchema(); err != nil {
+		return fmt.Errorf("create initial buckets error: %w", err)
+	}
+
+	migrator, err := db.NewMigrator(handler, logger, finalitygadget.Migrations(nil, cfg.BatchSize, logger))
+	if err != nil {
+		return fmt.Errorf("failed to create DB migrator: %w", err)
+	}
+	_, pending, err := migrator.Pending()
+	if err != nil {
+		return fmt.Errorf("failed to migrate DB: %w", err)
+	}
+	opts := db.MigrateOptions{
+		BackupPathPrefix: cfg.DBFilePath,
+		DryRun:           dryRun,
+	}
+
+	var migrations []db.Migration
+	if dryRun || !hasOnlineMigration(pending) {
+		migrations, err = migrator.Run(ctx, opts)
+	} else {
+		migrations, err = migrateDbOnline(ctx, cfg, handler, opts, logger)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to migrate DB: %w", err)
+	}
+	if len(migrations) == 0 {
+		logger.Info("DB is at the current schema version", zap.Uint64("schema_version", db.CurrentSchemaVersion))
+	}
+	return nil
+}
+
+// migrateDbOnline applies the pending DB migrations with a finality gadget, for the online migrations to fetch
+// the blocks from the L2 nodes
+func migrateDbOnline(ctx context.Context, cfg *config.Config, handler db.IDatabaseHandler, opts db.MigrateOptions, logger *zap.Logger) ([]db.Migration, error) {
+	fg, err := finalitygadget.NewFinalityGadget(cfg, handler, metrics.NewFinalityGadgetMetrics(), logger)
+	if err != nil {
+		return nil, fmt.Errorf("error creating finality gadget: %w", err)
+	}
+	defer fg.Close()
+	return fg.MigrateDb(ctx, opts)
+}
+
+// hasOnlineMigration returns whether any of the migrations is applied online
+func hasOnlineMigration(migrations []db.Migration) bool {
+	for _, migration := range migrations {
+		if !migration.Offline {
+			return true
+		}
+	}
+	return false
+}
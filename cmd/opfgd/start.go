@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/cosmos/cosmos-sdk/client"
 	"github.com/spf13/cobra"
@@ -13,12 +14,18 @@ import (
 	"github.com/babylonlabs-io/finality-gadget/db"
 	"github.com/babylonlabs-io/finality-gadget/finalitygadget"
 	"github.com/babylonlabs-io/finality-gadget/log"
+	"github.com/babylonlabs-io/finality-gadget/metrics"
 	"github.com/babylonlabs-io/finality-gadget/server"
+	"github.com/babylonlabs-io/finality-gadget/supervisor"
+	"github.com/babylonlabs-io/finality-gadget/webhook"
 	sig "github.com/lightningnetwork/lnd/signal"
 )
 
 const (
-	cfgFlag = "cfg"
+	cfgFlag           = "cfg"
+	migrateDryRunFlag = "migrate-dry-run"
+	// drainTimeout bounds how long the components have to stop once shutdown is requested
+	drainTimeout = 30 * time.Second
 )
 
 // CommandStart returns the start command of fpd daemon.
@@ -31,6 +38,7 @@ func CommandStart() *cobra.Command {
 		Args:    cobra.NoArgs,
 		RunE:    runEWithClientCtx(runStartCmd),
 	}
+	cmd.Flags().Bool(migrateDryRunFlag, false, "log the pending DB migrations and exit without starting the daemon")
 	return cmd
 }
 
@@ -40,6 +48,10 @@ func runStartCmd(ctx client.Context, cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	migrateDryRun, err := cmd.Flags().GetBool(migrateDryRunFlag)
+	if err != nil {
+		return err
+	}
 	cfg, err := config.Load(cfgPath)
 	if err != nil {
 		return fmt.Errorf("failed to load configuration: %w", err)
@@ -55,67 +67,139 @@ func runStartCmd(ctx client.Context, cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to create logger: %w", err)
 	}
 
+	// errors from here on are not usage errors, so the usage is not printed
+	cmd.SilenceUsage = true
+
+	// the DB is migrated once block processing starts, after being backed up
+	if migrateDryRun {
+		if cfg.ReadOnly {
+			return fmt.Errorf("the shared DB is migrated by the finality gadget processing blocks, not in read-only mode")
+		}
+		return migrateDb(cmd.Context(), cfg, true, logger)
+	}
+
 	// Init local DB for storing and querying blocks
-	db, err := db.NewBBoltHandler(cfg.DBFilePath, logger)
+	db, err := db.NewDatabaseHandler(cfg.DatabaseBackend(), cfg.DatabasePath(), logger)
 	if err != nil {
 		return fmt.Errorf("failed to create DB handler: %w", err)
 	}
-	defer func() {
-		logger.Info("Closing DB...")
+	// closeDb closes the db if the daemon fails before the supervisor owns it
+	closeDb := func() {
 		if dbErr := db.Close(); dbErr != nil {
 			logger.Error("Error closing DB", zap.Error(dbErr))
 		}
-	}()
-	err = db.CreateInitialSchema()
-	if err != nil {
-		return fmt.Errorf("create initial buckets error: %w", err)
+	}
+	// the schema of a shared db is created and migrated by the finality gadget processing blocks
+	if !cfg.ReadOnly {
+		err = db.CreateInitialSchema()
+		if err != nil {
+			closeDb()
+			return fmt.Errorf("create initial buckets error: %w", err)
+		}
 	}
 
+	// Create metrics, served by the HTTP server
+	fgMetrics := metrics.NewFinalityGadgetMetrics()
+
 	// Create finality gadget
-	fg, err := finalitygadget.NewFinalityGadget(cfg, db, logger)
+	fg, err := finalitygadget.NewFinalityGadget(cfg, db, fgMetrics, logger)
 	if err != nil {
-		logger.Fatal("Error creating finality gadget", zap.Error(err))
-		return fmt.Errorf("error creating finality gadget: %v", err)
+		closeDb()
+		return fmt.Errorf("error creating finality gadget: %w", err)
+	}
+	watcher := webhook.NewWatcher(&webhook.Config{
+		Secret:                cfg.WebhookSecret,
+		AllowedHosts:          cfg.WebhookAllowedHosts,
+		PollInterval:          cfg.PollInterval,
+		MaxWatches:            int(cfg.WebhookMaxWatches),
+		Workers:               int(cfg.WebhookWorkers),
+		AllowPrivateCallbacks: cfg.WebhookAllowPrivateCallbacks,
+	}, fg, db, logger)
+	srv := server.NewFinalityGadgetServer(cfg, db, fg, watcher, fgMetrics, logger)
+
+	/* Supervise the components, started in this order and stopped in reverse order
+	 *
+	 * - the db is closed last, once, after every component using it stopped
+	 * - the finality gadget closes the event subscriptions and the L2 client
+	 * - the BTC staking activation and BTC header monitors, and the webhook watcher
+	 * - the gRPC and HTTP servers, draining in-flight requests on shutdown
+	 * - block processing, started once the servers are listening, and the block pruner enforcing the
+	 *   retention policy, unless in the archive mode keeping all blocks
+	 * - in read-only mode, the components writing to the shared db run in the finality gadget processing
+	 *   blocks, and the blocks it finalizes into the shared db are followed to publish finality events
+	 */
+	sv := supervisor.NewSupervisor(drainTimeout, logger)
+	sv.Add(
+		&supervisor.FuncService{ServiceName: "db", StopFn: db.Close},
+		&supervisor.FuncService{ServiceName: "finality gadget", StopFn: func() error {
+			fg.Close()
+			return nil
+		}},
+	)
+	if !cfg.ReadOnly {
+		sv.Add(
+			supervisor.NewFuncService("btc staking activation monitor", func(ctx context.Context) error {
+				fg.MonitorBtcStakingActivation(ctx)
+				return nil
+			}),
+			supervisor.NewFuncService("btc header monitor", func(ctx context.Context) error {
+				fg.MonitorBtcHeaders(ctx)
+				return nil
+			}),
+		)
+	}
+	if watcher.Enabled() {
+		sv.Add(supervisor.NewFuncService("webhook watcher", func(ctx context.Context) error {
+			watcher.Run(ctx)
+			return nil
+		}))
+	}
+	sv.Add(srv)
+	if cfg.ReadOnly {
+		logger.Info("Running in read-only mode, serving queries from the shared db without processing blocks")
+		sv.Add(supervisor.NewFuncService("shared db follower", func(ctx context.Context) error {
+			fg.FollowSharedDb(ctx)
+			return nil
+		}))
+	} else {
+		sv.Add(supervisor.NewFuncService("block processing", func(ctx context.Context) error {
+			if err := fg.Startup(ctx); err != nil {
+				return fmt.Errorf("error starting finality gadget: %w", err)
+			}
+			if err := fg.ProcessBlocks(ctx); err != nil {
+				return fmt.Errorf("error processing blocks: %w", err)
+			}
+			return nil
+		}))
+		if !cfg.RetentionPolicy().IsArchive() {
+			sv.Add(supervisor.NewFuncService("block pruner", func(ctx context.Context) error {
+				fg.PruneBlocks(ctx)
+				return nil
+			}))
+		}
 	}
 
-	// Create a cancellable context
-	fgCtx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-	// Start monitoring BTC staking activation
-	go fg.MonitorBtcStakingActivation(fgCtx)
-
-	// Start both grpc and http servers
-	// Hook interceptor for os signals.
+	// Hook interceptor for os signals, to shut down the supervised components
 	shutdownInterceptor, err := sig.Intercept()
 	if err != nil {
+		closeDb()
 		return err
 	}
-	srv := server.NewFinalityGadgetServer(cfg, db, fg, shutdownInterceptor, logger)
+	svCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 	go func() {
-		err = srv.RunUntilShutdown()
-		if err != nil {
-			logger.Fatal("Finality gadget server error", zap.Error(err))
+		select {
+		case <-shutdownInterceptor.ShutdownChannel():
+			logger.Info("Shutdown requested, stopping finality gadget...")
+			cancel()
+		case <-svCtx.Done():
 		}
 	}()
 
-	// Start finality gadget
-	if err := fg.Startup(fgCtx); err != nil {
-		logger.Fatal("Error starting finality gadget", zap.Error(err))
+	if err := sv.Run(svCtx); err != nil {
+		logger.Error("Finality gadget stopped with an error", zap.Error(err))
+		return err
 	}
-
-	// Run finality gadget in a separate goroutine
-	go func() {
-		if err := fg.ProcessBlocks(fgCtx); err != nil {
-			logger.Fatal("Error processing blocks", zap.Error(err))
-		}
-	}()
-
-	// Wait for shutdown signal
-	<-shutdownInterceptor.ShutdownChannel()
-
-	// Call Close method when interrupt signal is received
-	logger.Info("Closing finality gadget server...")
-	fg.Close()
-
+	logger.Info("Finality gadget stopped")
 	return nil
 }
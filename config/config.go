@@ -4,30 +4,83 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/babylonlabs-io/finality-gadget/db"
+	"github.com/babylonlabs-io/finality-gadget/types"
 	"github.com/spf13/viper"
 )
 
 type Config struct {
-	L2RPCHost         string        `long:"l2-rpc-host" description:"rpc host address of the L2 node"`
-	BitcoinRPCHost    string        `long:"bitcoin-rpc-host" description:"rpc host address of the bitcoin node"`
-	BitcoinRPCUser    string        `long:"bitcoin-rpc-user" description:"rpc user of the bitcoin node"`
-	BitcoinRPCPass    string        `long:"bitcoin-rpc-pass" description:"rpc password of the bitcoin node"`
-	FGContractAddress string        `long:"fg-contract-address" description:"BabylonChain op finality gadget contract address"`
-	BBNChainID        string        `long:"bbn-chain-id" description:"BabylonChain chain ID"`
-	BBNRPCAddress     string        `long:"bbn-rpc-address" description:"BabylonChain chain RPC address"`
-	DBFilePath        string        `long:"db-file-path" description:"path to the DB file"`
-	GRPCListener      string        `long:"grpc-listener" description:"host:port to listen for gRPC connections"`
-	HTTPListener      string        `long:"http-listener" description:"host:port to listen for HTTP connections"`
-	LogLevel          string        `long:"log-level" description:"log level (debug, info, warn, error)"`
-	BitcoinDisableTLS bool          `long:"bitcoin-disable-tls" description:"disable TLS for RPC connections"`
-	PollInterval      time.Duration `long:"retry-interval" description:"interval in seconds to recheck Babylon finality of block"`
-	BatchSize         uint64        `long:"batch-size" description:"number of blocks to process in a batch"`
+	L2RPCHost         string `long:"l2-rpc-host" description:"rpc host address of the L2 node"`
+	BitcoinRPCHost    string `long:"bitcoin-rpc-host" description:"rpc host address of the bitcoin node"`
+	BitcoinRPCUser    string `long:"bitcoin-rpc-user" description:"rpc user of the bitcoin node"`
+	BitcoinRPCPass    string `long:"bitcoin-rpc-pass" description:"rpc password of the bitcoin node"`
+	FGContractAddress string `long:"fg-contract-address" description:"BabylonChain op finality gadget contract address"`
+	BBNChainID        string `long:"bbn-chain-id" description:"BabylonChain chain ID"`
+	BBNRPCAddress     string `long:"bbn-rpc-address" description:"BabylonChain chain RPC address"`
+	DBFilePath        string `long:"db-file-path" description:"path to the DB file"`
+	GRPCListener      string `long:"grpc-listener" description:"host:port to listen for gRPC connections"`
+	HTTPListener      string `long:"http-listener" description:"host:port to listen for HTTP connections"`
+	LogLevel          string `long:"log-level" description:"log level (debug, info, warn, error)"`
+	// WebhookSecret is the HMAC-SHA256 key transaction finality webhooks are signed with, webhooks are disabled if empty
+	WebhookSecret string `long:"webhook-secret" description:"secret used to sign transaction finality webhooks, webhooks are disabled if empty"`
+	// DBBackend is the storage engine of the DB, see db.Backend
+	DBBackend string `long:"db-backend" description:"storage engine of the DB (bbolt, pebble, postgres), defaults to bbolt"`
+	// DBPostgresURL is the connection URL of the DB for the postgres backend, which doesn't use DBFilePath
+	DBPostgresURL string `long:"db-postgres-url" description:"connection URL of the PostgreSQL DB, for the postgres backend"`
+	// BitcoinTimestampMapping is how L2 block timestamps are mapped to BTC heights, see types.BtcTimestampMapping
+	BitcoinTimestampMapping string `long:"bitcoin-timestamp-mapping" description:"how L2 timestamps are mapped to BTC heights (timestamp, mtp), defaults to timestamp"`
+	// RetentionMode is the policy deciding which finalized blocks are kept in the db, see types.RetentionMode
+	RetentionMode string `long:"retention-mode" description:"which finalized blocks are kept in the db (archive, blocks, age, eth-finalized), defaults to archive"`
+	// L2RPCHosts are fallback L2 nodes, queried if L2RPCHost fails, see L2RPCEndpoints
+	L2RPCHosts []string `long:"l2-rpc-hosts" description:"rpc host addresses of fallback L2 nodes"`
+	// BBNRPCAddresses are fallback Babylon nodes, queried if BBNRPCAddress fails, see BBNRPCEndpoints
+	BBNRPCAddresses []string `long:"bbn-rpc-addresses" description:"rpc addresses of fallback BabylonChain nodes"`
+	// WebhookAllowedHosts restricts the hosts of the webhook callback urls if not empty
+	WebhookAllowedHosts []string `long:"webhook-allowed-hosts" description:"hosts webhook callbacks can be sent to, any public host if empty"`
+	BitcoinDisableTLS   bool     `long:"bitcoin-disable-tls" description:"disable TLS for RPC connections"`
+	// ReadOnly serves queries from a DB shared with a finality gadget processing blocks, without processing
+	// blocks or writing to the DB
+	ReadOnly bool `long:"read-only" description:"serve queries from a shared DB without processing blocks"`
+	// BBNPinQueryHeight pins the Babylon queries made to evaluate a batch of blocks to the latest Babylon height at
+	// the start of the batch, so that finality decisions are reproducible at the recorded height
+	BBNPinQueryHeight bool `long:"bbn-pin-query-height" description:"pin the Babylon queries made to evaluate blocks to a Babylon height"`
+	// WebhookAllowPrivateCallbacks allows webhook callbacks to loopback, link-local and private addresses
+	WebhookAllowPrivateCallbacks bool          `long:"webhook-allow-private-callbacks" description:"allow webhook callbacks to loopback, link-local and private addresses"`
+	PollInterval                 time.Duration `long:"retry-interval" description:"interval in seconds to recheck Babylon finality of block"`
+	BatchSize                    uint64        `long:"batch-size" description:"number of blocks to process in a batch"`
+	// BitcoinIndexStartHeight is the BTC height the local BTC header index starts at when created
+	BitcoinIndexStartHeight uint64 `long:"bitcoin-index-start-height" description:"BTC height to start the BTC header index at, defaults to 2016 blocks below the tip"`
+	// QuorumThresholdNumerator and QuorumThresholdDenominator override the quorum threshold set in the contract
+	QuorumThresholdNumerator   uint64 `long:"quorum-threshold-numerator" description:"numerator of the quorum threshold, overrides the contract config"`
+	QuorumThresholdDenominator uint64 `long:"quorum-threshold-denominator" description:"denominator of the quorum threshold, overrides the contract config"`
+	// MaxFinalityLag is the number of L2 blocks the latest BTC finalized block can lag behind the latest L2 block
+	// before the finality gadget reports not ready, the check is disabled if 0
+	MaxFinalityLag uint64 `long:"max-finality-lag" description:"max number of L2 blocks the latest BTC finalized block can lag behind the L2 tip while ready, disabled if 0"`
+	// L2RPCQuorum is the number of L2 nodes that must return the same block hash before a block header is trusted,
+	// quorum reads are disabled if 0 or 1
+	L2RPCQuorum uint64 `long:"l2-rpc-quorum" description:"number of L2 nodes that must agree on a block hash, disabled if 0 or 1"`
+	// BBNVotersQuorum is the number of Babylon nodes that must return the same voters of a block before they are
+	// trusted, disabled if 0 or 1
+	BBNVotersQuorum uint64 `long:"bbn-voters-quorum" description:"number of BabylonChain nodes that must agree on the voters of a block, disabled if 0 or 1"`
+	// RetentionBlocks is the number of finalized blocks kept by the blocks retention mode
+	RetentionBlocks uint64 `long:"retention-blocks" description:"number of finalized blocks kept in the blocks retention mode"`
+	// RetentionAge is how old the finalized blocks kept by the age retention mode can be
+	RetentionAge time.Duration `long:"retention-age" description:"max age of the finalized blocks kept in the age retention mode"`
+	// RetentionEthFinalizedMargin is the number of blocks below the latest ETH finalized block kept by the
+	// eth-finalized retention mode
+	RetentionEthFinalizedMargin uint64 `long:"retention-eth-finalized-margin" description:"number of blocks below the ETH finalized block kept in the eth-finalized retention mode"`
+	// PruneInterval is the interval blocks are pruned at, outside of the archive retention mode
+	PruneInterval time.Duration `long:"prune-interval" description:"interval to prune the finalized blocks at, defaults to 1 minute"`
+	// WebhookMaxWatches is the max number of registered transaction watches, defaults to webhook.DefaultMaxWatches
+	WebhookMaxWatches uint64 `long:"webhook-max-watches" description:"max number of registered transaction watches, defaults to 10000"`
+	// WebhookWorkers is the number of transaction watches evaluated concurrently, defaults to webhook.DefaultWorkers
+	WebhookWorkers uint64 `long:"webhook-workers" description:"number of transaction watches evaluated concurrently, defaults to 16"`
 }
 
 func (c *Config) Validate() error {
 	// Required fields
-	if c.L2RPCHost == "" {
-		return fmt.Errorf("l2-rpc-host is required")
+	if len(c.L2RPCEndpoints()) == 0 {
+		return fmt.Errorf("l2-rpc-host or l2-rpc-hosts is required")
 	}
 	if c.BitcoinRPCHost == "" {
 		return fmt.Errorf("bitcoin-rpc-host is required")
@@ -38,12 +91,28 @@ func (c *Config) Validate() error {
 	if c.BBNChainID == "" {
 		return fmt.Errorf("bbn-chain-id is required")
 	}
-	if c.BBNRPCAddress == "" {
-		return fmt.Errorf("bbn-rpc-address is required")
+	if len(c.BBNRPCEndpoints()) == 0 {
+		return fmt.Errorf("bbn-rpc-address or bbn-rpc-addresses is required")
 	}
-	// TODO: add some default values if missing
-	if c.DBFilePath == "" {
-		return fmt.Errorf("db-file-path is required")
+	switch c.DatabaseBackend() {
+	case db.BackendBBolt, db.BackendPebble:
+		// TODO: add some default values if missing
+		if c.DBFilePath == "" {
+			return fmt.Errorf("db-file-path is required")
+		}
+	case db.BackendPostgres:
+		if c.DBPostgresURL == "" {
+			return fmt.Errorf("db-postgres-url is required for the postgres db-backend")
+		}
+	default:
+		return fmt.Errorf("invalid db-backend: %s", c.DBBackend)
+	}
+	// only one process can open a bbolt or pebble DB, so read-only finality gadgets need a shared DB
+	if c.ReadOnly && c.DatabaseBackend() != db.BackendPostgres {
+		return fmt.Errorf("read-only requires the postgres db-backend")
+	}
+	if c.ReadOnly && c.WebhookSecret != "" {
+		return fmt.Errorf("webhook-secret can't be set in read-only mode, webhooks are sent by the finality gadget processing blocks")
 	}
 	if c.GRPCListener == "" {
 		return fmt.Errorf("grpc-listener is required")
@@ -60,10 +129,100 @@ func (c *Config) Validate() error {
 	if c.BatchSize == 0 {
 		return fmt.Errorf("batch-size must be greater than 0")
 	}
+	switch c.BtcTimestampMapping() {
+	case types.BtcTimestampMappingBlockTime, types.BtcTimestampMappingMedianTimePast:
+	default:
+		return fmt.Errorf("invalid bitcoin-timestamp-mapping: %s", c.BitcoinTimestampMapping)
+	}
+	if (c.QuorumThresholdNumerator == 0) != (c.QuorumThresholdDenominator == 0) {
+		return fmt.Errorf("quorum-threshold-numerator and quorum-threshold-denominator must be set together")
+	}
+	if threshold := c.QuorumThreshold(); threshold != nil {
+		if err := threshold.Validate(); err != nil {
+			return err
+		}
+	}
+	if err := c.RetentionPolicy().Validate(); err != nil {
+		return err
+	}
+	// the shared db is pruned by the finality gadget processing blocks
+	if c.ReadOnly && !c.RetentionPolicy().IsArchive() {
+		return fmt.Errorf("retention-mode can't be set in read-only mode, the shared db is pruned by the finality gadget processing blocks")
+	}
+	if c.PruneInterval < 0 {
+		return fmt.Errorf("prune-interval must not be negative")
+	}
+	if c.L2RPCQuorum > uint64(len(c.L2RPCEndpoints())) {
+		return fmt.Errorf("l2-rpc-quorum %d exceeds the number of L2 nodes %d", c.L2RPCQuorum, len(c.L2RPCEndpoints()))
+	}
+	if c.BBNVotersQuorum > uint64(len(c.BBNRPCEndpoints())) {
+		return fmt.Errorf("bbn-voters-quorum %d exceeds the number of BabylonChain nodes %d", c.BBNVotersQuorum, len(c.BBNRPCEndpoints()))
+	}
 
 	return nil
 }
 
+// QuorumThreshold returns the locally configured quorum threshold, or nil if it is not set
+func (c *Config) QuorumThreshold() *types.QuorumThreshold {
+	if c.QuorumThresholdNumerator == 0 && c.QuorumThresholdDenominator == 0 {
+		return nil
+	}
+	return &types.QuorumThreshold{
+		Numerator:   c.QuorumThresholdNumerator,
+		Denominator: c.QuorumThresholdDenominator,
+	}
+}
+
+// RetentionPolicy returns the configured retention policy, defaulting to the archive mode keeping all blocks
+func (c *Config) RetentionPolicy() types.RetentionPolicy {
+	mode := types.RetentionMode(c.RetentionMode)
+	if mode == "" {
+		mode = types.RetentionModeArchive
+	}
+	return types.RetentionPolicy{
+		Mode:               mode,
+		Blocks:             c.RetentionBlocks,
+		Age:                c.RetentionAge,
+		EthFinalizedMargin: c.RetentionEthFinalizedMargin,
+	}
+}
+
+// L2RPCEndpoints returns the L2 node addresses in order of preference, L2RPCHost followed by L2RPCHosts, without
+// duplicates
+func (c *Config) L2RPCEndpoints() []string {
+	return endpoints(c.L2RPCHost, c.L2RPCHosts)
+}
+
+// BBNRPCEndpoints returns the Babylon node addresses in order of preference, BBNRPCAddress followed by
+// BBNRPCAddresses, without duplicates
+func (c *Config) BBNRPCEndpoints() []string {
+	return endpoints(c.BBNRPCAddress, c.BBNRPCAddresses)
+}
+
+// BtcTimestampMapping returns the configured BTC timestamp mapping, defaulting to the block timestamp
+func (c *Config) BtcTimestampMapping() types.BtcTimestampMapping {
+	if c.BitcoinTimestampMapping == "" {
+		return types.BtcTimestampMappingBlockTime
+	}
+	return types.BtcTimestampMapping(c.BitcoinTimestampMapping)
+}
+
+// DatabaseBackend returns the configured DB backend, defaulting to bbolt
+func (c *Config) DatabaseBackend() db.Backend {
+	if c.DBBackend == "" {
+		return db.BackendBBolt
+	}
+	return db.Backend(c.DBBackend)
+}
+
+// DatabasePath returns the path of the DB, or its connection URL for the postgres backend
+func (c *Config) DatabasePath() string {
+	if c.DatabaseBackend() == db.BackendPostgres {
+		return c.DBPostgresURL
+	}
+	return c.DBFilePath
+}
+
 func Load(configPath string) (*Config, error) {
 	viper.SetConfigFile(configPath)
 	viper.SetConfigType("toml")
@@ -88,3 +247,17 @@ func Load(configPath string) (*Config, error) {
 
 	return &config, nil
 }
+
+// endpoints returns the primary address followed by the fallback addresses, skipping empty and duplicate addresses
+func endpoints(primary string, fallbacks []string) []string {
+	endpoints := make([]string, 0, len(fallbacks)+1)
+	seen := make(map[string]bool)
+	for _, endpoint := range append([]string{primary}, fallbacks...) {
+		if endpoint == "" || seen[endpoint] {
+			continue
+		}
+		seen[endpoint] = true
+		endpoints = append(endpoints, endpoint)
+	}
+	return endpoints
+}
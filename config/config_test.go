@@ -0,0 +1,263 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/babylonlabs-io/finality-gadget/db"
+	"github.com/babylonlabs-io/finality-gadget/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateQuorumThreshold(t *testing.T) {
+	testCases := []struct {
+		name        string
+		numerator   uint64
+		denominator uint64
+		expectErr   bool
+	}{
+		{name: "not set", numerator: 0, denominator: 0, expectErr: false},
+		{name: "valid threshold", numerator: 3, denominator: 4, expectErr: false},
+		{name: "threshold of 1", numerator: 5, denominator: 5, expectErr: false},
+		{name: "only numerator set", numerator: 2, denominator: 0, expectErr: true},
+		{name: "only denominator set", numerator: 0, denominator: 3, expectErr: true},
+		{name: "threshold above 1", numerator: 4, denominator: 3, expectErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := validConfig()
+			cfg.QuorumThresholdNumerator = tc.numerator
+			cfg.QuorumThresholdDenominator = tc.denominator
+
+			err := cfg.Validate()
+			if tc.expectErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestQuorumThreshold(t *testing.T) {
+	cfg := validConfig()
+	require.Nil(t, cfg.QuorumThreshold())
+
+	cfg.QuorumThresholdNumerator = 3
+	cfg.QuorumThresholdDenominator = 4
+	require.Equal(t, &types.QuorumThreshold{Numerator: 3, Denominator: 4}, cfg.QuorumThreshold())
+}
+
+func TestBtcTimestampMapping(t *testing.T) {
+	testCases := []struct {
+		name      string
+		mapping   string
+		expected  types.BtcTimestampMapping
+		expectErr bool
+	}{
+		{name: "not set", mapping: "", expected: types.BtcTimestampMappingBlockTime},
+		{name: "block timestamp", mapping: "timestamp", expected: types.BtcTimestampMappingBlockTime},
+		{name: "median time past", mapping: "mtp", expected: types.BtcTimestampMappingMedianTimePast},
+		{name: "invalid mapping", mapping: "height", expectErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := validConfig()
+			cfg.BitcoinTimestampMapping = tc.mapping
+
+			err := cfg.Validate()
+			if tc.expectErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, cfg.BtcTimestampMapping())
+		})
+	}
+}
+
+func TestDatabaseBackend(t *testing.T) {
+	testCases := []struct {
+		name      string
+		backend   string
+		expected  db.Backend
+		expectErr bool
+	}{
+		{name: "not set", backend: "", expected: db.BackendBBolt},
+		{name: "bbolt", backend: "bbolt", expected: db.BackendBBolt},
+		{name: "pebble", backend: "pebble", expected: db.BackendPebble},
+		{name: "postgres without url", backend: "postgres", expectErr: true},
+		{name: "invalid backend", backend: "leveldb", expectErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := validConfig()
+			cfg.DBBackend = tc.backend
+
+			err := cfg.Validate()
+			if tc.expectErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, cfg.DatabaseBackend())
+		})
+	}
+}
+
+func TestDatabasePostgres(t *testing.T) {
+	cfg := validConfig()
+	cfg.DBBackend = "postgres"
+	cfg.DBPostgresURL = "postgres://localhost:5432/fg"
+	cfg.DBFilePath = ""
+	require.NoError(t, cfg.Validate())
+	require.Equal(t, db.BackendPostgres, cfg.DatabaseBackend())
+	require.Equal(t, "postgres://localhost:5432/fg", cfg.DatabasePath())
+
+	// read-only finality gadgets can't send webhooks
+	cfg.ReadOnly = true
+	require.NoError(t, cfg.Validate())
+	cfg.WebhookSecret = "secret"
+	require.Error(t, cfg.Validate())
+
+	// read-only requires a shared DB
+	cfg = validConfig()
+	cfg.ReadOnly = true
+	require.EqualError(t, cfg.Validate(), "read-only requires the postgres db-backend")
+	require.Equal(t, "data.db", cfg.DatabasePath())
+}
+
+func TestRetentionPolicy(t *testing.T) {
+	testCases := []struct {
+		name      string
+		mode      string
+		expected  types.RetentionPolicy
+		age       time.Duration
+		blocks    uint64
+		expectErr bool
+	}{
+		{name: "not set", expected: types.RetentionPolicy{Mode: types.RetentionModeArchive}},
+		{name: "archive", mode: "archive", expected: types.RetentionPolicy{Mode: types.RetentionModeArchive}},
+		{name: "blocks", mode: "blocks", blocks: 100, expected: types.RetentionPolicy{Mode: types.RetentionModeBlocks, Blocks: 100}},
+		{name: "blocks without count", mode: "blocks", expectErr: true},
+		{name: "age", mode: "age", age: time.Hour, expected: types.RetentionPolicy{Mode: types.RetentionModeAge, Age: time.Hour}},
+		{name: "age without duration", mode: "age", expectErr: true},
+		{name: "eth finalized", mode: "eth-finalized", expected: types.RetentionPolicy{Mode: types.RetentionModeEthFinalized}},
+		{name: "invalid mode", mode: "height", expectErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := validConfig()
+			cfg.RetentionMode = tc.mode
+			cfg.RetentionBlocks = tc.blocks
+			cfg.RetentionAge = tc.age
+
+			err := cfg.Validate()
+			if tc.expectErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, cfg.RetentionPolicy())
+		})
+	}
+
+	// the shared db is only pruned by the finality gadget processing blocks
+	cfg := validConfig()
+	cfg.DBBackend = "postgres"
+	cfg.DBPostgresURL = "postgres://localhost:5432/fg"
+	cfg.ReadOnly = true
+	cfg.RetentionMode = "archive"
+	require.NoError(t, cfg.Validate())
+	cfg.RetentionMode = "eth-finalized"
+	require.Error(t, cfg.Validate())
+}
+
+func TestL2RPCEndpoints(t *testing.T) {
+	testCases := []struct {
+		name      string
+		host      string
+		hosts     []string
+		expected  []string
+		quorum    uint64
+		expectErr bool
+	}{
+		{name: "single host", host: "http://a", expected: []string{"http://a"}},
+		{name: "fallback hosts", host: "http://a", hosts: []string{"http://b", "http://c"}, quorum: 2, expected: []string{"http://a", "http://b", "http://c"}},
+		{name: "hosts only", hosts: []string{"http://b", "http://c"}, quorum: 2, expected: []string{"http://b", "http://c"}},
+		{name: "duplicate hosts", host: "http://a", hosts: []string{"http://a", "http://b"}, expected: []string{"http://a", "http://b"}},
+		{name: "no host", expectErr: true},
+		{name: "quorum above the number of hosts", host: "http://a", hosts: []string{"http://a"}, quorum: 2, expectErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := validConfig()
+			cfg.L2RPCHost = tc.host
+			cfg.L2RPCHosts = tc.hosts
+			cfg.L2RPCQuorum = tc.quorum
+
+			err := cfg.Validate()
+			if tc.expectErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, cfg.L2RPCEndpoints())
+		})
+	}
+}
+
+func TestBBNRPCEndpoints(t *testing.T) {
+	testCases := []struct {
+		name      string
+		address   string
+		addresses []string
+		expected  []string
+		quorum    uint64
+		expectErr bool
+	}{
+		{name: "single address", address: "http://a", expected: []string{"http://a"}},
+		{name: "fallback addresses", address: "http://a", addresses: []string{"http://b", "http://c"}, quorum: 2, expected: []string{"http://a", "http://b", "http://c"}},
+		{name: "addresses only", addresses: []string{"http://b"}, expected: []string{"http://b"}},
+		{name: "duplicate addresses", address: "http://a", addresses: []string{"http://a"}, expected: []string{"http://a"}},
+		{name: "no address", expectErr: true},
+		{name: "quorum above the number of addresses", address: "http://a", addresses: []string{"http://a"}, quorum: 2, expectErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := validConfig()
+			cfg.BBNRPCAddress = tc.address
+			cfg.BBNRPCAddresses = tc.addresses
+			cfg.BBNVotersQuorum = tc.quorum
+
+			err := cfg.Validate()
+			if tc.expectErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, cfg.BBNRPCEndpoints())
+		})
+	}
+}
+
+func validConfig() *Config {
+	return &Config{
+		L2RPCHost:         "http://localhost:8545",
+		BitcoinRPCHost:    "localhost:18443",
+		FGContractAddress: "bbn1ghd753shjuwexxywmgs4xz7x2q732vcnkm6h2pyv9s6ah3hylvrqxxvh0f",
+		BBNChainID:        "chain-test",
+		BBNRPCAddress:     "http://localhost:26657",
+		DBFilePath:        "data.db",
+		GRPCListener:      "0.0.0.0:50051",
+		HTTPListener:      "0.0.0.0:8080",
+		PollInterval:      10 * time.Second,
+		BatchSize:         10,
+	}
+}
@@ -3,17 +3,31 @@ package cwclient
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	wasmtypes "github.com/CosmWasm/wasmd/x/wasm/types"
 	"github.com/babylonlabs-io/finality-gadget/types"
 	rpcclient "github.com/cometbft/cometbft/rpc/client"
 	cosmosclient "github.com/cosmos/cosmos-sdk/client"
+	grpctypes "github.com/cosmos/cosmos-sdk/types/grpc"
+	"google.golang.org/grpc/metadata"
 )
 
+// ErrNoVotersQuorum is returned if not enough Babylon endpoints agree on the voters of a block
+var ErrNoVotersQuorum = errors.New("block voters differ between Babylon endpoints")
+
 type CosmWasmClient struct {
 	rpcclient.Client
 	contractAddr string
+	// votersEndpoints are queried for the voters of a block if votersQuorum > 1
+	votersEndpoints []rpcclient.Client
+	// votersQuorum is the number of endpoints that must return the same voters of a block
+	votersQuorum int
 }
 
 const (
@@ -32,62 +46,91 @@ func NewCosmWasmClient(rpcClient rpcclient.Client, contractAddr string) *CosmWas
 	}
 }
 
+// WithVotersQuorum requires `quorum` of the given endpoints to return the same voters of a block before they are
+// trusted, as they decide whether the block is finalized. It is disabled if quorum <= 1.
+func (cwClient *CosmWasmClient) WithVotersQuorum(endpoints []rpcclient.Client, quorum int) *CosmWasmClient {
+	cwClient.votersEndpoints = endpoints
+	cwClient.votersQuorum = quorum
+	return cwClient
+}
+
 //////////////////////////////
 // METHODS
 //////////////////////////////
 
+// The queries below are made at the given Babylon height, or at the latest height if 0
+
 func (cwClient *CosmWasmClient) QueryListOfVotedFinalityProviders(
 	queryParams *types.Block,
+	babylonHeight int64,
 ) ([]string, error) {
 	queryData, err := createBlockVotersQueryData(queryParams)
 	if err != nil {
 		return nil, err
 	}
+	if cwClient.votersQuorum > 1 {
+		return cwClient.queryVotersQuorum(queryParams, queryData, babylonHeight)
+	}
+	return cwClient.queryVoters(cwClient.Client, queryData, babylonHeight)
+}
 
-	resp, err := cwClient.querySmartContractState(queryData)
+func (cwClient *CosmWasmClient) QueryConsumerId(babylonHeight int64) (string, error) {
+	queryData, err := createConfigQueryData()
 	if err != nil {
-		return nil, err
+		return "", err
 	}
-	// BlockVoters's return type is Option<HashSet<String>> in contract
-	// Check empty response before unmarshaling
-	if len(resp.Data) == 0 {
-		return nil, nil
+
+	resp, err := cwClient.querySmartContractState(queryData, babylonHeight)
+	if err != nil {
+		return "", err
 	}
 
-	votedFpPkHexList := &[]string{}
-	if err := json.Unmarshal(resp.Data, votedFpPkHexList); err != nil {
-		return nil, err
+	var data contractConfigResponse
+	if err := json.Unmarshal(resp.Data, &data); err != nil {
+		return "", err
 	}
 
-	return *votedFpPkHexList, nil
+	return data.ConsumerId, nil
 }
 
-func (cwClient *CosmWasmClient) QueryConsumerId() (string, error) {
+// QueryQuorumThreshold returns the quorum threshold set in the contract config, or nil if the
+// contract does not set one
+func (cwClient *CosmWasmClient) QueryQuorumThreshold(babylonHeight int64) (*types.QuorumThreshold, error) {
 	queryData, err := createConfigQueryData()
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	resp, err := cwClient.querySmartContractState(queryData)
+	resp, err := cwClient.querySmartContractState(queryData, babylonHeight)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	var data contractConfigResponse
 	if err := json.Unmarshal(resp.Data, &data); err != nil {
-		return "", err
+		return nil, err
+	}
+	if data.QuorumThreshold == nil {
+		return nil, nil
 	}
 
-	return data.ConsumerId, nil
+	threshold := &types.QuorumThreshold{
+		Numerator:   data.QuorumThreshold.Numerator,
+		Denominator: data.QuorumThreshold.Denominator,
+	}
+	if err := threshold.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid quorum threshold in contract config: %w", err)
+	}
+	return threshold, nil
 }
 
-func (cwClient *CosmWasmClient) QueryIsEnabled() (bool, error) {
+func (cwClient *CosmWasmClient) QueryIsEnabled(babylonHeight int64) (bool, error) {
 	queryData, err := createIsEnabledQueryData()
 	if err != nil {
 		return false, err
 	}
 
-	resp, err := cwClient.querySmartContractState(queryData)
+	resp, err := cwClient.querySmartContractState(queryData, babylonHeight)
 	if err != nil {
 		return false, err
 	}
@@ -119,7 +162,14 @@ func createBlockVotersQueryData(queryParams *types.Block) ([]byte, error) {
 }
 
 type contractConfigResponse struct {
-	ConsumerId string `json:"consumer_id"`
+	// QuorumThreshold is optional, contracts that don't set it use the default threshold
+	QuorumThreshold *contractQuorumThreshold `json:"quorum_threshold,omitempty"`
+	ConsumerId      string                   `json:"consumer_id"`
+}
+
+type contractQuorumThreshold struct {
+	Numerator   uint64 `json:"numerator"`
+	Denominator uint64 `json:"denominator"`
 }
 
 type ContractQueryMsgs struct {
@@ -159,14 +209,93 @@ func createIsEnabledQueryData() ([]byte, error) {
 	return data, nil
 }
 
+func (cwClient *CosmWasmClient) queryVoters(client rpcclient.Client, queryData []byte, babylonHeight int64) ([]string, error) {
+	resp, err := cwClient.querySmartContractStateWith(client, queryData, babylonHeight)
+	if err != nil {
+		return nil, err
+	}
+	// BlockVoters's return type is Option<HashSet<String>> in contract
+	// Check empty response before unmarshaling
+	if len(resp.Data) == 0 {
+		return nil, nil
+	}
+
+	votedFpPkHexList := &[]string{}
+	if err := json.Unmarshal(resp.Data, votedFpPkHexList); err != nil {
+		return nil, err
+	}
+
+	return *votedFpPkHexList, nil
+}
+
+/* queryVotersQuorum queries the voters of the block from all the voters endpoints in parallel
+ *
+ * - the voters are a set, so lists are compared regardless of their order
+ * - the voters are returned as soon as `votersQuorum` endpoints returned the same set
+ * - endpoints failing don't count towards the quorum
+ */
+func (cwClient *CosmWasmClient) queryVotersQuorum(block *types.Block, queryData []byte, babylonHeight int64) ([]string, error) {
+	type result struct {
+		err    error
+		voters []string
+	}
+	// results is buffered so that the remaining queries don't block once the quorum is reached
+	results := make(chan result, len(cwClient.votersEndpoints))
+	for _, client := range cwClient.votersEndpoints {
+		go func(client rpcclient.Client) {
+			voters, err := cwClient.queryVoters(client, queryData, babylonHeight)
+			results <- result{err: err, voters: voters}
+		}(client)
+	}
+
+	votes := make(map[string]int)
+	var errs []error
+	var maxVotes int
+	for range cwClient.votersEndpoints {
+		res := <-results
+		if res.err != nil {
+			errs = append(errs, res.err)
+			continue
+		}
+		sort.Strings(res.voters)
+		key := strings.Join(res.voters, ",")
+		votes[key]++
+		if votes[key] >= cwClient.votersQuorum {
+			return res.voters, nil
+		}
+		if votes[key] > maxVotes {
+			maxVotes = votes[key]
+		}
+	}
+
+	errs = append([]error{fmt.Errorf("%w at height %d: at most %d of %d endpoints agree, %d required",
+		ErrNoVotersQuorum, block.BlockHeight, maxVotes, len(cwClient.votersEndpoints), cwClient.votersQuorum)}, errs...)
+	return nil, errors.Join(errs...)
+}
+
 // querySmartContractState queries the smart contract state given the contract address and query data
 func (cwClient *CosmWasmClient) querySmartContractState(
 	queryData []byte,
+	babylonHeight int64,
+) (*wasmtypes.QuerySmartContractStateResponse, error) {
+	return cwClient.querySmartContractStateWith(cwClient.Client, queryData, babylonHeight)
+}
+
+// querySmartContractStateWith queries the smart contract state from the given RPC client, at the given Babylon
+// height or the latest height if 0
+func (cwClient *CosmWasmClient) querySmartContractStateWith(
+	client rpcclient.Client,
+	queryData []byte,
+	babylonHeight int64,
 ) (*wasmtypes.QuerySmartContractStateResponse, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
 	defer cancel()
+	if babylonHeight > 0 {
+		// the height is sent as the ABCI query height
+		ctx = metadata.AppendToOutgoingContext(ctx, grpctypes.GRPCBlockHeightHeader, strconv.FormatInt(babylonHeight, 10))
+	}
 
-	sdkClientCtx := cosmosclient.Context{Client: cwClient.Client}
+	sdkClientCtx := cosmosclient.Context{Client: client}
 	wasmQueryClient := wasmtypes.NewQueryClient(sdkClientCtx)
 
 	req := &wasmtypes.QuerySmartContractStateRequest{
@@ -0,0 +1,85 @@
+package cwclient
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	wasmtypes "github.com/CosmWasm/wasmd/x/wasm/types"
+	"github.com/babylonlabs-io/finality-gadget/types"
+	abci "github.com/cometbft/cometbft/abci/types"
+	"github.com/cometbft/cometbft/libs/bytes"
+	rpcclient "github.com/cometbft/cometbft/rpc/client"
+	ctypes "github.com/cometbft/cometbft/rpc/core/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryListOfVotedFinalityProviders(t *testing.T) {
+	block := &types.Block{BlockHeight: 10, BlockHash: "0x1", BlockTimestamp: 1}
+	node := &fakeContractNode{data: []byte(`["fp1","fp2"]`)}
+	cwClient := NewCosmWasmClient(node, "bbn1contract")
+
+	voters, err := cwClient.QueryListOfVotedFinalityProviders(block, 0)
+	require.NoError(t, err)
+	require.Equal(t, []string{"fp1", "fp2"}, voters)
+
+	// queries are pinned to the given Babylon height through the gRPC height header
+	_, err = cwClient.QueryListOfVotedFinalityProviders(block, 4242)
+	require.NoError(t, err)
+	require.Equal(t, int64(4242), node.height.Load())
+	_, err = cwClient.QueryListOfVotedFinalityProviders(block, 0)
+	require.NoError(t, err)
+	require.Equal(t, int64(0), node.height.Load())
+
+	// no voters
+	node.data = nil
+	voters, err = cwClient.QueryListOfVotedFinalityProviders(block, 0)
+	require.NoError(t, err)
+	require.Empty(t, voters)
+}
+
+func TestQueryListOfVotedFinalityProvidersQuorum(t *testing.T) {
+	block := &types.Block{BlockHeight: 10, BlockHash: "0x1", BlockTimestamp: 1}
+	honest1 := &fakeContractNode{data: []byte(`["fp1","fp2"]`)}
+	// voters are a set, so their order doesn't matter
+	honest2 := &fakeContractNode{data: []byte(`["fp2","fp1"]`)}
+	faulty := &fakeContractNode{data: []byte(`["fp1","fp2","fp3"]`)}
+	endpoints := []rpcclient.Client{faulty, honest1, honest2}
+	cwClient := NewCosmWasmClient(faulty, "bbn1contract").WithVotersQuorum(endpoints, 2)
+
+	// the voters returned by a quorum of endpoints are trusted
+	voters, err := cwClient.QueryListOfVotedFinalityProviders(block, 0)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"fp1", "fp2"}, voters)
+
+	// failing endpoints don't count towards the quorum
+	down := &fakeContractNode{err: errors.New("connection refused")}
+	endpoints = []rpcclient.Client{faulty, honest1, down}
+	cwClient = NewCosmWasmClient(faulty, "bbn1contract").WithVotersQuorum(endpoints, 2)
+	_, err = cwClient.QueryListOfVotedFinalityProviders(block, 0)
+	require.ErrorIs(t, err, ErrNoVotersQuorum)
+	require.ErrorContains(t, err, "at height 10: at most 1 of 3 endpoints agree, 2 required")
+	require.ErrorContains(t, err, "connection refused")
+}
+
+// fakeContractNode answers smart contract state queries with fixed data, or err if set, and records the height of
+// the last query
+type fakeContractNode struct {
+	rpcclient.Client
+	err    error
+	data   []byte
+	height atomic.Int64
+}
+
+func (n *fakeContractNode) ABCIQueryWithOptions(_ context.Context, _ string, _ bytes.HexBytes, opts rpcclient.ABCIQueryOptions) (*ctypes.ResultABCIQuery, error) {
+	n.height.Store(opts.Height)
+	if n.err != nil {
+		return nil, n.err
+	}
+	value, err := (&wasmtypes.QuerySmartContractStateResponse{Data: n.data}).Marshal()
+	if err != nil {
+		return nil, err
+	}
+	return &ctypes.ResultABCIQuery{Response: abci.ResponseQuery{Value: value}}, nil
+}
@@ -0,0 +1,49 @@
+package db
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// Backend is the storage engine the DB is kept in
+type Backend string
+
+const (
+	// BackendBBolt keeps the DB in a single bbolt B+tree file, the default
+	BackendBBolt Backend = "bbolt"
+	// BackendPebble keeps the DB in a Pebble LSM store directory, which has lower write amplification than bbolt
+	BackendPebble Backend = "pebble"
+	// BackendPostgres keeps the DB in PostgreSQL, where it can be shared with read-only finality gadgets
+	BackendPostgres Backend = "postgres"
+)
+
+// NewDatabaseHandler opens the DB at the given path with the given backend, the path is a connection URL
+// for the postgres backend
+func NewDatabaseHandler(backend Backend, path string, logger *zap.Logger) (IDatabaseHandler, error) {
+	var (
+		handler IDatabaseHandler
+		err     error
+	)
+	// handlers are only assigned on success, so a failed open returns a nil interface rather than a nil handler
+	switch backend {
+	case BackendBBolt:
+		var bb *BBoltHandler
+		if bb, err = NewBBoltHandler(path, logger); err == nil {
+			handler = bb
+		}
+	case BackendPebble:
+		var ph *PebbleHandler
+		if ph, err = NewPebbleHandler(path, logger); err == nil {
+			handler = ph
+		}
+	case BackendPostgres:
+		var pg *PostgresHandler
+		if pg, err = NewPostgresHandler(path, logger); err == nil {
+			handler = pg
+		}
+	default:
+		err = fmt.Errorf("unknown DB backend: %s", backend)
+	}
+	return handler, err
+}
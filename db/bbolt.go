@@ -23,10 +23,31 @@ var _ IDatabaseHandler = &BBoltHandler{}
 const (
 	blocksBucket          = "blocks"
 	blockHeightsBucket    = "block_heights"
+	prunedHashesBucket    = "pruned_block_hashes"
 	indexerBucket         = "indexer"
+	evidenceBucket        = "finality_evidence"
+	btcHeadersBucket      = "btc_headers"
+	txWatchesBucket       = "tx_watches"
 	earliestBlockKey      = "earliest"
 	latestBlockKey        = "latest"
+	prunedFromKey         = "pruned_from"
+	prunedToKey           = "pruned_to"
 	activatedTimestampKey = "activated_timestamp"
+	schemaVersionKey      = "schema_version"
+	earliestBtcHeaderKey  = "btc_earliest"
+	latestBtcHeaderKey    = "btc_latest"
+)
+
+const (
+	// SchemaVersionLegacy is the version of DBs created before schema versioning was introduced,
+	// where blocks only store hash, height and timestamp
+	SchemaVersionLegacy uint64 = 0
+	// SchemaVersionBlockMetadata adds parent hash, state root and L1 origin to stored blocks
+	SchemaVersionBlockMetadata uint64 = 1
+	// SchemaVersionBinaryBlocks stores blocks with the binary encoding instead of JSON, see encodeBlock
+	SchemaVersionBinaryBlocks uint64 = 2
+
+	CurrentSchemaVersion = SchemaVersionBinaryBlocks
 )
 
 //////////////////////////////
@@ -54,13 +75,24 @@ func NewBBoltHandler(path string, logger *zap.Logger) (*BBoltHandler, error) {
 func (bb *BBoltHandler) CreateInitialSchema() error {
 	bb.logger.Info("Initialising DB...")
 	return bb.db.Update(func(tx *bolt.Tx) error {
-		buckets := []string{blocksBucket, blockHeightsBucket, indexerBucket}
+		buckets := []string{blocksBucket, blockHeightsBucket, prunedHashesBucket, indexerBucket, evidenceBucket, btcHeadersBucket, txWatchesBucket}
 		for _, bucket := range buckets {
 			if err := bb.tryCreateBucket(tx, bucket); err != nil {
 				return err
 			}
 		}
-		return nil
+
+		// A fresh DB starts at the current schema version. DBs that already hold blocks
+		// but no version are legacy DBs and are left at version 0 to be migrated.
+		indexBucket := tx.Bucket([]byte(indexerBucket))
+		if indexBucket.Get([]byte(schemaVersionKey)) != nil {
+			return nil
+		}
+		if k, _ := tx.Bucket([]byte(blocksBucket)).Cursor().First(); k != nil {
+			bb.logger.Info("Found DB without schema version", zap.Uint64("schema_version", SchemaVersionLegacy))
+			return nil
+		}
+		return indexBucket.Put([]byte(schemaVersionKey), bb.itob(CurrentSchemaVersion))
 	})
 }
 
@@ -92,11 +124,7 @@ func (bb *BBoltHandler) InsertBlocks(blocks []*types.Block) error {
 			}
 
 			// Store block data
-			blockBytes, err := json.Marshal(block)
-			if err != nil {
-				bb.logger.Error("Error inserting block", zap.Error(err))
-				return err
-			}
+			blockBytes := encodeBlock(block)
 			bb.logger.Debug("Inserting block to db", zap.Uint64("block_height", block.BlockHeight), zap.String("block_hash", block.BlockHash))
 			if err := blocksBucket.Put(bb.itob(block.BlockHeight), blockBytes); err != nil {
 				bb.logger.Error("Error inserting block to db", zap.Error(err))
@@ -140,19 +168,21 @@ func (bb *BBoltHandler) InsertBlocks(blocks []*types.Block) error {
 }
 
 func (bb *BBoltHandler) GetBlockByHeight(height uint64) (*types.Block, error) {
-	var block types.Block
+	var block *types.Block
 	err := bb.db.View(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(blocksBucket))
 		v := b.Get(bb.itob(height))
 		if v == nil {
 			return types.ErrBlockNotFound
 		}
-		return json.Unmarshal(v, &block)
+		var err error
+		block, err = decodeBlock(v)
+		return err
 	})
 	if err != nil {
 		return nil, err
 	}
-	return &block, nil
+	return block, nil
 }
 
 func (bb *BBoltHandler) GetBlockByHash(hash string) (*types.Block, error) {
@@ -173,15 +203,21 @@ func (bb *BBoltHandler) GetBlockByHash(hash string) (*types.Block, error) {
 	return bb.GetBlockByHeight(blockHeight)
 }
 
+// QueryIsBlockFinalizedByHeight returns whether the block at the given height is stored, or was pruned
 func (bb *BBoltHandler) QueryIsBlockFinalizedByHeight(height uint64) (bool, error) {
-	_, err := bb.GetBlockByHeight(height)
-	if err != nil {
-		if errors.Is(err, types.ErrBlockNotFound) {
-			return false, nil
+	var isFinalized bool
+	err := bb.db.View(func(tx *bolt.Tx) error {
+		if tx.Bucket([]byte(blocksBucket)).Get(bb.itob(height)) != nil {
+			isFinalized = true
+			return nil
 		}
+		isFinalized = bb.getPrunedRange(tx.Bucket([]byte(indexerBucket))).contains(height)
+		return nil
+	})
+	if err != nil {
 		return false, err
 	}
-	return true, nil
+	return isFinalized, nil
 }
 
 func (bb *BBoltHandler) QueryIsBlockFinalizedByHash(hash string) (bool, error) {
@@ -206,21 +242,46 @@ func (bb *BBoltHandler) QueryIsBlockFinalizedByHash(hash string) (bool, error) {
 	return bb.QueryIsBlockFinalizedByHeight(blockHeight)
 }
 
+// QueryEarliestFinalizedBlock returns the earliest stored block. The index and the block are read in the
+// same transaction, so that the block is never missing while blocks are being pruned.
 func (bb *BBoltHandler) QueryEarliestFinalizedBlock() (*types.Block, error) {
-	var earliestBlockHeight uint64
+	var block *types.Block
 	err := bb.db.View(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte(indexerBucket))
-		v := b.Get([]byte(earliestBlockKey))
+		v := tx.Bucket([]byte(indexerBucket)).Get([]byte(earliestBlockKey))
 		if v == nil {
 			return types.ErrBlockNotFound
 		}
-		earliestBlockHeight = bb.btoi(v)
-		return nil
+		blockBytes := tx.Bucket([]byte(blocksBucket)).Get(v)
+		if blockBytes == nil {
+			return types.ErrBlockNotFound
+		}
+		var err error
+		block, err = decodeBlock(blockBytes)
+		return err
 	})
 	if err != nil {
 		return nil, err
 	}
-	return bb.GetBlockByHeight(earliestBlockHeight)
+	return block, nil
+}
+
+// QueryEarliestFinalizedHeight returns the height all blocks from which up to the latest block are finalized,
+// including the pruned blocks
+func (bb *BBoltHandler) QueryEarliestFinalizedHeight() (uint64, error) {
+	var height uint64
+	err := bb.db.View(func(tx *bolt.Tx) error {
+		indexBucket := tx.Bucket([]byte(indexerBucket))
+		v := indexBucket.Get([]byte(earliestBlockKey))
+		if v == nil {
+			return types.ErrBlockNotFound
+		}
+		height = bb.getPrunedRange(indexBucket).earliestFinalizedHeight(bb.btoi(v))
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return height, nil
 }
 
 func (bb *BBoltHandler) QueryLatestFinalizedBlock() (*types.Block, error) {
@@ -249,6 +310,336 @@ func (bb *BBoltHandler) QueryLatestFinalizedBlock() (*types.Block, error) {
 	return bb.GetBlockByHeight(latestBlockHeight)
 }
 
+// InsertFinalityEvidence stores the finality evidence of blocks, keyed by block height.
+// Evidence already stored at the same height is overwritten.
+func (bb *BBoltHandler) InsertFinalityEvidence(evidence []*types.FinalityEvidence) error {
+	if len(evidence) == 0 {
+		return nil
+	}
+
+	bb.logger.Info("Batch inserting finality evidence to DB", zap.Int("count", len(evidence)))
+
+	return bb.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(evidenceBucket))
+		for _, e := range evidence {
+			evidenceBytes, err := json.Marshal(e)
+			if err != nil {
+				bb.logger.Error("Error encoding finality evidence", zap.Error(err))
+				return err
+			}
+			bb.logger.Debug("Inserting finality evidence to db", zap.Uint64("block_height", e.BlockHeight), zap.Uint64("btc_height", e.BtcHeight))
+			if err := b.Put(bb.itob(e.BlockHeight), evidenceBytes); err != nil {
+				bb.logger.Error("Error inserting finality evidence to db", zap.Error(err))
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (bb *BBoltHandler) GetFinalityEvidenceByHeight(height uint64) (*types.FinalityEvidence, error) {
+	var evidence types.FinalityEvidence
+	err := bb.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(evidenceBucket))
+		v := b.Get(bb.itob(height))
+		if v == nil {
+			return types.ErrFinalityEvidenceNotFound
+		}
+		return json.Unmarshal(v, &evidence)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &evidence, nil
+}
+
+// RollbackToHeight removes all blocks above the given height, along with their hash to height
+// mappings and finality evidence, and moves the latest block index back to the given height. If
+// the given height is below the earliest stored block, all blocks are removed and the
+// earliest/latest indexes cleared.
+func (bb *BBoltHandler) RollbackToHeight(height uint64) error {
+	bb.logger.Info("Rolling back blocks in DB", zap.Uint64("to_height", height))
+
+	return bb.db.Update(func(tx *bolt.Tx) error {
+		blocksBucket := tx.Bucket([]byte(blocksBucket))
+		heightsBucket := tx.Bucket([]byte(blockHeightsBucket))
+		indexBucket := tx.Bucket([]byte(indexerBucket))
+		evidenceBucket := tx.Bucket([]byte(evidenceBucket))
+
+		// pruned blocks above the rollback height are no longer finalized
+		if err := bb.savePrunedRange(indexBucket, bb.getPrunedRange(indexBucket).afterRollback(height)); err != nil {
+			return err
+		}
+		if err := bb.removePrunedHashes(tx, height); err != nil {
+			return err
+		}
+
+		latestBytes := indexBucket.Get([]byte(latestBlockKey))
+		if latestBytes == nil || bb.btoi(latestBytes) <= height {
+			return nil
+		}
+
+		// Collect blocks above the rollback height. Keys are deleted after iterating
+		// as deleting under a bbolt cursor can skip entries.
+		var removed []*types.Block
+		c := blocksBucket.Cursor()
+		for k, v := c.Seek(bb.itob(height + 1)); k != nil; k, v = c.Next() {
+			block, err := decodeBlock(v)
+			if err != nil {
+				bb.logger.Error("Error decoding block during rollback", zap.Error(err))
+				return err
+			}
+			removed = append(removed, block)
+		}
+
+		// Remove blocks and hash mappings
+		for _, block := range removed {
+			bb.logger.Debug("Removing block from db", zap.Uint64("block_height", block.BlockHeight), zap.String("block_hash", block.BlockHash))
+			if err := heightsBucket.Delete([]byte(block.BlockHash)); err != nil {
+				bb.logger.Error("Error removing height mapping", zap.Error(err))
+				return err
+			}
+			if err := blocksBucket.Delete(bb.itob(block.BlockHeight)); err != nil {
+				bb.logger.Error("Error removing block", zap.Error(err))
+				return err
+			}
+			if err := evidenceBucket.Delete(bb.itob(block.BlockHeight)); err != nil {
+				bb.logger.Error("Error removing finality evidence", zap.Error(err))
+				return err
+			}
+		}
+
+		// Rolled back past the earliest block, so there are no blocks left
+		earliestBytes := indexBucket.Get([]byte(earliestBlockKey))
+		if earliestBytes == nil || bb.btoi(earliestBytes) > height {
+			if err := indexBucket.Delete([]byte(earliestBlockKey)); err != nil {
+				bb.logger.Error("Error removing earliest block", zap.Error(err))
+				return err
+			}
+			if err := indexBucket.Delete([]byte(latestBlockKey)); err != nil {
+				bb.logger.Error("Error removing latest block", zap.Error(err))
+				return err
+			}
+			return nil
+		}
+
+		bb.logger.Debug("Updating latest block in db", zap.Uint64("block_height", height))
+		if err := indexBucket.Put([]byte(latestBlockKey), bb.itob(height)); err != nil {
+			bb.logger.Error("Error inserting latest block", zap.Error(err))
+			return err
+		}
+		return nil
+	})
+}
+
+// PruneBlocks removes all blocks below the given height, along with their finality evidence, and advances
+// the earliest block index to the first remaining block in the same transaction. The pruned heights and the
+// hash mappings of the pruned blocks are kept, so that they are still reported finalized by height and by
+// hash. The latest block is never pruned. Returns the number of pruned blocks.
+func (bb *BBoltHandler) PruneBlocks(height uint64) (uint64, error) {
+	var pruned uint64
+	err := bb.db.Update(func(tx *bolt.Tx) error {
+		blocksBucket := tx.Bucket([]byte(blocksBucket))
+		prunedHashesBucket := tx.Bucket([]byte(prunedHashesBucket))
+		indexBucket := tx.Bucket([]byte(indexerBucket))
+		evidenceBucket := tx.Bucket([]byte(evidenceBucket))
+
+		latestBytes := indexBucket.Get([]byte(latestBlockKey))
+		if latestBytes == nil {
+			return nil
+		}
+		if latest := bb.btoi(latestBytes); height > latest {
+			height = latest
+		}
+
+		// Collect blocks below the prune height. Keys are deleted after iterating
+		// as deleting under a bbolt cursor can skip entries.
+		var removed []*types.Block
+		c := blocksBucket.Cursor()
+		for k, v := c.First(); k != nil && bb.btoi(k) < height; k, v = c.Next() {
+			block, err := decodeBlock(v)
+			if err != nil {
+				bb.logger.Error("Error decoding block during pruning", zap.Error(err))
+				return err
+			}
+			removed = append(removed, block)
+		}
+		if len(removed) == 0 {
+			return nil
+		}
+
+		// Remove blocks, recording their hashes so that their hash mappings are removed if they are
+		// rolled back
+		for _, block := range removed {
+			if err := prunedHashesBucket.Put(bb.itob(block.BlockHeight), []byte(block.BlockHash)); err != nil {
+				bb.logger.Error("Error recording pruned block hash", zap.Error(err))
+				return err
+			}
+			if err := blocksBucket.Delete(bb.itob(block.BlockHeight)); err != nil {
+				bb.logger.Error("Error removing block", zap.Error(err))
+				return err
+			}
+			if err := evidenceBucket.Delete(bb.itob(block.BlockHeight)); err != nil {
+				bb.logger.Error("Error removing finality evidence", zap.Error(err))
+				return err
+			}
+		}
+
+		pruned = uint64(len(removed))
+		prunedFrom := removed[0].BlockHeight
+		prunedTo := removed[len(removed)-1].BlockHeight + 1
+
+		// the latest block is never pruned, but the blocks may have been rolled back from under the
+		// latest block index
+		k, _ := blocksBucket.Cursor().First()
+		if k == nil {
+			bb.logger.Warn("No block left after pruning")
+			for _, key := range []string{earliestBlockKey, latestBlockKey} {
+				if err := indexBucket.Delete([]byte(key)); err != nil {
+					return err
+				}
+			}
+		} else {
+			earliest := bb.btoi(k)
+			prunedTo = earliest
+			bb.logger.Debug("Updating earliest block in db", zap.Uint64("block_height", earliest))
+			if err := indexBucket.Put([]byte(earliestBlockKey), bb.itob(earliest)); err != nil {
+				bb.logger.Error("Error inserting earliest block", zap.Error(err))
+				return err
+			}
+		}
+		return bb.savePrunedRange(indexBucket, bb.getPrunedRange(indexBucket).afterPrune(prunedFrom, prunedTo))
+	})
+	if err != nil {
+		return 0, err
+	}
+	return pruned, nil
+}
+
+// InsertBtcHeaders stores BTC headers keyed by height and extends the BTC header index range.
+// Headers already stored at the same height are overwritten.
+func (bb *BBoltHandler) InsertBtcHeaders(headers []*types.BtcHeader) error {
+	if len(headers) == 0 {
+		return nil
+	}
+
+	bb.logger.Debug("Batch inserting BTC headers to DB", zap.Int("count", len(headers)))
+
+	return bb.db.Update(func(tx *bolt.Tx) error {
+		headersBucket := tx.Bucket([]byte(btcHeadersBucket))
+		indexBucket := tx.Bucket([]byte(indexerBucket))
+
+		var minHeight, maxHeight uint64 = math.MaxUint64, 0
+		for _, header := range headers {
+			if header.Height < minHeight {
+				minHeight = header.Height
+			}
+			if header.Height > maxHeight {
+				maxHeight = header.Height
+			}
+
+			headerBytes, err := json.Marshal(header)
+			if err != nil {
+				bb.logger.Error("Error encoding BTC header", zap.Error(err))
+				return err
+			}
+			if err := headersBucket.Put(bb.itob(header.Height), headerBytes); err != nil {
+				bb.logger.Error("Error inserting BTC header to db", zap.Error(err))
+				return err
+			}
+		}
+
+		earliestBytes := indexBucket.Get([]byte(earliestBtcHeaderKey))
+		if earliestBytes == nil || minHeight < bb.btoi(earliestBytes) {
+			if err := indexBucket.Put([]byte(earliestBtcHeaderKey), bb.itob(minHeight)); err != nil {
+				bb.logger.Error("Error inserting earliest BTC header", zap.Error(err))
+				return err
+			}
+		}
+		latestBytes := indexBucket.Get([]byte(latestBtcHeaderKey))
+		if latestBytes == nil || maxHeight > bb.btoi(latestBytes) {
+			if err := indexBucket.Put([]byte(latestBtcHeaderKey), bb.itob(maxHeight)); err != nil {
+				bb.logger.Error("Error inserting latest BTC header", zap.Error(err))
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (bb *BBoltHandler) GetBtcHeaderByHeight(height uint64) (*types.BtcHeader, error) {
+	var header types.BtcHeader
+	err := bb.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(btcHeadersBucket))
+		v := b.Get(bb.itob(height))
+		if v == nil {
+			return types.ErrBtcHeaderNotFound
+		}
+		return json.Unmarshal(v, &header)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &header, nil
+}
+
+// QueryEarliestBtcHeader returns the lowest indexed BTC header, or nil if the index is empty
+func (bb *BBoltHandler) QueryEarliestBtcHeader() (*types.BtcHeader, error) {
+	return bb.queryBtcHeaderByIndexKey(earliestBtcHeaderKey)
+}
+
+// QueryLatestBtcHeader returns the highest indexed BTC header, or nil if the index is empty
+func (bb *BBoltHandler) QueryLatestBtcHeader() (*types.BtcHeader, error) {
+	return bb.queryBtcHeaderByIndexKey(latestBtcHeaderKey)
+}
+
+// RollbackBtcHeadersToHeight removes all BTC headers above the given height. If the given height
+// is below the earliest indexed header, the index is cleared.
+func (bb *BBoltHandler) RollbackBtcHeadersToHeight(height uint64) error {
+	bb.logger.Info("Rolling back BTC headers in DB", zap.Uint64("to_height", height))
+
+	return bb.db.Update(func(tx *bolt.Tx) error {
+		headersBucket := tx.Bucket([]byte(btcHeadersBucket))
+		indexBucket := tx.Bucket([]byte(indexerBucket))
+
+		latestBytes := indexBucket.Get([]byte(latestBtcHeaderKey))
+		if latestBytes == nil || bb.btoi(latestBytes) <= height {
+			return nil
+		}
+
+		// Keys are deleted after iterating as deleting under a bbolt cursor can skip entries
+		var removed [][]byte
+		c := headersBucket.Cursor()
+		for k, _ := c.Seek(bb.itob(height + 1)); k != nil; k, _ = c.Next() {
+			removed = append(removed, append([]byte{}, k...))
+		}
+		for _, k := range removed {
+			if err := headersBucket.Delete(k); err != nil {
+				bb.logger.Error("Error removing BTC header", zap.Error(err))
+				return err
+			}
+		}
+
+		earliestBytes := indexBucket.Get([]byte(earliestBtcHeaderKey))
+		if earliestBytes == nil || bb.btoi(earliestBytes) > height {
+			if err := indexBucket.Delete([]byte(earliestBtcHeaderKey)); err != nil {
+				bb.logger.Error("Error removing earliest BTC header", zap.Error(err))
+				return err
+			}
+			if err := indexBucket.Delete([]byte(latestBtcHeaderKey)); err != nil {
+				bb.logger.Error("Error removing latest BTC header", zap.Error(err))
+				return err
+			}
+			return nil
+		}
+		if err := indexBucket.Put([]byte(latestBtcHeaderKey), bb.itob(height)); err != nil {
+			bb.logger.Error("Error inserting latest BTC header", zap.Error(err))
+			return err
+		}
+		return nil
+	})
+}
+
 func (bb *BBoltHandler) GetActivatedTimestamp() (uint64, error) {
 	var timestamp uint64
 	err := bb.db.View(func(tx *bolt.Tx) error {
@@ -273,6 +664,110 @@ func (bb *BBoltHandler) SaveActivatedTimestamp(timestamp uint64) error {
 	})
 }
 
+// SaveTxWatch inserts or updates a transaction watch
+func (bb *BBoltHandler) SaveTxWatch(watch *types.TxWatch) error {
+	watchBytes, err := json.Marshal(watch)
+	if err != nil {
+		bb.logger.Error("Error encoding transaction watch", zap.Error(err))
+		return err
+	}
+	return bb.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(txWatchesBucket))
+		return b.Put([]byte(watch.Id), watchBytes)
+	})
+}
+
+func (bb *BBoltHandler) GetTxWatch(id string) (*types.TxWatch, error) {
+	var watch types.TxWatch
+	err := bb.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(txWatchesBucket))
+		v := b.Get([]byte(id))
+		if v == nil {
+			return types.ErrTxWatchNotFound
+		}
+		return json.Unmarshal(v, &watch)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &watch, nil
+}
+
+// QueryTxWatches returns all transaction watches, ordered by id
+func (bb *BBoltHandler) QueryTxWatches() ([]*types.TxWatch, error) {
+	var watches []*types.TxWatch
+	err := bb.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(txWatchesBucket))
+		return b.ForEach(func(k, v []byte) error {
+			var watch types.TxWatch
+			if err := json.Unmarshal(v, &watch); err != nil {
+				return err
+			}
+			watches = append(watches, &watch)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return watches, nil
+}
+
+// DeleteTxWatch removes a transaction watch, it is a no-op if the watch does not exist
+func (bb *BBoltHandler) DeleteTxWatch(id string) error {
+	return bb.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(txWatchesBucket))
+		return b.Delete([]byte(id))
+	})
+}
+
+// GetSchemaVersion returns the schema version of the DB, or SchemaVersionLegacy if it has none
+func (bb *BBoltHandler) GetSchemaVersion() (uint64, error) {
+	version := SchemaVersionLegacy
+	err := bb.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(indexerBucket))
+		v := b.Get([]byte(schemaVersionKey))
+		if v != nil {
+			version = bb.btoi(v)
+		}
+		return nil
+	})
+	if err != nil {
+		return SchemaVersionLegacy, err
+	}
+	return version, nil
+}
+
+func (bb *BBoltHandler) SaveSchemaVersion(version uint64) error {
+	bb.logger.Info("Saving DB schema version", zap.Uint64("schema_version", version))
+	return bb.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(indexerBucket))
+		return b.Put([]byte(schemaVersionKey), bb.itob(version))
+	})
+}
+
+// Backup copies the DB, as of the last committed update, to a new file at path
+func (bb *BBoltHandler) Backup(path string) error {
+	if err := checkPathAvailable(path); err != nil {
+		return err
+	}
+	bb.logger.Info("Backing up DB", zap.String("backup_path", path))
+	return bb.db.View(func(tx *bolt.Tx) error {
+		// 0600 = read/write permission for owner only
+		return tx.CopyFile(path, 0600)
+	})
+}
+
+// Ping checks the DB is open and readable
+func (bb *BBoltHandler) Ping() error {
+	return bb.db.View(func(tx *bolt.Tx) error {
+		if tx.Bucket([]byte(indexerBucket)) == nil {
+			return errors.New("db schema is not initialized")
+		}
+		return nil
+	})
+}
+
 func (bb *BBoltHandler) Close() error {
 	bb.logger.Info("Closing DB...")
 	return bb.db.Close()
@@ -290,6 +785,75 @@ func (bb *BBoltHandler) tryCreateBucket(tx *bolt.Tx, bucketName string) error {
 	return err
 }
 
+func (bb *BBoltHandler) queryBtcHeaderByIndexKey(key string) (*types.BtcHeader, error) {
+	var height uint64
+	found := false
+	err := bb.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(indexerBucket))
+		v := b.Get([]byte(key))
+		if v != nil {
+			height = bb.btoi(v)
+			found = true
+		}
+		return nil
+	})
+	if err != nil || !found {
+		return nil, err
+	}
+	return bb.GetBtcHeaderByHeight(height)
+}
+
+// getPrunedRange returns the range of the pruned blocks, nil if no block was pruned
+func (bb *BBoltHandler) getPrunedRange(indexBucket *bolt.Bucket) *prunedRange {
+	from := indexBucket.Get([]byte(prunedFromKey))
+	to := indexBucket.Get([]byte(prunedToKey))
+	if from == nil || to == nil {
+		return nil
+	}
+	return &prunedRange{from: bb.btoi(from), to: bb.btoi(to)}
+}
+
+// savePrunedRange stores the range of the pruned blocks, or removes it if nil
+func (bb *BBoltHandler) savePrunedRange(indexBucket *bolt.Bucket, r *prunedRange) error {
+	if r == nil {
+		if err := indexBucket.Delete([]byte(prunedFromKey)); err != nil {
+			return err
+		}
+		return indexBucket.Delete([]byte(prunedToKey))
+	}
+	if err := indexBucket.Put([]byte(prunedFromKey), bb.itob(r.from)); err != nil {
+		bb.logger.Error("Error inserting pruned range", zap.Error(err))
+		return err
+	}
+	return indexBucket.Put([]byte(prunedToKey), bb.itob(r.to))
+}
+
+// removePrunedHashes removes the hash mappings of the pruned blocks above the given height, as they are
+// rolled back
+func (bb *BBoltHandler) removePrunedHashes(tx *bolt.Tx, height uint64) error {
+	prunedHashesBucket := tx.Bucket([]byte(prunedHashesBucket))
+	heightsBucket := tx.Bucket([]byte(blockHeightsBucket))
+
+	// Keys are deleted after iterating as deleting under a bbolt cursor can skip entries
+	var heights, hashes [][]byte
+	c := prunedHashesBucket.Cursor()
+	for k, v := c.Seek(bb.itob(height + 1)); k != nil; k, v = c.Next() {
+		heights = append(heights, k)
+		hashes = append(hashes, v)
+	}
+	for i := range heights {
+		if err := heightsBucket.Delete(hashes[i]); err != nil {
+			bb.logger.Error("Error removing height mapping", zap.Error(err))
+			return err
+		}
+		if err := prunedHashesBucket.Delete(heights[i]); err != nil {
+			bb.logger.Error("Error removing pruned block hash", zap.Error(err))
+			return err
+		}
+	}
+	return nil
+}
+
 func (bb *BBoltHandler) itob(v uint64) []byte {
 	buf := new(bytes.Buffer)
 	err := binary.Write(buf, binary.BigEndian, v)
@@ -0,0 +1,687 @@
+package db
+
+import (
+	"math"
+	"testing"
+
+	"github.com/babylonlabs-io/finality-gadget/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// runConformanceTests runs the tests every IDatabaseHandler implementation must pass, each on a fresh DB returned
+// by setup along with its cleanup function
+func runConformanceTests(t *testing.T, setup func(t *testing.T) (IDatabaseHandler, func())) {
+	testCases := []struct {
+		test func(t *testing.T, handler IDatabaseHandler)
+		name string
+	}{
+		{name: "InsertBlocks", test: testInsertBlocks},
+		{name: "GetBlockByHeight", test: testGetBlockByHeight},
+		{name: "GetBlockByHeightForNonExistentBlock", test: testGetBlockByHeightForNonExistentBlock},
+		{name: "GetBlockByHash", test: testGetBlockByHash},
+		{name: "GetBlockByHashForNonExistentBlock", test: testGetBlockByHashForNonExistentBlock},
+		{name: "QueryIsBlockFinalizedByHeight", test: testQueryIsBlockFinalizedByHeight},
+		{name: "QueryIsBlockFinalizedByHeightForNonExistentBlock", test: testQueryIsBlockFinalizedByHeightForNonExistentBlock},
+		{name: "QueryIsBlockFinalizedByHash", test: testQueryIsBlockFinalizedByHash},
+		{name: "QueryIsBlockFinalizedByHashForNonExistentBlock", test: testQueryIsBlockFinalizedByHashForNonExistentBlock},
+		{name: "QueryEarliestFinalizedBlock", test: testQueryEarliestFinalizedBlock},
+		{name: "QueryLatestFinalizedBlock", test: testQueryLatestFinalizedBlock},
+		{name: "QueryLatestFinalizedBlockNonExistent", test: testQueryLatestFinalizedBlockNonExistent},
+		{name: "GetActivatedTimestamp", test: testGetActivatedTimestamp},
+		{name: "SaveActivatedTimestamp", test: testSaveActivatedTimestamp},
+		{name: "RollbackToHeight", test: testRollbackToHeight},
+		{name: "RollbackToHeightBelowEarliestBlock", test: testRollbackToHeightBelowEarliestBlock},
+		{name: "PruneBlocks", test: testPruneBlocks},
+		{name: "PruneBlocksKeepsLatestBlock", test: testPruneBlocksKeepsLatestBlock},
+		{name: "GetBlockWithMetadata", test: testGetBlockWithMetadata},
+		{name: "FinalityEvidence", test: testFinalityEvidence},
+		{name: "BtcHeaders", test: testBtcHeaders},
+		{name: "TxWatches", test: testTxWatches},
+		{name: "Ping", test: testPing},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			handler, cleanup := setup(t)
+			defer cleanup()
+			tc.test(t, handler)
+		})
+	}
+}
+
+func testInsertBlocks(t *testing.T, handler IDatabaseHandler) {
+	// Create test blocks
+	blocks := []*types.Block{
+		{
+			BlockHeight:    1,
+			BlockHash:      "0x123",
+			BlockTimestamp: 1000,
+		},
+		{
+			BlockHeight:    2,
+			BlockHash:      "0x456",
+			BlockTimestamp: 1050,
+		},
+		{
+			BlockHeight:    3,
+			BlockHash:      "0x789",
+			BlockTimestamp: 1100,
+		},
+	}
+
+	// Test batch insert
+	err := handler.InsertBlocks(blocks)
+	assert.NoError(t, err)
+
+	// Verify all blocks were inserted correctly
+	for _, block := range blocks {
+		// Check by height
+		retrievedBlock, blockErr := handler.GetBlockByHeight(block.BlockHeight)
+		assert.NoError(t, blockErr)
+		assert.Equal(t, block.BlockHeight, retrievedBlock.BlockHeight)
+		assert.Equal(t, block.BlockHash, retrievedBlock.BlockHash)
+		assert.Equal(t, block.BlockTimestamp, retrievedBlock.BlockTimestamp)
+
+		// Check by hash
+		retrievedBlock, err = handler.GetBlockByHash(block.BlockHash)
+		assert.NoError(t, err)
+		assert.Equal(t, block.BlockHeight, retrievedBlock.BlockHeight)
+		assert.Equal(t, block.BlockHash, retrievedBlock.BlockHash)
+		assert.Equal(t, block.BlockTimestamp, retrievedBlock.BlockTimestamp)
+	}
+
+	// Verify earliest and latest blocks
+	earliest, err := handler.QueryEarliestFinalizedBlock()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), earliest.BlockHeight)
+
+	latest, err := handler.QueryLatestFinalizedBlock()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3), latest.BlockHeight)
+
+	// Test empty slice
+	err = handler.InsertBlocks([]*types.Block{})
+	assert.NoError(t, err)
+}
+
+func testGetBlockByHeight(t *testing.T, handler IDatabaseHandler) {
+	// Insert a block
+	block := &types.Block{
+		BlockHeight:    1,
+		BlockHash:      "0x123",
+		BlockTimestamp: 1000,
+	}
+	err := handler.InsertBlocks([]*types.Block{block})
+	assert.NoError(t, err)
+
+	// Retrieve block by height
+	retrievedBlock, err := handler.GetBlockByHeight(block.BlockHeight)
+	assert.NoError(t, err)
+	assert.Equal(t, block.BlockHeight, retrievedBlock.BlockHeight)
+	assert.Equal(t, block.BlockHash, retrievedBlock.BlockHash)
+	assert.Equal(t, block.BlockTimestamp, retrievedBlock.BlockTimestamp)
+}
+
+func testGetBlockByHeightForNonExistentBlock(t *testing.T, handler IDatabaseHandler) {
+	block, err := handler.GetBlockByHeight(1)
+	assert.Nil(t, block)
+	assert.Equal(t, types.ErrBlockNotFound, err)
+}
+
+func testGetBlockByHash(t *testing.T, handler IDatabaseHandler) {
+	// Insert a block
+	block := &types.Block{
+		BlockHeight:    1,
+		BlockHash:      "0x123",
+		BlockTimestamp: 1000,
+	}
+	err := handler.InsertBlocks([]*types.Block{block})
+	assert.NoError(t, err)
+
+	// Retrieve block by hash
+	retrievedBlock, err := handler.GetBlockByHash(block.BlockHash)
+	assert.NoError(t, err)
+	assert.Equal(t, block.BlockHeight, retrievedBlock.BlockHeight)
+	assert.Equal(t, block.BlockHash, retrievedBlock.BlockHash)
+	assert.Equal(t, block.BlockTimestamp, retrievedBlock.BlockTimestamp)
+}
+
+func testGetBlockByHashForNonExistentBlock(t *testing.T, handler IDatabaseHandler) {
+	block, err := handler.GetBlockByHash("0x123")
+	assert.Nil(t, block)
+	assert.Equal(t, types.ErrBlockNotFound, err)
+}
+
+func testQueryIsBlockFinalizedByHeight(t *testing.T, handler IDatabaseHandler) {
+	// Insert a block
+	block := &types.Block{
+		BlockHeight:    1,
+		BlockHash:      "0x123",
+		BlockTimestamp: 1000,
+	}
+	err := handler.InsertBlocks([]*types.Block{block})
+	assert.NoError(t, err)
+
+	// Retrieve block status by height
+	isFinalized, err := handler.QueryIsBlockFinalizedByHeight(block.BlockHeight)
+	assert.NoError(t, err)
+	assert.Equal(t, isFinalized, true)
+}
+
+func testQueryIsBlockFinalizedByHeightForNonExistentBlock(t *testing.T, handler IDatabaseHandler) {
+	isFinalized, err := handler.QueryIsBlockFinalizedByHeight(1)
+	assert.NoError(t, err)
+	assert.Equal(t, isFinalized, false)
+}
+
+func testQueryIsBlockFinalizedByHash(t *testing.T, handler IDatabaseHandler) {
+	// Insert a block
+	block := &types.Block{
+		BlockHeight:    1,
+		BlockHash:      "0x123",
+		BlockTimestamp: 1000,
+	}
+	err := handler.InsertBlocks([]*types.Block{block})
+	assert.NoError(t, err)
+
+	// Retrieve block status by hash
+	isFinalized, err := handler.QueryIsBlockFinalizedByHash(block.BlockHash)
+	assert.NoError(t, err)
+	assert.Equal(t, isFinalized, true)
+}
+
+func testQueryIsBlockFinalizedByHashForNonExistentBlock(t *testing.T, handler IDatabaseHandler) {
+	isFinalized, err := handler.QueryIsBlockFinalizedByHash("0x123")
+	assert.NoError(t, err)
+	assert.Equal(t, isFinalized, false)
+}
+
+func testQueryEarliestFinalizedBlock(t *testing.T, handler IDatabaseHandler) {
+	// Insert two blocks
+	first := &types.Block{
+		BlockHeight:    1,
+		BlockHash:      "0x123",
+		BlockTimestamp: 1000,
+	}
+	second := &types.Block{
+		BlockHeight:    2,
+		BlockHash:      "0x456",
+		BlockTimestamp: 1050,
+	}
+	third := &types.Block{
+		BlockHeight:    3,
+		BlockHash:      "0x789",
+		BlockTimestamp: 1100,
+	}
+	err := handler.InsertBlocks([]*types.Block{first, second, third})
+	assert.NoError(t, err)
+
+	// Query earliest consecutively finalized block
+	earliestBlock, err := handler.QueryEarliestFinalizedBlock()
+	assert.NoError(t, err)
+	assert.Equal(t, earliestBlock.BlockHeight, first.BlockHeight)
+	assert.Equal(t, earliestBlock.BlockHash, first.BlockHash)
+	assert.Equal(t, earliestBlock.BlockTimestamp, first.BlockTimestamp)
+}
+
+func testQueryLatestFinalizedBlock(t *testing.T, handler IDatabaseHandler) {
+	// Insert two blocks
+	first := &types.Block{
+		BlockHeight:    1,
+		BlockHash:      "0x123",
+		BlockTimestamp: 1000,
+	}
+	second := &types.Block{
+		BlockHeight:    2,
+		BlockHash:      "0x456",
+		BlockTimestamp: 1050,
+	}
+	err := handler.InsertBlocks([]*types.Block{first, second})
+	assert.NoError(t, err)
+
+	// Retrieve latest block
+	latestBlock, err := handler.QueryLatestFinalizedBlock()
+	assert.NoError(t, err)
+	assert.Equal(t, latestBlock.BlockHeight, second.BlockHeight)
+	assert.Equal(t, latestBlock.BlockHash, second.BlockHash)
+	assert.Equal(t, latestBlock.BlockTimestamp, second.BlockTimestamp)
+}
+
+func testQueryLatestFinalizedBlockNonExistent(t *testing.T, handler IDatabaseHandler) {
+	latestBlock, err := handler.QueryLatestFinalizedBlock()
+	assert.Nil(t, latestBlock)
+	assert.NoError(t, err)
+}
+
+func testGetActivatedTimestamp(t *testing.T, handler IDatabaseHandler) {
+	// Test when timestamp is not set
+	timestamp, err := handler.GetActivatedTimestamp()
+	assert.Equal(t, uint64(math.MaxUint64), timestamp)
+	assert.Equal(t, types.ErrActivatedTimestampNotFound, err)
+
+	// Set timestamp
+	expectedTimestamp := uint64(1234567890)
+	err = handler.SaveActivatedTimestamp(expectedTimestamp)
+	assert.NoError(t, err)
+
+	// Test when timestamp is set
+	timestamp, err = handler.GetActivatedTimestamp()
+	assert.NoError(t, err)
+	assert.Equal(t, expectedTimestamp, timestamp)
+}
+
+func testSaveActivatedTimestamp(t *testing.T, handler IDatabaseHandler) {
+	// Set timestamp
+	expectedTimestamp := uint64(1234567890)
+	err := handler.SaveActivatedTimestamp(expectedTimestamp)
+	assert.NoError(t, err)
+
+	// Verify timestamp was saved
+	timestamp, err := handler.GetActivatedTimestamp()
+	assert.NoError(t, err)
+	assert.Equal(t, expectedTimestamp, timestamp)
+}
+
+func testRollbackToHeight(t *testing.T, handler IDatabaseHandler) {
+	blocks := []*types.Block{
+		{BlockHeight: 1, BlockHash: "0x123", BlockTimestamp: 1000},
+		{BlockHeight: 2, BlockHash: "0x456", BlockTimestamp: 1050, ParentHash: "0x123"},
+		{BlockHeight: 3, BlockHash: "0x789", BlockTimestamp: 1100, ParentHash: "0x456"},
+	}
+	err := handler.InsertBlocks(blocks)
+	assert.NoError(t, err)
+
+	// Roll back the last block
+	err = handler.RollbackToHeight(2)
+	assert.NoError(t, err)
+
+	// Verify block and hash mapping were removed
+	block, err := handler.GetBlockByHeight(3)
+	assert.Nil(t, block)
+	assert.Equal(t, types.ErrBlockNotFound, err)
+	isFinalized, err := handler.QueryIsBlockFinalizedByHash("0x789")
+	assert.NoError(t, err)
+	assert.False(t, isFinalized)
+
+	// Verify remaining blocks are untouched
+	retrievedBlock, err := handler.GetBlockByHash("0x456")
+	assert.NoError(t, err)
+	assert.Equal(t, blocks[1].ParentHash, retrievedBlock.ParentHash)
+
+	// Verify latest block was moved back
+	latest, err := handler.QueryLatestFinalizedBlock()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), latest.BlockHeight)
+
+	// Rolling back above the latest block is a no-op
+	err = handler.RollbackToHeight(5)
+	assert.NoError(t, err)
+	latest, err = handler.QueryLatestFinalizedBlock()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), latest.BlockHeight)
+
+	// A new block can be inserted at the rolled back height
+	err = handler.InsertBlocks([]*types.Block{{BlockHeight: 3, BlockHash: "0xabc", BlockTimestamp: 1100, ParentHash: "0x456"}})
+	assert.NoError(t, err)
+	latest, err = handler.QueryLatestFinalizedBlock()
+	assert.NoError(t, err)
+	assert.Equal(t, "0xabc", latest.BlockHash)
+}
+
+func testRollbackToHeightBelowEarliestBlock(t *testing.T, handler IDatabaseHandler) {
+	blocks := []*types.Block{
+		{BlockHeight: 5, BlockHash: "0x123", BlockTimestamp: 1000},
+		{BlockHeight: 6, BlockHash: "0x456", BlockTimestamp: 1050},
+	}
+	err := handler.InsertBlocks(blocks)
+	assert.NoError(t, err)
+
+	// Roll back past the earliest block
+	err = handler.RollbackToHeight(4)
+	assert.NoError(t, err)
+
+	// Verify all blocks and indexes were removed
+	for _, block := range blocks {
+		isFinalized, err := handler.QueryIsBlockFinalizedByHash(block.BlockHash)
+		assert.NoError(t, err)
+		assert.False(t, isFinalized)
+	}
+	latest, err := handler.QueryLatestFinalizedBlock()
+	assert.NoError(t, err)
+	assert.Nil(t, latest)
+	_, err = handler.QueryEarliestFinalizedBlock()
+	assert.Equal(t, types.ErrBlockNotFound, err)
+
+	// Earliest block is set again on the next insert
+	err = handler.InsertBlocks([]*types.Block{{BlockHeight: 7, BlockHash: "0x789", BlockTimestamp: 1100}})
+	assert.NoError(t, err)
+	earliest, err := handler.QueryEarliestFinalizedBlock()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(7), earliest.BlockHeight)
+}
+
+func testPruneBlocks(t *testing.T, handler IDatabaseHandler) {
+	// Pruning an empty DB is a no-op
+	pruned, err := handler.PruneBlocks(10)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), pruned)
+	_, err = handler.QueryEarliestFinalizedHeight()
+	assert.Equal(t, types.ErrBlockNotFound, err)
+
+	blocks := []*types.Block{
+		{BlockHeight: 1, BlockHash: "0x123", BlockTimestamp: 1000},
+		{BlockHeight: 2, BlockHash: "0x456", BlockTimestamp: 1050, ParentHash: "0x123"},
+		{BlockHeight: 3, BlockHash: "0x789", BlockTimestamp: 1100, ParentHash: "0x456"},
+		{BlockHeight: 4, BlockHash: "0xabc", BlockTimestamp: 1150, ParentHash: "0x789"},
+	}
+	err = handler.InsertBlocks(blocks)
+	assert.NoError(t, err)
+	err = handler.InsertFinalityEvidence([]*types.FinalityEvidence{
+		{BlockHeight: 1, BlockHash: "0x123", BtcHeight: 100},
+		{BlockHeight: 3, BlockHash: "0x789", BtcHeight: 100},
+	})
+	assert.NoError(t, err)
+	earliestHeight, err := handler.QueryEarliestFinalizedHeight()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), earliestHeight)
+
+	// Prune the blocks below height 3
+	pruned, err = handler.PruneBlocks(3)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), pruned)
+
+	// Verify blocks and evidence were removed, but are still finalized by hash
+	for _, block := range blocks[:2] {
+		retrievedBlock, err := handler.GetBlockByHeight(block.BlockHeight)
+		assert.Nil(t, retrievedBlock)
+		assert.Equal(t, types.ErrBlockNotFound, err)
+		retrievedBlock, err = handler.GetBlockByHash(block.BlockHash)
+		assert.Nil(t, retrievedBlock)
+		assert.Equal(t, types.ErrBlockNotFound, err)
+		isFinalized, err := handler.QueryIsBlockFinalizedByHash(block.BlockHash)
+		assert.NoError(t, err)
+		assert.True(t, isFinalized)
+	}
+	_, err = handler.GetFinalityEvidenceByHeight(1)
+	assert.Equal(t, types.ErrFinalityEvidenceNotFound, err)
+
+	// Verify remaining blocks and evidence are untouched
+	retrievedBlock, err := handler.GetBlockByHash("0x789")
+	assert.NoError(t, err)
+	assert.Equal(t, blocks[2], retrievedBlock)
+	_, err = handler.GetFinalityEvidenceByHeight(3)
+	assert.NoError(t, err)
+
+	// Verify earliest block was moved forward
+	earliest, err := handler.QueryEarliestFinalizedBlock()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3), earliest.BlockHeight)
+
+	// Verify pruned blocks are still finalized by height
+	for height, expected := range map[uint64]bool{0: false, 1: true, 2: true, 3: true, 5: false} {
+		isFinalized, err := handler.QueryIsBlockFinalizedByHeight(height)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, isFinalized, "height %d", height)
+	}
+	earliestHeight, err = handler.QueryEarliestFinalizedHeight()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), earliestHeight)
+
+	// Pruning below the earliest block is a no-op
+	pruned, err = handler.PruneBlocks(2)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), pruned)
+	earliest, err = handler.QueryEarliestFinalizedBlock()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3), earliest.BlockHeight)
+
+	// Pruning again extends the pruned heights
+	pruned, err = handler.PruneBlocks(4)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), pruned)
+	earliestHeight, err = handler.QueryEarliestFinalizedHeight()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), earliestHeight)
+	isFinalized, err := handler.QueryIsBlockFinalizedByHeight(3)
+	assert.NoError(t, err)
+	assert.True(t, isFinalized)
+
+	// Rolling back into the pruned heights removes the ones above the rollback height
+	err = handler.RollbackToHeight(1)
+	assert.NoError(t, err)
+	_, err = handler.QueryEarliestFinalizedHeight()
+	assert.Equal(t, types.ErrBlockNotFound, err)
+	for height, expected := range map[uint64]bool{1: true, 2: false, 3: false, 4: false} {
+		isFinalized, err := handler.QueryIsBlockFinalizedByHeight(height)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, isFinalized, "height %d", height)
+		isFinalized, err = handler.QueryIsBlockFinalizedByHash(blocks[height-1].BlockHash)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, isFinalized, "hash %s", blocks[height-1].BlockHash)
+	}
+
+	// Blocks rolled back from the pruned heights are not finalized by hash once replaced by a fork
+	err = handler.InsertBlocks([]*types.Block{{BlockHeight: 2, BlockHash: "0x457", BlockTimestamp: 1050, ParentHash: "0x123"}})
+	assert.NoError(t, err)
+	isFinalized, err = handler.QueryIsBlockFinalizedByHash("0x456")
+	assert.NoError(t, err)
+	assert.False(t, isFinalized)
+	err = handler.RollbackToHeight(1)
+	assert.NoError(t, err)
+
+	// Pruned heights only count towards the earliest finalized height if the stored blocks follow them
+	err = handler.InsertBlocks([]*types.Block{{BlockHeight: 5, BlockHash: "0xdef", BlockTimestamp: 1200}})
+	assert.NoError(t, err)
+	earliestHeight, err = handler.QueryEarliestFinalizedHeight()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(5), earliestHeight)
+
+	// Rolling back below the pruned heights removes them
+	err = handler.RollbackToHeight(0)
+	assert.NoError(t, err)
+	isFinalized, err = handler.QueryIsBlockFinalizedByHeight(1)
+	assert.NoError(t, err)
+	assert.False(t, isFinalized)
+	isFinalized, err = handler.QueryIsBlockFinalizedByHash("0x123")
+	assert.NoError(t, err)
+	assert.False(t, isFinalized)
+}
+
+func testPruneBlocksKeepsLatestBlock(t *testing.T, handler IDatabaseHandler) {
+	blocks := []*types.Block{
+		{BlockHeight: 5, BlockHash: "0x123", BlockTimestamp: 1000},
+		{BlockHeight: 6, BlockHash: "0x456", BlockTimestamp: 1050},
+	}
+	err := handler.InsertBlocks(blocks)
+	assert.NoError(t, err)
+
+	// Prune past the latest block
+	pruned, err := handler.PruneBlocks(10)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), pruned)
+
+	// Verify the latest block is kept as the earliest block
+	earliest, err := handler.QueryEarliestFinalizedBlock()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(6), earliest.BlockHeight)
+	earliestHeight, err := handler.QueryEarliestFinalizedHeight()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(5), earliestHeight)
+	latest, err := handler.QueryLatestFinalizedBlock()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(6), latest.BlockHeight)
+
+	// New blocks are inserted after the pruned blocks
+	err = handler.InsertBlocks([]*types.Block{{BlockHeight: 7, BlockHash: "0x789", BlockTimestamp: 1100}})
+	assert.NoError(t, err)
+	earliest, err = handler.QueryEarliestFinalizedBlock()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(6), earliest.BlockHeight)
+}
+
+func testGetBlockWithMetadata(t *testing.T, handler IDatabaseHandler) {
+	block := &types.Block{
+		BlockHeight:    1,
+		BlockHash:      "0x123",
+		BlockTimestamp: 1000,
+		ParentHash:     "0x012",
+		StateRoot:      "0xabc",
+		L1OriginHash:   "0xdef",
+		L1OriginNumber: 100,
+	}
+	err := handler.InsertBlocks([]*types.Block{block})
+	assert.NoError(t, err)
+
+	// Verify metadata is returned by both height and latest block queries
+	retrievedBlock, err := handler.GetBlockByHeight(block.BlockHeight)
+	assert.NoError(t, err)
+	assert.Equal(t, block, retrievedBlock)
+	latest, err := handler.QueryLatestFinalizedBlock()
+	assert.NoError(t, err)
+	assert.Equal(t, block, latest)
+}
+
+func testFinalityEvidence(t *testing.T, handler IDatabaseHandler) {
+	evidence := []*types.FinalityEvidence{
+		{
+			BlockHeight: 1,
+			BlockHash:   "0x123",
+			BtcHeight:   100,
+			TotalPower:  300,
+			VotedPower:  200,
+			Voters:      []*types.VoterPower{{FpBtcPkHex: "pk1", Power: 100}, {FpBtcPkHex: "pk2", Power: 100}},
+		},
+		{
+			BlockHeight:   2,
+			BlockHash:     "0x456",
+			BtcHeight:     101,
+			BabylonHeight: 4242,
+			TotalPower:    300,
+			VotedPower:    300,
+			Voters:        []*types.VoterPower{{FpBtcPkHex: "pk1", Power: 100}, {FpBtcPkHex: "pk2", Power: 200}},
+		},
+	}
+	err := handler.InsertFinalityEvidence(evidence)
+	assert.NoError(t, err)
+
+	// Verify evidence was stored
+	for _, e := range evidence {
+		retrieved, err := handler.GetFinalityEvidenceByHeight(e.BlockHeight)
+		assert.NoError(t, err)
+		assert.Equal(t, e, retrieved)
+	}
+
+	// Non-existent evidence
+	retrieved, err := handler.GetFinalityEvidenceByHeight(3)
+	assert.Nil(t, retrieved)
+	assert.Equal(t, types.ErrFinalityEvidenceNotFound, err)
+
+	// Rolling back blocks removes their evidence
+	err = handler.InsertBlocks([]*types.Block{
+		{BlockHeight: 1, BlockHash: "0x123", BlockTimestamp: 1000},
+		{BlockHeight: 2, BlockHash: "0x456", BlockTimestamp: 1050},
+	})
+	assert.NoError(t, err)
+	err = handler.RollbackToHeight(1)
+	assert.NoError(t, err)
+	_, err = handler.GetFinalityEvidenceByHeight(2)
+	assert.Equal(t, types.ErrFinalityEvidenceNotFound, err)
+	retrieved, err = handler.GetFinalityEvidenceByHeight(1)
+	assert.NoError(t, err)
+	assert.Equal(t, evidence[0], retrieved)
+}
+
+func testBtcHeaders(t *testing.T, handler IDatabaseHandler) {
+	// Empty index
+	earliest, err := handler.QueryEarliestBtcHeader()
+	assert.NoError(t, err)
+	assert.Nil(t, earliest)
+	latest, err := handler.QueryLatestBtcHeader()
+	assert.NoError(t, err)
+	assert.Nil(t, latest)
+
+	headers := []*types.BtcHeader{
+		{Height: 100, Hash: "0x100", PrevHash: "0x099", Timestamp: 1000, MedianTimePast: 900},
+		{Height: 101, Hash: "0x101", PrevHash: "0x100", Timestamp: 990, MedianTimePast: 910},
+		{Height: 102, Hash: "0x102", PrevHash: "0x101", Timestamp: 1200, MedianTimePast: 920},
+	}
+	err = handler.InsertBtcHeaders(headers)
+	assert.NoError(t, err)
+
+	// Verify headers were stored
+	for _, header := range headers {
+		retrieved, err := handler.GetBtcHeaderByHeight(header.Height)
+		assert.NoError(t, err)
+		assert.Equal(t, header, retrieved)
+	}
+	_, err = handler.GetBtcHeaderByHeight(103)
+	assert.Equal(t, types.ErrBtcHeaderNotFound, err)
+
+	// Verify the index range
+	earliest, err = handler.QueryEarliestBtcHeader()
+	assert.NoError(t, err)
+	assert.Equal(t, headers[0], earliest)
+	latest, err = handler.QueryLatestBtcHeader()
+	assert.NoError(t, err)
+	assert.Equal(t, headers[2], latest)
+
+	// Rolling back removes the headers above the height
+	err = handler.RollbackBtcHeadersToHeight(100)
+	assert.NoError(t, err)
+	_, err = handler.GetBtcHeaderByHeight(101)
+	assert.Equal(t, types.ErrBtcHeaderNotFound, err)
+	latest, err = handler.QueryLatestBtcHeader()
+	assert.NoError(t, err)
+	assert.Equal(t, headers[0], latest)
+
+	// Rolling back below the earliest header empties the index
+	err = handler.RollbackBtcHeadersToHeight(99)
+	assert.NoError(t, err)
+	_, err = handler.GetBtcHeaderByHeight(100)
+	assert.Equal(t, types.ErrBtcHeaderNotFound, err)
+	earliest, err = handler.QueryEarliestBtcHeader()
+	assert.NoError(t, err)
+	assert.Nil(t, earliest)
+	latest, err = handler.QueryLatestBtcHeader()
+	assert.NoError(t, err)
+	assert.Nil(t, latest)
+}
+
+func testTxWatches(t *testing.T, handler IDatabaseHandler) {
+	watches, err := handler.QueryTxWatches()
+	assert.NoError(t, err)
+	assert.Empty(t, watches)
+
+	first := &types.TxWatch{Id: "a", TxHash: "0x1", CallbackUrl: "https://example.com/1", CreatedAt: 1000}
+	second := &types.TxWatch{Id: "b", TxHash: "0x2", CallbackUrl: "https://example.com/2", CreatedAt: 2000}
+	assert.NoError(t, handler.SaveTxWatch(second))
+	assert.NoError(t, handler.SaveTxWatch(first))
+
+	watches, err = handler.QueryTxWatches()
+	assert.NoError(t, err)
+	assert.Equal(t, []*types.TxWatch{first, second}, watches)
+
+	// Saving an existing watch updates it
+	first.LastStatus = types.FinalityStatusSafe
+	assert.NoError(t, handler.SaveTxWatch(first))
+	retrieved, err := handler.GetTxWatch("a")
+	assert.NoError(t, err)
+	assert.Equal(t, first, retrieved)
+
+	// Deleted watches are no longer returned
+	assert.NoError(t, handler.DeleteTxWatch("a"))
+	_, err = handler.GetTxWatch("a")
+	assert.Equal(t, types.ErrTxWatchNotFound, err)
+	watches, err = handler.QueryTxWatches()
+	assert.NoError(t, err)
+	assert.Equal(t, []*types.TxWatch{second}, watches)
+	assert.NoError(t, handler.DeleteTxWatch("a"))
+}
+
+func testPing(t *testing.T, handler IDatabaseHandler) {
+	assert.NoError(t, handler.Ping())
+
+	// Ping fails once the DB is closed
+	assert.NoError(t, handler.Close())
+	assert.Error(t, handler.Ping())
+}
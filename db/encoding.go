@@ -0,0 +1,133 @@
+package db
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/babylonlabs-io/finality-gadget/types"
+)
+
+// blockEncodingV1 is the first byte of blocks stored with the binary encoding. Blocks stored as
+// JSON before it was introduced start with '{', so both can be read from the same bucket.
+const blockEncodingV1 byte = 0x01
+
+// kinds of the hash fields of binary encoded blocks
+const (
+	// hashFieldEmpty is an empty string
+	hashFieldEmpty byte = iota
+	// hashFieldRaw is a 0x prefixed lowercase hex 32-byte hash, stored as the raw 32 bytes
+	hashFieldRaw
+	// hashFieldString is any other string, stored as its uvarint length followed by its bytes
+	hashFieldString
+)
+
+const hashLength = 32
+
+var errTruncatedBlock = errors.New("truncated block encoding")
+
+/* encodeBlock encodes the block with the binary encoding
+ *
+ * - the encoding version byte, blockEncodingV1
+ * - the height, timestamp and L1 origin number as uvarints
+ * - the block hash, parent hash, state root and L1 origin hash, each as a kind byte followed by the
+ *   raw 32 bytes of 0x prefixed lowercase hex hashes, or the length and bytes of other strings, so
+ *   that any string round trips
+ */
+func encodeBlock(block *types.Block) []byte {
+	buf := make([]byte, 0, 1+3*binary.MaxVarintLen64+4*(1+hashLength))
+	buf = append(buf, blockEncodingV1)
+	buf = binary.AppendUvarint(buf, block.BlockHeight)
+	buf = binary.AppendUvarint(buf, block.BlockTimestamp)
+	buf = binary.AppendUvarint(buf, block.L1OriginNumber)
+	for _, hash := range []string{block.BlockHash, block.ParentHash, block.StateRoot, block.L1OriginHash} {
+		buf = appendHashField(buf, hash)
+	}
+	return buf
+}
+
+// decodeBlock decodes a block stored with the binary encoding, or as JSON
+func decodeBlock(data []byte) (*types.Block, error) {
+	if len(data) == 0 {
+		return nil, errTruncatedBlock
+	}
+	var block types.Block
+	switch data[0] {
+	case '{':
+		if err := json.Unmarshal(data, &block); err != nil {
+			return nil, err
+		}
+		return &block, nil
+	case blockEncodingV1:
+	default:
+		return nil, fmt.Errorf("unknown block encoding %#x", data[0])
+	}
+
+	data = data[1:]
+	for _, field := range []*uint64{&block.BlockHeight, &block.BlockTimestamp, &block.L1OriginNumber} {
+		v, n := binary.Uvarint(data)
+		if n <= 0 {
+			return nil, errTruncatedBlock
+		}
+		*field = v
+		data = data[n:]
+	}
+	for _, field := range []*string{&block.BlockHash, &block.ParentHash, &block.StateRoot, &block.L1OriginHash} {
+		var err error
+		if *field, data, err = readHashField(data); err != nil {
+			return nil, err
+		}
+	}
+	if len(data) > 0 {
+		return nil, fmt.Errorf("%d trailing bytes after block encoding", len(data))
+	}
+	return &block, nil
+}
+
+// isLegacyBlockEncoding returns whether the stored block is JSON encoded
+func isLegacyBlockEncoding(data []byte) bool {
+	return len(data) > 0 && data[0] == '{'
+}
+
+func appendHashField(buf []byte, hash string) []byte {
+	if hash == "" {
+		return append(buf, hashFieldEmpty)
+	}
+	if len(hash) == 2+2*hashLength && hash[:2] == "0x" {
+		raw, err := hex.DecodeString(hash[2:])
+		// uppercase hex would be lowercased by the raw encoding, so it is stored as a string
+		if err == nil && hex.EncodeToString(raw) == hash[2:] {
+			return append(append(buf, hashFieldRaw), raw...)
+		}
+	}
+	buf = append(buf, hashFieldString)
+	buf = binary.AppendUvarint(buf, uint64(len(hash)))
+	return append(buf, hash...)
+}
+
+func readHashField(data []byte) (string, []byte, error) {
+	if len(data) == 0 {
+		return "", nil, errTruncatedBlock
+	}
+	kind, data := data[0], data[1:]
+	switch kind {
+	case hashFieldEmpty:
+		return "", data, nil
+	case hashFieldRaw:
+		if len(data) < hashLength {
+			return "", nil, errTruncatedBlock
+		}
+		return "0x" + hex.EncodeToString(data[:hashLength]), data[hashLength:], nil
+	case hashFieldString:
+		length, n := binary.Uvarint(data)
+		if n <= 0 || uint64(len(data)-n) < length {
+			return "", nil, errTruncatedBlock
+		}
+		data = data[n:]
+		return string(data[:length]), data[length:], nil
+	default:
+		return "", nil, fmt.Errorf("unknown hash field kind %#x", kind)
+	}
+}
@@ -0,0 +1,114 @@
+package db
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/babylonlabs-io/finality-gadget/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlockEncoding(t *testing.T) {
+	testCases := []struct {
+		block *types.Block
+		name  string
+	}{
+		{
+			name: "full block",
+			block: &types.Block{
+				BlockHeight:    11,
+				BlockHash:      "0x1f4a9d0bc2e3ef0a1b2c3d4e5f60718293a4b5c6d7e8f90a1b2c3d4e5f607182",
+				BlockTimestamp: 1718000000,
+				ParentHash:     "0x0000000000000000000000000000000000000000000000000000000000000001",
+				StateRoot:      "0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff",
+				L1OriginNumber: 20000000,
+				L1OriginHash:   "0xabcdef0000000000000000000000000000000000000000000000000000abcdef",
+			},
+		},
+		{
+			name:  "legacy block without metadata",
+			block: &types.Block{BlockHeight: 1, BlockHash: "0x123", BlockTimestamp: 1000},
+		},
+		{
+			name: "hashes not in canonical form",
+			block: &types.Block{
+				BlockHeight: 2,
+				// uppercase, and without 0x prefix
+				BlockHash:  "0x1F4A9D0BC2E3EF0A1B2C3D4E5F60718293A4B5C6D7E8F90A1B2C3D4E5F607182",
+				ParentHash: "1f4a9d0bc2e3ef0a1b2c3d4e5f60718293a4b5c6d7e8f90a1b2c3d4e5f607182",
+				StateRoot:  "0xnothex0000000000000000000000000000000000000000000000000000000000",
+			},
+		},
+		{
+			name:  "empty block",
+			block: &types.Block{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			encoded := encodeBlock(tc.block)
+			require.Equal(t, blockEncodingV1, encoded[0])
+			require.False(t, isLegacyBlockEncoding(encoded))
+
+			decoded, err := decodeBlock(encoded)
+			require.NoError(t, err)
+			require.Equal(t, tc.block, decoded)
+
+			// JSON blocks stored by older versions are still readable
+			jsonBytes, err := json.Marshal(tc.block)
+			require.NoError(t, err)
+			require.True(t, isLegacyBlockEncoding(jsonBytes))
+			decoded, err = decodeBlock(jsonBytes)
+			require.NoError(t, err)
+			require.Equal(t, tc.block, decoded)
+			require.Less(t, len(encoded), len(jsonBytes))
+		})
+	}
+}
+
+func TestBlockEncodingSize(t *testing.T) {
+	block := &types.Block{
+		BlockHeight:    123456789,
+		BlockHash:      "0x1f4a9d0bc2e3ef0a1b2c3d4e5f60718293a4b5c6d7e8f90a1b2c3d4e5f607182",
+		BlockTimestamp: 1718000000,
+		ParentHash:     "0x0f4a9d0bc2e3ef0a1b2c3d4e5f60718293a4b5c6d7e8f90a1b2c3d4e5f607182",
+		StateRoot:      "0x2f4a9d0bc2e3ef0a1b2c3d4e5f60718293a4b5c6d7e8f90a1b2c3d4e5f607182",
+		L1OriginNumber: 20000000,
+		L1OriginHash:   "0x3f4a9d0bc2e3ef0a1b2c3d4e5f60718293a4b5c6d7e8f90a1b2c3d4e5f607182",
+	}
+	jsonBytes, err := json.Marshal(block)
+	require.NoError(t, err)
+
+	// version byte, 3 uvarints and 4 raw hashes with their kind byte
+	encoded := encodeBlock(block)
+	require.Equal(t, 1+4+5+4+4*(1+hashLength), len(encoded))
+	require.Less(t, len(encoded)*2, len(jsonBytes))
+}
+
+func TestDecodeBlockErrors(t *testing.T) {
+	encoded := encodeBlock(&types.Block{
+		BlockHeight: 11,
+		BlockHash:   "0x1f4a9d0bc2e3ef0a1b2c3d4e5f60718293a4b5c6d7e8f90a1b2c3d4e5f607182",
+		StateRoot:   "0x123",
+	})
+
+	// every truncation is rejected rather than decoded to a partial block
+	for i := 0; i < len(encoded); i++ {
+		_, err := decodeBlock(encoded[:i])
+		require.Error(t, err, "truncated to %d bytes", i)
+	}
+
+	_, err := decodeBlock(append(encoded, 0))
+	require.ErrorContains(t, err, "trailing bytes")
+
+	_, err = decodeBlock([]byte{0x02, 0x00})
+	require.ErrorContains(t, err, "unknown block encoding")
+
+	invalidKind := append([]byte{blockEncodingV1, 0, 0, 0}, 0x09)
+	_, err = decodeBlock(invalidKind)
+	require.ErrorContains(t, err, "unknown hash field kind")
+
+	_, err = decodeBlock([]byte(`{"block_height":`))
+	require.Error(t, err)
+}
@@ -10,8 +10,26 @@ type IDatabaseHandler interface {
 	QueryIsBlockFinalizedByHeight(height uint64) (bool, error)
 	QueryIsBlockFinalizedByHash(hash string) (bool, error)
 	QueryEarliestFinalizedBlock() (*types.Block, error)
+	QueryEarliestFinalizedHeight() (uint64, error)
 	QueryLatestFinalizedBlock() (*types.Block, error)
+	InsertFinalityEvidence(evidence []*types.FinalityEvidence) error
+	GetFinalityEvidenceByHeight(height uint64) (*types.FinalityEvidence, error)
+	RollbackToHeight(height uint64) error
+	PruneBlocks(height uint64) (uint64, error)
+	InsertBtcHeaders(headers []*types.BtcHeader) error
+	GetBtcHeaderByHeight(height uint64) (*types.BtcHeader, error)
+	QueryEarliestBtcHeader() (*types.BtcHeader, error)
+	QueryLatestBtcHeader() (*types.BtcHeader, error)
+	RollbackBtcHeadersToHeight(height uint64) error
 	GetActivatedTimestamp() (uint64, error)
 	SaveActivatedTimestamp(timestamp uint64) error
+	SaveTxWatch(watch *types.TxWatch) error
+	GetTxWatch(id string) (*types.TxWatch, error)
+	QueryTxWatches() ([]*types.TxWatch, error)
+	DeleteTxWatch(id string) error
+	GetSchemaVersion() (uint64, error)
+	SaveSchemaVersion(version uint64) error
+	Backup(path string) error
+	Ping() error
 	Close() error
 }
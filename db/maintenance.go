@@ -0,0 +1,125 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/babylonlabs-io/finality-gadget/types"
+	bolt "go.etcd.io/bbolt"
+	"go.uber.org/zap"
+)
+
+// compactTxMaxSize is the max size of the transactions copying entries to the compacted bbolt DB
+const compactTxMaxSize = 64 * 1024 * 1024
+
+// NewBlockEncodingMigration returns the migration re-encoding the blocks stored as JSON by older versions
+// with the binary block encoding, re-inserting them in batches of batchSize. It rewrites every block, so
+// it is an offline migration.
+func NewBlockEncodingMigration(batchSize uint64, logger *zap.Logger) Migration {
+	if batchSize == 0 {
+		batchSize = 1
+	}
+	return Migration{
+		Migrate: func(ctx context.Context, handler IDatabaseHandler) error {
+			earliestBlock, err := handler.QueryEarliestFinalizedBlock()
+			if err != nil {
+				if errors.Is(err, types.ErrBlockNotFound) {
+					return nil
+				}
+				return fmt.Errorf("error fetching earliest finalized block from db: %w", err)
+			}
+			latestBlock, err := handler.QueryLatestFinalizedBlock()
+			if err != nil {
+				return fmt.Errorf("error fetching latest finalized block from db: %w", err)
+			}
+			return reencodeBlockRange(ctx, handler, earliestBlock.BlockHeight, latestBlock.BlockHeight, batchSize, logger)
+		},
+		Description: "re-encode the blocks stored as JSON with the binary block encoding",
+		Version:     SchemaVersionBinaryBlocks,
+		Offline:     true,
+	}
+}
+
+// CompactBBolt copies the bbolt DB at srcPath to a new DB at dstPath, without the free pages left by
+// rewritten and deleted entries, which bbolt never returns to the file system. The DB at srcPath must
+// not be open, and dstPath must not exist.
+func CompactBBolt(srcPath, dstPath string, logger *zap.Logger) error {
+	if err := checkPathAvailable(dstPath); err != nil {
+		return err
+	}
+
+	src, err := bolt.Open(srcPath, 0600, &bolt.Options{Timeout: 1 * time.Second, ReadOnly: true})
+	if err != nil {
+		return fmt.Errorf("error opening DB %s: %w", srcPath, err)
+	}
+	defer src.Close()
+	// 0600 = read/write permission for owner only
+	dst, err := bolt.Open(dstPath, 0600, &bolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		return fmt.Errorf("error creating DB %s: %w", dstPath, err)
+	}
+	defer dst.Close()
+
+	logger.Info("Compacting DB", zap.String("src_path", srcPath), zap.String("dst_path", dstPath))
+	if err := bolt.Compact(dst, src, compactTxMaxSize); err != nil {
+		return fmt.Errorf("error compacting DB: %w", err)
+	}
+	return nil
+}
+
+//////////////////////////////
+// INTERNAL
+//////////////////////////////
+
+// reencodeBlockRange re-inserts the stored blocks in [startHeight, endHeight] in batches of batchSize
+func reencodeBlockRange(ctx context.Context, handler IDatabaseHandler, startHeight, endHeight, batchSize uint64, logger *zap.Logger) error {
+	for batchStartHeight := startHeight; batchStartHeight <= endHeight; batchStartHeight += batchSize {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		batchEndHeight := batchStartHeight + batchSize - 1
+		if batchEndHeight > endHeight || batchEndHeight < batchStartHeight {
+			batchEndHeight = endHeight
+		}
+
+		var blocks []*types.Block
+		for height := batchStartHeight; height <= batchEndHeight; height++ {
+			block, err := handler.GetBlockByHeight(height)
+			switch {
+			case errors.Is(err, types.ErrBlockNotFound):
+			case err != nil:
+				return fmt.Errorf("error fetching block %d from db: %w", height, err)
+			default:
+				blocks = append(blocks, block)
+			}
+			// avoid overflow when the end height is the max uint64 value
+			if height == batchEndHeight {
+				break
+			}
+		}
+
+		if err := handler.InsertBlocks(blocks); err != nil {
+			return fmt.Errorf("error storing re-encoded blocks: %w", err)
+		}
+		logger.Info("Re-encoded blocks", zap.Uint64("batch_start_height", batchStartHeight), zap.Uint64("batch_end_height", batchEndHeight))
+
+		// avoid overflow when the end height is the max uint64 value
+		if batchEndHeight == endHeight {
+			break
+		}
+	}
+	return nil
+}
+
+// checkPathAvailable returns an error if a file or directory already exists at path, so that it isn't overwritten
+func checkPathAvailable(path string) error {
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("%s already exists", path)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/babylonlabs-io/finality-gadget/types"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+	"go.uber.org/zap"
+)
+
+// insertJSONBlocks stores blocks as JSON, as versions before the binary block encoding did
+func insertJSONBlocks(t *testing.T, handler *BBoltHandler, blocks []*types.Block) {
+	require.NoError(t, handler.InsertBlocks(blocks))
+	err := handler.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(blocksBucket))
+		for _, block := range blocks {
+			blockBytes, err := json.Marshal(block)
+			if err != nil {
+				return err
+			}
+			if err := b.Put(handler.itob(block.BlockHeight), blockBytes); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+// storedBlockBytes returns the raw stored block at the given height
+func storedBlockBytes(t *testing.T, handler *BBoltHandler, height uint64) []byte {
+	var blockBytes []byte
+	err := handler.db.View(func(tx *bolt.Tx) error {
+		blockBytes = append([]byte{}, tx.Bucket([]byte(blocksBucket)).Get(handler.itob(height))...)
+		return nil
+	})
+	require.NoError(t, err)
+	return blockBytes
+}
+
+func TestBlockEncodingMigration(t *testing.T) {
+	handler, cleanup := setupDB(t)
+	defer cleanup()
+
+	blocks := []*types.Block{
+		{BlockHeight: 1, BlockHash: "0x123", BlockTimestamp: 1000},
+		{BlockHeight: 2, BlockHash: "0x456", BlockTimestamp: 1001, ParentHash: "0x123"},
+		{BlockHeight: 3, BlockHash: "0x789", BlockTimestamp: 1002, ParentHash: "0x456"},
+	}
+	insertJSONBlocks(t, handler, blocks)
+
+	// JSON blocks are read transparently
+	for _, block := range blocks {
+		require.True(t, isLegacyBlockEncoding(storedBlockBytes(t, handler, block.BlockHeight)))
+		stored, err := handler.GetBlockByHeight(block.BlockHeight)
+		require.NoError(t, err)
+		require.Equal(t, block, stored)
+	}
+
+	migration := NewBlockEncodingMigration(2, zap.NewNop())
+	require.Equal(t, SchemaVersionBinaryBlocks, migration.Version)
+	require.True(t, migration.Offline)
+	err := migration.Migrate(context.Background(), handler)
+	require.NoError(t, err)
+
+	for _, block := range blocks {
+		require.False(t, isLegacyBlockEncoding(storedBlockBytes(t, handler, block.BlockHeight)))
+		stored, err := handler.GetBlockByHeight(block.BlockHeight)
+		require.NoError(t, err)
+		require.Equal(t, block, stored)
+	}
+	earliest, err := handler.QueryEarliestFinalizedBlock()
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), earliest.BlockHeight)
+	latest, err := handler.QueryLatestFinalizedBlock()
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), latest.BlockHeight)
+
+	// re-running is a no-op
+	err = migration.Migrate(context.Background(), handler)
+	require.NoError(t, err)
+}
+
+func TestBlockEncodingMigrationWithEmptyDb(t *testing.T) {
+	handler, cleanup := setupDB(t)
+	defer cleanup()
+
+	err := NewBlockEncodingMigration(2, zap.NewNop()).Migrate(context.Background(), handler)
+	require.NoError(t, err)
+}
+
+func TestCompactBBolt(t *testing.T) {
+	handler, cleanup := setupDB(t)
+	defer cleanup()
+
+	var blocks []*types.Block
+	for height := uint64(1); height <= 1000; height++ {
+		blocks = append(blocks, &types.Block{
+			BlockHeight:    height,
+			BlockHash:      "0x1f4a9d0bc2e3ef0a1b2c3d4e5f60718293a4b5c6d7e8f90a1b2c3d4e5f607182",
+			BlockTimestamp: 1718000000 + height,
+			ParentHash:     "0x0f4a9d0bc2e3ef0a1b2c3d4e5f60718293a4b5c6d7e8f90a1b2c3d4e5f607182",
+			StateRoot:      "0x2f4a9d0bc2e3ef0a1b2c3d4e5f60718293a4b5c6d7e8f90a1b2c3d4e5f607182",
+		})
+	}
+	insertJSONBlocks(t, handler, blocks)
+	require.NoError(t, NewBlockEncodingMigration(100, zap.NewNop()).Migrate(context.Background(), handler))
+	srcPath := handler.db.Path()
+	require.NoError(t, handler.db.Close())
+
+	dstPath := filepath.Join(t.TempDir(), "compacted.db")
+	err := CompactBBolt(srcPath, dstPath, zap.NewNop())
+	require.NoError(t, err)
+
+	srcInfo, err := os.Stat(srcPath)
+	require.NoError(t, err)
+	dstInfo, err := os.Stat(dstPath)
+	require.NoError(t, err)
+	require.Less(t, dstInfo.Size(), srcInfo.Size())
+
+	compacted, err := NewBBoltHandler(dstPath, zap.NewNop())
+	require.NoError(t, err)
+	defer compacted.Close()
+	block, err := compacted.GetBlockByHeight(500)
+	require.NoError(t, err)
+	require.Equal(t, blocks[499], block)
+
+	// the compacted DB is never overwritten
+	err = CompactBBolt(srcPath, dstPath, zap.NewNop())
+	require.ErrorContains(t, err, "already exists")
+}
@@ -0,0 +1,173 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ErrBackupNotSupported is returned by the DB handlers that can't back up their DB
+var ErrBackupNotSupported = errors.New("backups are not supported by the db backend")
+
+// MigrateFunc upgrades the DB from the previous schema version. It must be safe to re-run, as a migration
+// interrupted before its version is saved is applied again on the next run.
+type MigrateFunc func(ctx context.Context, handler IDatabaseHandler) error
+
+// Migration upgrades the DB schema to Version from the previous version
+type Migration struct {
+	Migrate     MigrateFunc
+	Description string
+	Version     uint64
+	// Offline is set for migrations rewriting the whole DB, which are not run at startup but by the
+	// migrate-db command while the finality gadget is stopped
+	Offline bool
+}
+
+// MigrateOptions configures how a Migrator applies the pending migrations
+type MigrateOptions struct {
+	// BackupPathPrefix is the path the DB is backed up to before applying migrations, suffixed with the
+	// schema version and time of the backup. The DB is not backed up if empty.
+	BackupPathPrefix string
+	// DryRun only logs the pending migrations, without applying them
+	DryRun bool
+	// SkipOffline stops before the first pending offline migration
+	SkipOffline bool
+}
+
+// Migrator applies the migrations above the schema version stored in the DB, in order
+type Migrator struct {
+	handler    IDatabaseHandler
+	logger     *zap.Logger
+	migrations []Migration
+}
+
+//////////////////////////////
+// CONSTRUCTOR
+//////////////////////////////
+
+// NewMigrator returns a migrator applying the given migrations, whose versions must follow each other
+// from the first version after SchemaVersionLegacy
+func NewMigrator(handler IDatabaseHandler, logger *zap.Logger, migrations []Migration) (*Migrator, error) {
+	for i, migration := range migrations {
+		if migration.Version != SchemaVersionLegacy+uint64(i)+1 {
+			return nil, fmt.Errorf("migration %d has version %d, expected %d", i, migration.Version, SchemaVersionLegacy+uint64(i)+1)
+		}
+		if migration.Migrate == nil {
+			return nil, fmt.Errorf("migration to version %d has no migrate function", migration.Version)
+		}
+	}
+
+	return &Migrator{
+		handler:    handler,
+		logger:     logger,
+		migrations: migrations,
+	}, nil
+}
+
+//////////////////////////////
+// METHODS
+//////////////////////////////
+
+// Pending returns the schema version of the DB and the migrations to apply to it, in order
+func (m *Migrator) Pending() (uint64, []Migration, error) {
+	version, err := m.handler.GetSchemaVersion()
+	if err != nil {
+		return 0, nil, fmt.Errorf("error fetching db schema version: %w", err)
+	}
+	if version > m.latestVersion() {
+		return 0, nil, fmt.Errorf("db schema version %d was created by a newer version, the latest known schema version is %d", version, m.latestVersion())
+	}
+	return version, m.migrations[version-SchemaVersionLegacy:], nil
+}
+
+/* Run applies the pending migrations and returns them, or only returns them in dry-run mode
+ *
+ * - if the db is at the latest version, there is nothing to do
+ * - with `SkipOffline`, the migrations from the first offline one are left pending, for the
+ *   migrate-db command
+ * - in dry-run mode, log the migrations that would be applied and stop there
+ * - else, back up the db to `BackupPathPrefix` if set, so that it can be restored if a migration fails
+ * - apply the migrations in order, saving the schema version after each one so that a failed or
+ *   interrupted run resumes from the failing migration
+ */
+func (m *Migrator) Run(ctx context.Context, opts MigrateOptions) ([]Migration, error) {
+	version, pending, err := m.Pending()
+	if err != nil {
+		return nil, err
+	}
+	if opts.SkipOffline {
+		for i, migration := range pending {
+			if migration.Offline {
+				m.logger.Warn("Db has pending offline migrations, run `opfgd migrate-db` while the finality gadget is stopped",
+					zap.Uint64("schema_version", version),
+					zap.Uint64("offline_migration_version", migration.Version),
+					zap.String("description", migration.Description),
+				)
+				pending = pending[:i]
+				break
+			}
+		}
+	}
+	if len(pending) == 0 {
+		return nil, nil
+	}
+
+	for _, migration := range pending {
+		m.logger.Info("Pending db migration",
+			zap.Uint64("version", migration.Version),
+			zap.String("description", migration.Description),
+			zap.Bool("offline", migration.Offline),
+			zap.Bool("dry_run", opts.DryRun),
+		)
+	}
+	if opts.DryRun {
+		return pending, nil
+	}
+
+	if opts.BackupPathPrefix == "" {
+		m.logger.Warn("Not backing up the db before migrating it")
+	} else if err := m.backup(opts.BackupPathPrefix, version); err != nil {
+		return nil, err
+	}
+
+	for _, migration := range pending {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		m.logger.Info("Migrating db schema", zap.Uint64("from_version", migration.Version-1), zap.Uint64("to_version", migration.Version))
+		if err := migration.Migrate(ctx, m.handler); err != nil {
+			return nil, fmt.Errorf("error migrating db schema to version %d: %w", migration.Version, err)
+		}
+		if err := m.handler.SaveSchemaVersion(migration.Version); err != nil {
+			return nil, fmt.Errorf("error saving db schema version: %w", err)
+		}
+		m.logger.Info("Migrated db schema", zap.Uint64("schema_version", migration.Version))
+	}
+	return pending, nil
+}
+
+//////////////////////////////
+// INTERNAL
+//////////////////////////////
+
+func (m *Migrator) latestVersion() uint64 {
+	return SchemaVersionLegacy + uint64(len(m.migrations))
+}
+
+// backup backs up the db at the given schema version, handlers not supporting backups are only warned about
+func (m *Migrator) backup(pathPrefix string, version uint64) error {
+	path := fmt.Sprintf("%s.v%d-%s.backup", pathPrefix, version, time.Now().UTC().Format("20060102T150405Z"))
+	err := m.handler.Backup(path)
+	if errors.Is(err, ErrBackupNotSupported) {
+		m.logger.Warn("Not backing up the db before migrating it, as the db backend doesn't support backups", zap.Error(err))
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error backing up db before migrating it: %w", err)
+	}
+	m.logger.Info("Backed up db before migrating it", zap.String("backup_path", path), zap.Uint64("schema_version", version))
+	return nil
+}
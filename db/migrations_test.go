@@ -0,0 +1,262 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/babylonlabs-io/finality-gadget/types"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// The fixtures in testdata were created by older versions of the finality gadget, inserting blocks 100 to 104
+// and saving an activated timestamp:
+//   - bbolt-v0.db by the first version, with only the blocks, block heights and indexer buckets, JSON blocks
+//     without metadata and no schema version
+//   - bbolt-v1.db at SchemaVersionBlockMetadata, with JSON blocks with metadata
+const (
+	fixtureBBoltV0 = "bbolt-v0.db"
+	fixtureBBoltV1 = "bbolt-v1.db"
+)
+
+// openFixture opens a copy of the given fixture DB
+func openFixture(t *testing.T, name string) *BBoltHandler {
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, data, 0600))
+
+	handler, err := NewBBoltHandler(path, zap.NewNop())
+	require.NoError(t, err)
+	t.Cleanup(func() { handler.Close() })
+	require.NoError(t, handler.CreateInitialSchema())
+	return handler
+}
+
+// testMigrations returns the migrations to the current schema version, with the block metadata backfilled
+// from the block height rather than queried from the L2 RPC
+func testMigrations(batchSize uint64) []Migration {
+	return []Migration{
+		{
+			Migrate: func(ctx context.Context, handler IDatabaseHandler) error {
+				var blocks []*types.Block
+				for height := uint64(100); height <= 104; height++ {
+					block, err := handler.GetBlockByHeight(height)
+					if err != nil {
+						return err
+					}
+					block.StateRoot = fmt.Sprintf("0x%064x", height+1000)
+					blocks = append(blocks, block)
+				}
+				return handler.InsertBlocks(blocks)
+			},
+			Description: "backfill block metadata",
+			Version:     SchemaVersionBlockMetadata,
+		},
+		NewBlockEncodingMigration(batchSize, zap.NewNop()),
+	}
+}
+
+func requireFixtureBlocks(t *testing.T, handler IDatabaseHandler) {
+	for height := uint64(100); height <= 104; height++ {
+		block, err := handler.GetBlockByHeight(height)
+		require.NoError(t, err)
+		require.Equal(t, fmt.Sprintf("0x%064x", height), block.BlockHash)
+		require.Equal(t, 1718000000+height, block.BlockTimestamp)
+		require.Equal(t, fmt.Sprintf("0x%064x", height+1000), block.StateRoot)
+	}
+	earliest, err := handler.QueryEarliestFinalizedBlock()
+	require.NoError(t, err)
+	require.Equal(t, uint64(100), earliest.BlockHeight)
+	latest, err := handler.QueryLatestFinalizedBlock()
+	require.NoError(t, err)
+	require.Equal(t, uint64(104), latest.BlockHeight)
+	timestamp, err := handler.GetActivatedTimestamp()
+	require.NoError(t, err)
+	require.Equal(t, uint64(1718000000), timestamp)
+}
+
+func TestMigrateFixtureV0(t *testing.T) {
+	handler := openFixture(t, fixtureBBoltV0)
+
+	// the legacy DB is left at its version, with the buckets added since created
+	version, err := handler.GetSchemaVersion()
+	require.NoError(t, err)
+	require.Equal(t, SchemaVersionLegacy, version)
+	watches, err := handler.QueryTxWatches()
+	require.NoError(t, err)
+	require.Empty(t, watches)
+	block, err := handler.GetBlockByHeight(100)
+	require.NoError(t, err)
+	require.Equal(t, fmt.Sprintf("0x%064x", 100), block.BlockHash)
+	require.Empty(t, block.StateRoot)
+
+	migrator, err := NewMigrator(handler, zap.NewNop(), testMigrations(2))
+	require.NoError(t, err)
+	backupPathPrefix := filepath.Join(t.TempDir(), "finality-gadget.db")
+	applied, err := migrator.Run(context.Background(), MigrateOptions{BackupPathPrefix: backupPathPrefix})
+	require.NoError(t, err)
+	require.Len(t, applied, 2)
+
+	version, err = handler.GetSchemaVersion()
+	require.NoError(t, err)
+	require.Equal(t, CurrentSchemaVersion, version)
+	requireFixtureBlocks(t, handler)
+	for height := uint64(100); height <= 104; height++ {
+		require.False(t, isLegacyBlockEncoding(storedBlockBytes(t, handler, height)))
+	}
+
+	// the backup is the DB before the migrations
+	backups, err := filepath.Glob(backupPathPrefix + ".v0-*.backup")
+	require.NoError(t, err)
+	require.Len(t, backups, 1)
+	backup, err := NewBBoltHandler(backups[0], zap.NewNop())
+	require.NoError(t, err)
+	defer backup.Close()
+	version, err = backup.GetSchemaVersion()
+	require.NoError(t, err)
+	require.Equal(t, SchemaVersionLegacy, version)
+	require.True(t, isLegacyBlockEncoding(storedBlockBytes(t, backup, 100)))
+}
+
+func TestMigrateFixtureV1(t *testing.T) {
+	handler := openFixture(t, fixtureBBoltV1)
+
+	version, err := handler.GetSchemaVersion()
+	require.NoError(t, err)
+	require.Equal(t, SchemaVersionBlockMetadata, version)
+	requireFixtureBlocks(t, handler)
+
+	migrator, err := NewMigrator(handler, zap.NewNop(), testMigrations(2))
+	require.NoError(t, err)
+
+	// offline migrations are left to the migrate-db command
+	applied, err := migrator.Run(context.Background(), MigrateOptions{SkipOffline: true})
+	require.NoError(t, err)
+	require.Empty(t, applied)
+	version, err = handler.GetSchemaVersion()
+	require.NoError(t, err)
+	require.Equal(t, SchemaVersionBlockMetadata, version)
+	require.True(t, isLegacyBlockEncoding(storedBlockBytes(t, handler, 100)))
+
+	applied, err = migrator.Run(context.Background(), MigrateOptions{})
+	require.NoError(t, err)
+	require.Len(t, applied, 1)
+	require.Equal(t, SchemaVersionBinaryBlocks, applied[0].Version)
+	version, err = handler.GetSchemaVersion()
+	require.NoError(t, err)
+	require.Equal(t, CurrentSchemaVersion, version)
+	requireFixtureBlocks(t, handler)
+	require.False(t, isLegacyBlockEncoding(storedBlockBytes(t, handler, 100)))
+
+	// migrated DBs have nothing to migrate
+	applied, err = migrator.Run(context.Background(), MigrateOptions{})
+	require.NoError(t, err)
+	require.Empty(t, applied)
+}
+
+func TestMigrateDryRun(t *testing.T) {
+	handler := openFixture(t, fixtureBBoltV0)
+
+	migrator, err := NewMigrator(handler, zap.NewNop(), testMigrations(2))
+	require.NoError(t, err)
+	backupPathPrefix := filepath.Join(t.TempDir(), "finality-gadget.db")
+	pending, err := migrator.Run(context.Background(), MigrateOptions{BackupPathPrefix: backupPathPrefix, DryRun: true})
+	require.NoError(t, err)
+	require.Len(t, pending, 2)
+	require.Equal(t, SchemaVersionBlockMetadata, pending[0].Version)
+	require.Equal(t, SchemaVersionBinaryBlocks, pending[1].Version)
+
+	// nothing is migrated nor backed up
+	version, err := handler.GetSchemaVersion()
+	require.NoError(t, err)
+	require.Equal(t, SchemaVersionLegacy, version)
+	require.True(t, isLegacyBlockEncoding(storedBlockBytes(t, handler, 100)))
+	backups, err := filepath.Glob(backupPathPrefix + "*")
+	require.NoError(t, err)
+	require.Empty(t, backups)
+}
+
+func TestMigrateResumesFailedMigration(t *testing.T) {
+	handler := openFixture(t, fixtureBBoltV0)
+
+	migrations := testMigrations(2)
+	encodingMigration := migrations[1].Migrate
+	migrations[1].Migrate = func(ctx context.Context, handler IDatabaseHandler) error {
+		return errors.New("interrupted")
+	}
+	migrator, err := NewMigrator(handler, zap.NewNop(), migrations)
+	require.NoError(t, err)
+	_, err = migrator.Run(context.Background(), MigrateOptions{})
+	require.ErrorContains(t, err, "interrupted")
+
+	// the migrations applied before the failure are not re-applied
+	version, err := handler.GetSchemaVersion()
+	require.NoError(t, err)
+	require.Equal(t, SchemaVersionBlockMetadata, version)
+
+	migrations[1].Migrate = encodingMigration
+	migrator, err = NewMigrator(handler, zap.NewNop(), migrations)
+	require.NoError(t, err)
+	applied, err := migrator.Run(context.Background(), MigrateOptions{})
+	require.NoError(t, err)
+	require.Len(t, applied, 1)
+	requireFixtureBlocks(t, handler)
+}
+
+func TestMigrateNewerVersion(t *testing.T) {
+	handler, cleanup := setupDB(t)
+	defer cleanup()
+
+	require.NoError(t, handler.SaveSchemaVersion(CurrentSchemaVersion+1))
+	migrator, err := NewMigrator(handler, zap.NewNop(), testMigrations(2))
+	require.NoError(t, err)
+	_, err = migrator.Run(context.Background(), MigrateOptions{})
+	require.ErrorContains(t, err, "created by a newer version")
+}
+
+func TestMigrateWithoutBackupSupport(t *testing.T) {
+	handler, cleanup := setupPostgresDB(t)
+	defer cleanup()
+
+	require.NoError(t, handler.SaveSchemaVersion(SchemaVersionBlockMetadata))
+	migrator, err := NewMigrator(handler, zap.NewNop(), testMigrations(2))
+	require.NoError(t, err)
+	applied, err := migrator.Run(context.Background(), MigrateOptions{BackupPathPrefix: filepath.Join(t.TempDir(), "db")})
+	require.NoError(t, err)
+	require.Len(t, applied, 1)
+	version, err := handler.GetSchemaVersion()
+	require.NoError(t, err)
+	require.Equal(t, CurrentSchemaVersion, version)
+}
+
+func TestNewMigrator(t *testing.T) {
+	migrations := testMigrations(2)
+	_, err := NewMigrator(nil, zap.NewNop(), migrations)
+	require.NoError(t, err)
+
+	_, err = NewMigrator(nil, zap.NewNop(), migrations[1:])
+	require.ErrorContains(t, err, "migration 0 has version 2, expected 1")
+
+	migrations[1].Migrate = nil
+	_, err = NewMigrator(nil, zap.NewNop(), migrations)
+	require.ErrorContains(t, err, "no migrate function")
+}
+
+func TestBBoltBackup(t *testing.T) {
+	handler := openFixture(t, fixtureBBoltV1)
+
+	path := filepath.Join(t.TempDir(), "backup.db")
+	require.NoError(t, handler.Backup(path))
+	backup, err := NewBBoltHandler(path, zap.NewNop())
+	require.NoError(t, err)
+	defer backup.Close()
+	requireFixtureBlocks(t, backup)
+
+	// backups never overwrite existing files
+	require.ErrorContains(t, handler.Backup(path), "already exists")
+}
@@ -0,0 +1,856 @@
+package db
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math"
+	"sync"
+	"sync/atomic"
+
+	"github.com/babylonlabs-io/finality-gadget/types"
+	"github.com/cockroachdb/pebble"
+	"go.uber.org/zap"
+)
+
+// PebbleHandler stores the finality gadget data in a Pebble LSM store. Pebble has a single key
+// space, so the bbolt buckets are mapped to key prefixes, and the values are encoded as in bbolt.
+type PebbleHandler struct {
+	db     *pebble.DB
+	logger *zap.Logger
+	// writeMutex serializes updates, as pebble batches don't isolate the reads of concurrent
+	// read-modify-write updates from each other
+	writeMutex sync.Mutex
+	// closed is set once the DB is closed, as pebble panics on reads and writes to a closed DB
+	closed atomic.Bool
+}
+
+var _ IDatabaseHandler = &PebbleHandler{}
+
+// pebbleBucketSeparator separates the bucket prefix from the key, bucket names never contain it
+const pebbleBucketSeparator = 0x00
+
+var errPebbleClosed = errors.New("pebble DB is closed")
+
+//////////////////////////////
+// CONSTRUCTOR
+//////////////////////////////
+
+// NewPebbleHandler opens the Pebble store in the directory at the given path, creating it if needed
+func NewPebbleHandler(path string, logger *zap.Logger) (*PebbleHandler, error) {
+	db, err := pebble.Open(path, &pebble.Options{Logger: logger.Sugar()})
+	if err != nil {
+		logger.Error("Error opening DB", zap.Error(err))
+		return nil, err
+	}
+
+	return &PebbleHandler{
+		db:     db,
+		logger: logger,
+	}, nil
+}
+
+//////////////////////////////
+// METHODS
+//////////////////////////////
+
+func (ph *PebbleHandler) CreateInitialSchema() error {
+	ph.logger.Info("Initialising DB...")
+	return ph.update(func(batch *pebble.Batch) error {
+		// A fresh DB starts at the current schema version. DBs that already hold blocks
+		// but no version are legacy DBs and are left at version 0 to be migrated.
+		version, err := ph.get(batch, indexerBucket, []byte(schemaVersionKey))
+		if err != nil || version != nil {
+			return err
+		}
+		hasBlocks, err := ph.hasEntries(batch, blocksBucket)
+		if err != nil {
+			return err
+		}
+		if hasBlocks {
+			ph.logger.Info("Found DB without schema version", zap.Uint64("schema_version", SchemaVersionLegacy))
+			return nil
+		}
+		return ph.put(batch, indexerBucket, []byte(schemaVersionKey), ph.itob(CurrentSchemaVersion))
+	})
+}
+
+func (ph *PebbleHandler) InsertBlocks(blocks []*types.Block) error {
+	if len(blocks) == 0 {
+		return nil
+	}
+
+	ph.logger.Info("Batch inserting blocks to DB", zap.Int("count", len(blocks)))
+
+	// Single batch for all operations
+	return ph.update(func(batch *pebble.Batch) error {
+		var minHeight, maxHeight uint64 = math.MaxUint64, 0
+
+		// Insert all blocks
+		for _, block := range blocks {
+			if block.BlockHeight < minHeight {
+				minHeight = block.BlockHeight
+			}
+			if block.BlockHeight > maxHeight {
+				maxHeight = block.BlockHeight
+			}
+
+			// Store block data
+			blockBytes := encodeBlock(block)
+			ph.logger.Debug("Inserting block to db", zap.Uint64("block_height", block.BlockHeight), zap.String("block_hash", block.BlockHash))
+			if err := ph.put(batch, blocksBucket, ph.itob(block.BlockHeight), blockBytes); err != nil {
+				ph.logger.Error("Error inserting block to db", zap.Error(err))
+				return err
+			}
+
+			// Store height mapping
+			if err := ph.put(batch, blockHeightsBucket, []byte(block.BlockHash), ph.itob(block.BlockHeight)); err != nil {
+				ph.logger.Error("Error inserting height mapping", zap.Error(err))
+				return err
+			}
+		}
+
+		// Update earliest block if needed
+		earliestBytes, err := ph.get(batch, indexerBucket, []byte(earliestBlockKey))
+		if err != nil {
+			return err
+		}
+		if earliestBytes == nil {
+			ph.logger.Debug("Updating earliest block in db", zap.Uint64("block_height", minHeight))
+			if err := ph.put(batch, indexerBucket, []byte(earliestBlockKey), ph.itob(minHeight)); err != nil {
+				ph.logger.Error("Error inserting earliest block", zap.Error(err))
+				return err
+			}
+		}
+
+		// Update latest block if needed
+		latestBytes, err := ph.get(batch, indexerBucket, []byte(latestBlockKey))
+		if err != nil {
+			return err
+		}
+		if latestBytes == nil || maxHeight > ph.btoi(latestBytes) {
+			ph.logger.Debug("Updating latest block in db", zap.Uint64("block_height", maxHeight))
+			if err := ph.put(batch, indexerBucket, []byte(latestBlockKey), ph.itob(maxHeight)); err != nil {
+				ph.logger.Error("Error inserting latest block", zap.Error(err))
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (ph *PebbleHandler) GetBlockByHeight(height uint64) (*types.Block, error) {
+	v, err := ph.get(ph.db, blocksBucket, ph.itob(height))
+	if err != nil {
+		return nil, err
+	}
+	if v == nil {
+		return nil, types.ErrBlockNotFound
+	}
+	return decodeBlock(v)
+}
+
+func (ph *PebbleHandler) GetBlockByHash(hash string) (*types.Block, error) {
+	// Fetch block number corresponding to hash
+	v, err := ph.get(ph.db, blockHeightsBucket, []byte(hash))
+	if err != nil {
+		return nil, err
+	}
+	if v == nil {
+		return nil, types.ErrBlockNotFound
+	}
+	return ph.GetBlockByHeight(ph.btoi(v))
+}
+
+// QueryIsBlockFinalizedByHeight returns whether the block at the given height is stored, or was pruned
+func (ph *PebbleHandler) QueryIsBlockFinalizedByHeight(height uint64) (bool, error) {
+	if ph.closed.Load() {
+		return false, errPebbleClosed
+	}
+	snapshot := ph.db.NewSnapshot()
+	defer snapshot.Close()
+
+	blockBytes, err := ph.get(snapshot, blocksBucket, ph.itob(height))
+	if err != nil {
+		return false, err
+	}
+	if blockBytes != nil {
+		return true, nil
+	}
+	r, err := ph.getPrunedRange(snapshot)
+	if err != nil {
+		return false, err
+	}
+	return r.contains(height), nil
+}
+
+// QueryIsBlockFinalizedByHash returns whether the block with the given hash is stored, or was pruned
+func (ph *PebbleHandler) QueryIsBlockFinalizedByHash(hash string) (bool, error) {
+	v, err := ph.get(ph.db, blockHeightsBucket, []byte(hash))
+	if err != nil {
+		return false, err
+	}
+	if v == nil {
+		return false, nil
+	}
+	return ph.QueryIsBlockFinalizedByHeight(ph.btoi(v))
+}
+
+// QueryEarliestFinalizedBlock returns the earliest stored block. The index and the block are read from the
+// same snapshot, so that the block is never missing while blocks are being pruned.
+func (ph *PebbleHandler) QueryEarliestFinalizedBlock() (*types.Block, error) {
+	if ph.closed.Load() {
+		return nil, errPebbleClosed
+	}
+	snapshot := ph.db.NewSnapshot()
+	defer snapshot.Close()
+
+	v, err := ph.get(snapshot, indexerBucket, []byte(earliestBlockKey))
+	if err != nil {
+		return nil, err
+	}
+	if v == nil {
+		return nil, types.ErrBlockNotFound
+	}
+	blockBytes, err := ph.get(snapshot, blocksBucket, v)
+	if err != nil {
+		return nil, err
+	}
+	if blockBytes == nil {
+		return nil, types.ErrBlockNotFound
+	}
+	return decodeBlock(blockBytes)
+}
+
+// QueryEarliestFinalizedHeight returns the height all blocks from which up to the latest block are finalized,
+// including the pruned blocks
+func (ph *PebbleHandler) QueryEarliestFinalizedHeight() (uint64, error) {
+	if ph.closed.Load() {
+		return 0, errPebbleClosed
+	}
+	snapshot := ph.db.NewSnapshot()
+	defer snapshot.Close()
+
+	v, err := ph.get(snapshot, indexerBucket, []byte(earliestBlockKey))
+	if err != nil {
+		return 0, err
+	}
+	if v == nil {
+		return 0, types.ErrBlockNotFound
+	}
+	r, err := ph.getPrunedRange(snapshot)
+	if err != nil {
+		return 0, err
+	}
+	return r.earliestFinalizedHeight(ph.btoi(v)), nil
+}
+
+func (ph *PebbleHandler) QueryLatestFinalizedBlock() (*types.Block, error) {
+	v, err := ph.get(ph.db, indexerBucket, []byte(latestBlockKey))
+	if err != nil {
+		ph.logger.Error("Error getting latest block", zap.Error(err))
+		return nil, err
+	}
+	// If no latest block has been stored yet, return nil
+	if v == nil {
+		return nil, nil
+	}
+	return ph.GetBlockByHeight(ph.btoi(v))
+}
+
+// InsertFinalityEvidence stores the finality evidence of blocks, keyed by block height.
+// Evidence already stored at the same height is overwritten.
+func (ph *PebbleHandler) InsertFinalityEvidence(evidence []*types.FinalityEvidence) error {
+	if len(evidence) == 0 {
+		return nil
+	}
+
+	ph.logger.Info("Batch inserting finality evidence to DB", zap.Int("count", len(evidence)))
+
+	return ph.update(func(batch *pebble.Batch) error {
+		for _, e := range evidence {
+			evidenceBytes, err := json.Marshal(e)
+			if err != nil {
+				ph.logger.Error("Error encoding finality evidence", zap.Error(err))
+				return err
+			}
+			if err := ph.put(batch, evidenceBucket, ph.itob(e.BlockHeight), evidenceBytes); err != nil {
+				ph.logger.Error("Error inserting finality evidence to db", zap.Error(err))
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (ph *PebbleHandler) GetFinalityEvidenceByHeight(height uint64) (*types.FinalityEvidence, error) {
+	v, err := ph.get(ph.db, evidenceBucket, ph.itob(height))
+	if err != nil {
+		return nil, err
+	}
+	if v == nil {
+		return nil, types.ErrFinalityEvidenceNotFound
+	}
+	var evidence types.FinalityEvidence
+	if err := json.Unmarshal(v, &evidence); err != nil {
+		return nil, err
+	}
+	return &evidence, nil
+}
+
+// RollbackToHeight removes all blocks above the given height, along with their hash to height
+// mappings and finality evidence, and moves the latest block index back to the given height. If
+// the given height is below the earliest stored block, all blocks are removed and the
+// earliest/latest indexes cleared.
+func (ph *PebbleHandler) RollbackToHeight(height uint64) error {
+	ph.logger.Info("Rolling back blocks in DB", zap.Uint64("to_height", height))
+
+	return ph.update(func(batch *pebble.Batch) error {
+		// pruned blocks above the rollback height are no longer finalized
+		r, err := ph.getPrunedRange(batch)
+		if err != nil {
+			return err
+		}
+		if err := ph.savePrunedRange(batch, r.afterRollback(height)); err != nil {
+			return err
+		}
+		if err := ph.removePrunedHashes(batch, height); err != nil {
+			return err
+		}
+
+		latestBytes, err := ph.get(batch, indexerBucket, []byte(latestBlockKey))
+		if err != nil {
+			return err
+		}
+		if latestBytes == nil || ph.btoi(latestBytes) <= height {
+			return nil
+		}
+
+		// Collect blocks above the rollback height, then remove them along with their hash
+		// mappings and evidence
+		var removed []*types.Block
+		err = ph.iterate(batch, blocksBucket, ph.itob(height+1), func(_, v []byte) error {
+			block, err := decodeBlock(v)
+			if err != nil {
+				ph.logger.Error("Error decoding block during rollback", zap.Error(err))
+				return err
+			}
+			removed = append(removed, block)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, block := range removed {
+			ph.logger.Debug("Removing block from db", zap.Uint64("block_height", block.BlockHeight), zap.String("block_hash", block.BlockHash))
+			if err := ph.delete(batch, blockHeightsBucket, []byte(block.BlockHash)); err != nil {
+				return err
+			}
+			if err := ph.delete(batch, blocksBucket, ph.itob(block.BlockHeight)); err != nil {
+				return err
+			}
+			if err := ph.delete(batch, evidenceBucket, ph.itob(block.BlockHeight)); err != nil {
+				return err
+			}
+		}
+
+		// Rolled back past the earliest block, so there are no blocks left
+		earliestBytes, err := ph.get(batch, indexerBucket, []byte(earliestBlockKey))
+		if err != nil {
+			return err
+		}
+		if earliestBytes == nil || ph.btoi(earliestBytes) > height {
+			if err := ph.delete(batch, indexerBucket, []byte(earliestBlockKey)); err != nil {
+				return err
+			}
+			return ph.delete(batch, indexerBucket, []byte(latestBlockKey))
+		}
+
+		ph.logger.Debug("Updating latest block in db", zap.Uint64("block_height", height))
+		return ph.put(batch, indexerBucket, []byte(latestBlockKey), ph.itob(height))
+	})
+}
+
+// PruneBlocks removes all blocks below the given height, along with their finality evidence, and advances
+// the earliest block index to the first remaining block in the same batch. The pruned heights and the hash
+// mappings of the pruned blocks are kept, so that they are still reported finalized by height and by hash.
+// The latest block is never pruned. Returns the number of pruned blocks.
+func (ph *PebbleHandler) PruneBlocks(height uint64) (uint64, error) {
+	var pruned uint64
+	err := ph.update(func(batch *pebble.Batch) error {
+		latestBytes, err := ph.get(batch, indexerBucket, []byte(latestBlockKey))
+		if err != nil {
+			return err
+		}
+		if latestBytes == nil {
+			return nil
+		}
+		if latest := ph.btoi(latestBytes); height > latest {
+			height = latest
+		}
+
+		// Collect blocks below the prune height, then remove them along with their evidence, recording
+		// their hashes so that their hash mappings are removed if they are rolled back
+		var removed []*types.Block
+		err = ph.iterate(batch, blocksBucket, nil, func(k, v []byte) error {
+			if ph.btoi(k) >= height {
+				return errStopIteration
+			}
+			block, err := decodeBlock(v)
+			if err != nil {
+				ph.logger.Error("Error decoding block during pruning", zap.Error(err))
+				return err
+			}
+			removed = append(removed, block)
+			return nil
+		})
+		if err != nil && !errors.Is(err, errStopIteration) {
+			return err
+		}
+		if len(removed) == 0 {
+			return nil
+		}
+		for _, block := range removed {
+			if err := ph.put(batch, prunedHashesBucket, ph.itob(block.BlockHeight), []byte(block.BlockHash)); err != nil {
+				return err
+			}
+			if err := ph.delete(batch, blocksBucket, ph.itob(block.BlockHeight)); err != nil {
+				return err
+			}
+			if err := ph.delete(batch, evidenceBucket, ph.itob(block.BlockHeight)); err != nil {
+				return err
+			}
+		}
+
+		pruned = uint64(len(removed))
+		prunedFrom := removed[0].BlockHeight
+		prunedTo := removed[len(removed)-1].BlockHeight + 1
+
+		// the latest block is never pruned, but the blocks may have been rolled back from under the
+		// latest block index
+		var earliest []byte
+		err = ph.iterate(batch, blocksBucket, nil, func(k, _ []byte) error {
+			earliest = append([]byte{}, k...)
+			return errStopIteration
+		})
+		if err != nil && !errors.Is(err, errStopIteration) {
+			return err
+		}
+		if earliest == nil {
+			ph.logger.Warn("No block left after pruning")
+			if err := ph.delete(batch, indexerBucket, []byte(earliestBlockKey)); err != nil {
+				return err
+			}
+			if err := ph.delete(batch, indexerBucket, []byte(latestBlockKey)); err != nil {
+				return err
+			}
+		} else {
+			prunedTo = ph.btoi(earliest)
+			ph.logger.Debug("Updating earliest block in db", zap.Uint64("block_height", prunedTo))
+			if err := ph.put(batch, indexerBucket, []byte(earliestBlockKey), earliest); err != nil {
+				return err
+			}
+		}
+		r, err := ph.getPrunedRange(batch)
+		if err != nil {
+			return err
+		}
+		return ph.savePrunedRange(batch, r.afterPrune(prunedFrom, prunedTo))
+	})
+	if err != nil {
+		return 0, err
+	}
+	return pruned, nil
+}
+
+// InsertBtcHeaders stores BTC headers keyed by height and extends the BTC header index range.
+// Headers already stored at the same height are overwritten.
+func (ph *PebbleHandler) InsertBtcHeaders(headers []*types.BtcHeader) error {
+	if len(headers) == 0 {
+		return nil
+	}
+
+	ph.logger.Debug("Batch inserting BTC headers to DB", zap.Int("count", len(headers)))
+
+	return ph.update(func(batch *pebble.Batch) error {
+		var minHeight, maxHeight uint64 = math.MaxUint64, 0
+		for _, header := range headers {
+			if header.Height < minHeight {
+				minHeight = header.Height
+			}
+			if header.Height > maxHeight {
+				maxHeight = header.Height
+			}
+
+			headerBytes, err := json.Marshal(header)
+			if err != nil {
+				ph.logger.Error("Error encoding BTC header", zap.Error(err))
+				return err
+			}
+			if err := ph.put(batch, btcHeadersBucket, ph.itob(header.Height), headerBytes); err != nil {
+				ph.logger.Error("Error inserting BTC header to db", zap.Error(err))
+				return err
+			}
+		}
+
+		earliestBytes, err := ph.get(batch, indexerBucket, []byte(earliestBtcHeaderKey))
+		if err != nil {
+			return err
+		}
+		if earliestBytes == nil || minHeight < ph.btoi(earliestBytes) {
+			if err := ph.put(batch, indexerBucket, []byte(earliestBtcHeaderKey), ph.itob(minHeight)); err != nil {
+				return err
+			}
+		}
+		latestBytes, err := ph.get(batch, indexerBucket, []byte(latestBtcHeaderKey))
+		if err != nil {
+			return err
+		}
+		if latestBytes == nil || maxHeight > ph.btoi(latestBytes) {
+			return ph.put(batch, indexerBucket, []byte(latestBtcHeaderKey), ph.itob(maxHeight))
+		}
+		return nil
+	})
+}
+
+func (ph *PebbleHandler) GetBtcHeaderByHeight(height uint64) (*types.BtcHeader, error) {
+	v, err := ph.get(ph.db, btcHeadersBucket, ph.itob(height))
+	if err != nil {
+		return nil, err
+	}
+	if v == nil {
+		return nil, types.ErrBtcHeaderNotFound
+	}
+	var header types.BtcHeader
+	if err := json.Unmarshal(v, &header); err != nil {
+		return nil, err
+	}
+	return &header, nil
+}
+
+// QueryEarliestBtcHeader returns the lowest indexed BTC header, or nil if the index is empty
+func (ph *PebbleHandler) QueryEarliestBtcHeader() (*types.BtcHeader, error) {
+	return ph.queryBtcHeaderByIndexKey(earliestBtcHeaderKey)
+}
+
+// QueryLatestBtcHeader returns the highest indexed BTC header, or nil if the index is empty
+func (ph *PebbleHandler) QueryLatestBtcHeader() (*types.BtcHeader, error) {
+	return ph.queryBtcHeaderByIndexKey(latestBtcHeaderKey)
+}
+
+// RollbackBtcHeadersToHeight removes all BTC headers above the given height. If the given height
+// is below the earliest indexed header, the index is cleared.
+func (ph *PebbleHandler) RollbackBtcHeadersToHeight(height uint64) error {
+	ph.logger.Info("Rolling back BTC headers in DB", zap.Uint64("to_height", height))
+
+	return ph.update(func(batch *pebble.Batch) error {
+		latestBytes, err := ph.get(batch, indexerBucket, []byte(latestBtcHeaderKey))
+		if err != nil {
+			return err
+		}
+		if latestBytes == nil || ph.btoi(latestBytes) <= height {
+			return nil
+		}
+
+		// the headers above the rollback height are a contiguous key range
+		if err := batch.DeleteRange(
+			pebbleKey(btcHeadersBucket, ph.itob(height+1)), pebbleBucketEnd(btcHeadersBucket), nil,
+		); err != nil {
+			ph.logger.Error("Error removing BTC headers", zap.Error(err))
+			return err
+		}
+
+		earliestBytes, err := ph.get(batch, indexerBucket, []byte(earliestBtcHeaderKey))
+		if err != nil {
+			return err
+		}
+		if earliestBytes == nil || ph.btoi(earliestBytes) > height {
+			if err := ph.delete(batch, indexerBucket, []byte(earliestBtcHeaderKey)); err != nil {
+				return err
+			}
+			return ph.delete(batch, indexerBucket, []byte(latestBtcHeaderKey))
+		}
+		return ph.put(batch, indexerBucket, []byte(latestBtcHeaderKey), ph.itob(height))
+	})
+}
+
+func (ph *PebbleHandler) GetActivatedTimestamp() (uint64, error) {
+	v, err := ph.get(ph.db, indexerBucket, []byte(activatedTimestampKey))
+	if err != nil {
+		return math.MaxUint64, err
+	}
+	if v == nil {
+		return math.MaxUint64, types.ErrActivatedTimestampNotFound
+	}
+	return ph.btoi(v), nil
+}
+
+func (ph *PebbleHandler) SaveActivatedTimestamp(timestamp uint64) error {
+	return ph.update(func(batch *pebble.Batch) error {
+		return ph.put(batch, indexerBucket, []byte(activatedTimestampKey), ph.itob(timestamp))
+	})
+}
+
+// SaveTxWatch inserts or updates a transaction watch
+func (ph *PebbleHandler) SaveTxWatch(watch *types.TxWatch) error {
+	watchBytes, err := json.Marshal(watch)
+	if err != nil {
+		ph.logger.Error("Error encoding transaction watch", zap.Error(err))
+		return err
+	}
+	return ph.update(func(batch *pebble.Batch) error {
+		return ph.put(batch, txWatchesBucket, []byte(watch.Id), watchBytes)
+	})
+}
+
+func (ph *PebbleHandler) GetTxWatch(id string) (*types.TxWatch, error) {
+	v, err := ph.get(ph.db, txWatchesBucket, []byte(id))
+	if err != nil {
+		return nil, err
+	}
+	if v == nil {
+		return nil, types.ErrTxWatchNotFound
+	}
+	var watch types.TxWatch
+	if err := json.Unmarshal(v, &watch); err != nil {
+		return nil, err
+	}
+	return &watch, nil
+}
+
+// QueryTxWatches returns all transaction watches, ordered by id
+func (ph *PebbleHandler) QueryTxWatches() ([]*types.TxWatch, error) {
+	var watches []*types.TxWatch
+	err := ph.iterate(ph.db, txWatchesBucket, nil, func(_, v []byte) error {
+		var watch types.TxWatch
+		if err := json.Unmarshal(v, &watch); err != nil {
+			return err
+		}
+		watches = append(watches, &watch)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return watches, nil
+}
+
+// DeleteTxWatch removes a transaction watch, it is a no-op if the watch does not exist
+func (ph *PebbleHandler) DeleteTxWatch(id string) error {
+	return ph.update(func(batch *pebble.Batch) error {
+		return ph.delete(batch, txWatchesBucket, []byte(id))
+	})
+}
+
+// GetSchemaVersion returns the schema version of the DB, or SchemaVersionLegacy if it has none
+func (ph *PebbleHandler) GetSchemaVersion() (uint64, error) {
+	v, err := ph.get(ph.db, indexerBucket, []byte(schemaVersionKey))
+	if err != nil {
+		return SchemaVersionLegacy, err
+	}
+	if v == nil {
+		return SchemaVersionLegacy, nil
+	}
+	return ph.btoi(v), nil
+}
+
+func (ph *PebbleHandler) SaveSchemaVersion(version uint64) error {
+	ph.logger.Info("Saving DB schema version", zap.Uint64("schema_version", version))
+	return ph.update(func(batch *pebble.Batch) error {
+		return ph.put(batch, indexerBucket, []byte(schemaVersionKey), ph.itob(version))
+	})
+}
+
+// Backup checkpoints the DB, as of the last committed update, to a new directory at path. The
+// checkpoint hard links the immutable sstables of the DB, so it takes little extra space.
+func (ph *PebbleHandler) Backup(path string) error {
+	if ph.closed.Load() {
+		return errPebbleClosed
+	}
+	if err := checkPathAvailable(path); err != nil {
+		return err
+	}
+	ph.logger.Info("Backing up DB", zap.String("backup_path", path))
+	return ph.db.Checkpoint(path, pebble.WithFlushedWAL())
+}
+
+// Ping checks the DB is open and readable
+func (ph *PebbleHandler) Ping() error {
+	_, err := ph.get(ph.db, indexerBucket, []byte(schemaVersionKey))
+	return err
+}
+
+func (ph *PebbleHandler) Close() error {
+	if ph.closed.Swap(true) {
+		return nil
+	}
+	ph.logger.Info("Closing DB...")
+	// wait for in-flight updates
+	ph.writeMutex.Lock()
+	defer ph.writeMutex.Unlock()
+	return ph.db.Close()
+}
+
+//////////////////////////////
+// INTERNAL
+//////////////////////////////
+
+// update applies the changes made by fn in a single atomic batch, synced to disk. fn can read its
+// own writes from the batch.
+func (ph *PebbleHandler) update(fn func(batch *pebble.Batch) error) error {
+	ph.writeMutex.Lock()
+	defer ph.writeMutex.Unlock()
+	if ph.closed.Load() {
+		return errPebbleClosed
+	}
+
+	batch := ph.db.NewIndexedBatch()
+	defer batch.Close()
+	if err := fn(batch); err != nil {
+		return err
+	}
+	return batch.Commit(pebble.Sync)
+}
+
+// get returns a copy of the value of the key in the bucket, or nil if it is not set
+func (ph *PebbleHandler) get(r pebble.Reader, bucket string, key []byte) ([]byte, error) {
+	if ph.closed.Load() {
+		return nil, errPebbleClosed
+	}
+	v, closer, err := r.Get(pebbleKey(bucket, key))
+	if errors.Is(err, pebble.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer closer.Close()
+	return append([]byte{}, v...), nil
+}
+
+func (ph *PebbleHandler) put(batch *pebble.Batch, bucket string, key, value []byte) error {
+	return batch.Set(pebbleKey(bucket, key), value, nil)
+}
+
+func (ph *PebbleHandler) delete(batch *pebble.Batch, bucket string, key []byte) error {
+	return batch.Delete(pebbleKey(bucket, key), nil)
+}
+
+// iterate calls fn on the entries of the bucket from the given key on, in key order. The key and
+// value are only valid until fn returns.
+func (ph *PebbleHandler) iterate(r pebble.Reader, bucket string, from []byte, fn func(k, v []byte) error) error {
+	if ph.closed.Load() {
+		return errPebbleClosed
+	}
+	iter, err := r.NewIter(&pebble.IterOptions{
+		LowerBound: pebbleKey(bucket, from),
+		UpperBound: pebbleBucketEnd(bucket),
+	})
+	if err != nil {
+		return err
+	}
+	for iter.First(); iter.Valid(); iter.Next() {
+		if err := fn(iter.Key()[len(bucket)+1:], iter.Value()); err != nil {
+			return errors.Join(err, iter.Close())
+		}
+	}
+	return iter.Close()
+}
+
+// hasEntries returns whether the bucket has any entry
+func (ph *PebbleHandler) hasEntries(r pebble.Reader, bucket string) (bool, error) {
+	found := false
+	err := ph.iterate(r, bucket, nil, func(_, _ []byte) error {
+		found = true
+		return errStopIteration
+	})
+	if errors.Is(err, errStopIteration) {
+		err = nil
+	}
+	return found, err
+}
+
+func (ph *PebbleHandler) queryBtcHeaderByIndexKey(key string) (*types.BtcHeader, error) {
+	v, err := ph.get(ph.db, indexerBucket, []byte(key))
+	if err != nil || v == nil {
+		return nil, err
+	}
+	return ph.GetBtcHeaderByHeight(ph.btoi(v))
+}
+
+// getPrunedRange returns the range of the pruned blocks, nil if no block was pruned
+func (ph *PebbleHandler) getPrunedRange(r pebble.Reader) (*prunedRange, error) {
+	from, err := ph.get(r, indexerBucket, []byte(prunedFromKey))
+	if err != nil {
+		return nil, err
+	}
+	to, err := ph.get(r, indexerBucket, []byte(prunedToKey))
+	if err != nil {
+		return nil, err
+	}
+	if from == nil || to == nil {
+		return nil, nil
+	}
+	return &prunedRange{from: ph.btoi(from), to: ph.btoi(to)}, nil
+}
+
+// savePrunedRange stores the range of the pruned blocks, or removes it if nil
+func (ph *PebbleHandler) savePrunedRange(batch *pebble.Batch, r *prunedRange) error {
+	if r == nil {
+		if err := ph.delete(batch, indexerBucket, []byte(prunedFromKey)); err != nil {
+			return err
+		}
+		return ph.delete(batch, indexerBucket, []byte(prunedToKey))
+	}
+	if err := ph.put(batch, indexerBucket, []byte(prunedFromKey), ph.itob(r.from)); err != nil {
+		return err
+	}
+	return ph.put(batch, indexerBucket, []byte(prunedToKey), ph.itob(r.to))
+}
+
+// removePrunedHashes removes the hash mappings of the pruned blocks above the given height, as they are
+// rolled back
+func (ph *PebbleHandler) removePrunedHashes(batch *pebble.Batch, height uint64) error {
+	var heights, hashes [][]byte
+	err := ph.iterate(batch, prunedHashesBucket, ph.itob(height+1), func(k, v []byte) error {
+		heights = append(heights, append([]byte{}, k...))
+		hashes = append(hashes, append([]byte{}, v...))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for i := range heights {
+		if err := ph.delete(batch, blockHeightsBucket, hashes[i]); err != nil {
+			return err
+		}
+		if err := ph.delete(batch, prunedHashesBucket, heights[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ph *PebbleHandler) itob(v uint64) []byte {
+	return binary.BigEndian.AppendUint64(nil, v)
+}
+
+func (ph *PebbleHandler) btoi(b []byte) uint64 {
+	return binary.BigEndian.Uint64(b)
+}
+
+// errStopIteration stops an iteration early
+var errStopIteration = errors.New("stop iteration")
+
+// pebbleKey returns the key of an entry in a bucket, prefixed by the bucket name
+func pebbleKey(bucket string, key []byte) []byte {
+	k := make([]byte, 0, len(bucket)+1+len(key))
+	k = append(k, bucket...)
+	k = append(k, pebbleBucketSeparator)
+	return append(k, key...)
+}
+
+// pebbleBucketEnd returns the exclusive upper bound of the keys of a bucket
+func pebbleBucketEnd(bucket string) []byte {
+	return append([]byte(bucket), pebbleBucketSeparator+1)
+}
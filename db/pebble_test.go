@@ -0,0 +1,145 @@
+package db
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/babylonlabs-io/finality-gadget/log"
+	"github.com/babylonlabs-io/finality-gadget/types"
+	"github.com/cockroachdb/pebble"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func setupPebbleDB(t *testing.T) (*PebbleHandler, func()) {
+	// Create temp test directory.
+	tempDir, err := os.MkdirTemp("", "test-*.pebble")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+
+	// Create logger.
+	logger, err := log.NewRootLogger("console", zap.DebugLevel)
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	// Create a new PebbleHandler
+	db, err := NewPebbleHandler(tempDir, logger)
+	if err != nil {
+		t.Fatalf("Failed to create PebbleHandler: %v", err)
+	}
+
+	// Create initial schema
+	err = db.CreateInitialSchema()
+	if err != nil {
+		t.Fatalf("Failed to create initial schema: %v", err)
+	}
+
+	// Cleanup function to close DB and remove temp directory
+	cleanup := func() {
+		db.Close()
+		err := os.RemoveAll(tempDir)
+		if err != nil {
+			t.Fatalf("Failed to delete DB: %v", err)
+		}
+	}
+
+	return db, cleanup
+}
+
+func TestPebbleHandlerConformance(t *testing.T) {
+	runConformanceTests(t, func(t *testing.T) (IDatabaseHandler, func()) {
+		return setupPebbleDB(t)
+	})
+}
+
+func TestPebbleSchemaVersion(t *testing.T) {
+	handler, cleanup := setupPebbleDB(t)
+	defer cleanup()
+
+	// A fresh DB is created at the current schema version
+	version, err := handler.GetSchemaVersion()
+	assert.NoError(t, err)
+	assert.Equal(t, CurrentSchemaVersion, version)
+
+	// Simulate a DB created before schema versioning
+	err = handler.InsertBlocks([]*types.Block{{BlockHeight: 1, BlockHash: "0x123", BlockTimestamp: 1000}})
+	assert.NoError(t, err)
+	err = handler.update(func(batch *pebble.Batch) error {
+		return handler.delete(batch, indexerBucket, []byte(schemaVersionKey))
+	})
+	assert.NoError(t, err)
+
+	// Re-initialising a DB holding blocks leaves it at the legacy version
+	err = handler.CreateInitialSchema()
+	assert.NoError(t, err)
+	version, err = handler.GetSchemaVersion()
+	assert.NoError(t, err)
+	assert.Equal(t, SchemaVersionLegacy, version)
+
+	// Re-initialising does not overwrite a saved version
+	err = handler.SaveSchemaVersion(SchemaVersionBlockMetadata)
+	assert.NoError(t, err)
+	err = handler.CreateInitialSchema()
+	assert.NoError(t, err)
+	version, err = handler.GetSchemaVersion()
+	assert.NoError(t, err)
+	assert.Equal(t, SchemaVersionBlockMetadata, version)
+}
+
+func TestPebbleBucketsAreIsolated(t *testing.T) {
+	handler, cleanup := setupPebbleDB(t)
+	defer cleanup()
+
+	// Keys of a bucket whose name extends the blocks bucket name are not iterated as blocks
+	err := handler.InsertBlocks([]*types.Block{{BlockHeight: 1, BlockHash: "0x123", BlockTimestamp: 1000}})
+	assert.NoError(t, err)
+	err = handler.update(func(batch *pebble.Batch) error {
+		return handler.put(batch, "blocks_", []byte("x"), []byte("not a block"))
+	})
+	assert.NoError(t, err)
+	err = handler.RollbackToHeight(0)
+	assert.NoError(t, err)
+	_, err = handler.GetBlockByHeight(1)
+	assert.Equal(t, types.ErrBlockNotFound, err)
+}
+
+func TestPebbleBackup(t *testing.T) {
+	handler, cleanup := setupPebbleDB(t)
+	defer cleanup()
+
+	err := handler.InsertBlocks([]*types.Block{{BlockHeight: 1, BlockHash: "0x123", BlockTimestamp: 1000}})
+	assert.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "backup.pebble")
+	assert.NoError(t, handler.Backup(path))
+
+	// the checkpoint is a pebble DB on its own
+	backup, err := NewPebbleHandler(path, zap.NewNop())
+	assert.NoError(t, err)
+	defer backup.Close()
+	block, err := backup.GetBlockByHeight(1)
+	assert.NoError(t, err)
+	assert.Equal(t, "0x123", block.BlockHash)
+
+	assert.ErrorContains(t, handler.Backup(path), "already exists")
+}
+
+func TestNewDatabaseHandler(t *testing.T) {
+	logger := zap.NewNop()
+
+	handler, err := NewDatabaseHandler(BackendPebble, t.TempDir(), logger)
+	assert.NoError(t, err)
+	assert.IsType(t, &PebbleHandler{}, handler)
+	assert.NoError(t, handler.Close())
+
+	handler, err = NewDatabaseHandler(BackendBBolt, t.TempDir()+"/data.db", logger)
+	assert.NoError(t, err)
+	assert.IsType(t, &BBoltHandler{}, handler)
+	assert.NoError(t, handler.Close())
+
+	handler, err = NewDatabaseHandler("leveldb", t.TempDir(), logger)
+	assert.EqualError(t, err, "unknown DB backend: leveldb")
+	assert.Nil(t, handler)
+}
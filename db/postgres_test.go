@@ -0,0 +1,207 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/babylonlabs-io/finality-gadget/log"
+	"github.com/babylonlabs-io/finality-gadget/types"
+	embeddedpostgres "github.com/fergusstrange/embedded-postgres"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// testPostgresDSNEnv is the environment variable of the connection URL of a PostgreSQL DB to run the
+// tests against. Each test creates its own schema in the DB, and drops it once done. If not set, the
+// tests run against an ephemeral PostgreSQL server, whose binaries are downloaded on first use, and
+// are skipped if it can't be started.
+const testPostgresDSNEnv = "TEST_POSTGRES_DSN"
+
+var (
+	testSchemaCounter atomic.Uint64
+
+	// testPostgres is the ephemeral PostgreSQL server started by the first test needing it, and stopped
+	// once all tests ran
+	testPostgres     *embeddedpostgres.EmbeddedPostgres
+	testPostgresDir  string
+	testPostgresDSN  string
+	testPostgresErr  error
+	testPostgresOnce sync.Once
+)
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	if testPostgres != nil {
+		if err := testPostgres.Stop(); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to stop ephemeral PostgreSQL server: %v\n", err)
+		}
+	}
+	if testPostgresDir != "" {
+		os.RemoveAll(testPostgresDir)
+	}
+	os.Exit(code)
+}
+
+func setupPostgresDB(t *testing.T) (*PostgresHandler, func()) {
+	dsn := os.Getenv(testPostgresDSNEnv)
+	if dsn == "" {
+		testPostgresOnce.Do(startTestPostgres)
+		if testPostgresErr != nil {
+			t.Skipf("Skipping PostgreSQL test, set %s or allow the ephemeral PostgreSQL server to start: %v", testPostgresDSNEnv, testPostgresErr)
+		}
+		dsn = testPostgresDSN
+	}
+
+	// Create logger.
+	logger, err := log.NewRootLogger("console", zap.DebugLevel)
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	sqlDB, cleanup := openTestPostgres(t, dsn)
+	db := newPostgresHandler(sqlDB, logger)
+
+	// Create initial schema
+	err = db.CreateInitialSchema()
+	if err != nil {
+		t.Fatalf("Failed to create initial schema: %v", err)
+	}
+
+	return db, func() {
+		db.Close()
+		cleanup()
+	}
+}
+
+func TestPostgresHandlerConformance(t *testing.T) {
+	runConformanceTests(t, func(t *testing.T) (IDatabaseHandler, func()) {
+		return setupPostgresDB(t)
+	})
+}
+
+func TestPostgresSchemaVersion(t *testing.T) {
+	handler, cleanup := setupPostgresDB(t)
+	defer cleanup()
+
+	// A fresh DB is created at the current schema version
+	version, err := handler.GetSchemaVersion()
+	assert.NoError(t, err)
+	assert.Equal(t, CurrentSchemaVersion, version)
+
+	// Simulate a DB created before schema versioning
+	err = handler.InsertBlocks([]*types.Block{{BlockHeight: 1, BlockHash: "0x123", BlockTimestamp: 1000}})
+	assert.NoError(t, err)
+	err = handler.update(func(tx *sql.Tx) error {
+		return handler.deleteIndexes(tx, schemaVersionKey)
+	})
+	assert.NoError(t, err)
+
+	// Re-initialising a DB holding blocks leaves it at the legacy version, and doesn't reapply migrations
+	err = handler.CreateInitialSchema()
+	assert.NoError(t, err)
+	version, err = handler.GetSchemaVersion()
+	assert.NoError(t, err)
+	assert.Equal(t, SchemaVersionLegacy, version)
+	block, err := handler.GetBlockByHeight(1)
+	assert.NoError(t, err)
+	assert.Equal(t, "0x123", block.BlockHash)
+}
+
+func TestPostgresMigrations(t *testing.T) {
+	handler, cleanup := setupPostgresDB(t)
+	defer cleanup()
+
+	var applied int
+	err := handler.db.QueryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&applied)
+	require.NoError(t, err)
+	require.Equal(t, len(postgresMigrations), applied)
+
+	// DBs migrated by a newer version are rejected
+	_, err = handler.db.Exec(`INSERT INTO schema_migrations (version) VALUES ($1)`, len(postgresMigrations)+1)
+	require.NoError(t, err)
+	err = handler.CreateInitialSchema()
+	require.ErrorContains(t, err, "created by a newer version")
+}
+
+func TestPostgresSharedDB(t *testing.T) {
+	writer, cleanup := setupPostgresDB(t)
+	defer cleanup()
+	// a read-only finality gadget uses its own handler on the same DB
+	reader := newPostgresHandler(writer.db, zap.NewNop())
+
+	err := writer.InsertBlocks([]*types.Block{{BlockHeight: 1, BlockHash: "0x123", BlockTimestamp: 1000}})
+	require.NoError(t, err)
+	latest, err := reader.QueryLatestFinalizedBlock()
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), latest.BlockHeight)
+	require.NoError(t, reader.Ping())
+}
+
+// startTestPostgres starts an ephemeral PostgreSQL server on a free port, in a temporary directory
+func startTestPostgres() {
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		testPostgresErr = fmt.Errorf("failed to find a free port: %w", err)
+		return
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	testPostgresDir, err = os.MkdirTemp("", "fg-test-postgres")
+	if err != nil {
+		testPostgresErr = fmt.Errorf("failed to create runtime directory: %w", err)
+		return
+	}
+	cfg := embeddedpostgres.DefaultConfig().
+		Port(uint32(port)).
+		RuntimePath(filepath.Join(testPostgresDir, "runtime")).
+		Logger(io.Discard)
+	server := embeddedpostgres.NewDatabase(cfg)
+	if err := server.Start(); err != nil {
+		testPostgresErr = err
+		return
+	}
+	testPostgres = server
+	testPostgresDSN = cfg.GetConnectionURL() + "?sslmode=disable"
+}
+
+// openTestPostgres opens the PostgreSQL DB at the given connection URL, in a new schema dropped on cleanup
+func openTestPostgres(t *testing.T, dsn string) (*sql.DB, func()) {
+	adminDB, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("Failed to open PostgreSQL DB: %v", err)
+	}
+	schema := fmt.Sprintf("fg_test_%d_%d", time.Now().UnixNano(), testSchemaCounter.Add(1))
+	if _, err := adminDB.Exec(`CREATE SCHEMA ` + schema); err != nil {
+		t.Fatalf("Failed to create schema: %v", err)
+	}
+
+	u, err := url.Parse(dsn)
+	if err != nil {
+		t.Fatalf("Failed to parse %s, it must be a connection URL: %v", testPostgresDSNEnv, err)
+	}
+	query := u.Query()
+	query.Set("search_path", schema)
+	u.RawQuery = query.Encode()
+	sqlDB, err := sql.Open("postgres", u.String())
+	if err != nil {
+		t.Fatalf("Failed to open PostgreSQL DB: %v", err)
+	}
+
+	return sqlDB, func() {
+		if _, err := adminDB.Exec(`DROP SCHEMA ` + schema + ` CASCADE`); err != nil {
+			t.Errorf("Failed to drop schema: %v", err)
+		}
+		adminDB.Close()
+	}
+}
@@ -0,0 +1,43 @@
+package db
+
+// prunedRange is the range [from, to) of the heights of the blocks pruned from the db. Only consecutively
+// finalized blocks are stored, so the pruned blocks were all BTC-finalized.
+type prunedRange struct {
+	from uint64
+	to   uint64
+}
+
+// contains returns whether the block at the given height was pruned
+func (r *prunedRange) contains(height uint64) bool {
+	return r != nil && r.from <= height && height < r.to
+}
+
+// afterPrune returns the pruned range once the blocks in [from, to) are pruned, extending the current range if
+// they follow it
+func (r *prunedRange) afterPrune(from, to uint64) *prunedRange {
+	if r != nil && r.to == from {
+		return &prunedRange{from: r.from, to: to}
+	}
+	return &prunedRange{from: from, to: to}
+}
+
+// afterRollback returns the pruned range once the blocks above the given height are rolled back, nil if no
+// pruned block is left
+func (r *prunedRange) afterRollback(height uint64) *prunedRange {
+	if r == nil || height < r.from {
+		return nil
+	}
+	if height >= r.to {
+		return r
+	}
+	return &prunedRange{from: r.from, to: height + 1}
+}
+
+// earliestFinalizedHeight returns the height all blocks from which up to the latest block are finalized, given
+// the height of the earliest stored block. Pruned blocks only count if they are followed by the stored blocks.
+func (r *prunedRange) earliestFinalizedHeight(earliestBlockHeight uint64) uint64 {
+	if r != nil && r.to == earliestBlockHeight {
+		return r.from
+	}
+	return earliestBlockHeight
+}
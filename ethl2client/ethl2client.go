@@ -5,9 +5,12 @@ import (
 	"fmt"
 	"math/big"
 
+	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	eth "github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
 )
 
 type EthL2Client struct {
@@ -42,6 +45,99 @@ func (ec *EthL2Client) TransactionReceipt(ctx context.Context, txHash string) (*
 	return ec.client.TransactionReceipt(ctx, hash)
 }
 
+// TransactionReceipts returns the receipts of the given txs in a single JSON-RPC batch request. The
+// receipt of a tx that is not found, i.e. not mined yet, is nil.
+func (c *EthL2Client) TransactionReceipts(ctx context.Context, txHashes []string) ([]*eth.Receipt, error) {
+	receipts := make([]*eth.Receipt, len(txHashes))
+	batch := make([]rpc.BatchElem, len(txHashes))
+	for i, txHash := range txHashes {
+		batch[i] = rpc.BatchElem{
+			Method: "eth_getTransactionReceipt",
+			Args:   []interface{}{common.HexToHash(txHash)},
+			Result: &receipts[i],
+		}
+	}
+	if err := c.batchCall(ctx, batch); err != nil {
+		return nil, err
+	}
+	return receipts, nil
+}
+
+// HeadersByNumbers returns the headers of the blocks at the given heights, which can also be
+// negative rpc.BlockNumber labels such as safe and finalized, in a single JSON-RPC batch request.
+// Unlike HeaderByNumber, it fails if any block is not found.
+func (c *EthL2Client) HeadersByNumbers(ctx context.Context, numbers []*big.Int) ([]*eth.Header, error) {
+	headers := make([]*eth.Header, len(numbers))
+	batch := make([]rpc.BatchElem, len(numbers))
+	for i, number := range numbers {
+		batch[i] = rpc.BatchElem{
+			Method: "eth_getBlockByNumber",
+			Args:   []interface{}{toBlockNumArg(number), false},
+			Result: &headers[i],
+		}
+	}
+	if err := c.batchCall(ctx, batch); err != nil {
+		return nil, err
+	}
+	for i, header := range headers {
+		if header == nil {
+			return nil, fmt.Errorf("block %s %w", toBlockNumArg(numbers[i]), ethereum.NotFound)
+		}
+	}
+	return headers, nil
+}
+
+// L1OriginByNumber returns the L1 origin of the L2 block at the given height, decoded from the
+// L1 attributes deposit tx at index 0 of the block.
+//
+// Note: we query the raw tx instead of using `ethclient.BlockByNumber` as go-ethereum is unable
+// to decode OP stack deposit txs.
+func (c *EthL2Client) L1OriginByNumber(ctx context.Context, number *big.Int) (*L1Origin, error) {
+	var tx *struct {
+		Input hexutil.Bytes `json:"input"`
+	}
+	err := c.client.Client().CallContext(ctx, &tx, "eth_getTransactionByBlockNumberAndIndex", hexutil.EncodeBig(number), hexutil.Uint(0))
+	if err != nil {
+		return nil, err
+	}
+	if tx == nil {
+		return nil, fmt.Errorf("%w: block %s has no transactions", ErrUnknownL1InfoFormat, number)
+	}
+	return ParseL1Origin(tx.Input)
+}
+
 func (c *EthL2Client) Close() {
 	c.client.Close()
 }
+
+//////////////////////////////
+// INTERNAL
+//////////////////////////////
+
+// batchCall sends a JSON-RPC batch request and fails if any of its calls failed
+func (c *EthL2Client) batchCall(ctx context.Context, batch []rpc.BatchElem) error {
+	if len(batch) == 0 {
+		return nil
+	}
+	if err := c.client.Client().BatchCallContext(ctx, batch); err != nil {
+		return err
+	}
+	for _, elem := range batch {
+		if elem.Error != nil {
+			return fmt.Errorf("%s failed: %w", elem.Method, elem.Error)
+		}
+	}
+	return nil
+}
+
+// toBlockNumArg encodes a block number like ethclient does, mapping negative numbers to their
+// rpc.BlockNumber labels
+func toBlockNumArg(number *big.Int) string {
+	if number == nil {
+		return "latest"
+	}
+	if number.Sign() >= 0 {
+		return hexutil.EncodeBig(number)
+	}
+	return rpc.BlockNumber(number.Int64()).String()
+}
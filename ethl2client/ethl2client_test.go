@@ -0,0 +1,133 @@
+package ethl2client
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	eth "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransactionReceipts(t *testing.T) {
+	minedTxHash := common.HexToHash("0x01")
+	receipt := &eth.Receipt{
+		Status:      eth.ReceiptStatusSuccessful,
+		TxHash:      minedTxHash,
+		BlockNumber: big.NewInt(10),
+		Logs:        []*eth.Log{},
+	}
+	node := newFakeL2Node(t, func(method string, params []json.RawMessage) interface{} {
+		require.Equal(t, "eth_getTransactionReceipt", method)
+		var txHash common.Hash
+		require.NoError(t, json.Unmarshal(params[0], &txHash))
+		if txHash == minedTxHash {
+			return receipt
+		}
+		return nil
+	})
+
+	receipts, err := node.client.TransactionReceipts(context.Background(), []string{minedTxHash.Hex(), common.HexToHash("0x02").Hex()})
+	require.NoError(t, err)
+	require.Len(t, receipts, 2)
+	require.Equal(t, minedTxHash, receipts[0].TxHash)
+	require.Equal(t, uint64(10), receipts[0].BlockNumber.Uint64())
+	require.Nil(t, receipts[1])
+	require.Equal(t, 1, node.batches)
+}
+
+func TestHeadersByNumbers(t *testing.T) {
+	heights := map[string]int64{"safe": 8, "finalized": 5, "0xa": 10}
+	node := newFakeL2Node(t, func(method string, params []json.RawMessage) interface{} {
+		require.Equal(t, "eth_getBlockByNumber", method)
+		var number string
+		require.NoError(t, json.Unmarshal(params[0], &number))
+		height, ok := heights[number]
+		if !ok {
+			return nil
+		}
+		return &eth.Header{Number: big.NewInt(height), Difficulty: big.NewInt(0)}
+	})
+
+	headers, err := node.client.HeadersByNumbers(context.Background(), []*big.Int{
+		big.NewInt(rpc.SafeBlockNumber.Int64()),
+		big.NewInt(rpc.FinalizedBlockNumber.Int64()),
+		big.NewInt(10),
+	})
+	require.NoError(t, err)
+	require.Len(t, headers, 3)
+	require.Equal(t, uint64(8), headers[0].Number.Uint64())
+	require.Equal(t, uint64(5), headers[1].Number.Uint64())
+	require.Equal(t, uint64(10), headers[2].Number.Uint64())
+	require.Equal(t, 1, node.batches)
+
+	// a missing block fails the whole request
+	_, err = node.client.HeadersByNumbers(context.Background(), []*big.Int{big.NewInt(10), big.NewInt(11)})
+	require.ErrorContains(t, err, "block 0xb not found")
+}
+
+// fakeL2Node is a JSON-RPC server answering single and batch requests with the given handler
+type fakeL2Node struct {
+	client  *EthL2Client
+	url     string
+	batches int
+	// requests counts the single and batch requests received
+	requests atomic.Int32
+	// failing makes the node reply with an HTTP 503 error
+	failing atomic.Bool
+}
+
+func newFakeL2Node(t *testing.T, handle func(method string, params []json.RawMessage) interface{}) *fakeL2Node {
+	node := &fakeL2Node{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		node.requests.Add(1)
+		if node.failing.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		var body json.RawMessage
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		type request struct {
+			Id     json.RawMessage   `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		reply := func(req request) map[string]interface{} {
+			return map[string]interface{}{
+				"jsonrpc": "2.0",
+				"id":      req.Id,
+				"result":  handle(req.Method, req.Params),
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+
+		if body[0] != '[' {
+			var req request
+			require.NoError(t, json.Unmarshal(body, &req))
+			require.NoError(t, json.NewEncoder(w).Encode(reply(req)))
+			return
+		}
+		var reqs []request
+		require.NoError(t, json.Unmarshal(body, &reqs))
+		node.batches++
+		res := make([]map[string]interface{}, 0, len(reqs))
+		for _, req := range reqs {
+			res = append(res, reply(req))
+		}
+		require.NoError(t, json.NewEncoder(w).Encode(res))
+	}))
+	t.Cleanup(srv.Close)
+
+	client, err := NewEthL2Client(srv.URL)
+	require.NoError(t, err)
+	t.Cleanup(client.Close)
+	node.client = client
+	node.url = srv.URL
+	return node
+}
@@ -0,0 +1,62 @@
+package ethl2client
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// The first transaction of every OP stack L2 block is the L1 attributes deposit tx, which calls
+// the L1Block predeploy with the L1 origin of the L2 block. The calldata layout depends on the
+// hardfork the L2 chain is on.
+// https://specs.optimism.io/protocol/deposits.html#l1-attributes-deposited-transaction
+var (
+	// setL1BlockValues(uint64,uint64,uint256,bytes32,uint64,bytes32,uint256,uint256)
+	bedrockL1InfoSelector = []byte{0x01, 0x5d, 0x8e, 0xb9}
+	// setL1BlockValuesEcotone()
+	ecotoneL1InfoSelector = []byte{0x44, 0x0a, 0x5e, 0x20}
+	// setL1BlockValuesIsthmus()
+	isthmusL1InfoSelector = []byte{0x09, 0x89, 0x99, 0xbe}
+)
+
+var ErrUnknownL1InfoFormat = errors.New("unknown L1 attributes deposit tx format")
+
+// L1Origin is the L1 block an L2 block was derived from
+type L1Origin struct {
+	Hash   common.Hash
+	Number uint64
+}
+
+// ParseL1Origin decodes the L1 origin from the calldata of an L1 attributes deposit tx
+func ParseL1Origin(data []byte) (*L1Origin, error) {
+	if len(data) < 4 {
+		return nil, fmt.Errorf("%w: calldata too short", ErrUnknownL1InfoFormat)
+	}
+
+	switch {
+	case bytes.Equal(data[:4], bedrockL1InfoSelector):
+		// abi encoded: selector | number (32) | timestamp (32) | basefee (32) | hash (32) | ...
+		if len(data) < 4+32*4 {
+			return nil, fmt.Errorf("%w: bedrock calldata too short", ErrUnknownL1InfoFormat)
+		}
+		return &L1Origin{
+			Number: binary.BigEndian.Uint64(data[4+24 : 4+32]),
+			Hash:   common.BytesToHash(data[4+32*3 : 4+32*4]),
+		}, nil
+	case bytes.Equal(data[:4], ecotoneL1InfoSelector), bytes.Equal(data[:4], isthmusL1InfoSelector):
+		// packed: selector | base fee scalar (4) | blob base fee scalar (4) | sequence number (8) |
+		// timestamp (8) | number (8) | basefee (32) | blob basefee (32) | hash (32) | ...
+		if len(data) < 132 {
+			return nil, fmt.Errorf("%w: ecotone calldata too short", ErrUnknownL1InfoFormat)
+		}
+		return &L1Origin{
+			Number: binary.BigEndian.Uint64(data[28:36]),
+			Hash:   common.BytesToHash(data[100:132]),
+		}, nil
+	default:
+		return nil, fmt.Errorf("%w: selector %x", ErrUnknownL1InfoFormat, data[:4])
+	}
+}
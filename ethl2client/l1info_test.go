@@ -0,0 +1,61 @@
+package ethl2client
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseL1Origin(t *testing.T) {
+	l1Hash := common.HexToHash("0xd4e56740f876aef8c010b86a40d5f56745a118d0906a34e69aec8c0db1cb8fa3")
+	l1Number := uint64(20_000_123)
+
+	// bedrock: abi encoded calldata
+	bedrockData := make([]byte, 4+32*8)
+	copy(bedrockData, bedrockL1InfoSelector)
+	binary.BigEndian.PutUint64(bedrockData[4+24:4+32], l1Number)
+	binary.BigEndian.PutUint64(bedrockData[4+32+24:4+32*2], 1718839311)
+	copy(bedrockData[4+32*3:4+32*4], l1Hash.Bytes())
+
+	// ecotone: packed calldata
+	ecotoneData := make([]byte, 164)
+	copy(ecotoneData, ecotoneL1InfoSelector)
+	binary.BigEndian.PutUint64(ecotoneData[12:20], 3)
+	binary.BigEndian.PutUint64(ecotoneData[20:28], 1718839311)
+	binary.BigEndian.PutUint64(ecotoneData[28:36], l1Number)
+	copy(ecotoneData[100:132], l1Hash.Bytes())
+
+	// isthmus shares the ecotone layout prefix
+	isthmusData := make([]byte, 176)
+	copy(isthmusData, ecotoneData)
+	copy(isthmusData, isthmusL1InfoSelector)
+
+	testCases := []struct {
+		expectedErr error
+		name        string
+		data        []byte
+	}{
+		{name: "bedrock", data: bedrockData, expectedErr: nil},
+		{name: "ecotone", data: ecotoneData, expectedErr: nil},
+		{name: "isthmus", data: isthmusData, expectedErr: nil},
+		{name: "truncated ecotone", data: ecotoneData[:100], expectedErr: ErrUnknownL1InfoFormat},
+		{name: "unknown selector", data: []byte{0xde, 0xad, 0xbe, 0xef, 0x00}, expectedErr: ErrUnknownL1InfoFormat},
+		{name: "empty calldata", data: nil, expectedErr: ErrUnknownL1InfoFormat},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			origin, err := ParseL1Origin(tc.data)
+			if tc.expectedErr != nil {
+				require.ErrorIs(t, err, tc.expectedErr)
+				require.Nil(t, origin)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, l1Number, origin.Number)
+			require.Equal(t, l1Hash, origin.Hash)
+		})
+	}
+}
@@ -0,0 +1,454 @@
+package ethl2client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/babylonlabs-io/finality-gadget/metrics"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	eth "github.com/ethereum/go-ethereum/core/types"
+	"go.uber.org/zap"
+)
+
+const (
+	// endpointBaseCooldown is how long a failing endpoint is skipped after its first failure, doubling with each
+	// consecutive failure up to endpointMaxCooldown
+	endpointBaseCooldown = 5 * time.Second
+	endpointMaxCooldown  = time.Minute
+)
+
+// ErrNoQuorum is returned if not enough endpoints agree on the hash of a block
+var ErrNoQuorum = errors.New("L2 endpoints did not reach quorum")
+
+// EthL2ClientPool is an L2 client querying a list of L2 nodes. Each call is sent to the first healthy endpoint in
+// order of preference, failing over to the next one on error. Endpoints are skipped for a cooldown after failing.
+//
+// With quorum reads enabled, block headers are queried from all endpoints and only returned once `quorum` endpoints
+// returned the same block hash, so that a single faulty or malicious node can't make the gadget trust a block that
+// isn't canonical.
+type EthL2ClientPool struct {
+	metrics   *metrics.FinalityGadgetMetrics
+	logger    *zap.Logger
+	endpoints []*endpoint
+	// quorum is the number of endpoints that must agree on a block hash, quorum reads are disabled if <= 1
+	quorum int
+}
+
+// endpoint is an L2 node of the pool and its health
+type endpoint struct {
+	// unhealthyUntil is the time until which the endpoint is only queried if all healthy endpoints failed
+	unhealthyUntil time.Time
+	client         *EthL2Client
+	// name identifies the endpoint in logs and metrics, without the path and query of its address as they may
+	// contain API keys
+	name     string
+	failures int
+	mutex    sync.Mutex
+}
+
+//////////////////////////////
+// CONSTRUCTOR
+//////////////////////////////
+
+func NewEthL2ClientPool(rpcHostAddrs []string, quorum int, metrics *metrics.FinalityGadgetMetrics, logger *zap.Logger) (*EthL2ClientPool, error) {
+	if len(rpcHostAddrs) == 0 {
+		return nil, errors.New("no L2 endpoints")
+	}
+	if quorum > len(rpcHostAddrs) {
+		return nil, fmt.Errorf("quorum %d exceeds the number of L2 endpoints %d", quorum, len(rpcHostAddrs))
+	}
+
+	pool := &EthL2ClientPool{
+		endpoints: make([]*endpoint, 0, len(rpcHostAddrs)),
+		metrics:   metrics,
+		logger:    logger,
+		quorum:    quorum,
+	}
+	names := make(map[string]bool)
+	for i, addr := range rpcHostAddrs {
+		client, err := NewEthL2Client(addr)
+		if err != nil {
+			pool.Close()
+			return nil, err
+		}
+		name := endpointName(addr)
+		if names[name] {
+			name = fmt.Sprintf("%s#%d", name, i)
+		}
+		names[name] = true
+		pool.endpoints = append(pool.endpoints, &endpoint{client: client, name: name})
+		metrics.SetL2EndpointHealthy(name, true)
+	}
+	return pool, nil
+}
+
+//////////////////////////////
+// METHODS
+//////////////////////////////
+
+func (p *EthL2ClientPool) HeaderByNumber(ctx context.Context, number *big.Int) (*eth.Header, error) {
+	if p.quorum > 1 {
+		headers, err := p.quorumHeadersByNumbers(ctx, []*big.Int{number})
+		if err != nil {
+			return nil, err
+		}
+		return headers[0], nil
+	}
+
+	var header *eth.Header
+	err := p.withFailover(ctx, "HeaderByNumber", func(c *EthL2Client) error {
+		var err error
+		header, err = c.HeaderByNumber(ctx, number)
+		return err
+	})
+	return header, err
+}
+
+func (p *EthL2ClientPool) TransactionReceipt(ctx context.Context, txHash string) (*eth.Receipt, error) {
+	var receipt *eth.Receipt
+	err := p.withFailover(ctx, "TransactionReceipt", func(c *EthL2Client) error {
+		var err error
+		receipt, err = c.TransactionReceipt(ctx, txHash)
+		return err
+	})
+	return receipt, err
+}
+
+// TransactionReceipts returns the receipts of the given txs, nil for the txs no endpoint found. The txs an endpoint
+// didn't find are queried from the next endpoint, as a lagging endpoint may not have their blocks yet.
+func (p *EthL2ClientPool) TransactionReceipts(ctx context.Context, txHashes []string) ([]*eth.Receipt, error) {
+	receipts := make([]*eth.Receipt, len(txHashes))
+	// missing holds the indexes of the txs not found yet
+	missing := make([]int, len(txHashes))
+	for i := range missing {
+		missing[i] = i
+	}
+	var answered bool
+	err := p.withFailover(ctx, "TransactionReceipts", func(c *EthL2Client) error {
+		missingHashes := make([]string, len(missing))
+		for i, index := range missing {
+			missingHashes[i] = txHashes[index]
+		}
+		found, err := c.TransactionReceipts(ctx, missingHashes)
+		if err != nil {
+			return err
+		}
+		answered = true
+		var stillMissing []int
+		for i, receipt := range found {
+			if receipt == nil {
+				stillMissing = append(stillMissing, missing[i])
+				continue
+			}
+			receipts[missing[i]] = receipt
+		}
+		missing = stillMissing
+		if len(missing) > 0 {
+			return fmt.Errorf("%d of %d transaction receipts %w", len(missing), len(txHashes), ethereum.NotFound)
+		}
+		return nil
+	})
+	// the txs still missing once an endpoint answered are not mined yet
+	if err != nil && (!answered || ctx.Err() != nil) {
+		return nil, err
+	}
+	return receipts, nil
+}
+
+func (p *EthL2ClientPool) HeadersByNumbers(ctx context.Context, numbers []*big.Int) ([]*eth.Header, error) {
+	if p.quorum > 1 {
+		return p.quorumHeadersByNumbers(ctx, numbers)
+	}
+
+	var headers []*eth.Header
+	err := p.withFailover(ctx, "HeadersByNumbers", func(c *EthL2Client) error {
+		var err error
+		headers, err = c.HeadersByNumbers(ctx, numbers)
+		return err
+	})
+	return headers, err
+}
+
+func (p *EthL2ClientPool) L1OriginByNumber(ctx context.Context, number *big.Int) (*L1Origin, error) {
+	var origin *L1Origin
+	err := p.withFailover(ctx, "L1OriginByNumber", func(c *EthL2Client) error {
+		var err error
+		origin, err = c.L1OriginByNumber(ctx, number)
+		return err
+	})
+	return origin, err
+}
+
+func (p *EthL2ClientPool) Close() {
+	for _, e := range p.endpoints {
+		e.client.Close()
+	}
+}
+
+//////////////////////////////
+// INTERNAL
+//////////////////////////////
+
+/* withFailover calls the endpoints in order of preference until a call succeeds
+ *
+ * - healthy endpoints are called first, in the configured order, then the unhealthy ones, soonest to recover first
+ * - an endpoint returning an error is marked unhealthy. an endpoint not finding the requested block or tx is
+ *   likely lagging behind, so the next endpoint is called without marking it unhealthy
+ * - if all endpoints fail, their errors are returned
+ */
+func (p *EthL2ClientPool) withFailover(ctx context.Context, method string, call func(c *EthL2Client) error) error {
+	var errs []error
+	for _, e := range p.byPreference() {
+		start := time.Now()
+		err := call(e.client)
+		p.metrics.ObserveL2EndpointRequest(e.name, method, start, err)
+		if err == nil {
+			p.recordSuccess(e)
+			return nil
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+		if !errors.Is(err, ethereum.NotFound) {
+			p.recordFailure(e, err)
+		}
+		errs = append(errs, fmt.Errorf("L2 endpoint %s: %w", e.name, err))
+	}
+	return errors.Join(errs...)
+}
+
+/* quorumHeadersByNumbers returns the headers of the blocks at the given heights once enough endpoints agree on them
+ *
+ * - block number labels such as latest or finalized are first resolved to heights all endpoints are asked for, see
+ *   resolveBlockNumbers, as endpoints following the tip at slightly different paces would rarely agree on them
+ * - the headers are then queried from all endpoints in parallel, and returned as soon as `quorum` endpoints
+ *   returned the same hash for every block
+ * - endpoints failing or not finding a block don't count towards the quorum
+ */
+func (p *EthL2ClientPool) quorumHeadersByNumbers(ctx context.Context, numbers []*big.Int) ([]*eth.Header, error) {
+	heights, err := p.resolveBlockNumbers(ctx, numbers)
+	if err != nil {
+		return nil, err
+	}
+
+	type result struct {
+		err     error
+		e       *endpoint
+		headers []*eth.Header
+	}
+	// results is buffered so that the remaining queries don't block once the quorum is reached
+	results := make(chan result, len(p.endpoints))
+	for _, e := range p.endpoints {
+		go func(e *endpoint) {
+			start := time.Now()
+			headers, err := e.client.HeadersByNumbers(ctx, heights)
+			p.metrics.ObserveL2EndpointRequest(e.name, "HeadersByNumbers", start, err)
+			results <- result{err: err, e: e, headers: headers}
+		}(e)
+	}
+
+	votes := make([]map[common.Hash][]*eth.Header, len(heights))
+	for i := range votes {
+		votes[i] = make(map[common.Hash][]*eth.Header)
+	}
+	var errs []error
+	for range p.endpoints {
+		res := <-results
+		if res.err != nil {
+			if ctx.Err() != nil {
+				return nil, res.err
+			}
+			if !errors.Is(res.err, ethereum.NotFound) {
+				p.recordFailure(res.e, res.err)
+			}
+			errs = append(errs, fmt.Errorf("L2 endpoint %s: %w", res.e.name, res.err))
+			continue
+		}
+		p.recordSuccess(res.e)
+		for i, header := range res.headers {
+			votes[i][header.Hash()] = append(votes[i][header.Hash()], header)
+		}
+		if headers := p.agreedHeaders(votes); headers != nil {
+			return headers, nil
+		}
+	}
+
+	p.metrics.IncL2QuorumFailures()
+	for i, blockVotes := range votes {
+		var maxVotes int
+		for _, headers := range blockVotes {
+			if len(headers) > maxVotes {
+				maxVotes = len(headers)
+			}
+		}
+		if maxVotes < p.quorum {
+			errs = append([]error{fmt.Errorf("%w on block %s: at most %d of %d endpoints agree, %d required",
+				ErrNoQuorum, toBlockNumArg(heights[i]), maxVotes, len(p.endpoints), p.quorum)}, errs...)
+			break
+		}
+	}
+	p.logger.Warn("L2 endpoints did not reach quorum", zap.Error(errors.Join(errs...)))
+	return nil, errors.Join(errs...)
+}
+
+/* resolveBlockNumbers maps the block number labels to the heights of the blocks they refer to
+ *
+ * - the labels are resolved by all endpoints in parallel
+ * - each label is mapped to the highest height reached by `quorum` of the endpoints that answered, so that
+ *   enough endpoints have the block to agree on it. endpoints further ahead also have it
+ * - if fewer endpoints answered, the label is mapped to the lowest height they returned
+ */
+func (p *EthL2ClientPool) resolveBlockNumbers(ctx context.Context, numbers []*big.Int) ([]*big.Int, error) {
+	heights := make([]*big.Int, len(numbers))
+	var labels []*big.Int
+	var labelIndexes []int
+	for i, number := range numbers {
+		if number != nil && number.Sign() >= 0 {
+			heights[i] = number
+			continue
+		}
+		labels = append(labels, number)
+		labelIndexes = append(labelIndexes, i)
+	}
+	if len(labels) == 0 {
+		return heights, nil
+	}
+
+	type result struct {
+		err     error
+		e       *endpoint
+		headers []*eth.Header
+	}
+	results := make(chan result, len(p.endpoints))
+	for _, e := range p.endpoints {
+		go func(e *endpoint) {
+			start := time.Now()
+			headers, err := e.client.HeadersByNumbers(ctx, labels)
+			p.metrics.ObserveL2EndpointRequest(e.name, "HeadersByNumbers", start, err)
+			results <- result{err: err, e: e, headers: headers}
+		}(e)
+	}
+
+	// resolved holds the heights each label was resolved to by the endpoints that answered
+	resolved := make([][]*big.Int, len(labels))
+	var errs []error
+	for range p.endpoints {
+		res := <-results
+		if res.err != nil {
+			if ctx.Err() != nil {
+				return nil, res.err
+			}
+			if !errors.Is(res.err, ethereum.NotFound) {
+				p.recordFailure(res.e, res.err)
+			}
+			errs = append(errs, fmt.Errorf("L2 endpoint %s: %w", res.e.name, res.err))
+			continue
+		}
+		p.recordSuccess(res.e)
+		for i, header := range res.headers {
+			resolved[i] = append(resolved[i], header.Number)
+		}
+	}
+	if len(resolved[0]) == 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	for i, labelHeights := range resolved {
+		// sort the heights in descending order
+		sort.Slice(labelHeights, func(a, b int) bool { return labelHeights[a].Cmp(labelHeights[b]) > 0 })
+		heights[labelIndexes[i]] = labelHeights[min(p.quorum, len(labelHeights))-1]
+	}
+	return heights, nil
+}
+
+// agreedHeaders returns the headers for which `quorum` endpoints voted, nil if any block hasn't reached the quorum
+func (p *EthL2ClientPool) agreedHeaders(votes []map[common.Hash][]*eth.Header) []*eth.Header {
+	headers := make([]*eth.Header, len(votes))
+	for i, blockVotes := range votes {
+		for _, agreed := range blockVotes {
+			if len(agreed) >= p.quorum {
+				headers[i] = agreed[0]
+				break
+			}
+		}
+		if headers[i] == nil {
+			return nil
+		}
+	}
+	return headers
+}
+
+// byPreference returns the endpoints in the order they should be called: the healthy endpoints in the configured
+// order, then the unhealthy endpoints, soonest to recover first
+func (p *EthL2ClientPool) byPreference() []*endpoint {
+	now := time.Now()
+	healthy := make([]*endpoint, 0, len(p.endpoints))
+	var unhealthy []*endpoint
+	unhealthyUntil := make(map[*endpoint]time.Time)
+	for _, e := range p.endpoints {
+		e.mutex.Lock()
+		until := e.unhealthyUntil
+		e.mutex.Unlock()
+		if now.Before(until) {
+			unhealthy = append(unhealthy, e)
+			unhealthyUntil[e] = until
+			continue
+		}
+		healthy = append(healthy, e)
+	}
+	sort.SliceStable(unhealthy, func(i, j int) bool {
+		return unhealthyUntil[unhealthy[i]].Before(unhealthyUntil[unhealthy[j]])
+	})
+	return append(healthy, unhealthy...)
+}
+
+// recordFailure marks the endpoint unhealthy for a cooldown doubling with each consecutive failure
+func (p *EthL2ClientPool) recordFailure(e *endpoint, err error) {
+	e.mutex.Lock()
+	e.failures++
+	cooldown := endpointMaxCooldown
+	if e.failures < 32 && endpointBaseCooldown<<(e.failures-1) < endpointMaxCooldown {
+		cooldown = endpointBaseCooldown << (e.failures - 1)
+	}
+	e.unhealthyUntil = time.Now().Add(cooldown)
+	failures := e.failures
+	e.mutex.Unlock()
+
+	p.metrics.SetL2EndpointHealthy(e.name, false)
+	p.logger.Warn("L2 endpoint failed, skipping it",
+		zap.String("endpoint", e.name),
+		zap.Int("consecutive_failures", failures),
+		zap.Duration("cooldown", cooldown),
+		zap.Error(err),
+	)
+}
+
+// recordSuccess marks the endpoint healthy
+func (p *EthL2ClientPool) recordSuccess(e *endpoint) {
+	e.mutex.Lock()
+	recovered := e.failures > 0
+	e.failures = 0
+	e.unhealthyUntil = time.Time{}
+	e.mutex.Unlock()
+
+	if recovered {
+		p.metrics.SetL2EndpointHealthy(e.name, true)
+		p.logger.Info("L2 endpoint recovered", zap.String("endpoint", e.name))
+	}
+}
+
+// endpointName returns the scheme and host of the endpoint address, or the address if it can't be parsed
+func endpointName(addr string) string {
+	u, err := url.Parse(addr)
+	if err != nil || u.Host == "" {
+		return addr
+	}
+	return u.Scheme + "://" + u.Host
+}
@@ -0,0 +1,227 @@
+package ethl2client
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/babylonlabs-io/finality-gadget/metrics"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	eth "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestPoolFailover(t *testing.T) {
+	header := testHeader(10, "0x1")
+	primary := newFakeL2Node(t, blockHandler(t, map[string]*eth.Header{"0xa": header}))
+	fallback := newFakeL2Node(t, blockHandler(t, map[string]*eth.Header{"0xa": header}))
+	pool := newTestPool(t, 1, primary, fallback)
+
+	// the primary endpoint is preferred
+	res, err := pool.HeaderByNumber(context.Background(), big.NewInt(10))
+	require.NoError(t, err)
+	require.Equal(t, header.Hash(), res.Hash())
+	require.Equal(t, int32(1), primary.requests.Load())
+	require.Equal(t, int32(0), fallback.requests.Load())
+
+	// calls fail over to the fallback endpoint while the primary endpoint is down
+	primary.failing.Store(true)
+	res, err = pool.HeaderByNumber(context.Background(), big.NewInt(10))
+	require.NoError(t, err)
+	require.Equal(t, header.Hash(), res.Hash())
+	require.Equal(t, int32(2), primary.requests.Load())
+	require.Equal(t, int32(1), fallback.requests.Load())
+
+	// the failed endpoint is skipped during its cooldown
+	_, err = pool.HeadersByNumbers(context.Background(), []*big.Int{big.NewInt(10)})
+	require.NoError(t, err)
+	require.Equal(t, int32(2), primary.requests.Load())
+	require.Equal(t, int32(2), fallback.requests.Load())
+	require.Equal(t, []*endpoint{pool.endpoints[1], pool.endpoints[0]}, pool.byPreference())
+
+	// and tried again as a last resort
+	fallback.failing.Store(true)
+	_, err = pool.HeaderByNumber(context.Background(), big.NewInt(10))
+	require.ErrorContains(t, err, "L2 endpoint "+fallback.url)
+	require.ErrorContains(t, err, "L2 endpoint "+primary.url)
+	var httpErr rpc.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	require.Equal(t, 2, pool.endpoints[0].failures)
+
+	// endpoints are healthy again once they succeed
+	primary.failing.Store(false)
+	pool.endpoints[0].unhealthyUntil = pool.endpoints[0].unhealthyUntil.Add(-endpointMaxCooldown)
+	_, err = pool.HeaderByNumber(context.Background(), big.NewInt(10))
+	require.NoError(t, err)
+	require.Equal(t, 0, pool.endpoints[0].failures)
+	require.Equal(t, pool.endpoints[0], pool.byPreference()[0])
+}
+
+func TestPoolLaggingEndpoint(t *testing.T) {
+	header := testHeader(10, "0x1")
+	lagging := newFakeL2Node(t, blockHandler(t, map[string]*eth.Header{}))
+	synced := newFakeL2Node(t, blockHandler(t, map[string]*eth.Header{"0xa": header}))
+	pool := newTestPool(t, 1, lagging, synced)
+
+	// blocks not found by lagging endpoints are queried from the next endpoint, without marking them unhealthy
+	res, err := pool.HeaderByNumber(context.Background(), big.NewInt(10))
+	require.NoError(t, err)
+	require.Equal(t, header.Hash(), res.Hash())
+	require.Equal(t, 0, pool.endpoints[0].failures)
+
+	// not found if no endpoint has the block
+	_, err = pool.HeadersByNumbers(context.Background(), []*big.Int{big.NewInt(11)})
+	require.ErrorIs(t, err, ethereum.NotFound)
+	require.Equal(t, 0, pool.endpoints[0].failures)
+	require.Equal(t, 0, pool.endpoints[1].failures)
+}
+
+func TestPoolLaggingEndpointReceipts(t *testing.T) {
+	oldTxHash, newTxHash, pendingTxHash := common.HexToHash("0x01"), common.HexToHash("0x02"), common.HexToHash("0x03")
+	oldReceipt := &eth.Receipt{TxHash: oldTxHash, BlockNumber: big.NewInt(9), Logs: []*eth.Log{}}
+	newReceipt := &eth.Receipt{TxHash: newTxHash, BlockNumber: big.NewInt(10), Logs: []*eth.Log{}}
+	lagging := newFakeL2Node(t, receiptHandler(t, map[common.Hash]*eth.Receipt{oldTxHash: oldReceipt}, nil))
+	var queried []common.Hash
+	synced := newFakeL2Node(t, receiptHandler(t, map[common.Hash]*eth.Receipt{oldTxHash: oldReceipt, newTxHash: newReceipt}, &queried))
+	pool := newTestPool(t, 1, lagging, synced)
+
+	// the receipts not found by lagging endpoints are queried from the next endpoint, without marking them unhealthy
+	receipts, err := pool.TransactionReceipts(context.Background(), []string{oldTxHash.Hex(), newTxHash.Hex(), pendingTxHash.Hex()})
+	require.NoError(t, err)
+	require.Len(t, receipts, 3)
+	require.Equal(t, oldTxHash, receipts[0].TxHash)
+	require.Equal(t, newTxHash, receipts[1].TxHash)
+	require.Equal(t, uint64(10), receipts[1].BlockNumber.Uint64())
+	require.Equal(t, []common.Hash{newTxHash, pendingTxHash}, queried)
+	require.Equal(t, 0, pool.endpoints[0].failures)
+	require.Equal(t, 0, pool.endpoints[1].failures)
+
+	// the receipts of txs no endpoint found are nil
+	require.Nil(t, receipts[2])
+
+	// and failing endpoints are skipped
+	synced.failing.Store(true)
+	receipts, err = pool.TransactionReceipts(context.Background(), []string{oldTxHash.Hex(), newTxHash.Hex()})
+	require.NoError(t, err)
+	require.Equal(t, oldTxHash, receipts[0].TxHash)
+	require.Nil(t, receipts[1])
+	require.Equal(t, 1, pool.endpoints[1].failures)
+}
+
+func TestPoolQuorum(t *testing.T) {
+	canonical := testHeader(10, "0x1")
+	forked := testHeader(10, "0x2")
+	honest1 := newFakeL2Node(t, blockHandler(t, map[string]*eth.Header{"latest": canonical, "0xa": canonical}))
+	honest2 := newFakeL2Node(t, blockHandler(t, map[string]*eth.Header{"0xa": canonical}))
+	faulty := newFakeL2Node(t, blockHandler(t, map[string]*eth.Header{"latest": forked, "0xa": forked}))
+	pool := newTestPool(t, 2, faulty, honest1, honest2)
+
+	// the header returned by a quorum of endpoints is trusted
+	res, err := pool.HeaderByNumber(context.Background(), big.NewInt(10))
+	require.NoError(t, err)
+	require.Equal(t, canonical.Hash(), res.Hash())
+
+	// labels are resolved to heights by all endpoints, the header is still checked by the quorum
+	res, err = pool.HeaderByNumber(context.Background(), big.NewInt(rpc.LatestBlockNumber.Int64()))
+	require.NoError(t, err)
+	require.Equal(t, canonical.Hash(), res.Hash())
+
+	// without a quorum, the header is not trusted
+	honest2.failing.Store(true)
+	_, err = pool.HeadersByNumbers(context.Background(), []*big.Int{big.NewInt(10)})
+	require.ErrorIs(t, err, ErrNoQuorum)
+	require.ErrorContains(t, err, "on block 0xa: at most 1 of 3 endpoints agree, 2 required")
+}
+
+func TestPoolQuorumResolvesLabelsToAgreedHeight(t *testing.T) {
+	header10 := testHeader(10, "0x1")
+	header11 := testHeader(11, "0x1")
+	header12 := testHeader(12, "0x1")
+	// the endpoints follow the tip at different paces
+	ahead := newFakeL2Node(t, blockHandler(t, map[string]*eth.Header{"latest": header12, "0xa": header10, "0xb": header11, "0xc": header12}))
+	synced := newFakeL2Node(t, blockHandler(t, map[string]*eth.Header{"latest": header11, "0xa": header10, "0xb": header11}))
+	behind := newFakeL2Node(t, blockHandler(t, map[string]*eth.Header{"latest": header10, "0xa": header10}))
+
+	// the latest block is the highest block reached by a quorum of endpoints
+	pool := newTestPool(t, 2, ahead, synced, behind)
+	res, err := pool.HeaderByNumber(context.Background(), big.NewInt(rpc.LatestBlockNumber.Int64()))
+	require.NoError(t, err)
+	require.Equal(t, header11.Hash(), res.Hash())
+
+	pool = newTestPool(t, 3, ahead, synced, behind)
+	res, err = pool.HeaderByNumber(context.Background(), big.NewInt(rpc.LatestBlockNumber.Int64()))
+	require.NoError(t, err)
+	require.Equal(t, header10.Hash(), res.Hash())
+
+	// endpoints failing to resolve the label are ignored
+	synced.failing.Store(true)
+	pool = newTestPool(t, 2, ahead, synced, behind)
+	res, err = pool.HeaderByNumber(context.Background(), big.NewInt(rpc.LatestBlockNumber.Int64()))
+	require.NoError(t, err)
+	require.Equal(t, header10.Hash(), res.Hash())
+}
+
+func TestNewEthL2ClientPool(t *testing.T) {
+	_, err := NewEthL2ClientPool(nil, 0, nil, zap.NewNop())
+	require.Error(t, err)
+	_, err = NewEthL2ClientPool([]string{"http://localhost:8545"}, 2, nil, zap.NewNop())
+	require.EqualError(t, err, "quorum 2 exceeds the number of L2 endpoints 1")
+
+	// endpoint names don't leak the API keys in the address path
+	pool, err := NewEthL2ClientPool([]string{"https://rpc.example.com/v2/key1", "https://rpc.example.com/v2/key2"}, 0, nil, zap.NewNop())
+	require.NoError(t, err)
+	defer pool.Close()
+	require.Equal(t, "https://rpc.example.com", pool.endpoints[0].name)
+	require.Equal(t, "https://rpc.example.com#1", pool.endpoints[1].name)
+}
+
+func newTestPool(t *testing.T, quorum int, nodes ...*fakeL2Node) *EthL2ClientPool {
+	addrs := make([]string, len(nodes))
+	for i, node := range nodes {
+		addrs[i] = node.url
+	}
+	pool, err := NewEthL2ClientPool(addrs, quorum, metrics.NewFinalityGadgetMetrics(), zap.NewNop())
+	require.NoError(t, err)
+	t.Cleanup(pool.Close)
+	return pool
+}
+
+// blockHandler answers eth_getBlockByNumber with the headers by block number argument, and null for other blocks
+func blockHandler(t *testing.T, headers map[string]*eth.Header) func(method string, params []json.RawMessage) interface{} {
+	return func(method string, params []json.RawMessage) interface{} {
+		require.Equal(t, "eth_getBlockByNumber", method)
+		var number string
+		require.NoError(t, json.Unmarshal(params[0], &number))
+		header, ok := headers[number]
+		if !ok {
+			return nil
+		}
+		return header
+	}
+}
+
+// receiptHandler answers eth_getTransactionReceipt with the receipts by tx hash, and null for other txs. The
+// queried tx hashes are recorded if queried is set.
+func receiptHandler(t *testing.T, receipts map[common.Hash]*eth.Receipt, queried *[]common.Hash) func(method string, params []json.RawMessage) interface{} {
+	return func(method string, params []json.RawMessage) interface{} {
+		require.Equal(t, "eth_getTransactionReceipt", method)
+		var txHash common.Hash
+		require.NoError(t, json.Unmarshal(params[0], &txHash))
+		if queried != nil {
+			*queried = append(*queried, txHash)
+		}
+		receipt, ok := receipts[txHash]
+		if !ok {
+			return nil
+		}
+		return receipt
+	}
+}
+
+func testHeader(height int64, parentHash string) *eth.Header {
+	return &eth.Header{Number: big.NewInt(height), ParentHash: common.HexToHash(parentHash), Difficulty: big.NewInt(0)}
+}
@@ -0,0 +1,80 @@
+package finalitygadget
+
+import (
+	"sync"
+
+	"github.com/babylonlabs-io/finality-gadget/types"
+)
+
+// eventBus fans out events to subscribers without ever blocking the publisher. A subscriber whose
+// buffer is full is dropped and its channel closed, so it must resubscribe and catch up from the
+// db. The zero value is ready to use.
+type eventBus struct {
+	subscribers map[uint64]chan *types.Event
+	nextId      uint64
+	closed      bool
+	mutex       sync.Mutex
+}
+
+func (b *eventBus) subscribe(bufferSize int) (<-chan *types.Event, func()) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	events := make(chan *types.Event, bufferSize)
+	if b.closed {
+		close(events)
+		return events, func() {}
+	}
+	if b.subscribers == nil {
+		b.subscribers = make(map[uint64]chan *types.Event)
+	}
+	id := b.nextId
+	b.nextId++
+	b.subscribers[id] = events
+
+	unsubscribe := func() {
+		b.mutex.Lock()
+		defer b.mutex.Unlock()
+		b.remove(id)
+	}
+	return events, unsubscribe
+}
+
+func (b *eventBus) publish(event *types.Event) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for id, events := range b.subscribers {
+		select {
+		case events <- event:
+		default:
+			// the subscriber fell behind, drop it rather than blocking block processing
+			b.remove(id)
+		}
+	}
+}
+
+func (b *eventBus) hasSubscribers() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return len(b.subscribers) > 0
+}
+
+// close closes all subscriptions and rejects new ones
+func (b *eventBus) close() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for id := range b.subscribers {
+		b.remove(id)
+	}
+	b.closed = true
+}
+
+// remove closes and removes a subscriber. The caller must hold the mutex.
+func (b *eventBus) remove(id uint64) {
+	if events, ok := b.subscribers[id]; ok {
+		close(events)
+		delete(b.subscribers, id)
+	}
+}
@@ -0,0 +1,129 @@
+package finalitygadget
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/babylonlabs-io/finality-gadget/testutil/mocks"
+	"github.com/babylonlabs-io/finality-gadget/types"
+	eth "github.com/ethereum/go-ethereum/core/types"
+	ethrpc "github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+)
+
+func TestEventBus(t *testing.T) {
+	var bus eventBus
+
+	events1, unsubscribe1 := bus.subscribe(2)
+	events2, unsubscribe2 := bus.subscribe(2)
+	defer unsubscribe2()
+
+	event := &types.Event{Type: types.EventTypeBlockFinalized, Block: &types.Block{BlockHeight: 1}}
+	bus.publish(event)
+	require.Equal(t, event, <-events1)
+	require.Equal(t, event, <-events2)
+
+	// unsubscribed subscribers no longer receive events
+	unsubscribe1()
+	_, ok := <-events1
+	require.False(t, ok)
+	bus.publish(event)
+	require.Equal(t, event, <-events2)
+
+	// unsubscribing twice is a no-op
+	unsubscribe1()
+}
+
+func TestEventBusDropsSlowSubscribers(t *testing.T) {
+	var bus eventBus
+
+	slowEvents, unsubscribeSlow := bus.subscribe(1)
+	defer unsubscribeSlow()
+	events, unsubscribe := bus.subscribe(3)
+	defer unsubscribe()
+
+	for height := uint64(1); height <= 2; height++ {
+		bus.publish(&types.Event{Type: types.EventTypeBlockFinalized, Block: &types.Block{BlockHeight: height}})
+	}
+
+	// the slow subscriber gets the buffered event, then its channel is closed
+	event, ok := <-slowEvents
+	require.True(t, ok)
+	require.Equal(t, uint64(1), event.Block.BlockHeight)
+	_, ok = <-slowEvents
+	require.False(t, ok)
+
+	// other subscribers are not affected
+	require.Equal(t, uint64(1), (<-events).Block.BlockHeight)
+	require.Equal(t, uint64(2), (<-events).Block.BlockHeight)
+}
+
+func TestEventBusClose(t *testing.T) {
+	var bus eventBus
+
+	events, unsubscribe := bus.subscribe(1)
+	bus.close()
+	_, ok := <-events
+	require.False(t, ok)
+	unsubscribe()
+
+	// subscribing after close returns a closed channel
+	events, _ = bus.subscribe(1)
+	_, ok = <-events
+	require.False(t, ok)
+}
+
+func TestPublishChainSyncStatus(t *testing.T) {
+	ctl := gomock.NewController(t)
+	mockDbHandler := mocks.NewMockIDatabaseHandler(ctl)
+	mockL2Client := mocks.NewMockIEthL2Client(ctl)
+
+	fg := &FinalityGadget{
+		db:       mockDbHandler,
+		l2Client: mockL2Client,
+		logger:   zap.NewNop(),
+	}
+
+	// nothing is queried without subscribers
+	fg.publishChainSyncStatus()
+
+	events, unsubscribe := fg.SubscribeEvents(10)
+	defer unsubscribe()
+
+	latestHeight := int64(10)
+	mockL2Client.EXPECT().HeaderByNumber(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, number *big.Int) (*eth.Header, error) {
+		if number.Int64() == ethrpc.FinalizedBlockNumber.Int64() {
+			return &eth.Header{Number: big.NewInt(5)}, nil
+		}
+		return &eth.Header{Number: big.NewInt(latestHeight)}, nil
+	}).Times(6)
+	mockDbHandler.EXPECT().QueryLatestFinalizedBlock().Return(&types.Block{BlockHeight: 8}, nil).Times(3)
+	mockDbHandler.EXPECT().QueryEarliestFinalizedBlock().Return(&types.Block{BlockHeight: 1}, nil).Times(3)
+
+	// the status is published the first time, then only once it changes
+	fg.publishChainSyncStatus()
+	fg.publishChainSyncStatus()
+	latestHeight = 11
+	fg.publishChainSyncStatus()
+
+	event := <-events
+	require.Equal(t, types.EventTypeChainSyncStatus, event.Type)
+	require.Equal(t, &types.ChainSyncStatus{
+		LatestBlockHeight:               10,
+		LatestBtcFinalizedBlockHeight:   8,
+		EarliestBtcFinalizedBlockHeight: 1,
+		LatestEthFinalizedBlockHeight:   5,
+	}, event.ChainSyncStatus)
+	event = <-events
+	require.Equal(t, uint64(11), event.ChainSyncStatus.LatestBlockHeight)
+	require.Empty(t, events)
+
+	// nothing is published before the first block is finalized
+	mockL2Client.EXPECT().HeaderByNumber(gomock.Any(), gomock.Any()).Return(&eth.Header{Number: big.NewInt(12)}, nil).Times(1)
+	mockDbHandler.EXPECT().QueryLatestFinalizedBlock().Return(nil, types.ErrBlockNotFound).Times(1)
+	fg.publishChainSyncStatus()
+	require.Empty(t, events)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"math/big"
 
+	"github.com/babylonlabs-io/finality-gadget/ethl2client"
 	"github.com/babylonlabs-io/finality-gadget/types"
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/wire"
@@ -18,21 +19,30 @@ type IBitcoinClient interface {
 	GetBlockTimestampByHeight(height uint64) (uint64, error)
 }
 
+// IBabylonClient queries Babylon. The queries taking a babylonHeight are made at that Babylon height, or at the
+// latest height if 0.
 type IBabylonClient interface {
-	QueryAllFpBtcPubKeys(consumerId string) ([]string, error)
-	QueryFpPower(fpPubkeyHex string, btcHeight uint64) (uint64, error)
-	QueryMultiFpPower(fpPubkeyHexList []string, btcHeight uint64) (map[string]uint64, error)
-	QueryEarliestActiveDelBtcHeight(fpPubkeyHexList []string) (uint64, error)
+	QueryLatestHeight() (int64, error)
+	QueryAllFpBtcPubKeys(consumerId string, babylonHeight int64) ([]string, error)
+	QueryFpPower(fpPubkeyHex string, btcHeight uint64, babylonHeight int64) (uint64, error)
+	QueryMultiFpPower(fpPubkeyHexList []string, btcHeight uint64, babylonHeight int64) (map[string]uint64, error)
+	QueryEarliestActiveDelBtcHeight(fpPubkeyHexList []string, babylonHeight int64) (uint64, error)
 }
 
+// ICosmWasmClient queries the finality gadget contract on Babylon, at the given Babylon height or at the latest
+// height if 0
 type ICosmWasmClient interface {
-	QueryListOfVotedFinalityProviders(queryParams *types.Block) ([]string, error)
-	QueryConsumerId() (string, error)
-	QueryIsEnabled() (bool, error)
+	QueryListOfVotedFinalityProviders(queryParams *types.Block, babylonHeight int64) ([]string, error)
+	QueryConsumerId(babylonHeight int64) (string, error)
+	QueryQuorumThreshold(babylonHeight int64) (*types.QuorumThreshold, error)
+	QueryIsEnabled(babylonHeight int64) (bool, error)
 }
 
 type IEthL2Client interface {
 	HeaderByNumber(ctx context.Context, number *big.Int) (*eth.Header, error)
 	TransactionReceipt(ctx context.Context, txHash string) (*eth.Receipt, error)
+	TransactionReceipts(ctx context.Context, txHashes []string) ([]*eth.Receipt, error)
+	HeadersByNumbers(ctx context.Context, numbers []*big.Int) ([]*eth.Header, error)
+	L1OriginByNumber(ctx context.Context, number *big.Int) (*ethl2client.L1Origin, error)
 	Close()
 }
@@ -7,56 +7,101 @@ import (
 	"fmt"
 	"math"
 	"math/big"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	bbnclient "github.com/babylonlabs-io/babylon/client/client"
 	bbncfg "github.com/babylonlabs-io/babylon/client/config"
+	bbnquery "github.com/babylonlabs-io/babylon/client/query"
 	fgbbnclient "github.com/babylonlabs-io/finality-gadget/bbnclient"
 	"github.com/babylonlabs-io/finality-gadget/btcclient"
+	"github.com/babylonlabs-io/finality-gadget/btcindex"
 	"github.com/babylonlabs-io/finality-gadget/config"
 	"github.com/babylonlabs-io/finality-gadget/cwclient"
 	"github.com/babylonlabs-io/finality-gadget/db"
 	"github.com/babylonlabs-io/finality-gadget/ethl2client"
+	"github.com/babylonlabs-io/finality-gadget/metrics"
 	"github.com/babylonlabs-io/finality-gadget/testutil/mocks"
 	"github.com/babylonlabs-io/finality-gadget/types"
 	"github.com/ethereum/go-ethereum/common"
+	eth "github.com/ethereum/go-ethereum/core/types"
 	ethrpc "github.com/ethereum/go-ethereum/rpc"
 	"go.uber.org/zap"
 )
 
 var _ IFinalityGadget = &FinalityGadget{}
 
+// MaxTransactionsStatusQuerySize is the maximum number of txs queried by QueryTransactionsStatus
+const MaxTransactionsStatusQuerySize = 100
+
 type FinalityGadget struct {
 	btcClient IBitcoinClient
+	btcIndex  *btcindex.BtcHeaderIndex
 	bbnClient IBabylonClient
 	cwClient  ICosmWasmClient
 	l2Client  IEthL2Client
 
-	db     db.IDatabaseHandler
-	logger *zap.Logger
-	mutex  sync.Mutex
-
-	pollInterval        time.Duration
+	db      db.IDatabaseHandler
+	metrics *metrics.FinalityGadgetMetrics
+	logger  *zap.Logger
+	// quorumThreshold overrides the quorum threshold set in the contract, nil if not set
+	quorumThreshold *types.QuorumThreshold
+	// lastChainSyncStatus is the last chain sync status published to subscribers
+	lastChainSyncStatus *types.ChainSyncStatus
+	// dbBackupPathPrefix is the path prefix of the db backups taken before migrating the db at startup, the
+	// db is not backed up if empty
+	dbBackupPathPrefix string
+	// processing tracks the transient errors retried by the startup and block processing loops
+	processing processingStatus
+	// createdAt is when the finality gadget was created, liveness is measured from it until the first heartbeat
+	createdAt time.Time
+	// events publishes finality gadget events to subscribers
+	events eventBus
+	// retention decides which finalized blocks are kept in the db, older blocks are pruned by PruneBlocks
+	retention types.RetentionPolicy
+	mutex     sync.Mutex
+	// lastHeartbeat is the unix nano time the startup or block processing loop last made progress
+	lastHeartbeat atomic.Int64
+
+	pollInterval time.Duration
+	// pruneInterval is the interval blocks are pruned at, defaultPruneInterval if 0
+	pruneInterval       time.Duration
 	lastProcessedHeight uint64
 	batchSize           uint64
+	// maxFinalityLag is the max number of L2 blocks the latest BTC finalized block can lag behind the L2 tip while
+	// ready, 0 to disable the check
+	maxFinalityLag uint64
+	// pinBabylonHeight pins the Babylon queries made to evaluate a block to the Babylon height at the start of the
+	// evaluation, instead of querying the latest state
+	pinBabylonHeight bool
+	// readOnly is set if the finality gadget only serves queries from a db shared with the finality gadget
+	// processing blocks
+	readOnly bool
 }
 
 //////////////////////////////
 // CONSTRUCTOR
 //////////////////////////////
 
-func NewFinalityGadget(cfg *config.Config, db db.IDatabaseHandler, logger *zap.Logger) (*FinalityGadget, error) {
-	// Create babylon client
+func NewFinalityGadget(cfg *config.Config, db db.IDatabaseHandler, metrics *metrics.FinalityGadgetMetrics, logger *zap.Logger) (*FinalityGadget, error) {
+	// Create babylon client, failing over between the Babylon nodes
 	bbnConfig := bbncfg.DefaultBabylonConfig()
-	bbnConfig.RPCAddr = cfg.BBNRPCAddress
+	bbnConfig.RPCAddr = cfg.BBNRPCEndpoints()[0]
 	bbnConfig.ChainID = cfg.BBNChainID
-	babylonClient, err := bbnclient.New(
-		&bbnConfig,
-		logger,
-	)
-	bbnClient := fgbbnclient.NewBabylonClient(babylonClient.QueryClient)
+	if err := bbnConfig.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid Babylon config: %w", err)
+	}
+	bbnRPCPool, err := fgbbnclient.NewRPCPool(cfg.BBNRPCEndpoints(), bbnConfig.Timeout, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Babylon client: %w", err)
+	}
+	queryClient, err := bbnquery.NewWithClient(bbnRPCPool, bbnConfig.Timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Babylon client: %w", err)
+	}
+	bbnClient, err := fgbbnclient.NewBabylonClient(queryClient, fgbbnclient.DefaultCacheConfig())
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Babylon client: %w", err)
 	}
@@ -72,21 +117,36 @@ func NewFinalityGadget(cfg *config.Config, db db.IDatabaseHandler, logger *zap.L
 		btcConfig.DisableTLS = true
 	}
 	var btcClient IBitcoinClient
+	var btcIndex *btcindex.BtcHeaderIndex
 	switch cfg.BitcoinRPCHost {
 	case "mock-btc-client":
-		btcClient, err = mocks.NewMockBitcoinClient(btcConfig, logger)
+		var mockBtcClient *mocks.MockBitcoinClient
+		mockBtcClient, err = mocks.NewMockBitcoinClient(btcConfig, logger)
+		btcClient = &instrumentedBtcClient{client: mockBtcClient, metrics: metrics}
+		if cfg.BtcTimestampMapping() != types.BtcTimestampMappingBlockTime {
+			logger.Warn("The mock BTC client only supports the block timestamp mapping")
+		}
 	default:
-		btcClient, err = btcclient.NewBitcoinClient(btcConfig, logger)
+		var btcRPCClient *btcclient.BitcoinClient
+		btcRPCClient, err = btcclient.NewBitcoinClient(btcConfig, logger)
+		if err == nil {
+			// serve BTC timestamp lookups from the local header index
+			instrumentedRPCClient := &instrumentedBtcClient{client: btcRPCClient, metrics: metrics}
+			btcIndex = btcindex.NewBtcHeaderIndex(instrumentedRPCClient, db, cfg.BtcTimestampMapping(), cfg.BitcoinIndexStartHeight, cfg.PollInterval, logger)
+			btcClient = btcIndex
+		}
 	}
 	if err != nil {
 		return nil, err
 	}
 
 	// Create cosmwasm client
-	cwClient := cwclient.NewCosmWasmClient(babylonClient.QueryClient.RPCClient, cfg.FGContractAddress)
+	// the voters decide whether a block is finalized, so they can be required to match between the Babylon nodes
+	cwClient := cwclient.NewCosmWasmClient(bbnRPCPool, cfg.FGContractAddress).
+		WithVotersQuorum(bbnRPCPool.Clients(), int(cfg.BBNVotersQuorum))
 
-	// Create L2 client
-	l2Client, err := ethl2client.NewEthL2Client(cfg.L2RPCHost)
+	// Create L2 client, failing over between the L2 nodes
+	l2Client, err := ethl2client.NewEthL2ClientPool(cfg.L2RPCEndpoints(), int(cfg.L2RPCQuorum), metrics, logger)
 	if err != nil {
 		return nil, err
 	}
@@ -98,18 +158,29 @@ func NewFinalityGadget(cfg *config.Config, db db.IDatabaseHandler, logger *zap.L
 	}
 	if latestBlock != nil {
 		lastProcessedHeight = latestBlock.BlockHeight
+		metrics.SetLatestBtcFinalizedBlock(latestBlock.BlockHeight, latestBlock.BlockTimestamp)
 	}
 
-	// Create finality gadget
+	// Create finality gadget, recording the latency and errors of all client calls
 	return &FinalityGadget{
 		btcClient:           btcClient,
-		bbnClient:           bbnClient,
-		cwClient:            cwClient,
-		l2Client:            l2Client,
+		btcIndex:            btcIndex,
+		bbnClient:           &instrumentedBabylonClient{client: bbnClient, metrics: metrics},
+		cwClient:            &instrumentedCosmWasmClient{client: cwClient, metrics: metrics},
+		l2Client:            &instrumentedL2Client{client: l2Client, metrics: metrics},
+		metrics:             metrics,
 		db:                  db,
 		pollInterval:        cfg.PollInterval,
 		batchSize:           cfg.BatchSize,
 		lastProcessedHeight: lastProcessedHeight,
+		quorumThreshold:     cfg.QuorumThreshold(),
+		maxFinalityLag:      cfg.MaxFinalityLag,
+		pinBabylonHeight:    cfg.BBNPinQueryHeight,
+		readOnly:            cfg.ReadOnly,
+		dbBackupPathPrefix:  cfg.DBFilePath,
+		retention:           cfg.RetentionPolicy(),
+		pruneInterval:       cfg.PruneInterval,
+		createdAt:           time.Now(),
 		logger:              logger,
 	}, nil
 }
@@ -133,51 +204,73 @@ func NewFinalityGadget(cfg *config.Config, db db.IDatabaseHandler, logger *zap.L
  *   - calculate total voting power
  *   - get all FPs that voted this L2 block with the same height and hash
  *   - calculate voted voting power
- *   - check if the voted voting power is more than 2/3 of the total voting power
+ *   - check if the voted voting power reaches the quorum threshold (2/3 of the total voting power by default)
+ *
+ * - if Babylon height pinning is enabled, all Babylon queries are made at the latest Babylon height when the
+ *   check starts, so the result doesn't depend on Babylon state changing during the check
  */
 func (fg *FinalityGadget) QueryIsBlockBabylonFinalizedFromBabylon(block *types.Block) (bool, error) {
+	babylonHeight, err := fg.queryBabylonHeight()
+	if err != nil {
+		return false, err
+	}
+	isFinalized, _, err := fg.queryBlockFinalityFromBabylon(block, babylonHeight)
+	return isFinalized, err
+}
+
+// queryBlockFinalityFromBabylon implements QueryIsBlockBabylonFinalizedFromBabylon at the given Babylon
+// height, or at the latest height if 0, and additionally returns the evidence of the quorum that
+// finalized the block. Evidence is nil if the block is not finalized, or if the finality gadget is
+// disabled.
+func (fg *FinalityGadget) queryBlockFinalityFromBabylon(block *types.Block, babylonHeight int64) (bool, *types.FinalityEvidence, error) {
 	if block == nil {
-		return false, fmt.Errorf("block is nil")
+		return false, nil, fmt.Errorf("block is nil")
 	}
 
 	// check if the finality gadget is enabled
 	// if not, always return true to pass through op derivation pipeline
-	isEnabled, err := fg.cwClient.QueryIsEnabled()
+	isEnabled, err := fg.cwClient.QueryIsEnabled(babylonHeight)
 	if err != nil {
-		return false, err
+		return false, nil, err
 	}
 	if !isEnabled {
-		return true, nil
+		return true, nil, nil
 	}
 
 	// trim prefix 0x for the L2 block hash
 	block.BlockHash = strings.TrimPrefix(block.BlockHash, "0x")
 
 	// get all FPs pubkey for the consumer chain
-	allFpPks, err := fg.queryAllFpBtcPubKeys()
+	allFpPks, err := fg.queryAllFpBtcPubKeys(babylonHeight)
 	if err != nil {
-		return false, err
+		return false, nil, err
 	}
 
 	// convert the L2 timestamp to BTC height
+	// with the median time past mapping, blocks newer than the BTC tip are not finalized until the BTC height
+	// they map to is final
 	btcblockHeight, err := fg.btcClient.GetBlockHeightByTimestamp(block.BlockTimestamp)
+	if errors.Is(err, types.ErrBtcHeightNotFinal) {
+		fg.logger.Debug("BTC height of block is not final yet", zap.Uint64("block_height", block.BlockHeight), zap.Error(err))
+		return false, nil, nil
+	}
 	if err != nil {
-		return false, err
+		return false, nil, err
 	}
 
 	// check whether the btc staking is actived
-	earliestDelHeight, err := fg.bbnClient.QueryEarliestActiveDelBtcHeight(allFpPks)
+	earliestDelHeight, err := fg.bbnClient.QueryEarliestActiveDelBtcHeight(allFpPks, babylonHeight)
 	if err != nil {
-		return false, err
+		return false, nil, err
 	}
 	if btcblockHeight < earliestDelHeight {
-		return false, types.ErrBtcStakingNotActivated
+		return false, nil, types.ErrBtcStakingNotActivated
 	}
 
 	// get all FPs voting power at this BTC height
-	allFpPower, err := fg.bbnClient.QueryMultiFpPower(allFpPks, btcblockHeight)
+	allFpPower, err := fg.bbnClient.QueryMultiFpPower(allFpPks, btcblockHeight, babylonHeight)
 	if err != nil {
-		return false, err
+		return false, nil, err
 	}
 
 	// calculate total voting power
@@ -188,37 +281,56 @@ func (fg *FinalityGadget) QueryIsBlockBabylonFinalizedFromBabylon(block *types.B
 
 	// no FP has voting power for the consumer chain
 	if totalPower == 0 {
-		return false, types.ErrNoFpHasVotingPower
+		return false, nil, types.ErrNoFpHasVotingPower
 	}
 
 	// get all FPs that voted this (L2 block height, L2 block hash) combination
-	votedFpPks, err := fg.cwClient.QueryListOfVotedFinalityProviders(block)
+	votedFpPks, err := fg.cwClient.QueryListOfVotedFinalityProviders(block, babylonHeight)
 	if err != nil {
-		return false, err
+		return false, nil, err
 	}
 	if votedFpPks == nil {
-		return false, nil
+		return false, nil, nil
 	}
 	// calculate voted voting power
 	var votedPower uint64 = 0
+	voters := make([]*types.VoterPower, 0, len(votedFpPks))
 	for _, key := range votedFpPks {
 		if power, exists := allFpPower[key]; exists {
 			votedPower += power
+			voters = append(voters, &types.VoterPower{FpBtcPkHex: key, Power: power})
 		}
 	}
 
-	// quorom < 2/3
-	if votedPower*3 < totalPower*2 {
-		return false, nil
-	}
-	return true, nil
+	// check the quorum threshold is reached
+	quorumThreshold, err := fg.queryQuorumThreshold(babylonHeight)
+	if err != nil {
+		return false, nil, err
+	}
+	if !quorumThreshold.IsMet(votedPower, totalPower) {
+		return false, nil, nil
+	}
+
+	sort.Slice(voters, func(i, j int) bool {
+		return voters[i].FpBtcPkHex < voters[j].FpBtcPkHex
+	})
+	return true, &types.FinalityEvidence{
+		BlockHeight:     block.BlockHeight,
+		BlockHash:       normalizeBlockHash(block.BlockHash),
+		BtcHeight:       btcblockHeight,
+		BabylonHeight:   uint64(babylonHeight),
+		TotalPower:      totalPower,
+		VotedPower:      votedPower,
+		Voters:          voters,
+		QuorumThreshold: quorumThreshold,
+	}, nil
 }
 
 // QueryIsBlockBabylonFinalized queries the finality status of a given block height from the internal db
 func (fg *FinalityGadget) QueryIsBlockBabylonFinalized(block *types.Block) (bool, error) {
 	// check if the finality gadget is enabled
 	// if not, always return true to pass through op derivation pipeline
-	isEnabled, err := fg.cwClient.QueryIsEnabled()
+	isEnabled, err := fg.cwClient.QueryIsEnabled(0)
 	if err != nil {
 		return false, err
 	}
@@ -227,7 +339,11 @@ func (fg *FinalityGadget) QueryIsBlockBabylonFinalized(block *types.Block) (bool
 	}
 
 	// convert the L2 timestamp to BTC height
+	// blocks whose BTC height is not final yet are never finalized
 	btcblockHeight, err := fg.btcClient.GetBlockHeightByTimestamp(block.BlockTimestamp)
+	if errors.Is(err, types.ErrBtcHeightNotFinal) {
+		return false, nil
+	}
 	if err != nil {
 		return false, err
 	}
@@ -251,6 +367,8 @@ func (fg *FinalityGadget) QueryIsBlockBabylonFinalized(block *types.Block) (bool
  *
  * - if no block in the range is finalized, return (nil, nil)
  * - else, return the height of the last found consecutive finalized block, return error if any
+ * - blocks pruned by the retention policy are still finalized, so ranges starting at pruned heights are
+ *   reported finalized as well
  *
  * Example: if give block range 1-10, and block 1-5 are finalized, and when querying block 6 we meet an error, then
  * return (5, error)
@@ -271,15 +389,12 @@ func (fg *FinalityGadget) QueryBlockRangeBabylonFinalized(
 		}
 	}
 
-	// query the earliest and latest finalized block from internal db
-	earliestFinalizedBlock, err := fg.db.QueryEarliestFinalizedBlock()
+	// query the earliest finalized height and latest finalized block from internal db. the earliest finalized
+	// height includes the blocks pruned by the retention policy
+	earliestFinalizedHeight, err := fg.db.QueryEarliestFinalizedHeight()
 	if err != nil {
 		return nil, err
 	}
-	if earliestFinalizedBlock == nil {
-		fg.logger.Error("No earliest finalized block found")
-		return nil, fmt.Errorf("no earliest finalized block found")
-	}
 	latestFinalizedBlock, err := fg.QueryLatestFinalizedBlock()
 	if err != nil {
 		return nil, err
@@ -294,7 +409,7 @@ func (fg *FinalityGadget) QueryBlockRangeBabylonFinalized(
 
 	// block range starts before earliest finalized block, or ends after latest finalized block,
 	// then no blocks are consecutively finalized
-	if queryBlocks[0].BlockHeight < earliestFinalizedBlock.BlockHeight ||
+	if queryBlocks[0].BlockHeight < earliestFinalizedHeight ||
 		queryBlocks[len(queryBlocks)-1].BlockHeight > latestFinalizedBlock.BlockHeight {
 		return nil, nil
 	}
@@ -326,6 +441,10 @@ func (fg *FinalityGadget) QueryBtcStakingActivatedTimestamp() (uint64, error) {
 	return timestamp, nil
 }
 
+func (fg *FinalityGadget) QueryBlockFinalityEvidence(height uint64) (*types.FinalityEvidence, error) {
+	return fg.db.GetFinalityEvidenceByHeight(height)
+}
+
 func (fg *FinalityGadget) GetBlockByHeight(height uint64) (*types.Block, error) {
 	return fg.db.GetBlockByHeight(height)
 }
@@ -352,46 +471,104 @@ func (fg *FinalityGadget) QueryTransactionStatus(txHash string) (*types.Transact
 		return nil, err
 	}
 
-	// get babylon finalized info
-	isBabylonFinalized, err := fg.QueryIsBlockFinalizedByHeight(txReceipt.BlockNumber.Uint64())
-	fg.logger.Debug("Babylon finalization status", zap.Bool("is_finalized", isBabylonFinalized))
+	// get safe and finalized blocks
+	safeBlock, err := fg.l2Client.HeaderByNumber(ctx, big.NewInt(ethrpc.SafeBlockNumber.Int64()))
 	if err != nil {
 		return nil, err
 	}
-
-	// get safe and finalized blocks
-	safeBlock, err := fg.l2Client.HeaderByNumber(ctx, big.NewInt(ethrpc.SafeBlockNumber.Int64()))
 	fg.logger.Debug("Safe block", zap.Uint64("block_number", safeBlock.Number.Uint64()))
+	finalizedBlock, err := fg.l2Client.HeaderByNumber(ctx, big.NewInt(ethrpc.FinalizedBlockNumber.Int64()))
 	if err != nil {
 		return nil, err
 	}
-	finalizedBlock, err := fg.l2Client.HeaderByNumber(ctx, big.NewInt(ethrpc.FinalizedBlockNumber.Int64()))
 	fg.logger.Debug("Finalized block", zap.Uint64("block_number", finalizedBlock.Number.Uint64()))
+
+	return fg.transactionInfo(txReceipt, header, safeBlock, finalizedBlock)
+}
+
+/* QueryTransactionsStatus returns the finality status of up to MaxTransactionsStatusQuerySize txs
+ *
+ * - invalid hashes get a result with an error and are not queried
+ * - the receipts of the other txs are fetched in a single batch request
+ * - the safe and finalized heads and the headers of the blocks of the mined txs are fetched in a
+ *   second batch request
+ * - txs without a receipt, i.e. not mined yet, get a not found result
+ * - txs whose receipt is in another block than the block fetched at its height, e.g. reorged or
+ *   not agreed on by the L2 endpoints, get a result with an error
+ */
+func (fg *FinalityGadget) QueryTransactionsStatus(txHashes []string) ([]*types.TransactionStatusResult, error) {
+	if len(txHashes) > MaxTransactionsStatusQuerySize {
+		return nil, fmt.Errorf("%w: got %d, max %d", types.ErrTooManyTransactions, len(txHashes), MaxTransactionsStatusQuerySize)
+	}
+
+	results := make([]*types.TransactionStatusResult, len(txHashes))
+	validTxHashes := make([]string, 0, len(txHashes))
+	validResults := make([]*types.TransactionStatusResult, 0, len(txHashes))
+	for i, txHash := range txHashes {
+		results[i] = &types.TransactionStatusResult{TxHash: txHash}
+		if err := validateEVMTxHash(txHash); err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		validTxHashes = append(validTxHashes, txHash)
+		validResults = append(validResults, results[i])
+	}
+	if len(validTxHashes) == 0 {
+		return results, nil
+	}
+
+	ctx := context.Background()
+	receipts, err := fg.l2Client.TransactionReceipts(ctx, validTxHashes)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("error fetching transaction receipts: %w", err)
 	}
 
-	var status types.FinalityStatus
-	if finalizedBlock.Number.Uint64() >= header.Number.Uint64() {
-		status = types.FinalityStatusFinalized
-	} else if isBabylonFinalized {
-		status = types.FinalityStatusBitcoinFinalized
-	} else if safeBlock.Number.Uint64() >= header.Number.Uint64() {
-		status = types.FinalityStatusSafe
-	} else {
-		status = types.FinalityStatusPending
+	// fetch the safe and finalized heads along with the blocks of the mined txs
+	numbers := []*big.Int{big.NewInt(ethrpc.SafeBlockNumber.Int64()), big.NewInt(ethrpc.FinalizedBlockNumber.Int64())}
+	headerIndexes := make(map[uint64]int)
+	for _, receipt := range receipts {
+		if receipt == nil {
+			continue
+		}
+		if _, ok := headerIndexes[receipt.BlockNumber.Uint64()]; !ok {
+			headerIndexes[receipt.BlockNumber.Uint64()] = len(numbers)
+			numbers = append(numbers, receipt.BlockNumber)
+		}
+	}
+	headers, err := fg.l2Client.HeadersByNumbers(ctx, numbers)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching block headers: %w", err)
 	}
+	safeBlock, finalizedBlock := headers[0], headers[1]
 
-	fg.logger.Debug("Transaction status", zap.String("block_hash", header.Hash().Hex()), zap.Uint64("block_height", header.Number.Uint64()), zap.String("tx_hash", txHash), zap.String("status", string(status)))
+	for i, receipt := range receipts {
+		if receipt == nil {
+			continue
+		}
+		header := headers[headerIndexes[receipt.BlockNumber.Uint64()]]
+		// the receipt may come from another endpoint than the header, with quorum reads a single endpoint could
+		// return the receipt of a tx in a block that isn't canonical, or the block of the tx may have been reorged
+		if receipt.BlockHash != header.Hash() {
+			fg.logger.Warn("Transaction receipt is not in the canonical block",
+				zap.String("tx_hash", receipt.TxHash.Hex()),
+				zap.String("receipt_block_hash", receipt.BlockHash.Hex()),
+				zap.String("block_hash", header.Hash().Hex()),
+				zap.Uint64("block_height", header.Number.Uint64()),
+			)
+			validResults[i].Error = fmt.Sprintf("transaction receipt block %s does not match block %s at height %d",
+				receipt.BlockHash.Hex(), header.Hash().Hex(), header.Number.Uint64())
+			continue
+		}
+		txInfo, err := fg.transactionInfo(receipt, header, safeBlock, finalizedBlock)
+		if err != nil {
+			return nil, err
+		}
+		validResults[i].Found = true
+		validResults[i].Transaction = txInfo
+	}
 
-	return &types.TransactionInfo{
-		TxHash:           txReceipt.TxHash.Hex(),
-		BlockHeight:      header.Number.Uint64(),
-		BlockHash:        hex.EncodeToString(header.Hash().Bytes()),
-		BlockTimestamp:   header.Time,
-		Status:           status,
-		BabylonFinalized: isBabylonFinalized || status == types.FinalityStatusFinalized,
-	}, nil
+	fg.logger.Debug("Transactions status", zap.Int("count", len(txHashes)), zap.Int("found", len(headerIndexes)))
+	return results, nil
 }
 
 func (fg *FinalityGadget) QueryChainSyncStatus() (*types.ChainSyncStatus, error) {
@@ -450,91 +627,24 @@ func (fg *FinalityGadget) QueryLatestFinalizedBlock() (*types.Block, error) {
 //  4. Enable FG on CW contract (for network with multiple nodes, enable after majority of nodes upgrade)
 func (fg *FinalityGadget) Startup(ctx context.Context) error {
 	fg.logger.Info("Starting up finality gadget...")
-	// Start polling for new blocks at set interval
-	ticker := time.NewTicker(fg.pollInterval)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ctx.Done():
-			return nil
-		case <-ticker.C:
-			// query rpc for latest eth finalized block
-			// at this point, FG is disabled so the derivation pipeline passes through
-			latestFinalizedBlock, err := fg.l2Client.HeaderByNumber(ctx, big.NewInt(ethrpc.FinalizedBlockNumber.Int64()))
-			if err != nil {
-				return fmt.Errorf("error fetching latest finalized L2 block: %w", err)
-			}
-			latestFinalizedHeight := latestFinalizedBlock.Number.Uint64()
-			latestFinalizedBlockTime := latestFinalizedBlock.Time
 
-			// get the BTC staking activation timestamp
-			btcStakingActivatedTimestamp, err := fg.QueryBtcStakingActivatedTimestamp()
-			if err != nil {
-				if errors.Is(err, types.ErrBtcStakingNotActivated) {
-					fg.logger.Info("BTC staking not yet activated, waiting...")
-					continue
-				}
-				return fmt.Errorf("error querying BTC staking activation timestamp: %w", err)
-			}
-
-			// throw error if btc staking activated before the first block was finalized (see startup order above)
-			if latestFinalizedHeight == 0 && btcStakingActivatedTimestamp < latestFinalizedBlockTime {
-				return fmt.Errorf("BTC staking activated before the first finalized block")
-			}
-
-			// skip blocks before btc staking is activated
-			if latestFinalizedBlockTime < btcStakingActivatedTimestamp {
-				fg.logger.Info("Skipping block before BTC staking activation", zap.Uint64("block_height", latestFinalizedHeight))
-				continue
-			}
-
-			// otherwise, startup the FG at latest finalized block (taking the later of the db and rpc values)
-			latestFinalizedBlockDb, err := fg.QueryLatestFinalizedBlock()
-			if err != nil {
-				return fmt.Errorf("error fetching latest finalized block from db: %w", err)
-			}
-			fg.lastProcessedHeight = latestFinalizedHeight - 1
-			if latestFinalizedBlockDb != nil && latestFinalizedBlockDb.BlockHeight > latestFinalizedHeight {
-				fg.lastProcessedHeight = latestFinalizedBlockDb.BlockHeight
-			}
-			fg.logger.Info("Starting finality gadget from block", zap.Uint64("block_height", fg.lastProcessedHeight+1))
-
-			return nil
-		}
+	// upgrade blocks stored by older versions before processing new blocks, retrying transient errors
+	if err := fg.migrateDb(ctx); err != nil {
+		return fmt.Errorf("error migrating db: %w", err)
 	}
+
+	// Start polling for new blocks at set interval, retrying transient errors
+	return fg.runWithRetry(ctx, "startup", fg.tryStartup)
 }
 
 // This function process blocks indefinitely, starting from the last finalized block.
+// Transient upstream errors are retried with backoff, while queries keep being served from the db.
 func (fg *FinalityGadget) ProcessBlocks(ctx context.Context) error {
 	fg.logger.Info("Processing blocks...")
-	// Start polling for new blocks at set interval
-	ticker := time.NewTicker(fg.pollInterval)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ctx.Done():
-			fg.logger.Debug("Exiting block processing loop...")
-			return nil
-		case <-ticker.C:
-			fg.logger.Debug("Processing new blocks...")
-			// get latest block
-			latestBlock, err := fg.l2Client.HeaderByNumber(ctx, big.NewInt(ethrpc.LatestBlockNumber.Int64()))
-			if err != nil {
-				return fmt.Errorf("error fetching latest L2 block: %w", err)
-			}
-			fg.logger.Debug("Received latest block", zap.Uint64("block_height", latestBlock.Number.Uint64()))
-
-			// if the last processed block is less than the latest block, process all intervening blocks
-			if fg.lastProcessedHeight < latestBlock.Number.Uint64() {
-				fg.logger.Info("Processing new blocks", zap.Uint64("start_height", fg.lastProcessedHeight+1), zap.Uint64("end_height", latestBlock.Number.Uint64()))
-				if err := fg.processBlocksTillHeight(ctx, latestBlock.Number.Uint64()); err != nil {
-					return fmt.Errorf("error processing block %d: %w", latestBlock.Number.Uint64(), err)
-				}
-			}
-		}
-	}
+	// Start polling for new blocks at set interval, retrying transient errors
+	return fg.runWithRetry(ctx, "block processing", func(ctx context.Context) (bool, error) {
+		return false, fg.processNewBlocks(ctx)
+	})
 }
 
 func (fg *FinalityGadget) insertBlocks(blocks []*types.Block) error {
@@ -542,63 +652,246 @@ func (fg *FinalityGadget) insertBlocks(blocks []*types.Block) error {
 	fg.mutex.Lock()
 	defer fg.mutex.Unlock()
 
-	// Normalize block hashes
-	normalizedBlocks := make([]*types.Block, len(blocks))
-	for i, block := range blocks {
-		normalizedBlocks[i] = &types.Block{
-			BlockHeight:    block.BlockHeight,
-			BlockHash:      normalizeBlockHash(block.BlockHash),
-			BlockTimestamp: block.BlockTimestamp,
-		}
+	normalizedBlocks, err := fg.storeBlocks(blocks)
+	if err != nil {
+		return err
 	}
 
-	// Store blocks in DB
-	if err := fg.db.InsertBlocks(normalizedBlocks); err != nil {
-		return fmt.Errorf("failed to batch insert blocks: %w", err)
+	// Notify subscribers once the blocks are committed
+	for _, block := range normalizedBlocks {
+		fg.events.publish(&types.Event{Type: types.EventTypeBlockFinalized, Block: block})
 	}
 
 	return nil
 }
 
+func (fg *FinalityGadget) SubscribeEvents(bufferSize int) (<-chan *types.Event, func()) {
+	return fg.events.subscribe(bufferSize)
+}
+
+// Close closes the event subscriptions and the L2 client. The db is not closed, as it is owned by the caller.
 func (fg *FinalityGadget) Close() {
+	fg.events.close()
 	fg.l2Client.Close()
-
-	if err := fg.db.Close(); err != nil {
-		fg.logger.Error("Error closing database", zap.Error(err))
-	}
-
 }
 
 //////////////////////////////
 // INTERNAL
 //////////////////////////////
 
-func (fg *FinalityGadget) queryAllFpBtcPubKeys() ([]string, error) {
+// storeBlocks normalizes the block hashes and stores the blocks in the db, without notifying subscribers.
+// The caller must hold the mutex.
+func (fg *FinalityGadget) storeBlocks(blocks []*types.Block) ([]*types.Block, error) {
+	normalizedBlocks := make([]*types.Block, len(blocks))
+	for i, block := range blocks {
+		normalizedBlocks[i] = normalizedBlock(block)
+	}
+	if err := fg.db.InsertBlocks(normalizedBlocks); err != nil {
+		return nil, fmt.Errorf("failed to batch insert blocks: %w", err)
+	}
+	return normalizedBlocks, nil
+}
+
+// publishChainSyncStatus publishes the chain sync status to subscribers if it changed since it was
+// last published. It is skipped if there are no subscribers, as it queries the L2 node.
+func (fg *FinalityGadget) publishChainSyncStatus() {
+	if !fg.events.hasSubscribers() {
+		return
+	}
+	status, err := fg.QueryChainSyncStatus()
+	if err != nil {
+		// no finalized block is stored yet
+		if errors.Is(err, types.ErrBlockNotFound) {
+			return
+		}
+		fg.logger.Warn("Failed to query chain sync status", zap.Error(err))
+		return
+	}
+	if fg.lastChainSyncStatus != nil && *fg.lastChainSyncStatus == *status {
+		return
+	}
+	fg.lastChainSyncStatus = status
+	fg.events.publish(&types.Event{Type: types.EventTypeChainSyncStatus, ChainSyncStatus: status})
+}
+
+// transactionInfo returns the finality status of a tx given its receipt, the header of its block and
+// the safe and finalized L2 heads
+func (fg *FinalityGadget) transactionInfo(txReceipt *eth.Receipt, header *eth.Header, safeBlock *eth.Header, finalizedBlock *eth.Header) (*types.TransactionInfo, error) {
+	// get babylon finalized info
+	isBabylonFinalized, err := fg.QueryIsBlockFinalizedByHeight(header.Number.Uint64())
+	if err != nil {
+		return nil, err
+	}
+	fg.logger.Debug("Babylon finalization status", zap.Bool("is_finalized", isBabylonFinalized))
+
+	var status types.FinalityStatus
+	if finalizedBlock.Number.Uint64() >= header.Number.Uint64() {
+		status = types.FinalityStatusFinalized
+	} else if isBabylonFinalized {
+		status = types.FinalityStatusBitcoinFinalized
+	} else if safeBlock.Number.Uint64() >= header.Number.Uint64() {
+		status = types.FinalityStatusSafe
+	} else {
+		status = types.FinalityStatusPending
+	}
+
+	fg.logger.Debug("Transaction status", zap.String("block_hash", header.Hash().Hex()), zap.Uint64("block_height", header.Number.Uint64()), zap.String("tx_hash", txReceipt.TxHash.Hex()), zap.String("status", string(status)))
+
+	return &types.TransactionInfo{
+		TxHash:           txReceipt.TxHash.Hex(),
+		BlockHeight:      header.Number.Uint64(),
+		BlockHash:        hex.EncodeToString(header.Hash().Bytes()),
+		BlockTimestamp:   header.Time,
+		Status:           status,
+		BabylonFinalized: isBabylonFinalized || status == types.FinalityStatusFinalized,
+	}, nil
+}
+
+func (fg *FinalityGadget) queryAllFpBtcPubKeys(babylonHeight int64) ([]string, error) {
 	// get the consumer chain id
-	consumerId, err := fg.cwClient.QueryConsumerId()
+	consumerId, err := fg.cwClient.QueryConsumerId(babylonHeight)
 	if err != nil {
 		return nil, err
 	}
 
 	// get all the FPs pubkey for the consumer chain
-	allFpPks, err := fg.bbnClient.QueryAllFpBtcPubKeys(consumerId)
+	allFpPks, err := fg.bbnClient.QueryAllFpBtcPubKeys(consumerId, babylonHeight)
 	if err != nil {
 		return nil, err
 	}
 	return allFpPks, nil
 }
 
+// queryQuorumThreshold returns the quorum threshold to finalize blocks with. The local config
+// override takes precedence over the contract config, which takes precedence over the default 2/3.
+func (fg *FinalityGadget) queryQuorumThreshold(babylonHeight int64) (types.QuorumThreshold, error) {
+	if fg.quorumThreshold != nil {
+		return *fg.quorumThreshold, nil
+	}
+	threshold, err := fg.cwClient.QueryQuorumThreshold(babylonHeight)
+	if err != nil {
+		return types.QuorumThreshold{}, err
+	}
+	if threshold == nil {
+		return types.DefaultQuorumThreshold, nil
+	}
+	return *threshold, nil
+}
+
+// queryBabylonHeight returns the Babylon height to evaluate blocks at: the latest Babylon height if
+// height pinning is enabled, or 0 to query the latest state
+func (fg *FinalityGadget) queryBabylonHeight() (int64, error) {
+	if !fg.pinBabylonHeight {
+		return 0, nil
+	}
+	height, err := fg.bbnClient.QueryLatestHeight()
+	if err != nil {
+		return 0, fmt.Errorf("failed to query the latest Babylon height: %w", err)
+	}
+	return height, nil
+}
+
 // Get block by number
 func (fg *FinalityGadget) queryBlockByHeight(blockNumber int64) (*types.Block, error) {
 	header, err := fg.l2Client.HeaderByNumber(context.Background(), big.NewInt(blockNumber))
 	if err != nil {
 		return nil, err
 	}
-	return &types.Block{
+	block := &types.Block{
 		BlockHeight:    header.Number.Uint64(),
 		BlockHash:      hex.EncodeToString(header.Hash().Bytes()),
 		BlockTimestamp: header.Time,
-	}, nil
+		ParentHash:     hex.EncodeToString(header.ParentHash.Bytes()),
+		StateRoot:      hex.EncodeToString(header.Root.Bytes()),
+	}
+
+	// the L1 origin is only available on OP stack chains, so we store the block without it
+	// if the first tx of the block is not an L1 attributes deposit tx
+	l1Origin, err := fg.l2Client.L1OriginByNumber(context.Background(), big.NewInt(blockNumber))
+	if err != nil {
+		if !errors.Is(err, ethl2client.ErrUnknownL1InfoFormat) {
+			return nil, fmt.Errorf("error fetching L1 origin of block %d: %w", blockNumber, err)
+		}
+		fg.logger.Warn("Unable to decode L1 origin of block", zap.Int64("block_height", blockNumber), zap.Error(err))
+		return block, nil
+	}
+	block.L1OriginNumber = l1Origin.Number
+	block.L1OriginHash = l1Origin.Hash.Hex()
+	return block, nil
+}
+
+// tryStartup starts the FG at the latest finalized block once BTC staking is activated, and returns
+// whether it started
+func (fg *FinalityGadget) tryStartup(ctx context.Context) (bool, error) {
+	// query rpc for latest eth finalized block
+	// at this point, FG is disabled so the derivation pipeline passes through
+	latestFinalizedBlock, err := fg.l2Client.HeaderByNumber(ctx, big.NewInt(ethrpc.FinalizedBlockNumber.Int64()))
+	if err != nil {
+		return false, fmt.Errorf("error fetching latest finalized L2 block: %w", err)
+	}
+	latestFinalizedHeight := latestFinalizedBlock.Number.Uint64()
+	latestFinalizedBlockTime := latestFinalizedBlock.Time
+
+	// get the BTC staking activation timestamp
+	btcStakingActivatedTimestamp, err := fg.QueryBtcStakingActivatedTimestamp()
+	if err != nil {
+		if errors.Is(err, types.ErrBtcStakingNotActivated) {
+			fg.logger.Info("BTC staking not yet activated, waiting...")
+			return false, nil
+		}
+		return false, fmt.Errorf("error querying BTC staking activation timestamp: %w", err)
+	}
+
+	// throw error if btc staking activated before the first block was finalized (see startup order above)
+	if latestFinalizedHeight == 0 && btcStakingActivatedTimestamp < latestFinalizedBlockTime {
+		return false, fmt.Errorf("BTC staking activated before the first finalized block")
+	}
+
+	// skip blocks before btc staking is activated
+	if latestFinalizedBlockTime < btcStakingActivatedTimestamp {
+		fg.logger.Info("Skipping block before BTC staking activation", zap.Uint64("block_height", latestFinalizedHeight))
+		return false, nil
+	}
+
+	// otherwise, startup the FG at latest finalized block (taking the later of the db and rpc values)
+	latestFinalizedBlockDb, err := fg.QueryLatestFinalizedBlock()
+	if err != nil {
+		return false, fmt.Errorf("error fetching latest finalized block from db: %w", err)
+	}
+	fg.lastProcessedHeight = latestFinalizedHeight - 1
+	if latestFinalizedBlockDb != nil && latestFinalizedBlockDb.BlockHeight > latestFinalizedHeight {
+		fg.lastProcessedHeight = latestFinalizedBlockDb.BlockHeight
+	}
+	fg.logger.Info("Starting finality gadget from block", zap.Uint64("block_height", fg.lastProcessedHeight+1))
+	return true, nil
+}
+
+// processNewBlocks rolls back reorged blocks, then processes the blocks up to the latest L2 block
+func (fg *FinalityGadget) processNewBlocks(ctx context.Context) error {
+	fg.logger.Debug("Processing new blocks...")
+	// roll back stored blocks that are no longer part of the L2 chain
+	if _, err := fg.detectAndHandleReorg(ctx); err != nil {
+		return fmt.Errorf("error checking for L2 reorg: %w", err)
+	}
+
+	// get latest block
+	latestBlock, err := fg.l2Client.HeaderByNumber(ctx, big.NewInt(ethrpc.LatestBlockNumber.Int64()))
+	if err != nil {
+		return fmt.Errorf("error fetching latest L2 block: %w", err)
+	}
+	fg.logger.Debug("Received latest block", zap.Uint64("block_height", latestBlock.Number.Uint64()))
+	fg.metrics.SetLatestL2Block(latestBlock.Number.Uint64(), latestBlock.Time)
+
+	// if the last processed block is less than the latest block, process all intervening blocks
+	if fg.lastProcessedHeight < latestBlock.Number.Uint64() {
+		fg.logger.Info("Processing new blocks", zap.Uint64("start_height", fg.lastProcessedHeight+1), zap.Uint64("end_height", latestBlock.Number.Uint64()))
+		if err := fg.processBlocksTillHeight(ctx, latestBlock.Number.Uint64()); err != nil {
+			return fmt.Errorf("error processing block %d: %w", latestBlock.Number.Uint64(), err)
+		}
+	}
+
+	fg.publishChainSyncStatus()
+	return nil
 }
 
 // Process blocks in batches of size `fg.batchSize` until the latest height
@@ -616,9 +909,17 @@ func (fg *FinalityGadget) processBlocksTillHeight(ctx context.Context, latestHei
 				batchEndHeight = latestHeight
 			}
 			fg.logger.Info("Processing batch of blocks", zap.Uint64("batch_start_height", batchStartHeight), zap.Uint64("batch_end_height", batchEndHeight))
+			batchStartTime := time.Now()
+
+			// Evaluate the whole batch at the same Babylon height, so that the Babylon query caches are shared
+			babylonHeight, err := fg.queryBabylonHeight()
+			if err != nil {
+				return err
+			}
 
 			// Create batch of blocks to check in parallel
 			results := make(chan *types.Block, batchEndHeight-batchStartHeight+1)
+			evidence := make(chan *types.FinalityEvidence, batchEndHeight-batchStartHeight+1)
 			errors := make(chan error, batchEndHeight-batchStartHeight+1)
 			var wg sync.WaitGroup
 
@@ -627,11 +928,12 @@ func (fg *FinalityGadget) processBlocksTillHeight(ctx context.Context, latestHei
 				wg.Add(1)
 				go func(h uint64) {
 					defer wg.Done()
-					block, err := fg.processHeight(h)
+					block, blockEvidence, err := fg.processHeight(h, babylonHeight)
 					if block != nil && err == nil {
 						fg.logger.Debug("Processed block", zap.Uint64("block_height", h), zap.String("block_hash", block.BlockHash), zap.Uint64("batch_start_height", batchStartHeight), zap.Uint64("batch_end_height", batchEndHeight))
 					}
 					results <- block
+					evidence <- blockEvidence
 					errors <- err
 				}(height)
 			}
@@ -641,6 +943,7 @@ func (fg *FinalityGadget) processBlocksTillHeight(ctx context.Context, latestHei
 				wg.Wait()
 				fg.logger.Debug("Closing channels for batch", zap.Uint64("batch_start_height", batchStartHeight), zap.Uint64("batch_end_height", batchEndHeight))
 				close(results)
+				close(evidence)
 				close(errors)
 			}()
 
@@ -650,6 +953,8 @@ func (fg *FinalityGadget) processBlocksTillHeight(ctx context.Context, latestHei
 					return err
 				}
 			}
+			fg.metrics.ObserveBatchProcessingDuration(time.Since(batchStartTime))
+			fg.heartbeat()
 
 			// Extract blocks and find last consecutive finalized block.
 			// As channels are async, blocks will NOT be ordered by height
@@ -661,8 +966,15 @@ func (fg *FinalityGadget) processBlocksTillHeight(ctx context.Context, latestHei
 					sortedBlocks[block.BlockHeight] = block
 				}
 			}
+			sortedEvidence := make(map[uint64]*types.FinalityEvidence)
+			for e := range evidence {
+				if e != nil {
+					sortedEvidence[e.BlockHeight] = e
+				}
+			}
 
 			var finalizedBlocks []*types.Block
+			var finalizedEvidence []*types.FinalityEvidence
 			var lastFinalizedHeight uint64
 			for i := batchStartHeight; i <= batchEndHeight; i++ {
 				if block, ok := sortedBlocks[i]; ok {
@@ -670,6 +982,9 @@ func (fg *FinalityGadget) processBlocksTillHeight(ctx context.Context, latestHei
 						finalizedBlocks = append(finalizedBlocks, block)
 						lastFinalizedHeight = block.BlockHeight
 					}
+					if e, ok := sortedEvidence[i]; ok {
+						finalizedEvidence = append(finalizedEvidence, e)
+					}
 				} else {
 					break
 				}
@@ -682,12 +997,30 @@ func (fg *FinalityGadget) processBlocksTillHeight(ctx context.Context, latestHei
 				return nil
 			}
 
+			// Make sure the batch extends the stored chain. If not, the L2 chain has reorged since
+			// the blocks were stored, so we roll back and retry on the next poll
+			if err := fg.verifyChainContinuity(finalizedBlocks); err != nil {
+				fg.logger.Warn("Finalized blocks do not extend the stored chain", zap.Error(err))
+				if _, err := fg.detectAndHandleReorg(ctx); err != nil {
+					return fmt.Errorf("error checking for L2 reorg: %w", err)
+				}
+				return nil
+			}
+
+			// Store the finality evidence before the blocks, so every stored block has its evidence.
+			// Evidence left behind by a failed block insert is overwritten once the block is re-processed.
+			if err := fg.db.InsertFinalityEvidence(finalizedEvidence); err != nil {
+				return fmt.Errorf("error storing finality evidence: %w", err)
+			}
+
 			// Batch insert all consecutive finalized blocks
 			fg.logger.Debug("Inserting finalized blocks", zap.Uint64("start_height", finalizedBlocks[0].BlockHeight), zap.Uint64("end_height", finalizedBlocks[len(finalizedBlocks)-1].BlockHeight))
 			if err := fg.insertBlocks(finalizedBlocks); err != nil {
 				return fmt.Errorf("error storing blocks: %w", err)
 			}
 			fg.lastProcessedHeight = lastFinalizedHeight
+			lastFinalizedBlock := finalizedBlocks[len(finalizedBlocks)-1]
+			fg.metrics.SetLatestBtcFinalizedBlock(lastFinalizedBlock.BlockHeight, lastFinalizedBlock.BlockTimestamp)
 
 			// Update start height for next batch
 			batchStartHeight = lastFinalizedHeight + 1
@@ -697,47 +1030,49 @@ func (fg *FinalityGadget) processBlocksTillHeight(ctx context.Context, latestHei
 	return nil
 }
 
-func (fg *FinalityGadget) processHeight(height uint64) (*types.Block, error) {
+// processHeight returns the block at the given height and the evidence of its finality at the given
+// Babylon height if the block is finalized, or (nil, nil, nil) if it is not
+func (fg *FinalityGadget) processHeight(height uint64, babylonHeight int64) (*types.Block, *types.FinalityEvidence, error) {
 	fg.logger.Debug("Processing block", zap.Uint64("block_height", height))
 	// Fetch block from rpc
 	if height > math.MaxInt64 {
 		fg.logger.Debug("Block height exceeds maximum int64 value", zap.Uint64("block_height", height))
-		return nil, fmt.Errorf("block height %d exceeds maximum int64 value", height)
+		return nil, nil, fmt.Errorf("block height %d exceeds maximum int64 value", height)
 	}
 	block, err := fg.queryBlockByHeight(int64(height))
 	if err != nil || block == nil {
 		fg.logger.Error("Error fetching block", zap.Uint64("block_height", height), zap.Error(err))
-		return nil, fmt.Errorf("error getting block at height %d: %w", height, err)
+		return nil, nil, fmt.Errorf("error getting block at height %d: %w", height, err)
 	}
 	fg.logger.Debug("Fetched block", zap.Uint64("block_height", height), zap.String("block_hash", block.BlockHash))
 
 	// Check finalization
-	isFinalized, err := fg.QueryIsBlockBabylonFinalizedFromBabylon(block)
+	isFinalized, evidence, err := fg.queryBlockFinalityFromBabylon(block, babylonHeight)
 	if err != nil {
 		fg.logger.Error("Error checking if block is finalized from babylon", zap.Uint64("block_height", height), zap.Error(err))
-		return nil, fmt.Errorf("error checking is block %d finalized from babylon: %w", height, err)
+		return nil, nil, fmt.Errorf("error checking is block %d finalized from babylon: %w", height, err)
 	}
-	fg.logger.Debug("Fetched block finality status", zap.Uint64("block_height", height), zap.Bool("is_finalized", isFinalized))
+	fg.logger.Debug("Fetched block finality status", zap.Uint64("block_height", height), zap.Int64("babylon_height", babylonHeight), zap.Bool("is_finalized", isFinalized))
 
 	if !isFinalized {
 		fg.logger.Debug("Block not finalized", zap.Uint64("block_height", height))
-		return nil, nil
+		return nil, nil, nil
 	}
 
 	fg.logger.Debug("Block finalized", zap.Uint64("block_height", height))
-	return block, nil
+	return block, evidence, nil
 }
 
 // Query the BTC staking activation timestamp from bbnClient
 // returns math.MaxUint64, ErrBtcStakingNotActivated if the BTC staking is not activated
 func (fg *FinalityGadget) queryBtcStakingActivationTimestamp() (uint64, error) {
-	allFpPks, err := fg.queryAllFpBtcPubKeys()
+	allFpPks, err := fg.queryAllFpBtcPubKeys(0)
 	if err != nil {
 		return math.MaxUint64, err
 	}
 	fg.logger.Debug("All consumer FP public keys", zap.Strings("allFpPks", allFpPks))
 
-	earliestDelHeight, err := fg.bbnClient.QueryEarliestActiveDelBtcHeight(allFpPks)
+	earliestDelHeight, err := fg.bbnClient.QueryEarliestActiveDelBtcHeight(allFpPks, 0)
 	if err != nil {
 		return math.MaxUint64, err
 	}
@@ -782,19 +1117,49 @@ func (fg *FinalityGadget) MonitorBtcStakingActivation(ctx context.Context) {
 				continue
 			}
 			fg.logger.Debug("Saved BTC staking activated timestamp to database", zap.Uint64("timestamp", timestamp))
+			fg.events.publish(&types.Event{Type: types.EventTypeBtcStakingActivated, BtcStakingActivatedTimestamp: timestamp})
 			return
 		}
 	}
 }
 
+// MonitorBtcHeaders keeps the local BTC header index in sync with the BTC chain until the
+// context is cancelled. It returns immediately if the BTC header index is not used.
+func (fg *FinalityGadget) MonitorBtcHeaders(ctx context.Context) {
+	if fg.btcIndex == nil {
+		return
+	}
+	fg.btcIndex.Run(ctx)
+}
+
 func normalizeBlockHash(hash string) string {
 	return common.HexToHash(hash).Hex()
 }
 
+// normalizedBlock returns a copy of the block with normalized hashes
+func normalizedBlock(block *types.Block) *types.Block {
+	normalized := &types.Block{
+		BlockHeight:    block.BlockHeight,
+		BlockHash:      normalizeBlockHash(block.BlockHash),
+		BlockTimestamp: block.BlockTimestamp,
+	}
+	if block.ParentHash != "" {
+		normalized.ParentHash = normalizeBlockHash(block.ParentHash)
+	}
+	if block.StateRoot != "" {
+		normalized.StateRoot = normalizeBlockHash(block.StateRoot)
+	}
+	if block.L1OriginHash != "" {
+		normalized.L1OriginHash = normalizeBlockHash(block.L1OriginHash)
+		normalized.L1OriginNumber = block.L1OriginNumber
+	}
+	return normalized
+}
+
 // validateEVMTxHash checks if the given string is a valid EVM transaction hash
 func validateEVMTxHash(txHash string) error {
 	if len(txHash) != 66 || txHash[:2] != "0x" {
-		return fmt.Errorf("invalid EVM transaction hash")
+		return types.ErrInvalidTxHash
 	}
 	return nil
 }
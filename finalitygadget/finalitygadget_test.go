@@ -4,14 +4,19 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"math/big"
 	"math/rand"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/babylonlabs-io/finality-gadget/ethl2client"
 	"github.com/babylonlabs-io/finality-gadget/testutil"
 	"github.com/babylonlabs-io/finality-gadget/testutil/mocks"
 	"github.com/babylonlabs-io/finality-gadget/types"
+	"github.com/ethereum/go-ethereum/common"
+	eth "github.com/ethereum/go-ethereum/core/types"
+	ethrpc "github.com/ethereum/go-ethereum/rpc"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
 	"go.uber.org/zap"
@@ -24,7 +29,7 @@ func TestFinalityGadgetDisabled(t *testing.T) {
 
 	// mock CwClient
 	mockCwClient := mocks.NewMockICosmWasmClient(ctl)
-	mockCwClient.EXPECT().QueryIsEnabled().Return(false, nil).Times(1)
+	mockCwClient.EXPECT().QueryIsEnabled(int64(0)).Return(false, nil).Times(1)
 
 	mockTestFinalityGadget := &FinalityGadget{
 		cwClient:  mockCwClient,
@@ -55,6 +60,8 @@ func TestQueryIsBlockBabylonFinalized(t *testing.T) {
 		name                    string
 		expectedErr             error
 		block                   *types.Block
+		contractQuorumThreshold *types.QuorumThreshold
+		localQuorumThreshold    *types.QuorumThreshold
 		allFpPks                []string
 		fpPowers                map[string]uint64
 		votedProviders          []string
@@ -121,6 +128,38 @@ func TestQueryIsBlockBabylonFinalized(t *testing.T) {
 			expectResult:            true,
 			expectedErr:             nil,
 		},
+		{
+			name:                    "2/3 votes below contract quorum threshold of 3/4, expects false",
+			block:                   &blockWithHashTrimmed,
+			contractQuorumThreshold: &types.QuorumThreshold{Numerator: 3, Denominator: 4},
+			allFpPks:                []string{"pk1", "pk2", "pk3"},
+			fpPowers:                map[string]uint64{"pk1": 100, "pk2": 100, "pk3": 100},
+			votedProviders:          []string{"pk1", "pk2"},
+			stakingActivationHeight: BTCHeight - 1,
+			expectResult:            false,
+			expectedErr:             nil,
+		},
+		{
+			name:                    "50% votes reaching local quorum threshold of 1/2, expects true",
+			block:                   &blockWithHashTrimmed,
+			localQuorumThreshold:    &types.QuorumThreshold{Numerator: 1, Denominator: 2},
+			allFpPks:                []string{"pk1", "pk2"},
+			fpPowers:                map[string]uint64{"pk1": 100, "pk2": 100},
+			votedProviders:          []string{"pk1"},
+			stakingActivationHeight: BTCHeight - 1,
+			expectResult:            true,
+			expectedErr:             nil,
+		},
+		{
+			name:                    "voting power overflowing uint64 when multiplied, 75% votes, expects true",
+			block:                   &blockWithHashTrimmed,
+			allFpPks:                []string{"pk1", "pk2"},
+			fpPowers:                map[string]uint64{"pk1": math.MaxUint64 / 4, "pk2": math.MaxUint64 / 4 * 3},
+			votedProviders:          []string{"pk2"},
+			stakingActivationHeight: BTCHeight - 1,
+			expectResult:            true,
+			expectedErr:             nil,
+		},
 		{
 			name:                    "zero voting power, 100% votes, expects false",
 			block:                   &blockWithHashUntrimmed,
@@ -149,8 +188,8 @@ func TestQueryIsBlockBabylonFinalized(t *testing.T) {
 			defer ctl.Finish()
 
 			mockCwClient := mocks.NewMockICosmWasmClient(ctl)
-			mockCwClient.EXPECT().QueryIsEnabled().Return(true, nil).Times(1)
-			mockCwClient.EXPECT().QueryConsumerId().Return(consumerChainID, nil).Times(1)
+			mockCwClient.EXPECT().QueryIsEnabled(int64(0)).Return(true, nil).Times(1)
+			mockCwClient.EXPECT().QueryConsumerId(int64(0)).Return(consumerChainID, nil).Times(1)
 			mockBTCClient := mocks.NewMockIBitcoinClient(ctl)
 			mockBTCClient.EXPECT().
 				GetBlockHeightByTimestamp(tc.block.BlockTimestamp).
@@ -159,32 +198,36 @@ func TestQueryIsBlockBabylonFinalized(t *testing.T) {
 
 			mockBBNClient := mocks.NewMockIBabylonClient(ctl)
 			mockBBNClient.EXPECT().
-				QueryAllFpBtcPubKeys(consumerChainID).
+				QueryAllFpBtcPubKeys(consumerChainID, int64(0)).
 				Return(tc.allFpPks, nil).
 				Times(1)
 			mockBBNClient.EXPECT().
-				QueryEarliestActiveDelBtcHeight(tc.allFpPks).
+				QueryEarliestActiveDelBtcHeight(tc.allFpPks, int64(0)).
 				Return(tc.stakingActivationHeight, nil).
 				Times(1)
 
 			if !errors.Is(tc.expectedErr, types.ErrBtcStakingNotActivated) {
 				mockBBNClient.EXPECT().
-					QueryMultiFpPower(tc.allFpPks, BTCHeight).
+					QueryMultiFpPower(tc.allFpPks, BTCHeight, int64(0)).
 					Return(tc.fpPowers, nil).
 					Times(1)
 
 				if !errors.Is(tc.expectedErr, types.ErrNoFpHasVotingPower) {
 					mockCwClient.EXPECT().
-						QueryListOfVotedFinalityProviders(&blockWithHashTrimmed).
+						QueryListOfVotedFinalityProviders(&blockWithHashTrimmed, int64(0)).
 						Return(tc.votedProviders, tc.expectedErr).
 						Times(1)
 				}
 			}
+			if tc.expectedErr == nil && tc.localQuorumThreshold == nil {
+				mockCwClient.EXPECT().QueryQuorumThreshold(int64(0)).Return(tc.contractQuorumThreshold, nil).Times(1)
+			}
 
 			mockFinalityGadget := &FinalityGadget{
-				cwClient:  mockCwClient,
-				bbnClient: mockBBNClient,
-				btcClient: mockBTCClient,
+				cwClient:        mockCwClient,
+				bbnClient:       mockBBNClient,
+				btcClient:       mockBTCClient,
+				quorumThreshold: tc.localQuorumThreshold,
 			}
 
 			res, err := mockFinalityGadget.QueryIsBlockBabylonFinalizedFromBabylon(tc.block)
@@ -194,6 +237,124 @@ func TestQueryIsBlockBabylonFinalized(t *testing.T) {
 	}
 }
 
+func TestQueryBlockFinalityFromBabylonEvidence(t *testing.T) {
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+
+	block := &types.Block{
+		BlockHash:      "0xd4e56740f876aef8c010b86a40d5f56745a118d0906a34e69aec8c0db1cb8fa3",
+		BlockHeight:    123,
+		BlockTimestamp: 12345,
+	}
+	// the block hash is trimmed in place, so keep the original for comparison
+	blockHash := block.BlockHash
+	const consumerChainID = "consumer-chain-id"
+	const BTCHeight = uint64(111)
+	const babylonHeight = int64(4242)
+	allFpPks := []string{"pk1", "pk2", "pk3", "pk4"}
+	fpPowers := map[string]uint64{"pk1": 100, "pk2": 200, "pk3": 300, "pk4": 0}
+
+	mockCwClient := mocks.NewMockICosmWasmClient(ctl)
+	mockCwClient.EXPECT().QueryIsEnabled(babylonHeight).Return(true, nil).Times(1)
+	mockCwClient.EXPECT().QueryConsumerId(babylonHeight).Return(consumerChainID, nil).Times(1)
+	mockCwClient.EXPECT().QueryListOfVotedFinalityProviders(gomock.Any(), babylonHeight).Return([]string{"pk3", "pk1", "pk5"}, nil).Times(1)
+	mockCwClient.EXPECT().QueryQuorumThreshold(babylonHeight).Return(nil, nil).Times(1)
+	mockBTCClient := mocks.NewMockIBitcoinClient(ctl)
+	mockBTCClient.EXPECT().GetBlockHeightByTimestamp(block.BlockTimestamp).Return(BTCHeight, nil).Times(1)
+	mockBBNClient := mocks.NewMockIBabylonClient(ctl)
+	mockBBNClient.EXPECT().QueryAllFpBtcPubKeys(consumerChainID, babylonHeight).Return(allFpPks, nil).Times(1)
+	mockBBNClient.EXPECT().QueryEarliestActiveDelBtcHeight(allFpPks, babylonHeight).Return(BTCHeight-1, nil).Times(1)
+	mockBBNClient.EXPECT().QueryMultiFpPower(allFpPks, BTCHeight, babylonHeight).Return(fpPowers, nil).Times(1)
+
+	mockFinalityGadget := &FinalityGadget{
+		cwClient:  mockCwClient,
+		bbnClient: mockBBNClient,
+		btcClient: mockBTCClient,
+	}
+
+	isFinalized, evidence, err := mockFinalityGadget.queryBlockFinalityFromBabylon(block, babylonHeight)
+	require.NoError(t, err)
+	require.True(t, isFinalized)
+
+	// voters unknown to the consumer chain are not part of the evidence, and voters are sorted by pubkey.
+	// all queries are made at the Babylon height recorded in the evidence
+	require.Equal(t, &types.FinalityEvidence{
+		BlockHash:     blockHash,
+		BlockHeight:   block.BlockHeight,
+		BtcHeight:     BTCHeight,
+		BabylonHeight: uint64(babylonHeight),
+		TotalPower:    600,
+		VotedPower:    400,
+		Voters: []*types.VoterPower{
+			{FpBtcPkHex: "pk1", Power: 100},
+			{FpBtcPkHex: "pk3", Power: 300},
+		},
+		QuorumThreshold: types.DefaultQuorumThreshold,
+	}, evidence)
+}
+
+func TestProcessHeightWithBtcHeightNotFinal(t *testing.T) {
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+
+	header := genL2Headers(123, 123, nil)[123]
+	const consumerChainID = "consumer-chain-id"
+	const babylonHeight = int64(4242)
+	allFpPks := []string{"pk1", "pk2"}
+
+	mockL2Client := mocks.NewMockIEthL2Client(ctl)
+	mockL2Client.EXPECT().HeaderByNumber(gomock.Any(), big.NewInt(123)).Return(header, nil).Times(1)
+	mockL2Client.EXPECT().L1OriginByNumber(gomock.Any(), big.NewInt(123)).Return(nil, ethl2client.ErrUnknownL1InfoFormat).Times(1)
+	mockCwClient := mocks.NewMockICosmWasmClient(ctl)
+	mockCwClient.EXPECT().QueryIsEnabled(babylonHeight).Return(true, nil).Times(1)
+	mockCwClient.EXPECT().QueryConsumerId(babylonHeight).Return(consumerChainID, nil).Times(1)
+	mockBBNClient := mocks.NewMockIBabylonClient(ctl)
+	mockBBNClient.EXPECT().QueryAllFpBtcPubKeys(consumerChainID, babylonHeight).Return(allFpPks, nil).Times(1)
+
+	// with the median time past mapping, a block after the median time past of the BTC tip maps to no BTC
+	// height yet, so it is left unfinalized until the next poll rather than failing the batch
+	mockBTCClient := mocks.NewMockIBitcoinClient(ctl)
+	mockBTCClient.EXPECT().GetBlockHeightByTimestamp(header.Time).
+		Return(uint64(0), fmt.Errorf("%w: timestamp %d is at or after the median time past of the BTC tip", types.ErrBtcHeightNotFinal, header.Time)).Times(1)
+
+	mockFinalityGadget := &FinalityGadget{
+		l2Client:  mockL2Client,
+		cwClient:  mockCwClient,
+		bbnClient: mockBBNClient,
+		btcClient: mockBTCClient,
+		logger:    zap.NewNop(),
+	}
+
+	block, evidence, err := mockFinalityGadget.processHeight(123, babylonHeight)
+	require.NoError(t, err)
+	require.Nil(t, block)
+	require.Nil(t, evidence)
+}
+
+func TestQueryBabylonHeight(t *testing.T) {
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+	mockBBNClient := mocks.NewMockIBabylonClient(ctl)
+	fg := &FinalityGadget{bbnClient: mockBBNClient}
+
+	// the latest state is queried if height pinning is disabled
+	height, err := fg.queryBabylonHeight()
+	require.NoError(t, err)
+	require.Equal(t, int64(0), height)
+
+	// queries are pinned to the latest Babylon height otherwise
+	fg.pinBabylonHeight = true
+	mockBBNClient.EXPECT().QueryLatestHeight().Return(int64(4242), nil).Times(1)
+	height, err = fg.queryBabylonHeight()
+	require.NoError(t, err)
+	require.Equal(t, int64(4242), height)
+
+	rpcErr := errors.New("connection refused")
+	mockBBNClient.EXPECT().QueryLatestHeight().Return(int64(0), rpcErr).Times(1)
+	_, err = fg.QueryIsBlockBabylonFinalizedFromBabylon(&types.Block{BlockHeight: 1})
+	require.ErrorIs(t, err, rpcErr)
+}
+
 func TestQueryBlockRangeBabylonFinalized(t *testing.T) {
 	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
 
@@ -272,8 +433,8 @@ func TestQueryBlockRangeBabylonFinalized(t *testing.T) {
 			// Setup mock DB responses
 			if len(tc.queryBlocks) > 0 && tc.queryDB {
 				mockDbHandler.EXPECT().
-					QueryEarliestFinalizedBlock().
-					Return(&blockA, nil).
+					QueryEarliestFinalizedHeight().
+					Return(blockA.BlockHeight, nil).
 					Times(1)
 				mockDbHandler.EXPECT().
 					QueryLatestFinalizedBlock().
@@ -747,9 +908,9 @@ func TestQueryBtcStakingActivatedTimestamp(t *testing.T) {
 
 	// Test case 2: Timestamp is not in the database, need to query from bbnClient
 	mockDbHandler.EXPECT().GetActivatedTimestamp().Return(uint64(0), types.ErrActivatedTimestampNotFound)
-	mockCwClient.EXPECT().QueryConsumerId().Return("consumer-chain-id", nil)
-	mockBBNClient.EXPECT().QueryAllFpBtcPubKeys("consumer-chain-id").Return([]string{"pk1", "pk2"}, nil)
-	mockBBNClient.EXPECT().QueryEarliestActiveDelBtcHeight([]string{"pk1", "pk2"}).Return(uint64(100), nil)
+	mockCwClient.EXPECT().QueryConsumerId(int64(0)).Return("consumer-chain-id", nil)
+	mockBBNClient.EXPECT().QueryAllFpBtcPubKeys("consumer-chain-id", int64(0)).Return([]string{"pk1", "pk2"}, nil)
+	mockBBNClient.EXPECT().QueryEarliestActiveDelBtcHeight([]string{"pk1", "pk2"}, int64(0)).Return(uint64(100), nil)
 	mockBTCClient.EXPECT().GetBlockTimestampByHeight(uint64(100)).Return(uint64(1234567890), nil)
 
 	timestamp, err = mockFinalityGadget.QueryBtcStakingActivatedTimestamp()
@@ -758,19 +919,103 @@ func TestQueryBtcStakingActivatedTimestamp(t *testing.T) {
 
 	// Test case 3: BTC staking is not activated
 	mockDbHandler.EXPECT().GetActivatedTimestamp().Return(uint64(0), types.ErrActivatedTimestampNotFound)
-	mockCwClient.EXPECT().QueryConsumerId().Return("consumer-chain-id", nil)
-	mockBBNClient.EXPECT().QueryAllFpBtcPubKeys("consumer-chain-id").Return([]string{"pk1", "pk2"}, nil)
-	mockBBNClient.EXPECT().QueryEarliestActiveDelBtcHeight([]string{"pk1", "pk2"}).Return(uint64(math.MaxUint64), nil)
+	mockCwClient.EXPECT().QueryConsumerId(int64(0)).Return("consumer-chain-id", nil)
+	mockBBNClient.EXPECT().QueryAllFpBtcPubKeys("consumer-chain-id", int64(0)).Return([]string{"pk1", "pk2"}, nil)
+	mockBBNClient.EXPECT().QueryEarliestActiveDelBtcHeight([]string{"pk1", "pk2"}, int64(0)).Return(uint64(math.MaxUint64), nil)
 
 	timestamp, err = mockFinalityGadget.QueryBtcStakingActivatedTimestamp()
 	require.Equal(t, types.ErrBtcStakingNotActivated, err)
 	require.Equal(t, uint64(math.MaxUint64), timestamp)
 }
 
-func normalizedBlock(block *types.Block) *types.Block {
-	return &types.Block{
-		BlockHeight:    block.BlockHeight,
-		BlockHash:      normalizeBlockHash(block.BlockHash),
-		BlockTimestamp: block.BlockTimestamp,
+func TestQueryTransactionsStatus(t *testing.T) {
+	ctl := gomock.NewController(t)
+	mockDbHandler := mocks.NewMockIDatabaseHandler(ctl)
+	mockL2Client := mocks.NewMockIEthL2Client(ctl)
+
+	mockFinalityGadget := &FinalityGadget{
+		db:       mockDbHandler,
+		l2Client: mockL2Client,
+		logger:   zap.NewNop(),
+	}
+
+	txHashes := []string{
+		common.HexToHash("0x01").Hex(), // finalized
+		common.HexToHash("0x02").Hex(), // btc finalized, in the same block as 0x03
+		common.HexToHash("0x03").Hex(),
+		"0x1234",                       // invalid
+		common.HexToHash("0x04").Hex(), // safe
+		common.HexToHash("0x05").Hex(), // pending
+		common.HexToHash("0x06").Hex(), // not found
+		common.HexToHash("0x07").Hex(), // in a reorged block
+	}
+	validTxHashes := []string{txHashes[0], txHashes[1], txHashes[2], txHashes[4], txHashes[5], txHashes[6], txHashes[7]}
+
+	// the safe and finalized heads are fetched along with each block once
+	numbers := []*big.Int{
+		big.NewInt(ethrpc.SafeBlockNumber.Int64()),
+		big.NewInt(ethrpc.FinalizedBlockNumber.Int64()),
+		big.NewInt(5),
+		big.NewInt(7),
+		big.NewInt(8),
+		big.NewInt(9),
+	}
+	headers := make([]*eth.Header, 0, len(numbers))
+	blockHashes := make(map[int64]common.Hash)
+	for _, height := range []int64{8, 6, 5, 7, 8, 9} {
+		header := &eth.Header{Number: big.NewInt(height), Time: uint64(height * 100)}
+		headers = append(headers, header)
+		blockHashes[height] = header.Hash()
+	}
+
+	receipt := func(txHash string, height int64) *eth.Receipt {
+		return &eth.Receipt{TxHash: common.HexToHash(txHash), BlockNumber: big.NewInt(height), BlockHash: blockHashes[height]}
+	}
+	reorgedReceipt := receipt(txHashes[7], 9)
+	reorgedReceipt.BlockHash = common.HexToHash("0xdead")
+	mockL2Client.EXPECT().TransactionReceipts(gomock.Any(), validTxHashes).Return([]*eth.Receipt{
+		receipt(txHashes[0], 5),
+		receipt(txHashes[1], 7),
+		receipt(txHashes[2], 7),
+		receipt(txHashes[4], 8),
+		receipt(txHashes[5], 9),
+		nil,
+		reorgedReceipt,
+	}, nil).Times(1)
+	mockL2Client.EXPECT().HeadersByNumbers(gomock.Any(), numbers).Return(headers, nil).Times(1)
+	mockDbHandler.EXPECT().QueryIsBlockFinalizedByHeight(gomock.Any()).DoAndReturn(func(height uint64) (bool, error) {
+		return height <= 7, nil
+	}).Times(5)
+
+	results, err := mockFinalityGadget.QueryTransactionsStatus(txHashes)
+	require.NoError(t, err)
+	require.Len(t, results, len(txHashes))
+	for i, expectedStatus := range []types.FinalityStatus{
+		types.FinalityStatusFinalized,
+		types.FinalityStatusBitcoinFinalized,
+		types.FinalityStatusBitcoinFinalized,
+		"",
+		types.FinalityStatusSafe,
+		types.FinalityStatusPending,
+		"",
+		"",
+	} {
+		require.Equal(t, txHashes[i], results[i].TxHash)
+		if expectedStatus == "" {
+			require.False(t, results[i].Found)
+			require.Nil(t, results[i].Transaction)
+			continue
+		}
+		require.True(t, results[i].Found)
+		require.Equal(t, expectedStatus, results[i].Transaction.Status)
+		require.Equal(t, txHashes[i], results[i].Transaction.TxHash)
 	}
+	require.Equal(t, uint64(700), results[1].Transaction.BlockTimestamp)
+	require.NotEmpty(t, results[3].Error)
+	require.Empty(t, results[6].Error)
+	require.Contains(t, results[7].Error, "does not match block")
+
+	// too many transactions
+	_, err = mockFinalityGadget.QueryTransactionsStatus(make([]string, MaxTransactionsStatusQuerySize+1))
+	require.ErrorIs(t, err, types.ErrTooManyTransactions)
 }
@@ -0,0 +1,173 @@
+package finalitygadget
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/babylonlabs-io/finality-gadget/types"
+	"go.uber.org/zap"
+)
+
+// followedBlocksWindow is the number of the last published blocks kept by FollowSharedDb to find the fork
+// height of the blocks rolled back in the shared db
+const followedBlocksWindow = 1000
+
+/* FollowSharedDb publishes the blocks finalized into the shared db by the finality gadget processing blocks,
+ * every poll interval until the context is cancelled. It is run in read-only mode, where no block is
+ * processed locally, so that streaming subscribers still receive finality events.
+ *
+ * - the blocks stored before it starts are not published, subscribers replay them from the db
+ * - the blocks stored since the last poll are published in order, skipping pruned blocks
+ * - the blocks rolled back since the last poll are published as a reorg, forking at the last published
+ *   block still stored
+ * - the chain sync status is published once it changes
+ */
+func (fg *FinalityGadget) FollowSharedDb(ctx context.Context) {
+	fg.logger.Info("Following the blocks finalized into the shared db", zap.Duration("poll_interval", fg.pollInterval))
+
+	var published []*types.Block
+	latestBlock, err := fg.queryLatestStoredBlock()
+	if err != nil {
+		fg.logger.Warn("Failed to query latest finalized block from the shared db", zap.Error(err))
+	} else if latestBlock != nil {
+		published = append(published, latestBlock)
+	}
+
+	ticker := time.NewTicker(fg.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if published, err = fg.followSharedDb(published); err != nil {
+				fg.logger.Warn("Failed to follow the shared db", zap.Error(err))
+			}
+			fg.publishChainSyncStatus()
+		}
+	}
+}
+
+//////////////////////////////
+// INTERNAL
+//////////////////////////////
+
+// followSharedDb publishes the blocks rolled back and stored since the given published blocks, and returns
+// the published blocks to follow from at the next poll
+func (fg *FinalityGadget) followSharedDb(published []*types.Block) ([]*types.Block, error) {
+	latestBlock, err := fg.queryLatestStoredBlock()
+	if err != nil {
+		return published, err
+	}
+	var latestHeight uint64
+	if latestBlock != nil {
+		latestHeight = latestBlock.BlockHeight
+	}
+
+	published, err = fg.publishSharedDbRollback(published, latestHeight)
+	if err != nil {
+		return published, err
+	}
+	if latestBlock == nil {
+		return published, nil
+	}
+
+	fromHeight := uint64(0)
+	if len(published) > 0 {
+		fromHeight = published[len(published)-1].BlockHeight + 1
+	}
+	earliestBlock, err := fg.db.QueryEarliestFinalizedBlock()
+	if err != nil {
+		// all blocks were rolled back since the latest block was queried
+		if errors.Is(err, types.ErrBlockNotFound) {
+			return published, nil
+		}
+		return published, fmt.Errorf("error fetching earliest finalized block from db: %w", err)
+	}
+	if fromHeight < earliestBlock.BlockHeight {
+		fromHeight = earliestBlock.BlockHeight
+	}
+
+	for height := fromHeight; height <= latestHeight; height++ {
+		block, err := fg.db.GetBlockByHeight(height)
+		if err != nil {
+			// the block was rolled back since the latest block was queried, the rollback is published at the
+			// next poll
+			if errors.Is(err, types.ErrBlockNotFound) {
+				return published, nil
+			}
+			return published, fmt.Errorf("error fetching block %d from db: %w", height, err)
+		}
+		fg.events.publish(&types.Event{Type: types.EventTypeBlockFinalized, Block: block})
+		published = append(published, block)
+		if len(published) > followedBlocksWindow {
+			published = published[len(published)-followedBlocksWindow:]
+		}
+	}
+	return published, nil
+}
+
+// publishSharedDbRollback publishes a reorg if the last published blocks were rolled back in the shared db,
+// and returns the published blocks still stored
+func (fg *FinalityGadget) publishSharedDbRollback(published []*types.Block, latestHeight uint64) ([]*types.Block, error) {
+	kept := len(published)
+	for ; kept > 0; kept-- {
+		block := published[kept-1]
+		if block.BlockHeight > latestHeight {
+			continue
+		}
+		storedBlock, err := fg.db.GetBlockByHeight(block.BlockHeight)
+		if err != nil {
+			// blocks are stored consecutively up to the latest block, so the missing block was pruned
+			if errors.Is(err, types.ErrBlockNotFound) {
+				break
+			}
+			return published, fmt.Errorf("error fetching block %d from db: %w", block.BlockHeight, err)
+		}
+		if storedBlock.BlockHash == block.BlockHash {
+			break
+		}
+	}
+	if kept == len(published) {
+		return published, nil
+	}
+
+	// if no published block is still stored, the fork is below the followed blocks
+	var forkHeight uint64
+	if kept > 0 {
+		forkHeight = published[kept-1].BlockHeight
+	} else if published[0].BlockHeight > 0 {
+		forkHeight = published[0].BlockHeight - 1
+	}
+	oldTip := published[len(published)-1]
+	event := &types.ReorgEvent{
+		ForkHeight:    forkHeight,
+		OldTipHeight:  oldTip.BlockHeight,
+		OldTipHash:    oldTip.BlockHash,
+		RolledBackNum: oldTip.BlockHeight - forkHeight,
+	}
+	fg.events.publish(&types.Event{Type: types.EventTypeReorg, Reorg: event})
+	fg.logger.Warn(
+		"Finalized blocks rolled back in the shared db",
+		zap.Uint64("fork_height", event.ForkHeight),
+		zap.Uint64("old_tip_height", event.OldTipHeight),
+		zap.String("old_tip_hash", event.OldTipHash),
+		zap.Uint64("rolled_back_num", event.RolledBackNum),
+	)
+	return published[:kept], nil
+}
+
+// queryLatestStoredBlock returns the latest finalized block stored in the db, nil if none
+func (fg *FinalityGadget) queryLatestStoredBlock() (*types.Block, error) {
+	latestBlock, err := fg.db.QueryLatestFinalizedBlock()
+	if err != nil {
+		if errors.Is(err, types.ErrBlockNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error fetching latest finalized block from db: %w", err)
+	}
+	return latestBlock, nil
+}
@@ -0,0 +1,113 @@
+package finalitygadget
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/babylonlabs-io/finality-gadget/db"
+	"github.com/babylonlabs-io/finality-gadget/types"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestFollowSharedDb(t *testing.T) {
+	handler, err := db.NewBBoltHandler(filepath.Join(t.TempDir(), "finality-gadget.db"), zap.NewNop())
+	require.NoError(t, err)
+	defer handler.Close()
+	require.NoError(t, handler.CreateInitialSchema())
+
+	newBlocks := func(from, to uint64, fork string) []*types.Block {
+		var blocks []*types.Block
+		for height := from; height <= to; height++ {
+			blocks = append(blocks, &types.Block{BlockHeight: height, BlockHash: fmt.Sprintf("%s%d", fork, height), BlockTimestamp: height})
+		}
+		return blocks
+	}
+
+	fg := &FinalityGadget{db: handler, logger: zap.NewNop()}
+	events, unsubscribe := fg.SubscribeEvents(100)
+	defer unsubscribe()
+
+	// the blocks stored by the finality gadget processing blocks are published in order
+	published, err := fg.followSharedDb(nil)
+	require.NoError(t, err)
+	require.Empty(t, published)
+	require.NoError(t, handler.InsertBlocks(newBlocks(1, 3, "a")))
+	published, err = fg.followSharedDb(published)
+	require.NoError(t, err)
+	require.Len(t, published, 3)
+	for height := uint64(1); height <= 3; height++ {
+		event := <-events
+		require.Equal(t, types.EventTypeBlockFinalized, event.Type)
+		require.Equal(t, height, event.Block.BlockHeight)
+	}
+
+	// nothing is published until new blocks are stored
+	published, err = fg.followSharedDb(published)
+	require.NoError(t, err)
+	require.Empty(t, events)
+
+	// blocks 3 to 5 are rolled back and replaced by a fork, the reorg is published before the new blocks
+	require.NoError(t, handler.InsertBlocks(newBlocks(4, 5, "a")))
+	published, err = fg.followSharedDb(published)
+	require.NoError(t, err)
+	require.Equal(t, uint64(4), (<-events).Block.BlockHeight)
+	require.Equal(t, uint64(5), (<-events).Block.BlockHeight)
+	require.NoError(t, handler.RollbackToHeight(2))
+	require.NoError(t, handler.InsertBlocks(newBlocks(3, 4, "b")))
+	published, err = fg.followSharedDb(published)
+	require.NoError(t, err)
+	event := <-events
+	require.Equal(t, types.EventTypeReorg, event.Type)
+	require.Equal(t, &types.ReorgEvent{ForkHeight: 2, OldTipHeight: 5, OldTipHash: "a5", RolledBackNum: 3}, event.Reorg)
+	require.Equal(t, "b3", (<-events).Block.BlockHash)
+	require.Equal(t, "b4", (<-events).Block.BlockHash)
+	require.Empty(t, events)
+
+	// pruned blocks are not mistaken for rolled back blocks
+	require.NoError(t, handler.InsertBlocks(newBlocks(5, 6, "b")))
+	_, err = handler.PruneBlocks(6)
+	require.NoError(t, err)
+	published, err = fg.followSharedDb(published)
+	require.NoError(t, err)
+	require.Equal(t, "b6", (<-events).Block.BlockHash)
+	require.Empty(t, events)
+	require.Equal(t, uint64(6), published[len(published)-1].BlockHeight)
+}
+
+func TestFollowSharedDbSkipsPrunedBlocks(t *testing.T) {
+	handler, err := db.NewBBoltHandler(filepath.Join(t.TempDir(), "finality-gadget.db"), zap.NewNop())
+	require.NoError(t, err)
+	defer handler.Close()
+	require.NoError(t, handler.CreateInitialSchema())
+
+	var blocks []*types.Block
+	for height := uint64(1); height <= 10; height++ {
+		blocks = append(blocks, &types.Block{BlockHeight: height, BlockHash: fmt.Sprintf("a%d", height), BlockTimestamp: height})
+	}
+	require.NoError(t, handler.InsertBlocks(blocks[:2]))
+
+	fg := &FinalityGadget{db: handler, logger: zap.NewNop()}
+	events, unsubscribe := fg.SubscribeEvents(100)
+	defer unsubscribe()
+	published, err := fg.followSharedDb(nil)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	for range 2 {
+		<-events
+	}
+
+	// blocks 3 to 6 are pruned before being followed
+	require.NoError(t, handler.InsertBlocks(blocks[2:]))
+	_, err = handler.PruneBlocks(7)
+	require.NoError(t, err)
+	_, err = fg.followSharedDb(published)
+	require.NoError(t, err)
+	for height := uint64(7); height <= 10; height++ {
+		event := <-events
+		require.Equal(t, types.EventTypeBlockFinalized, event.Type)
+		require.Equal(t, height, event.Block.BlockHeight)
+	}
+	require.Empty(t, events)
+}
@@ -0,0 +1,213 @@
+package finalitygadget
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/babylonlabs-io/finality-gadget/types"
+	ethrpc "github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// healthCheckTimeout bounds each readiness check, as the Babylon and BTC clients are not
+	// cancellable
+	healthCheckTimeout = 5 * time.Second
+	// minProcessingStallTimeout is the minimum time without a heartbeat after which block
+	// processing is considered stalled
+	minProcessingStallTimeout = time.Minute
+)
+
+// names of the readiness checks
+const (
+	HealthCheckDb              = "db"
+	HealthCheckL2              = "l2"
+	HealthCheckBabylon         = "babylon"
+	HealthCheckBtc             = "btc"
+	HealthCheckBlockProcessing = "block_processing"
+	HealthCheckFinalityLag     = "finality_lag"
+)
+
+// healthCheck is a named check run as part of a health report
+type healthCheck struct {
+	check func(ctx context.Context) error
+	name  string
+}
+
+// CheckLiveness checks whether the startup or block processing loop is making progress, i.e. it
+// ticked within the stall timeout. Before the first tick, the timeout runs from the creation of
+// the finality gadget. Upstream nodes are not checked, as restarting doesn't help if they are down.
+// Read-only finality gadgets don't process blocks, so they are always live.
+func (fg *FinalityGadget) CheckLiveness() *types.HealthReport {
+	if fg.readOnly {
+		return runHealthChecks(context.Background(), nil)
+	}
+	return runHealthChecks(context.Background(), []healthCheck{
+		{fg.checkHeartbeat, HealthCheckBlockProcessing},
+	})
+}
+
+/* CheckReadiness checks whether the finality gadget is able to serve up to date finality data
+ *
+ * - the db is open and readable
+ * - the L2, Babylon and BTC nodes are reachable
+ * - the startup and block processing loops are making progress, i.e. they ticked within the
+ *   stall timeout. while they retry transient errors, the check passes but is reported degraded,
+ *   as queries are still served from the db
+ * - the latest BTC finalized block doesn't lag behind the latest L2 block by more than the max
+ *   finality lag, if configured
+ * - read-only finality gadgets skip the block processing check, the finality lag check covers the
+ *   finality gadget processing blocks into the shared db
+ * - the checks run in parallel, each bounded by healthCheckTimeout
+ */
+func (fg *FinalityGadget) CheckReadiness(ctx context.Context) *types.HealthReport {
+	checks := []healthCheck{
+		{fg.checkDb, HealthCheckDb},
+		{fg.checkL2, HealthCheckL2},
+		{fg.checkBabylon, HealthCheckBabylon},
+		{fg.checkBtc, HealthCheckBtc},
+	}
+	if !fg.readOnly {
+		checks = append(checks, healthCheck{fg.checkBlockProcessing, HealthCheckBlockProcessing})
+	}
+	checks = append(checks, healthCheck{fg.checkFinalityLag, HealthCheckFinalityLag})
+	return runHealthChecks(ctx, checks)
+}
+
+//////////////////////////////
+// INTERNAL
+//////////////////////////////
+
+// runHealthChecks runs the checks in parallel, each bounded by healthCheckTimeout
+func runHealthChecks(ctx context.Context, checks []healthCheck) *types.HealthReport {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	report := &types.HealthReport{
+		Checks:  make([]*types.HealthCheck, len(checks)),
+		Healthy: true,
+	}
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, name string, check func(ctx context.Context) error) {
+			defer wg.Done()
+			report.Checks[i] = runHealthCheck(ctx, name, check)
+		}(i, c.name, c.check)
+	}
+	wg.Wait()
+
+	for _, check := range report.Checks {
+		report.Healthy = report.Healthy && check.Healthy
+		report.Degraded = report.Degraded || check.Degraded
+	}
+	return report
+}
+
+// heartbeat records that the startup or block processing loop is making progress
+func (fg *FinalityGadget) heartbeat() {
+	fg.lastHeartbeat.Store(time.Now().UnixNano())
+}
+
+func (fg *FinalityGadget) checkDb(ctx context.Context) error {
+	return fg.db.Ping()
+}
+
+func (fg *FinalityGadget) checkL2(ctx context.Context) error {
+	_, err := fg.l2Client.HeaderByNumber(ctx, big.NewInt(ethrpc.LatestBlockNumber.Int64()))
+	return err
+}
+
+func (fg *FinalityGadget) checkBabylon(ctx context.Context) error {
+	_, err := fg.cwClient.QueryIsEnabled(0)
+	return err
+}
+
+func (fg *FinalityGadget) checkBtc(ctx context.Context) error {
+	_, err := fg.btcClient.GetBlockCount()
+	return err
+}
+
+func (fg *FinalityGadget) checkBlockProcessing(ctx context.Context) error {
+	if fg.lastHeartbeat.Load() == 0 {
+		return errors.New("block processing has not started")
+	}
+	if err := fg.checkHeartbeat(ctx); err != nil {
+		return err
+	}
+	return fg.processing.degradedErr()
+}
+
+func (fg *FinalityGadget) checkHeartbeat(ctx context.Context) error {
+	lastProgress := fg.createdAt
+	if lastHeartbeat := fg.lastHeartbeat.Load(); lastHeartbeat != 0 {
+		lastProgress = time.Unix(0, lastHeartbeat)
+	}
+	if since := time.Since(lastProgress); since > fg.processingStallTimeout() {
+		return fmt.Errorf("block processing stalled, last progress %s ago", since.Truncate(time.Second))
+	}
+	return nil
+}
+
+// processingStallTimeout is the time without a heartbeat after which block processing is
+// considered stalled
+func (fg *FinalityGadget) processingStallTimeout() time.Duration {
+	stallTimeout := 5 * fg.pollInterval
+	if stallTimeout < minProcessingStallTimeout {
+		stallTimeout = minProcessingStallTimeout
+	}
+	return stallTimeout
+}
+
+// checkFinalityLag checks the latest BTC finalized block is within maxFinalityLag blocks of the
+// latest L2 block. It passes until the first block is finalized, as nothing can be finalized
+// before BTC staking is activated.
+func (fg *FinalityGadget) checkFinalityLag(ctx context.Context) error {
+	if fg.maxFinalityLag == 0 {
+		return nil
+	}
+	latestBlock, err := fg.l2Client.HeaderByNumber(ctx, big.NewInt(ethrpc.LatestBlockNumber.Int64()))
+	if err != nil {
+		return fmt.Errorf("error fetching latest L2 block: %w", err)
+	}
+	latestFinalizedBlock, err := fg.db.QueryLatestFinalizedBlock()
+	if err != nil && !errors.Is(err, types.ErrBlockNotFound) {
+		return fmt.Errorf("error fetching latest finalized block: %w", err)
+	}
+	if latestFinalizedBlock == nil {
+		return nil
+	}
+
+	latestHeight := latestBlock.Number.Uint64()
+	if latestHeight > latestFinalizedBlock.BlockHeight && latestHeight-latestFinalizedBlock.BlockHeight > fg.maxFinalityLag {
+		return fmt.Errorf("latest finalized block %d lags %d blocks behind latest block %d, max %d",
+			latestFinalizedBlock.BlockHeight, latestHeight-latestFinalizedBlock.BlockHeight, latestHeight, fg.maxFinalityLag)
+	}
+	return nil
+}
+
+// runHealthCheck runs the check until it returns or the context is done
+func runHealthCheck(ctx context.Context, name string, check func(ctx context.Context) error) *types.HealthCheck {
+	errs := make(chan error, 1)
+	go func() {
+		errs <- check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-errs:
+	case <-ctx.Done():
+		err = fmt.Errorf("timed out: %w", ctx.Err())
+	}
+	var degradedErr *degradedError
+	if errors.As(err, &degradedErr) {
+		return &types.HealthCheck{Name: name, Error: err.Error(), Healthy: true, Degraded: true}
+	}
+	if err != nil {
+		return &types.HealthCheck{Name: name, Error: err.Error()}
+	}
+	return &types.HealthCheck{Name: name, Healthy: true}
+}
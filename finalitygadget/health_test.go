@@ -0,0 +1,173 @@
+package finalitygadget
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/babylonlabs-io/finality-gadget/testutil/mocks"
+	"github.com/babylonlabs-io/finality-gadget/types"
+	eth "github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestCheckReadiness(t *testing.T) {
+	ctl := gomock.NewController(t)
+	mockDbHandler := mocks.NewMockIDatabaseHandler(ctl)
+	mockL2Client := mocks.NewMockIEthL2Client(ctl)
+	mockCwClient := mocks.NewMockICosmWasmClient(ctl)
+	mockBTCClient := mocks.NewMockIBitcoinClient(ctl)
+
+	fg := &FinalityGadget{
+		db:           mockDbHandler,
+		l2Client:     mockL2Client,
+		cwClient:     mockCwClient,
+		btcClient:    mockBTCClient,
+		pollInterval: time.Second,
+	}
+
+	mockDbHandler.EXPECT().Ping().Return(nil).Times(3)
+	mockL2Client.EXPECT().HeaderByNumber(gomock.Any(), gomock.Any()).Return(&eth.Header{Number: big.NewInt(10)}, nil).Times(3)
+	mockCwClient.EXPECT().QueryIsEnabled(int64(0)).Return(true, nil).Times(2)
+	mockBTCClient.EXPECT().GetBlockCount().Return(uint64(100), nil).Times(3)
+
+	// not ready until block processing starts
+	report := fg.CheckReadiness(context.Background())
+	require.False(t, report.Healthy)
+	require.Len(t, report.Checks, 6)
+	for _, check := range report.Checks {
+		require.Equal(t, check.Name != HealthCheckBlockProcessing, check.Healthy, check.Name)
+	}
+
+	// ready once block processing makes progress
+	fg.heartbeat()
+	report = fg.CheckReadiness(context.Background())
+	require.True(t, report.Healthy)
+	require.Equal(t, []*types.HealthCheck{
+		{Name: HealthCheckDb, Healthy: true},
+		{Name: HealthCheckL2, Healthy: true},
+		{Name: HealthCheckBabylon, Healthy: true},
+		{Name: HealthCheckBtc, Healthy: true},
+		{Name: HealthCheckBlockProcessing, Healthy: true},
+		{Name: HealthCheckFinalityLag, Healthy: true},
+	}, report.Checks)
+
+	// not ready if an upstream node is unreachable or block processing stalled
+	mockCwClient.EXPECT().QueryIsEnabled(int64(0)).Return(false, errors.New("connection refused")).Times(1)
+	fg.lastHeartbeat.Store(time.Now().Add(-2 * minProcessingStallTimeout).UnixNano())
+	report = fg.CheckReadiness(context.Background())
+	require.False(t, report.Healthy)
+	require.Equal(t, &types.HealthCheck{Name: HealthCheckBabylon, Error: "connection refused"}, report.Checks[2])
+	require.False(t, report.Checks[4].Healthy)
+	require.Contains(t, report.Checks[4].Error, "block processing stalled")
+}
+
+func TestCheckLiveness(t *testing.T) {
+	fg := &FinalityGadget{pollInterval: time.Second, createdAt: time.Now()}
+
+	// alive before the first heartbeat, until the stall timeout
+	require.True(t, fg.CheckLiveness().Healthy)
+	fg.createdAt = time.Now().Add(-2 * minProcessingStallTimeout)
+	report := fg.CheckLiveness()
+	require.False(t, report.Healthy)
+	require.Contains(t, report.Checks[0].Error, "block processing stalled")
+
+	// alive as long as heartbeats are recorded
+	fg.heartbeat()
+	require.Equal(t, &types.HealthReport{
+		Checks:  []*types.HealthCheck{{Name: HealthCheckBlockProcessing, Healthy: true}},
+		Healthy: true,
+	}, fg.CheckLiveness())
+
+	// the stall timeout scales with the poll interval
+	fg.pollInterval = time.Minute
+	fg.lastHeartbeat.Store(time.Now().Add(-2 * minProcessingStallTimeout).UnixNano())
+	require.True(t, fg.CheckLiveness().Healthy)
+	fg.lastHeartbeat.Store(time.Now().Add(-6 * time.Minute).UnixNano())
+	require.False(t, fg.CheckLiveness().Healthy)
+}
+
+func TestCheckHealthReadOnly(t *testing.T) {
+	ctl := gomock.NewController(t)
+	mockDbHandler := mocks.NewMockIDatabaseHandler(ctl)
+	mockL2Client := mocks.NewMockIEthL2Client(ctl)
+	mockCwClient := mocks.NewMockICosmWasmClient(ctl)
+	mockBTCClient := mocks.NewMockIBitcoinClient(ctl)
+
+	// read-only finality gadgets never process blocks
+	fg := &FinalityGadget{
+		db:           mockDbHandler,
+		l2Client:     mockL2Client,
+		cwClient:     mockCwClient,
+		btcClient:    mockBTCClient,
+		pollInterval: time.Second,
+		createdAt:    time.Now().Add(-2 * minProcessingStallTimeout),
+		readOnly:     true,
+	}
+	require.Equal(t, &types.HealthReport{Checks: []*types.HealthCheck{}, Healthy: true}, fg.CheckLiveness())
+
+	mockDbHandler.EXPECT().Ping().Return(nil)
+	mockL2Client.EXPECT().HeaderByNumber(gomock.Any(), gomock.Any()).Return(&eth.Header{Number: big.NewInt(10)}, nil)
+	mockCwClient.EXPECT().QueryIsEnabled(int64(0)).Return(true, nil)
+	mockBTCClient.EXPECT().GetBlockCount().Return(uint64(100), nil)
+	require.Equal(t, &types.HealthReport{
+		Checks: []*types.HealthCheck{
+			{Name: HealthCheckDb, Healthy: true},
+			{Name: HealthCheckL2, Healthy: true},
+			{Name: HealthCheckBabylon, Healthy: true},
+			{Name: HealthCheckBtc, Healthy: true},
+			{Name: HealthCheckFinalityLag, Healthy: true},
+		},
+		Healthy: true,
+	}, fg.CheckReadiness(context.Background()))
+}
+
+func TestCheckFinalityLag(t *testing.T) {
+	ctl := gomock.NewController(t)
+	mockDbHandler := mocks.NewMockIDatabaseHandler(ctl)
+	mockL2Client := mocks.NewMockIEthL2Client(ctl)
+	fg := &FinalityGadget{db: mockDbHandler, l2Client: mockL2Client}
+
+	// disabled by default
+	require.NoError(t, fg.checkFinalityLag(context.Background()))
+
+	fg.maxFinalityLag = 10
+	mockL2Client.EXPECT().HeaderByNumber(gomock.Any(), gomock.Any()).Return(&eth.Header{Number: big.NewInt(100)}, nil).Times(4)
+
+	// passes until the first block is finalized
+	mockDbHandler.EXPECT().QueryLatestFinalizedBlock().Return(nil, types.ErrBlockNotFound).Times(1)
+	require.NoError(t, fg.checkFinalityLag(context.Background()))
+
+	// passes within the max lag
+	mockDbHandler.EXPECT().QueryLatestFinalizedBlock().Return(&types.Block{BlockHeight: 90}, nil).Times(1)
+	require.NoError(t, fg.checkFinalityLag(context.Background()))
+	mockDbHandler.EXPECT().QueryLatestFinalizedBlock().Return(&types.Block{BlockHeight: 100}, nil).Times(1)
+	require.NoError(t, fg.checkFinalityLag(context.Background()))
+
+	// fails beyond the max lag
+	mockDbHandler.EXPECT().QueryLatestFinalizedBlock().Return(&types.Block{BlockHeight: 89}, nil).Times(1)
+	require.EqualError(t, fg.checkFinalityLag(context.Background()), "latest finalized block 89 lags 11 blocks behind latest block 100, max 10")
+}
+
+func TestCheckReadinessTimeout(t *testing.T) {
+	ctl := gomock.NewController(t)
+	mockBTCClient := mocks.NewMockIBitcoinClient(ctl)
+	fg := &FinalityGadget{btcClient: mockBTCClient}
+
+	// checks that don't return before the context is done fail
+	unblock := make(chan struct{})
+	defer close(unblock)
+	mockBTCClient.EXPECT().GetBlockCount().DoAndReturn(func() (uint64, error) {
+		<-unblock
+		return 0, nil
+	}).Times(1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	check := runHealthCheck(ctx, HealthCheckBtc, fg.checkBtc)
+	require.False(t, check.Healthy)
+	require.Contains(t, check.Error, "timed out")
+}
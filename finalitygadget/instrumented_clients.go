@@ -0,0 +1,183 @@
+package finalitygadget
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	"github.com/babylonlabs-io/finality-gadget/ethl2client"
+	"github.com/babylonlabs-io/finality-gadget/metrics"
+	"github.com/babylonlabs-io/finality-gadget/types"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	eth "github.com/ethereum/go-ethereum/core/types"
+)
+
+// client labels of the RPC metrics
+const (
+	btcClientLabel = "btcclient"
+	bbnClientLabel = "bbnclient"
+	cwClientLabel  = "cwclient"
+	l2ClientLabel  = "ethl2client"
+)
+
+// instrumentedBtcClient records the latency and errors of the wrapped BTC client calls
+type instrumentedBtcClient struct {
+	client  IBitcoinClient
+	metrics *metrics.FinalityGadgetMetrics
+}
+
+func (c *instrumentedBtcClient) GetBlockCount() (uint64, error) {
+	start := time.Now()
+	count, err := c.client.GetBlockCount()
+	c.metrics.ObserveRPCRequest(btcClientLabel, "GetBlockCount", start, err)
+	return count, err
+}
+
+func (c *instrumentedBtcClient) GetBlockHashByHeight(height uint64) (*chainhash.Hash, error) {
+	start := time.Now()
+	hash, err := c.client.GetBlockHashByHeight(height)
+	c.metrics.ObserveRPCRequest(btcClientLabel, "GetBlockHashByHeight", start, err)
+	return hash, err
+}
+
+func (c *instrumentedBtcClient) GetBlockHeaderByHash(blockHash *chainhash.Hash) (*wire.BlockHeader, error) {
+	start := time.Now()
+	header, err := c.client.GetBlockHeaderByHash(blockHash)
+	c.metrics.ObserveRPCRequest(btcClientLabel, "GetBlockHeaderByHash", start, err)
+	return header, err
+}
+
+func (c *instrumentedBtcClient) GetBlockHeightByTimestamp(targetTimestamp uint64) (uint64, error) {
+	start := time.Now()
+	height, err := c.client.GetBlockHeightByTimestamp(targetTimestamp)
+	c.metrics.ObserveRPCRequest(btcClientLabel, "GetBlockHeightByTimestamp", start, err)
+	return height, err
+}
+
+func (c *instrumentedBtcClient) GetBlockTimestampByHeight(height uint64) (uint64, error) {
+	start := time.Now()
+	timestamp, err := c.client.GetBlockTimestampByHeight(height)
+	c.metrics.ObserveRPCRequest(btcClientLabel, "GetBlockTimestampByHeight", start, err)
+	return timestamp, err
+}
+
+// instrumentedBabylonClient records the latency and errors of the wrapped Babylon client calls
+type instrumentedBabylonClient struct {
+	client  IBabylonClient
+	metrics *metrics.FinalityGadgetMetrics
+}
+
+func (c *instrumentedBabylonClient) QueryLatestHeight() (int64, error) {
+	start := time.Now()
+	height, err := c.client.QueryLatestHeight()
+	c.metrics.ObserveRPCRequest(bbnClientLabel, "QueryLatestHeight", start, err)
+	return height, err
+}
+
+func (c *instrumentedBabylonClient) QueryAllFpBtcPubKeys(consumerId string, babylonHeight int64) ([]string, error) {
+	start := time.Now()
+	pks, err := c.client.QueryAllFpBtcPubKeys(consumerId, babylonHeight)
+	c.metrics.ObserveRPCRequest(bbnClientLabel, "QueryAllFpBtcPubKeys", start, err)
+	return pks, err
+}
+
+func (c *instrumentedBabylonClient) QueryFpPower(fpPubkeyHex string, btcHeight uint64, babylonHeight int64) (uint64, error) {
+	start := time.Now()
+	power, err := c.client.QueryFpPower(fpPubkeyHex, btcHeight, babylonHeight)
+	c.metrics.ObserveRPCRequest(bbnClientLabel, "QueryFpPower", start, err)
+	return power, err
+}
+
+func (c *instrumentedBabylonClient) QueryMultiFpPower(fpPubkeyHexList []string, btcHeight uint64, babylonHeight int64) (map[string]uint64, error) {
+	start := time.Now()
+	power, err := c.client.QueryMultiFpPower(fpPubkeyHexList, btcHeight, babylonHeight)
+	c.metrics.ObserveRPCRequest(bbnClientLabel, "QueryMultiFpPower", start, err)
+	return power, err
+}
+
+func (c *instrumentedBabylonClient) QueryEarliestActiveDelBtcHeight(fpPubkeyHexList []string, babylonHeight int64) (uint64, error) {
+	start := time.Now()
+	height, err := c.client.QueryEarliestActiveDelBtcHeight(fpPubkeyHexList, babylonHeight)
+	c.metrics.ObserveRPCRequest(bbnClientLabel, "QueryEarliestActiveDelBtcHeight", start, err)
+	return height, err
+}
+
+// instrumentedCosmWasmClient records the latency and errors of the wrapped CosmWasm client calls
+type instrumentedCosmWasmClient struct {
+	client  ICosmWasmClient
+	metrics *metrics.FinalityGadgetMetrics
+}
+
+func (c *instrumentedCosmWasmClient) QueryListOfVotedFinalityProviders(queryParams *types.Block, babylonHeight int64) ([]string, error) {
+	start := time.Now()
+	pks, err := c.client.QueryListOfVotedFinalityProviders(queryParams, babylonHeight)
+	c.metrics.ObserveRPCRequest(cwClientLabel, "QueryListOfVotedFinalityProviders", start, err)
+	return pks, err
+}
+
+func (c *instrumentedCosmWasmClient) QueryConsumerId(babylonHeight int64) (string, error) {
+	start := time.Now()
+	consumerId, err := c.client.QueryConsumerId(babylonHeight)
+	c.metrics.ObserveRPCRequest(cwClientLabel, "QueryConsumerId", start, err)
+	return consumerId, err
+}
+
+func (c *instrumentedCosmWasmClient) QueryQuorumThreshold(babylonHeight int64) (*types.QuorumThreshold, error) {
+	start := time.Now()
+	threshold, err := c.client.QueryQuorumThreshold(babylonHeight)
+	c.metrics.ObserveRPCRequest(cwClientLabel, "QueryQuorumThreshold", start, err)
+	return threshold, err
+}
+
+func (c *instrumentedCosmWasmClient) QueryIsEnabled(babylonHeight int64) (bool, error) {
+	start := time.Now()
+	isEnabled, err := c.client.QueryIsEnabled(babylonHeight)
+	c.metrics.ObserveRPCRequest(cwClientLabel, "QueryIsEnabled", start, err)
+	return isEnabled, err
+}
+
+// instrumentedL2Client records the latency and errors of the wrapped L2 client calls
+type instrumentedL2Client struct {
+	client  IEthL2Client
+	metrics *metrics.FinalityGadgetMetrics
+}
+
+func (c *instrumentedL2Client) HeaderByNumber(ctx context.Context, number *big.Int) (*eth.Header, error) {
+	start := time.Now()
+	header, err := c.client.HeaderByNumber(ctx, number)
+	c.metrics.ObserveRPCRequest(l2ClientLabel, "HeaderByNumber", start, err)
+	return header, err
+}
+
+func (c *instrumentedL2Client) TransactionReceipt(ctx context.Context, txHash string) (*eth.Receipt, error) {
+	start := time.Now()
+	receipt, err := c.client.TransactionReceipt(ctx, txHash)
+	c.metrics.ObserveRPCRequest(l2ClientLabel, "TransactionReceipt", start, err)
+	return receipt, err
+}
+
+func (c *instrumentedL2Client) TransactionReceipts(ctx context.Context, txHashes []string) ([]*eth.Receipt, error) {
+	start := time.Now()
+	receipts, err := c.client.TransactionReceipts(ctx, txHashes)
+	c.metrics.ObserveRPCRequest(l2ClientLabel, "TransactionReceipts", start, err)
+	return receipts, err
+}
+
+func (c *instrumentedL2Client) HeadersByNumbers(ctx context.Context, numbers []*big.Int) ([]*eth.Header, error) {
+	start := time.Now()
+	headers, err := c.client.HeadersByNumbers(ctx, numbers)
+	c.metrics.ObserveRPCRequest(l2ClientLabel, "HeadersByNumbers", start, err)
+	return headers, err
+}
+
+func (c *instrumentedL2Client) L1OriginByNumber(ctx context.Context, number *big.Int) (*ethl2client.L1Origin, error) {
+	start := time.Now()
+	origin, err := c.client.L1OriginByNumber(ctx, number)
+	c.metrics.ObserveRPCRequest(l2ClientLabel, "L1OriginByNumber", start, err)
+	return origin, err
+}
+
+func (c *instrumentedL2Client) Close() {
+	c.client.Close()
+}
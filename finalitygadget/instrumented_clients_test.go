@@ -0,0 +1,65 @@
+package finalitygadget
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/babylonlabs-io/finality-gadget/metrics"
+	"github.com/babylonlabs-io/finality-gadget/testutil/mocks"
+	"github.com/babylonlabs-io/finality-gadget/types"
+	eth "github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestInstrumentedClients(t *testing.T) {
+	ctl := gomock.NewController(t)
+	fgMetrics := metrics.NewFinalityGadgetMetrics()
+	rpcErr := errors.New("rpc error")
+
+	mockBtcClient := mocks.NewMockIBitcoinClient(ctl)
+	mockBtcClient.EXPECT().GetBlockCount().Return(uint64(100), nil).Times(1)
+	mockBtcClient.EXPECT().GetBlockTimestampByHeight(uint64(100)).Return(uint64(0), rpcErr).Times(1)
+	btcClient := &instrumentedBtcClient{client: mockBtcClient, metrics: fgMetrics}
+
+	mockBbnClient := mocks.NewMockIBabylonClient(ctl)
+	mockBbnClient.EXPECT().QueryFpPower("pk1", uint64(100), int64(0)).Return(uint64(10), nil).Times(1)
+	bbnClient := &instrumentedBabylonClient{client: mockBbnClient, metrics: fgMetrics}
+
+	mockCwClient := mocks.NewMockICosmWasmClient(ctl)
+	mockCwClient.EXPECT().QueryListOfVotedFinalityProviders(gomock.Any(), int64(0)).Return(nil, rpcErr).Times(1)
+	cwClient := &instrumentedCosmWasmClient{client: mockCwClient, metrics: fgMetrics}
+
+	mockL2Client := mocks.NewMockIEthL2Client(ctl)
+	mockL2Client.EXPECT().HeaderByNumber(gomock.Any(), big.NewInt(1)).Return(&eth.Header{Number: big.NewInt(1)}, nil).Times(1)
+	l2Client := &instrumentedL2Client{client: mockL2Client, metrics: fgMetrics}
+
+	// results and errors of the wrapped clients are passed through
+	count, err := btcClient.GetBlockCount()
+	require.NoError(t, err)
+	require.Equal(t, uint64(100), count)
+	_, err = btcClient.GetBlockTimestampByHeight(100)
+	require.ErrorIs(t, err, rpcErr)
+	power, err := bbnClient.QueryFpPower("pk1", 100, 0)
+	require.NoError(t, err)
+	require.Equal(t, uint64(10), power)
+	_, err = cwClient.QueryListOfVotedFinalityProviders(&types.Block{}, 0)
+	require.ErrorIs(t, err, rpcErr)
+	header, err := l2Client.HeaderByNumber(context.Background(), big.NewInt(1))
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(1), header.Number)
+
+	// requests and errors are recorded per client and method
+	recorder := httptest.NewRecorder()
+	fgMetrics.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	body := recorder.Body.String()
+	require.Contains(t, body, `finality_gadget_rpc_request_duration_seconds_count{client="btcclient",method="GetBlockCount"} 1`)
+	require.Contains(t, body, `finality_gadget_rpc_request_duration_seconds_count{client="bbnclient",method="QueryFpPower"} 1`)
+	require.Contains(t, body, `finality_gadget_rpc_request_duration_seconds_count{client="ethl2client",method="HeaderByNumber"} 1`)
+	require.Contains(t, body, `finality_gadget_rpc_errors_total{client="btcclient",method="GetBlockTimestampByHeight"} 1`)
+	require.Contains(t, body, `finality_gadget_rpc_errors_total{client="cwclient",method="QueryListOfVotedFinalityProviders"} 1`)
+	require.NotContains(t, body, `finality_gadget_rpc_errors_total{client="btcclient",method="GetBlockCount"}`)
+}
@@ -1,6 +1,10 @@
 package finalitygadget
 
-import "github.com/babylonlabs-io/finality-gadget/types"
+import (
+	"context"
+
+	"github.com/babylonlabs-io/finality-gadget/types"
+)
 
 type IFinalityGadget interface {
 	// TODO: make this method internal once fully tested. External services should query the database instead.
@@ -18,7 +22,7 @@ type IFinalityGadget interface {
 	 *   - calculate total voting power
 	 *   - get all FPs that voted this L2 block with the same height and hash
 	 *   - calculate voted voting power
-	 *   - check if the voted voting power is more than 2/3 of the total voting power
+	 *   - check if the voted voting power reaches the quorum threshold (2/3 of the total voting power by default)
 	 */
 	QueryIsBlockBabylonFinalizedFromBabylon(block *types.Block) (bool, error)
 
@@ -59,6 +63,10 @@ type IFinalityGadget interface {
 	 */
 	QueryBtcStakingActivatedTimestamp() (uint64, error)
 
+	// QueryBlockFinalityEvidence returns the voters, voting power and BTC height that made the block at given
+	// height btc finalized by querying the local db
+	QueryBlockFinalityEvidence(height uint64) (*types.FinalityEvidence, error)
+
 	// GetBlockByHeight returns the btc finalized block at given height by querying the local db
 	GetBlockByHeight(height uint64) (*types.Block, error)
 
@@ -77,6 +85,21 @@ type IFinalityGadget interface {
 	// QueryTransactionStatus returns the finality status of a transaction
 	QueryTransactionStatus(txHash string) (*types.TransactionInfo, error)
 
+	// QueryTransactionsStatus returns the finality status of multiple transactions, with batched L2 RPC calls
+	QueryTransactionsStatus(txHashes []string) ([]*types.TransactionStatusResult, error)
+
 	// QueryChainSyncStatus returns the latest finalized blocks for display by the finality explorer
 	QueryChainSyncStatus() (*types.ChainSyncStatus, error)
+
+	// SubscribeEvents subscribes to the events published by the finality gadget, such as newly finalized blocks.
+	// Up to bufferSize events are buffered. If the subscriber falls behind, the returned channel is closed and it
+	// must resubscribe. Call the returned function to unsubscribe.
+	SubscribeEvents(bufferSize int) (<-chan *types.Event, func())
+
+	// CheckLiveness checks that block processing is not stalled
+	CheckLiveness() *types.HealthReport
+
+	// CheckReadiness checks the db, the L2, Babylon and BTC nodes, that block processing is not stalled and that
+	// the finality lag is within the configured max
+	CheckReadiness(ctx context.Context) *types.HealthReport
 }
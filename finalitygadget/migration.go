@@ -0,0 +1,196 @@
+package finalitygadget
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/babylonlabs-io/finality-gadget/db"
+	"github.com/babylonlabs-io/finality-gadget/types"
+	"go.uber.org/zap"
+)
+
+// errMigrationNeedsFinalityGadget is returned by the online migrations if no finality gadget is given to fetch
+// the blocks with
+var errMigrationNeedsFinalityGadget = errors.New("online db migration needs the finality gadget")
+
+/* MigrateDb upgrades a db created by an older version of the finality gadget to the current db schema
+ * version, see db.Migrator
+ *
+ * - the block metadata migration re-fetches every stored block from the L2 RPC to backfill the parent
+ *   hash, state root and L1 origin. Blocks whose hash no longer matches the L2 chain are left untouched,
+ *   they are rolled back by the reorg detection once block processing starts
+ * - the block encoding migration re-encodes the blocks stored as JSON with the binary encoding, it
+ *   rewrites the whole db so it is an offline migration
+ */
+func (fg *FinalityGadget) MigrateDb(ctx context.Context, opts db.MigrateOptions) ([]db.Migration, error) {
+	migrator, err := db.NewMigrator(fg.db, fg.logger, Migrations(fg, fg.batchSize, fg.logger))
+	if err != nil {
+		return nil, err
+	}
+	return migrator.Run(ctx, opts)
+}
+
+/* Migrations returns the db migrations, in order of version
+ *
+ * - the online migrations fetch the blocks from the L2 RPC through the given finality gadget
+ * - the finality gadget can be nil, for the migrate-db command to list the pending migrations and apply
+ *   the offline ones without connecting to the L2, Babylon and BTC nodes. The online migrations then fail
+ */
+func Migrations(fg *FinalityGadget, batchSize uint64, logger *zap.Logger) []db.Migration {
+	return []db.Migration{
+		{
+			Migrate: func(ctx context.Context, handler db.IDatabaseHandler) error {
+				if fg == nil {
+					return errMigrationNeedsFinalityGadget
+				}
+				return fg.migrateBlockMetadata(ctx, handler)
+			},
+			Description: "backfill the parent hash, state root and L1 origin of the stored blocks from the L2 RPC",
+			Version:     db.SchemaVersionBlockMetadata,
+		},
+		db.NewBlockEncodingMigration(batchSize, logger),
+	}
+}
+
+/* migrateDb applies the pending online migrations at startup, retrying transient errors like the block
+ * processing loop
+ *
+ * - the db is backed up before the first attempt only, a retry resumes from the failing migration
+ * - the backfill records a heartbeat after every batch, so liveness checks pass during a long migration
+ */
+func (fg *FinalityGadget) migrateDb(ctx context.Context) error {
+	opts := db.MigrateOptions{
+		BackupPathPrefix: fg.dbBackupPathPrefix,
+		SkipOffline:      true,
+	}
+	return fg.runWithRetry(ctx, "db migration", func(ctx context.Context) (bool, error) {
+		_, err := fg.MigrateDb(ctx, opts)
+		opts.BackupPathPrefix = ""
+		return true, err
+	})
+}
+
+//////////////////////////////
+// INTERNAL
+//////////////////////////////
+
+// migrateBlockMetadata backfills the metadata of the stored blocks, from the earliest to the latest
+func (fg *FinalityGadget) migrateBlockMetadata(ctx context.Context, handler db.IDatabaseHandler) error {
+	earliestBlock, err := handler.QueryEarliestFinalizedBlock()
+	if err != nil {
+		if errors.Is(err, types.ErrBlockNotFound) {
+			return nil
+		}
+		return fmt.Errorf("error fetching earliest finalized block from db: %w", err)
+	}
+	latestBlock, err := handler.QueryLatestFinalizedBlock()
+	if err != nil {
+		return fmt.Errorf("error fetching latest finalized block from db: %w", err)
+	}
+	if latestBlock.BlockHeight > math.MaxInt64 {
+		return fmt.Errorf("block height %d exceeds maximum int64 value", latestBlock.BlockHeight)
+	}
+	return fg.backfillBlockMetadata(ctx, earliestBlock.BlockHeight, latestBlock.BlockHeight)
+}
+
+// backfillBlockMetadata re-fetches the stored blocks in [startHeight, endHeight] and re-inserts
+// them with the parent hash, state root and L1 origin filled in
+func (fg *FinalityGadget) backfillBlockMetadata(ctx context.Context, startHeight, endHeight uint64) error {
+	batchSize := fg.batchSize
+	if batchSize == 0 {
+		batchSize = 1
+	}
+
+	for batchStartHeight := startHeight; batchStartHeight <= endHeight; batchStartHeight += batchSize {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		batchEndHeight := batchStartHeight + batchSize - 1
+		if batchEndHeight > endHeight {
+			batchEndHeight = endHeight
+		}
+
+		blocks, err := fg.queryBlockMetadataBatch(batchStartHeight, batchEndHeight)
+		if err != nil {
+			return err
+		}
+
+		// the blocks were already finalized, so subscribers are not notified again
+		if len(blocks) > 0 {
+			fg.mutex.Lock()
+			_, err = fg.storeBlocks(blocks)
+			fg.mutex.Unlock()
+			if err != nil {
+				return fmt.Errorf("error storing migrated blocks: %w", err)
+			}
+		}
+		fg.logger.Info("Migrated blocks", zap.Uint64("batch_start_height", batchStartHeight), zap.Uint64("batch_end_height", batchEndHeight))
+		fg.heartbeat()
+
+		// avoid overflow when the end height is the max uint64 value
+		if batchEndHeight == endHeight {
+			break
+		}
+	}
+	return nil
+}
+
+/* queryBlockMetadataBatch returns the L2 blocks to backfill the stored blocks in [startHeight, endHeight] with
+ *
+ * - blocks already backfilled, i.e. with a state root, are skipped, so an interrupted migration resumes
+ *   where it stopped
+ * - the L2 blocks are fetched in parallel, like the blocks of a processing batch
+ * - blocks whose hash no longer matches the L2 chain are skipped
+ */
+func (fg *FinalityGadget) queryBlockMetadataBatch(startHeight, endHeight uint64) ([]*types.Block, error) {
+	var storedBlocks []*types.Block
+	for height := startHeight; height <= endHeight; height++ {
+		storedBlock, err := fg.db.GetBlockByHeight(height)
+		if err != nil {
+			if errors.Is(err, types.ErrBlockNotFound) {
+				continue
+			}
+			return nil, fmt.Errorf("error fetching block %d from db: %w", height, err)
+		}
+		if storedBlock.StateRoot == "" {
+			storedBlocks = append(storedBlocks, storedBlock)
+		}
+		// avoid overflow when the end height is the max uint64 value
+		if height == endHeight {
+			break
+		}
+	}
+
+	l2Blocks := make([]*types.Block, len(storedBlocks))
+	errs := make([]error, len(storedBlocks))
+	var wg sync.WaitGroup
+	for i, storedBlock := range storedBlocks {
+		wg.Add(1)
+		go func(i int, height uint64) {
+			defer wg.Done()
+			l2Blocks[i], errs[i] = fg.queryBlockByHeight(int64(height))
+		}(i, storedBlock.BlockHeight)
+	}
+	wg.Wait()
+
+	var blocks []*types.Block
+	for i, storedBlock := range storedBlocks {
+		if errs[i] != nil {
+			return nil, fmt.Errorf("error fetching L2 block %d: %w", storedBlock.BlockHeight, errs[i])
+		}
+		block := l2Blocks[i]
+		if normalizeBlockHash(block.BlockHash) != normalizeBlockHash(storedBlock.BlockHash) {
+			fg.logger.Warn("Stored block does not match L2 block, skipping migration",
+				zap.Uint64("block_height", storedBlock.BlockHeight),
+				zap.String("stored_block_hash", storedBlock.BlockHash),
+				zap.String("l2_block_hash", block.BlockHash),
+			)
+			continue
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks, nil
+}
@@ -0,0 +1,265 @@
+package finalitygadget
+
+import (
+	"context"
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/babylonlabs-io/finality-gadget/db"
+	"github.com/babylonlabs-io/finality-gadget/ethl2client"
+	"github.com/babylonlabs-io/finality-gadget/testutil/mocks"
+	"github.com/babylonlabs-io/finality-gadget/types"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	eth "github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+)
+
+func TestMigrateDb(t *testing.T) {
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+
+	headers := genL2Headers(1, 4, nil)
+	for h, header := range headers {
+		header.Root = common.BigToHash(new(big.Int).SetUint64(h + 100))
+	}
+	forkHeaders := genL2Headers(4, 4, headers[3])
+
+	// legacy blocks only store hash, height and timestamp. block 4 has since been reorged out
+	storedBlocks := map[uint64]*types.Block{
+		2: legacyBlock(headers[2]),
+		3: legacyBlock(headers[3]),
+		4: legacyBlock(forkHeaders[4]),
+	}
+
+	mockDbHandler := mocks.NewMockIDatabaseHandler(ctl)
+	mockDbHandler.EXPECT().GetSchemaVersion().Return(db.SchemaVersionLegacy, nil).Times(1)
+	mockDbHandler.EXPECT().QueryEarliestFinalizedBlock().Return(storedBlocks[2], nil).Times(1)
+	mockDbHandler.EXPECT().QueryLatestFinalizedBlock().Return(storedBlocks[4], nil).Times(1)
+	mockDbHandler.EXPECT().GetBlockByHeight(gomock.Any()).DoAndReturn(func(height uint64) (*types.Block, error) {
+		return storedBlocks[height], nil
+	}).Times(3)
+
+	var migratedBlocks []*types.Block
+	mockDbHandler.EXPECT().InsertBlocks(gomock.Any()).DoAndReturn(func(blocks []*types.Block) error {
+		migratedBlocks = append(migratedBlocks, blocks...)
+		return nil
+	}).Times(1)
+	mockDbHandler.EXPECT().SaveSchemaVersion(db.SchemaVersionBlockMetadata).Return(nil).Times(1)
+
+	mockL2Client := mocks.NewMockIEthL2Client(ctl)
+	mockL2Client.EXPECT().HeaderByNumber(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, number *big.Int) (*eth.Header, error) {
+		return headers[number.Uint64()], nil
+	}).Times(3)
+	mockL2Client.EXPECT().L1OriginByNumber(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, number *big.Int) (*ethl2client.L1Origin, error) {
+		return &ethl2client.L1Origin{
+			Number: number.Uint64() + 1000,
+			Hash:   common.BigToHash(new(big.Int).Add(number, big.NewInt(1000))),
+		}, nil
+	}).Times(3)
+
+	mockFinalityGadget := &FinalityGadget{
+		db:        mockDbHandler,
+		l2Client:  mockL2Client,
+		logger:    zap.NewNop(),
+		batchSize: 2,
+	}
+
+	events, unsubscribe := mockFinalityGadget.SubscribeEvents(10)
+	defer unsubscribe()
+
+	err := mockFinalityGadget.migrateDb(context.Background())
+	require.NoError(t, err)
+
+	// only the blocks still on the L2 chain are migrated, without publishing them as newly finalized
+	require.Len(t, migratedBlocks, 2)
+	require.Empty(t, events)
+	for _, block := range migratedBlocks {
+		header := headers[block.BlockHeight]
+		require.Equal(t, normalizeBlockHash(storedBlocks[block.BlockHeight].BlockHash), block.BlockHash)
+		require.Equal(t, header.ParentHash.Hex(), block.ParentHash)
+		require.Equal(t, header.Root.Hex(), block.StateRoot)
+		require.Equal(t, block.BlockHeight+1000, block.L1OriginNumber)
+		require.Equal(t, common.BigToHash(new(big.Int).SetUint64(block.BlockHeight+1000)).Hex(), block.L1OriginHash)
+	}
+}
+
+func TestMigrateDbRetriesTransientErrors(t *testing.T) {
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+
+	headers := genL2Headers(1, 2, nil)
+	storedBlocks := map[uint64]*types.Block{
+		1: legacyBlock(headers[1]),
+		2: legacyBlock(headers[2]),
+	}
+
+	// the db is backed up once, and the migration is retried after the L2 node fails to return block 2
+	mockDbHandler := mocks.NewMockIDatabaseHandler(ctl)
+	mockDbHandler.EXPECT().GetSchemaVersion().Return(db.SchemaVersionLegacy, nil).Times(2)
+	mockDbHandler.EXPECT().Backup(gomock.Any()).Return(nil).Times(1)
+	mockDbHandler.EXPECT().QueryEarliestFinalizedBlock().Return(storedBlocks[1], nil).Times(2)
+	mockDbHandler.EXPECT().QueryLatestFinalizedBlock().Return(storedBlocks[2], nil).Times(2)
+	mockDbHandler.EXPECT().GetBlockByHeight(gomock.Any()).DoAndReturn(func(height uint64) (*types.Block, error) {
+		return storedBlocks[height], nil
+	}).Times(4)
+	mockDbHandler.EXPECT().InsertBlocks(gomock.Any()).DoAndReturn(func(blocks []*types.Block) error {
+		require.Len(t, blocks, 2)
+		return nil
+	}).Times(1)
+	mockDbHandler.EXPECT().SaveSchemaVersion(db.SchemaVersionBlockMetadata).Return(nil).Times(1)
+
+	var failed atomic.Bool
+	mockL2Client := mocks.NewMockIEthL2Client(ctl)
+	mockL2Client.EXPECT().HeaderByNumber(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, number *big.Int) (*eth.Header, error) {
+		if number.Uint64() == 2 && failed.CompareAndSwap(false, true) {
+			return nil, ethereum.NotFound
+		}
+		return headers[number.Uint64()], nil
+	}).Times(4)
+	mockL2Client.EXPECT().L1OriginByNumber(gomock.Any(), gomock.Any()).Return(&ethl2client.L1Origin{}, nil).Times(3)
+
+	mockFinalityGadget := &FinalityGadget{
+		db:                 mockDbHandler,
+		l2Client:           mockL2Client,
+		dbBackupPathPrefix: "/data/finality-gadget.db",
+		logger:             zap.NewNop(),
+		batchSize:          2,
+	}
+
+	err := mockFinalityGadget.migrateDb(context.Background())
+	require.NoError(t, err)
+	require.NotZero(t, mockFinalityGadget.lastHeartbeat.Load())
+}
+
+func TestMigrateDbAtCurrentVersion(t *testing.T) {
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+
+	mockDbHandler := mocks.NewMockIDatabaseHandler(ctl)
+	mockDbHandler.EXPECT().GetSchemaVersion().Return(db.CurrentSchemaVersion, nil).Times(1)
+
+	mockFinalityGadget := &FinalityGadget{
+		db:     mockDbHandler,
+		logger: zap.NewNop(),
+	}
+
+	err := mockFinalityGadget.migrateDb(context.Background())
+	require.NoError(t, err)
+}
+
+func TestMigrateDbLeavesBlockEncodingToMigrateCommand(t *testing.T) {
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+
+	// blocks stored as JSON are not re-encoded at startup, nor the schema version saved
+	mockDbHandler := mocks.NewMockIDatabaseHandler(ctl)
+	mockDbHandler.EXPECT().GetSchemaVersion().Return(db.SchemaVersionBlockMetadata, nil).Times(1)
+
+	mockFinalityGadget := &FinalityGadget{
+		db:     mockDbHandler,
+		logger: zap.NewNop(),
+	}
+
+	err := mockFinalityGadget.migrateDb(context.Background())
+	require.NoError(t, err)
+}
+
+func TestMigrateDbWithEmptyDb(t *testing.T) {
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+
+	mockDbHandler := mocks.NewMockIDatabaseHandler(ctl)
+	mockDbHandler.EXPECT().GetSchemaVersion().Return(db.SchemaVersionLegacy, nil).Times(1)
+	mockDbHandler.EXPECT().QueryEarliestFinalizedBlock().Return(nil, types.ErrBlockNotFound).Times(1)
+	mockDbHandler.EXPECT().SaveSchemaVersion(db.SchemaVersionBlockMetadata).Return(nil).Times(1)
+
+	mockFinalityGadget := &FinalityGadget{
+		db:     mockDbHandler,
+		logger: zap.NewNop(),
+	}
+
+	err := mockFinalityGadget.migrateDb(context.Background())
+	require.NoError(t, err)
+}
+
+func TestMigrateDbBacksUpDb(t *testing.T) {
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+
+	mockDbHandler := mocks.NewMockIDatabaseHandler(ctl)
+	mockDbHandler.EXPECT().GetSchemaVersion().Return(db.SchemaVersionLegacy, nil).Times(1)
+	mockDbHandler.EXPECT().Backup(gomock.Any()).DoAndReturn(func(path string) error {
+		require.True(t, strings.HasPrefix(path, "/data/finality-gadget.db.v0-"), path)
+		return nil
+	}).Times(1)
+	mockDbHandler.EXPECT().QueryEarliestFinalizedBlock().Return(nil, types.ErrBlockNotFound).Times(1)
+	mockDbHandler.EXPECT().SaveSchemaVersion(db.SchemaVersionBlockMetadata).Return(nil).Times(1)
+
+	mockFinalityGadget := &FinalityGadget{
+		db:                 mockDbHandler,
+		dbBackupPathPrefix: "/data/finality-gadget.db",
+		logger:             zap.NewNop(),
+	}
+
+	err := mockFinalityGadget.migrateDb(context.Background())
+	require.NoError(t, err)
+}
+
+func TestMigrateDbDryRun(t *testing.T) {
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+
+	// nothing is written, including offline migrations
+	mockDbHandler := mocks.NewMockIDatabaseHandler(ctl)
+	mockDbHandler.EXPECT().GetSchemaVersion().Return(db.SchemaVersionLegacy, nil).Times(1)
+
+	mockFinalityGadget := &FinalityGadget{
+		db:                 mockDbHandler,
+		dbBackupPathPrefix: "/data/finality-gadget.db",
+		logger:             zap.NewNop(),
+	}
+
+	pending, err := mockFinalityGadget.MigrateDb(context.Background(), db.MigrateOptions{DryRun: true})
+	require.NoError(t, err)
+	require.Len(t, pending, 2)
+	require.Equal(t, db.CurrentSchemaVersion, pending[len(pending)-1].Version)
+	require.True(t, pending[len(pending)-1].Offline)
+}
+
+func TestMigrationsWithoutFinalityGadget(t *testing.T) {
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+
+	// the offline migrations are applied without a finality gadget
+	mockDbHandler := mocks.NewMockIDatabaseHandler(ctl)
+	mockDbHandler.EXPECT().GetSchemaVersion().Return(db.SchemaVersionBlockMetadata, nil).Times(1)
+	mockDbHandler.EXPECT().QueryEarliestFinalizedBlock().Return(nil, types.ErrBlockNotFound).Times(1)
+	mockDbHandler.EXPECT().SaveSchemaVersion(db.CurrentSchemaVersion).Return(nil).Times(1)
+
+	migrations := Migrations(nil, 10, zap.NewNop())
+	migrator, err := db.NewMigrator(mockDbHandler, zap.NewNop(), migrations)
+	require.NoError(t, err)
+	applied, err := migrator.Run(context.Background(), db.MigrateOptions{})
+	require.NoError(t, err)
+	require.Len(t, applied, 1)
+	require.True(t, applied[0].Offline)
+
+	// the online migrations need one
+	require.False(t, migrations[0].Offline)
+	err = migrations[0].Migrate(context.Background(), mockDbHandler)
+	require.ErrorIs(t, err, errMigrationNeedsFinalityGadget)
+}
+
+func legacyBlock(header *eth.Header) *types.Block {
+	return &types.Block{
+		BlockHeight:    header.Number.Uint64(),
+		BlockHash:      hex.EncodeToString(header.Hash().Bytes()),
+		BlockTimestamp: header.Time,
+	}
+}
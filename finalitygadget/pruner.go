@@ -0,0 +1,193 @@
+package finalitygadget
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/babylonlabs-io/finality-gadget/types"
+	ethrpc "github.com/ethereum/go-ethereum/rpc"
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultPruneInterval is the interval blocks are pruned at if not configured
+	defaultPruneInterval = time.Minute
+	// pruneBatchSize is the max number of blocks pruned in a single db transaction, so that pruning a large
+	// backlog of blocks doesn't block inserting new blocks for long
+	pruneBatchSize uint64 = 1000
+)
+
+/* PruneBlocks prunes the finalized blocks outside of the retention policy every prune interval until
+ * the context is cancelled. It returns immediately in the archive mode, which keeps all blocks.
+ *
+ * - blocks are pruned from the earliest block up to the retention height, in batches
+ * - every batch advances the earliest block atomically with the removal of its blocks, so queries
+ *   relying on the earliest block, like QueryBlockRangeBabylonFinalized, stay consistent
+ * - the latest finalized block is never pruned, so block processing resumes after it
+ * - blocks rolled back by a reorg while pruning stop the current pruning, which resumes at the next
+ *   interval
+ * - errors are logged and pruning is retried at the next interval
+ */
+func (fg *FinalityGadget) PruneBlocks(ctx context.Context) {
+	if fg.retention.IsArchive() {
+		return
+	}
+	interval := fg.pruneInterval
+	if interval <= 0 {
+		interval = defaultPruneInterval
+	}
+	fg.logger.Info("Pruning blocks outside of the retention policy",
+		zap.String("retention_mode", string(fg.retention.Mode)),
+		zap.Duration("prune_interval", interval),
+	)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := fg.pruneBlocks(ctx); err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				fg.logger.Error("Failed to prune blocks", zap.Error(err))
+			}
+		}
+	}
+}
+
+//////////////////////////////
+// INTERNAL
+//////////////////////////////
+
+// pruneBlocks prunes the blocks below the retention height in batches, and returns the number of pruned blocks
+func (fg *FinalityGadget) pruneBlocks(ctx context.Context) (uint64, error) {
+	earliestBlock, err := fg.db.QueryEarliestFinalizedBlock()
+	if err != nil {
+		if errors.Is(err, types.ErrBlockNotFound) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("error fetching earliest finalized block from db: %w", err)
+	}
+	retentionHeight, err := fg.retentionHeight(ctx, earliestBlock)
+	if err != nil {
+		return 0, err
+	}
+	if retentionHeight <= earliestBlock.BlockHeight {
+		return 0, nil
+	}
+
+	var pruned uint64
+	for height := earliestBlock.BlockHeight; height < retentionHeight; {
+		if err := ctx.Err(); err != nil {
+			return pruned, err
+		}
+		height = min(height+pruneBatchSize, retentionHeight)
+		batchPruned, err := fg.db.PruneBlocks(height)
+		if err != nil {
+			return pruned, fmt.Errorf("error pruning blocks below %d: %w", height, err)
+		}
+		pruned += batchPruned
+		// the blocks were rolled back by a reorg since the retention height was computed
+		if batchPruned == 0 {
+			break
+		}
+	}
+	if pruned == 0 {
+		return 0, nil
+	}
+	fg.metrics.AddPrunedBlocks(pruned)
+
+	earliestBlock, err = fg.db.QueryEarliestFinalizedBlock()
+	if err != nil {
+		if errors.Is(err, types.ErrBlockNotFound) {
+			fg.logger.Info("Pruned blocks, no block left after a rollback", zap.Uint64("pruned_num", pruned))
+			return pruned, nil
+		}
+		return pruned, fmt.Errorf("error fetching earliest finalized block from db: %w", err)
+	}
+	fg.metrics.SetEarliestBlock(earliestBlock.BlockHeight)
+	fg.logger.Info("Pruned blocks",
+		zap.Uint64("pruned_num", pruned),
+		zap.Uint64("earliest_block_height", earliestBlock.BlockHeight),
+	)
+	return pruned, nil
+}
+
+/* retentionHeight returns the height of the earliest block kept by the retention policy, the blocks below it
+ * are pruned. 0 if all blocks are kept.
+ *
+ * - blocks: the last N blocks up to the latest finalized block are kept
+ * - age: the blocks with a timestamp within the retention age of the current time are kept. block
+ *   timestamps increase with the height, so the earliest one is found by binary search
+ * - eth-finalized: the blocks above the latest ETH finalized block minus the margin are kept
+ */
+func (fg *FinalityGadget) retentionHeight(ctx context.Context, earliestBlock *types.Block) (uint64, error) {
+	switch fg.retention.Mode {
+	case types.RetentionModeBlocks:
+		latestBlock, err := fg.db.QueryLatestFinalizedBlock()
+		if err != nil {
+			return 0, fmt.Errorf("error fetching latest finalized block from db: %w", err)
+		}
+		if latestBlock == nil || latestBlock.BlockHeight < fg.retention.Blocks {
+			return 0, nil
+		}
+		return latestBlock.BlockHeight + 1 - fg.retention.Blocks, nil
+	case types.RetentionModeAge:
+		cutoff := time.Now().Add(-fg.retention.Age).Unix()
+		if cutoff <= 0 {
+			return 0, nil
+		}
+		return fg.firstBlockAtOrAfter(earliestBlock, uint64(cutoff))
+	case types.RetentionModeEthFinalized:
+		finalizedHeader, err := fg.l2Client.HeaderByNumber(ctx, big.NewInt(ethrpc.FinalizedBlockNumber.Int64()))
+		if err != nil {
+			return 0, fmt.Errorf("error fetching latest ETH finalized block: %w", err)
+		}
+		finalizedHeight := finalizedHeader.Number.Uint64()
+		if finalizedHeight < fg.retention.EthFinalizedMargin {
+			return 0, nil
+		}
+		return finalizedHeight - fg.retention.EthFinalizedMargin, nil
+	default:
+		return 0, nil
+	}
+}
+
+// firstBlockAtOrAfter returns the height of the first stored block with a timestamp at or after the given
+// timestamp, or the height after the latest block if all blocks are older
+func (fg *FinalityGadget) firstBlockAtOrAfter(earliestBlock *types.Block, timestamp uint64) (uint64, error) {
+	latestBlock, err := fg.db.QueryLatestFinalizedBlock()
+	if err != nil {
+		return 0, fmt.Errorf("error fetching latest finalized block from db: %w", err)
+	}
+	if latestBlock == nil {
+		return 0, nil
+	}
+
+	// search [low, high) for the first block at or after the timestamp
+	low, high := earliestBlock.BlockHeight, latestBlock.BlockHeight+1
+	for low < high {
+		mid := low + (high-low)/2
+		block, err := fg.db.GetBlockByHeight(mid)
+		if err != nil {
+			// the blocks were rolled back by a reorg while searching, they are pruned at the next interval
+			if errors.Is(err, types.ErrBlockNotFound) {
+				return 0, nil
+			}
+			return 0, fmt.Errorf("error fetching block %d from db: %w", mid, err)
+		}
+		if block.BlockTimestamp >= timestamp {
+			high = mid
+		} else {
+			low = mid + 1
+		}
+	}
+	return low, nil
+}
@@ -0,0 +1,249 @@
+package finalitygadget
+
+import (
+	"context"
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/babylonlabs-io/finality-gadget/db"
+	"github.com/babylonlabs-io/finality-gadget/testutil/mocks"
+	"github.com/babylonlabs-io/finality-gadget/types"
+	eth "github.com/ethereum/go-ethereum/core/types"
+	ethrpc "github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+)
+
+func TestPruneBlocksRetainsLastBlocks(t *testing.T) {
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+
+	// the last 3 blocks up to block 2500 are kept, blocks 1 to 2497 are pruned in batches
+	mockDbHandler := mocks.NewMockIDatabaseHandler(ctl)
+	gomock.InOrder(
+		mockDbHandler.EXPECT().QueryEarliestFinalizedBlock().Return(&types.Block{BlockHeight: 1}, nil),
+		mockDbHandler.EXPECT().QueryLatestFinalizedBlock().Return(&types.Block{BlockHeight: 2500}, nil),
+		mockDbHandler.EXPECT().PruneBlocks(uint64(1001)).Return(uint64(1000), nil),
+		mockDbHandler.EXPECT().PruneBlocks(uint64(2001)).Return(uint64(1000), nil),
+		mockDbHandler.EXPECT().PruneBlocks(uint64(2498)).Return(uint64(497), nil),
+		mockDbHandler.EXPECT().QueryEarliestFinalizedBlock().Return(&types.Block{BlockHeight: 2498}, nil),
+	)
+
+	mockFinalityGadget := &FinalityGadget{
+		db:        mockDbHandler,
+		retention: types.RetentionPolicy{Mode: types.RetentionModeBlocks, Blocks: 3},
+		logger:    zap.NewNop(),
+	}
+
+	pruned, err := mockFinalityGadget.pruneBlocks(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, uint64(2497), pruned)
+}
+
+func TestPruneBlocksWithinRetention(t *testing.T) {
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+
+	// blocks 95 to 100 are all within the last 10 blocks
+	mockDbHandler := mocks.NewMockIDatabaseHandler(ctl)
+	mockDbHandler.EXPECT().QueryEarliestFinalizedBlock().Return(&types.Block{BlockHeight: 95}, nil).Times(1)
+	mockDbHandler.EXPECT().QueryLatestFinalizedBlock().Return(&types.Block{BlockHeight: 100}, nil).Times(1)
+
+	mockFinalityGadget := &FinalityGadget{
+		db:        mockDbHandler,
+		retention: types.RetentionPolicy{Mode: types.RetentionModeBlocks, Blocks: 10},
+		logger:    zap.NewNop(),
+	}
+
+	pruned, err := mockFinalityGadget.pruneBlocks(context.Background())
+	require.NoError(t, err)
+	require.Zero(t, pruned)
+}
+
+func TestPruneBlocksDuringRollback(t *testing.T) {
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+
+	// all blocks are rolled back by a reorg after the first batch is pruned
+	mockDbHandler := mocks.NewMockIDatabaseHandler(ctl)
+	gomock.InOrder(
+		mockDbHandler.EXPECT().QueryEarliestFinalizedBlock().Return(&types.Block{BlockHeight: 1}, nil),
+		mockDbHandler.EXPECT().QueryLatestFinalizedBlock().Return(&types.Block{BlockHeight: 2500}, nil),
+		mockDbHandler.EXPECT().PruneBlocks(uint64(1001)).Return(uint64(1000), nil),
+		mockDbHandler.EXPECT().PruneBlocks(uint64(2001)).Return(uint64(0), nil),
+		mockDbHandler.EXPECT().QueryEarliestFinalizedBlock().Return(nil, types.ErrBlockNotFound),
+	)
+
+	mockFinalityGadget := &FinalityGadget{
+		db:        mockDbHandler,
+		retention: types.RetentionPolicy{Mode: types.RetentionModeBlocks, Blocks: 3},
+		logger:    zap.NewNop(),
+	}
+
+	pruned, err := mockFinalityGadget.pruneBlocks(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, uint64(1000), pruned)
+}
+
+func TestPruneBlocksWithEmptyDb(t *testing.T) {
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+
+	mockDbHandler := mocks.NewMockIDatabaseHandler(ctl)
+	mockDbHandler.EXPECT().QueryEarliestFinalizedBlock().Return(nil, types.ErrBlockNotFound).Times(1)
+
+	mockFinalityGadget := &FinalityGadget{
+		db:        mockDbHandler,
+		retention: types.RetentionPolicy{Mode: types.RetentionModeBlocks, Blocks: 10},
+		logger:    zap.NewNop(),
+	}
+
+	pruned, err := mockFinalityGadget.pruneBlocks(context.Background())
+	require.NoError(t, err)
+	require.Zero(t, pruned)
+}
+
+func TestPruneBlocksByAge(t *testing.T) {
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+
+	// blocks 1 to 10 are 10 seconds apart, blocks 1 to 5 are more than 50 seconds old
+	now := uint64(time.Now().Unix())
+	blocks := make(map[uint64]*types.Block)
+	for height := uint64(1); height <= 10; height++ {
+		blocks[height] = &types.Block{BlockHeight: height, BlockTimestamp: now - 105 + 10*height}
+	}
+
+	mockDbHandler := mocks.NewMockIDatabaseHandler(ctl)
+	gomock.InOrder(
+		mockDbHandler.EXPECT().QueryEarliestFinalizedBlock().Return(blocks[1], nil),
+		mockDbHandler.EXPECT().QueryLatestFinalizedBlock().Return(blocks[10], nil),
+		mockDbHandler.EXPECT().PruneBlocks(uint64(6)).Return(uint64(5), nil),
+		mockDbHandler.EXPECT().QueryEarliestFinalizedBlock().Return(blocks[6], nil),
+	)
+	mockDbHandler.EXPECT().GetBlockByHeight(gomock.Any()).DoAndReturn(func(height uint64) (*types.Block, error) {
+		return blocks[height], nil
+	}).AnyTimes()
+
+	mockFinalityGadget := &FinalityGadget{
+		db:        mockDbHandler,
+		retention: types.RetentionPolicy{Mode: types.RetentionModeAge, Age: 50 * time.Second},
+		logger:    zap.NewNop(),
+	}
+
+	pruned, err := mockFinalityGadget.pruneBlocks(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, uint64(5), pruned)
+}
+
+func TestPruneBlocksBelowEthFinalized(t *testing.T) {
+	testCases := []struct {
+		name            string
+		margin          uint64
+		retentionHeight uint64
+	}{
+		{name: "with margin", margin: 10, retentionHeight: 90},
+		{name: "without margin", margin: 0, retentionHeight: 100},
+		{name: "margin above eth finalized block", margin: 200, retentionHeight: 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctl := gomock.NewController(t)
+			defer ctl.Finish()
+
+			mockL2Client := mocks.NewMockIEthL2Client(ctl)
+			mockL2Client.EXPECT().
+				HeaderByNumber(gomock.Any(), big.NewInt(ethrpc.FinalizedBlockNumber.Int64())).
+				Return(&eth.Header{Number: big.NewInt(100)}, nil).
+				Times(1)
+
+			mockDbHandler := mocks.NewMockIDatabaseHandler(ctl)
+			mockDbHandler.EXPECT().QueryEarliestFinalizedBlock().Return(&types.Block{BlockHeight: 50}, nil).Times(1)
+			if tc.retentionHeight > 0 {
+				mockDbHandler.EXPECT().PruneBlocks(tc.retentionHeight).Return(tc.retentionHeight-50, nil).Times(1)
+				mockDbHandler.EXPECT().QueryEarliestFinalizedBlock().Return(&types.Block{BlockHeight: tc.retentionHeight}, nil).Times(1)
+			}
+
+			mockFinalityGadget := &FinalityGadget{
+				db:        mockDbHandler,
+				l2Client:  mockL2Client,
+				retention: types.RetentionPolicy{Mode: types.RetentionModeEthFinalized, EthFinalizedMargin: tc.margin},
+				logger:    zap.NewNop(),
+			}
+
+			_, err := mockFinalityGadget.pruneBlocks(context.Background())
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestQueryBlockRangeAcrossPrunedBlocks(t *testing.T) {
+	handler, err := db.NewBBoltHandler(filepath.Join(t.TempDir(), "finality-gadget.db"), zap.NewNop())
+	require.NoError(t, err)
+	defer handler.Close()
+	require.NoError(t, handler.CreateInitialSchema())
+
+	var blocks []*types.Block
+	for height := uint64(1); height <= 10; height++ {
+		blocks = append(blocks, &types.Block{BlockHeight: height, BlockHash: big.NewInt(int64(height)).String(), BlockTimestamp: height})
+	}
+	require.NoError(t, handler.InsertBlocks(blocks))
+
+	// only the last 4 blocks are kept
+	fg := &FinalityGadget{
+		db:        handler,
+		retention: types.RetentionPolicy{Mode: types.RetentionModeBlocks, Blocks: 4},
+		logger:    zap.NewNop(),
+	}
+	pruned, err := fg.pruneBlocks(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, uint64(6), pruned)
+	earliest, err := handler.QueryEarliestFinalizedBlock()
+	require.NoError(t, err)
+	require.Equal(t, uint64(7), earliest.BlockHeight)
+
+	// ranges starting at pruned heights are still finalized up to the latest block
+	finalized, err := fg.QueryBlockRangeBabylonFinalized(blocks[2:8])
+	require.NoError(t, err)
+	require.Equal(t, uint64(8), *finalized)
+	finalized, err = fg.QueryBlockRangeBabylonFinalized(blocks[:3])
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), *finalized)
+	finalized, err = fg.QueryBlockRangeBabylonFinalized([]*types.Block{{BlockHeight: 10}, {BlockHeight: 11}})
+	require.NoError(t, err)
+	require.Nil(t, finalized)
+
+	isFinalized, err := fg.QueryIsBlockFinalizedByHeight(2)
+	require.NoError(t, err)
+	require.True(t, isFinalized)
+	isFinalized, err = fg.QueryIsBlockFinalizedByHeight(11)
+	require.NoError(t, err)
+	require.False(t, isFinalized)
+}
+
+func TestPruneBlocksArchive(t *testing.T) {
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+
+	// the archive mode keeps all blocks, so the pruner returns without touching the db
+	mockFinalityGadget := &FinalityGadget{
+		db:        mocks.NewMockIDatabaseHandler(ctl),
+		retention: types.RetentionPolicy{Mode: types.RetentionModeArchive},
+		logger:    zap.NewNop(),
+	}
+
+	done := make(chan struct{})
+	go func() {
+		mockFinalityGadget.PruneBlocks(context.Background())
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("pruner didn't return in archive mode")
+	}
+}
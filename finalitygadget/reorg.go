@@ -0,0 +1,168 @@
+package finalitygadget
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+
+	"github.com/babylonlabs-io/finality-gadget/types"
+	"github.com/ethereum/go-ethereum"
+	"go.uber.org/zap"
+)
+
+/* detectAndHandleReorg checks the locally stored chain against the L2 RPC and rolls the db back
+ * to the fork point if they diverge
+ *
+ * - compare the hash of the latest stored block against the L2 block at the same height
+ * - if they match, there is nothing to do
+ * - else, binary search the highest stored block matching the L2 chain (if any), and roll back all
+ *   blocks above that fork point. stored blocks are consecutive and linked by their parent hashes, so
+ *   the blocks up to the fork point match the L2 chain and the blocks above it don't
+ *
+ * returns the reorg event if a rollback was performed, nil otherwise
+ */
+func (fg *FinalityGadget) detectAndHandleReorg(ctx context.Context) (*types.ReorgEvent, error) {
+	latestBlock, err := fg.db.QueryLatestFinalizedBlock()
+	if err != nil {
+		if errors.Is(err, types.ErrBlockNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error fetching latest finalized block from db: %w", err)
+	}
+	if latestBlock == nil {
+		return nil, nil
+	}
+
+	isCanonical, err := fg.isCanonicalBlock(ctx, latestBlock)
+	if err != nil {
+		return nil, err
+	}
+	if isCanonical {
+		return nil, nil
+	}
+
+	earliestBlock, err := fg.db.QueryEarliestFinalizedBlock()
+	if err != nil {
+		return nil, fmt.Errorf("error fetching earliest finalized block from db: %w", err)
+	}
+
+	// search [low, high) for the first stored block not matching the L2 chain, the latest block doesn't
+	// match. if no stored block matches, roll back everything
+	low, high := earliestBlock.BlockHeight, latestBlock.BlockHeight
+	for low < high {
+		mid := low + (high-low)/2
+		storedBlock, err := fg.db.GetBlockByHeight(mid)
+		if err != nil {
+			return nil, fmt.Errorf("error fetching block %d from db: %w", mid, err)
+		}
+		isCanonical, err := fg.isCanonicalBlock(ctx, storedBlock)
+		if err != nil {
+			return nil, err
+		}
+		if isCanonical {
+			low = mid + 1
+		} else {
+			high = mid
+		}
+	}
+	var forkHeight uint64
+	if low > 0 {
+		forkHeight = low - 1
+	}
+
+	event := &types.ReorgEvent{
+		ForkHeight:    forkHeight,
+		OldTipHeight:  latestBlock.BlockHeight,
+		OldTipHash:    latestBlock.BlockHash,
+		RolledBackNum: latestBlock.BlockHeight - forkHeight,
+	}
+	if err := fg.rollbackToHeight(forkHeight); err != nil {
+		return nil, err
+	}
+	fg.events.publish(&types.Event{Type: types.EventTypeReorg, Reorg: event})
+
+	fg.logger.Warn(
+		"L2 reorg detected, rolled back finalized blocks",
+		zap.Uint64("fork_height", event.ForkHeight),
+		zap.Uint64("old_tip_height", event.OldTipHeight),
+		zap.String("old_tip_hash", event.OldTipHash),
+		zap.Uint64("rolled_back_num", event.RolledBackNum),
+	)
+
+	return event, nil
+}
+
+// verifyChainContinuity checks that the given consecutive blocks link to each other and to the
+// latest stored block via their parent hashes. Blocks without a parent hash (e.g. stored by an
+// older version) are not checked.
+func (fg *FinalityGadget) verifyChainContinuity(blocks []*types.Block) error {
+	if len(blocks) == 0 {
+		return nil
+	}
+
+	// check the first block extends the stored chain
+	first := blocks[0]
+	if first.BlockHeight > 0 {
+		prevBlock, err := fg.db.GetBlockByHeight(first.BlockHeight - 1)
+		if err != nil && !errors.Is(err, types.ErrBlockNotFound) {
+			return fmt.Errorf("error fetching block %d from db: %w", first.BlockHeight-1, err)
+		}
+		if prevBlock != nil && !isParentOf(prevBlock, first) {
+			return fmt.Errorf("%w: block %d has parent hash %s, stored block has hash %s",
+				types.ErrChainDiscontinuity, first.BlockHeight, first.ParentHash, prevBlock.BlockHash)
+		}
+	}
+
+	// check the blocks link to each other
+	for i := 1; i < len(blocks); i++ {
+		if !isParentOf(blocks[i-1], blocks[i]) {
+			return fmt.Errorf("%w: block %d has parent hash %s, block %d has hash %s",
+				types.ErrChainDiscontinuity, blocks[i].BlockHeight, blocks[i].ParentHash, blocks[i-1].BlockHeight, blocks[i-1].BlockHash)
+		}
+	}
+
+	return nil
+}
+
+//////////////////////////////
+// INTERNAL
+//////////////////////////////
+
+// isCanonicalBlock returns whether the given stored block matches the L2 block at the same height. It
+// doesn't if the L2 chain reorged to a chain shorter than the block height.
+func (fg *FinalityGadget) isCanonicalBlock(ctx context.Context, block *types.Block) (bool, error) {
+	if block.BlockHeight > math.MaxInt64 {
+		return false, fmt.Errorf("block height %d exceeds maximum int64 value", block.BlockHeight)
+	}
+	header, err := fg.l2Client.HeaderByNumber(ctx, big.NewInt(int64(block.BlockHeight)))
+	if err != nil {
+		if errors.Is(err, ethereum.NotFound) {
+			return false, nil
+		}
+		return false, fmt.Errorf("error fetching L2 block %d: %w", block.BlockHeight, err)
+	}
+	return normalizeBlockHash(hex.EncodeToString(header.Hash().Bytes())) == normalizeBlockHash(block.BlockHash), nil
+}
+
+func (fg *FinalityGadget) rollbackToHeight(height uint64) error {
+	fg.mutex.Lock()
+	defer fg.mutex.Unlock()
+
+	if err := fg.db.RollbackToHeight(height); err != nil {
+		return fmt.Errorf("failed to roll back blocks: %w", err)
+	}
+	if fg.lastProcessedHeight > height {
+		fg.lastProcessedHeight = height
+	}
+	return nil
+}
+
+func isParentOf(parent, child *types.Block) bool {
+	if parent.BlockHash == "" || child.ParentHash == "" {
+		return true
+	}
+	return normalizeBlockHash(parent.BlockHash) == normalizeBlockHash(child.ParentHash)
+}
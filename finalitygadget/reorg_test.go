@@ -0,0 +1,271 @@
+package finalitygadget
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/babylonlabs-io/finality-gadget/testutil/mocks"
+	"github.com/babylonlabs-io/finality-gadget/types"
+	"github.com/ethereum/go-ethereum"
+	eth "github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+)
+
+func TestDetectAndHandleReorg(t *testing.T) {
+	// chain A is the chain stored in the db, chain B forks off chain A after `forkHeight`
+	const earliestHeight, latestHeight = uint64(3), uint64(8)
+
+	testCases := []struct {
+		name       string
+		forkHeight uint64
+		// l2Height is the height of the L2 chain B, latestHeight if 0
+		l2Height       uint64
+		expectRollback bool
+	}{
+		{
+			name:           "no reorg",
+			forkHeight:     latestHeight,
+			expectRollback: false,
+		},
+		{
+			name:           "reorg of the latest block",
+			forkHeight:     latestHeight - 1,
+			expectRollback: true,
+		},
+		{
+			name:           "reorg of multiple blocks",
+			forkHeight:     5,
+			expectRollback: true,
+		},
+		{
+			name:           "reorg of all stored blocks",
+			forkHeight:     earliestHeight - 1,
+			expectRollback: true,
+		},
+		{
+			name:           "reorg to a shorter chain",
+			forkHeight:     5,
+			l2Height:       6,
+			expectRollback: true,
+		},
+		{
+			name:           "reorg to a chain below the stored blocks",
+			forkHeight:     earliestHeight - 1,
+			l2Height:       earliestHeight - 1,
+			expectRollback: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctl := gomock.NewController(t)
+			defer ctl.Finish()
+
+			chainA := genL2Headers(1, latestHeight, nil)
+			l2Height := tc.l2Height
+			if l2Height == 0 {
+				l2Height = latestHeight
+			}
+			chainB := genL2Headers(tc.forkHeight+1, l2Height, chainA[tc.forkHeight])
+			for h := uint64(1); h <= tc.forkHeight; h++ {
+				chainB[h] = chainA[h]
+			}
+
+			storedBlocks := make(map[uint64]*types.Block)
+			for h := earliestHeight; h <= latestHeight; h++ {
+				storedBlocks[h] = headerToBlock(chainA[h])
+			}
+
+			mockDbHandler := mocks.NewMockIDatabaseHandler(ctl)
+			mockDbHandler.EXPECT().QueryLatestFinalizedBlock().Return(storedBlocks[latestHeight], nil).Times(1)
+			mockDbHandler.EXPECT().QueryEarliestFinalizedBlock().Return(storedBlocks[earliestHeight], nil).AnyTimes()
+			mockDbHandler.EXPECT().GetBlockByHeight(gomock.Any()).DoAndReturn(func(height uint64) (*types.Block, error) {
+				if block, ok := storedBlocks[height]; ok {
+					return block, nil
+				}
+				return nil, types.ErrBlockNotFound
+			}).AnyTimes()
+			if tc.expectRollback {
+				mockDbHandler.EXPECT().RollbackToHeight(tc.forkHeight).Return(nil).Times(1)
+			}
+
+			mockL2Client := mocks.NewMockIEthL2Client(ctl)
+			mockL2Client.EXPECT().HeaderByNumber(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, number *big.Int) (*eth.Header, error) {
+				if header, ok := chainB[number.Uint64()]; ok {
+					return header, nil
+				}
+				return nil, ethereum.NotFound
+			}).AnyTimes()
+
+			mockFinalityGadget := &FinalityGadget{
+				db:                  mockDbHandler,
+				l2Client:            mockL2Client,
+				logger:              zap.NewNop(),
+				lastProcessedHeight: latestHeight,
+			}
+
+			event, err := mockFinalityGadget.detectAndHandleReorg(context.Background())
+			require.NoError(t, err)
+			if !tc.expectRollback {
+				require.Nil(t, event)
+				require.Equal(t, latestHeight, mockFinalityGadget.lastProcessedHeight)
+				return
+			}
+
+			require.NotNil(t, event)
+			require.Equal(t, tc.forkHeight, event.ForkHeight)
+			require.Equal(t, latestHeight, event.OldTipHeight)
+			require.Equal(t, storedBlocks[latestHeight].BlockHash, event.OldTipHash)
+			require.Equal(t, latestHeight-tc.forkHeight, event.RolledBackNum)
+			require.Equal(t, tc.forkHeight, mockFinalityGadget.lastProcessedHeight)
+		})
+	}
+}
+
+func TestDetectAndHandleDeepReorg(t *testing.T) {
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+
+	// 10000 blocks are stored, the L2 chain forks off after block 1234
+	const latestHeight, forkHeight = uint64(10000), uint64(1234)
+	chainA := genL2Headers(1, latestHeight, nil)
+	chainB := genL2Headers(forkHeight+1, latestHeight, chainA[forkHeight])
+	for h := uint64(1); h <= forkHeight; h++ {
+		chainB[h] = chainA[h]
+	}
+
+	mockDbHandler := mocks.NewMockIDatabaseHandler(ctl)
+	mockDbHandler.EXPECT().QueryLatestFinalizedBlock().Return(headerToBlock(chainA[latestHeight]), nil).Times(1)
+	mockDbHandler.EXPECT().QueryEarliestFinalizedBlock().Return(headerToBlock(chainA[1]), nil).Times(1)
+	mockDbHandler.EXPECT().GetBlockByHeight(gomock.Any()).DoAndReturn(func(height uint64) (*types.Block, error) {
+		return headerToBlock(chainA[height]), nil
+	}).AnyTimes()
+	mockDbHandler.EXPECT().RollbackToHeight(forkHeight).Return(nil).Times(1)
+
+	// the fork point is found with a number of L2 queries logarithmic in the number of stored blocks
+	var queries int
+	mockL2Client := mocks.NewMockIEthL2Client(ctl)
+	mockL2Client.EXPECT().HeaderByNumber(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, number *big.Int) (*eth.Header, error) {
+		queries++
+		return chainB[number.Uint64()], nil
+	}).AnyTimes()
+
+	mockFinalityGadget := &FinalityGadget{
+		db:       mockDbHandler,
+		l2Client: mockL2Client,
+		logger:   zap.NewNop(),
+	}
+
+	event, err := mockFinalityGadget.detectAndHandleReorg(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, forkHeight, event.ForkHeight)
+	require.LessOrEqual(t, queries, 16)
+}
+
+func TestDetectAndHandleReorgWithEmptyDb(t *testing.T) {
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+
+	mockDbHandler := mocks.NewMockIDatabaseHandler(ctl)
+	mockDbHandler.EXPECT().QueryLatestFinalizedBlock().Return(nil, nil).Times(1)
+
+	mockFinalityGadget := &FinalityGadget{
+		db:     mockDbHandler,
+		logger: zap.NewNop(),
+	}
+
+	event, err := mockFinalityGadget.detectAndHandleReorg(context.Background())
+	require.NoError(t, err)
+	require.Nil(t, event)
+}
+
+func TestVerifyChainContinuity(t *testing.T) {
+	headers := genL2Headers(1, 5, nil)
+	forkHeaders := genL2Headers(3, 5, headers[1])
+
+	stored := headerToBlock(headers[2])
+	testCases := []struct {
+		expectedErr error
+		name        string
+		blocks      []*types.Block
+	}{
+		{
+			name:        "blocks extend the stored chain",
+			blocks:      []*types.Block{headerToBlock(headers[3]), headerToBlock(headers[4]), headerToBlock(headers[5])},
+			expectedErr: nil,
+		},
+		{
+			name:        "first block does not extend the stored chain",
+			blocks:      []*types.Block{headerToBlock(forkHeaders[3]), headerToBlock(forkHeaders[4])},
+			expectedErr: types.ErrChainDiscontinuity,
+		},
+		{
+			name:        "blocks do not link to each other",
+			blocks:      []*types.Block{headerToBlock(headers[3]), headerToBlock(forkHeaders[4])},
+			expectedErr: types.ErrChainDiscontinuity,
+		},
+		{
+			name: "blocks without parent hash are not checked",
+			blocks: []*types.Block{
+				{BlockHeight: 3, BlockHash: "0x123"},
+				{BlockHeight: 4, BlockHash: "0x456"},
+			},
+			expectedErr: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctl := gomock.NewController(t)
+			defer ctl.Finish()
+
+			mockDbHandler := mocks.NewMockIDatabaseHandler(ctl)
+			mockDbHandler.EXPECT().GetBlockByHeight(stored.BlockHeight).Return(stored, nil).Times(1)
+
+			mockFinalityGadget := &FinalityGadget{
+				db:     mockDbHandler,
+				logger: zap.NewNop(),
+			}
+
+			err := mockFinalityGadget.verifyChainContinuity(tc.blocks)
+			if tc.expectedErr == nil {
+				require.NoError(t, err)
+			} else {
+				require.True(t, errors.Is(err, tc.expectedErr))
+			}
+		})
+	}
+}
+
+// genL2Headers generates a chain of L2 headers from `from` to `to` (inclusive) on top of `parent`
+func genL2Headers(from, to uint64, parent *eth.Header) map[uint64]*eth.Header {
+	headers := make(map[uint64]*eth.Header)
+	// use a different time offset for each fork so forks get different hashes
+	offset := from * 1000
+	for h := from; h <= to; h++ {
+		header := &eth.Header{
+			Number: new(big.Int).SetUint64(h),
+			Time:   offset + h,
+		}
+		if parent != nil {
+			header.ParentHash = parent.Hash()
+		}
+		headers[h] = header
+		parent = header
+	}
+	return headers
+}
+
+func headerToBlock(header *eth.Header) *types.Block {
+	return normalizedBlock(&types.Block{
+		BlockHeight:    header.Number.Uint64(),
+		BlockHash:      hex.EncodeToString(header.Hash().Bytes()),
+		BlockTimestamp: header.Time,
+		ParentHash:     hex.EncodeToString(header.ParentHash.Bytes()),
+	})
+}
@@ -0,0 +1,238 @@
+package finalitygadget
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/babylonlabs-io/finality-gadget/cwclient"
+	"github.com/babylonlabs-io/finality-gadget/ethl2client"
+	"github.com/babylonlabs-io/finality-gadget/types"
+	"github.com/ethereum/go-ethereum"
+	ethrpc "github.com/ethereum/go-ethereum/rpc"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// transientErrorMessages are matched against errors that don't expose their cause, e.g. errors
+// flattened to strings by the Babylon and CosmWasm clients
+var transientErrorMessages = []string{
+	"connection refused",
+	"connection reset",
+	"broken pipe",
+	"no such host",
+	"timeout",
+	"timed out",
+	"eof",
+	"too many requests",
+	"service unavailable",
+	"bad gateway",
+	"gateway timeout",
+}
+
+// errorClass is how the block processing loop handles an error
+type errorClass string
+
+const (
+	// errorClassTransient errors are caused by the upstream nodes being unreachable, overloaded or
+	// lagging, and are retried with backoff
+	errorClassTransient errorClass = "transient"
+	// errorClassFatal errors, such as db failures, can't be fixed by retrying and stop the loop
+	errorClassFatal errorClass = "fatal"
+)
+
+/* classifyError returns whether the error is transient or fatal
+ *
+ * - network errors, timeouts and unexpected EOFs are transient
+ * - L2 RPC errors are transient: missing blocks (the node is lagging), JSON-RPC error responses,
+ *   and 408, 429 and 5xx HTTP statuses
+ * - Babylon gRPC errors are transient if the node is unavailable, overloaded or timed out
+ * - the L2 chain reorging while a batch is processed, or the L2 or Babylon nodes disagreeing on a block, is transient
+ * - the BTC header index not being synced yet is transient
+ * - everything else is fatal
+ */
+func classifyError(err error) errorClass {
+	var netErr net.Error
+	var httpErr ethrpc.HTTPError
+	var rpcErr ethrpc.Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded),
+		errors.Is(err, io.EOF),
+		errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, syscall.ECONNREFUSED),
+		errors.Is(err, syscall.ECONNRESET),
+		errors.Is(err, syscall.EPIPE),
+		errors.As(err, &netErr):
+		return errorClassTransient
+	case errors.Is(err, ethereum.NotFound),
+		errors.Is(err, types.ErrChainDiscontinuity),
+		errors.Is(err, types.ErrBtcHeaderNotIndexed),
+		errors.Is(err, ethl2client.ErrNoQuorum),
+		errors.Is(err, cwclient.ErrNoVotersQuorum),
+		errors.As(err, &rpcErr):
+		return errorClassTransient
+	case errors.As(err, &httpErr):
+		if httpErr.StatusCode == http.StatusRequestTimeout || httpErr.StatusCode == http.StatusTooManyRequests || httpErr.StatusCode >= 500 {
+			return errorClassTransient
+		}
+		return errorClassFatal
+	}
+
+	if s, ok := status.FromError(err); ok {
+		switch s.Code() {
+		case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Aborted:
+			return errorClassTransient
+		}
+	}
+
+	msg := strings.ToLower(err.Error())
+	for _, transientMsg := range transientErrorMessages {
+		if strings.Contains(msg, transientMsg) {
+			return errorClassTransient
+		}
+	}
+	return errorClassFatal
+}
+
+/* runWithRetry calls tick every poll interval until it returns done, a fatal error, or the context
+ * is cancelled
+ *
+ * - a heartbeat is recorded before every call, so liveness checks pass while retrying
+ * - transient errors are retried with exponential backoff, capped below the processing stall
+ *   timeout. the loop is reported degraded until a call succeeds again
+ * - fatal errors are returned
+ */
+func (fg *FinalityGadget) runWithRetry(ctx context.Context, name string, tick func(ctx context.Context) (bool, error)) error {
+	backoff := &retryBackoff{initial: fg.pollInterval, max: fg.processingStallTimeout() / 2}
+	timer := time.NewTimer(fg.pollInterval)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			fg.logger.Debug("Exiting loop...", zap.String("loop", name))
+			return nil
+		case <-timer.C:
+			fg.heartbeat()
+			done, err := tick(ctx)
+			if err != nil && ctx.Err() != nil {
+				// errors caused by the cancellation are not failures
+				return nil
+			}
+			if err != nil {
+				class := classifyError(err)
+				fg.metrics.IncBlockProcessingErrors(string(class))
+				if class == errorClassFatal {
+					return err
+				}
+				failures := fg.processing.recordFailure(err)
+				fg.metrics.SetBlockProcessingDegraded(true)
+				delay := backoff.next()
+				fg.logger.Warn("Transient error, retrying",
+					zap.String("loop", name),
+					zap.Int("consecutive_failures", failures),
+					zap.Duration("retry_in", delay),
+					zap.Error(err),
+				)
+				timer.Reset(delay)
+				continue
+			}
+
+			backoff.reset()
+			if fg.processing.recordSuccess() {
+				fg.logger.Info("Recovered from transient errors", zap.String("loop", name))
+				fg.metrics.SetBlockProcessingDegraded(false)
+			}
+			if done {
+				return nil
+			}
+			timer.Reset(fg.pollInterval)
+		}
+	}
+}
+
+// retryBackoff computes exponentially growing delays between the retries of a failing operation,
+// with jitter so that gadgets sharing upstream nodes don't retry in lockstep
+type retryBackoff struct {
+	initial time.Duration
+	max     time.Duration
+	attempt uint
+}
+
+// next returns the delay before the next retry, between half and all of the exponential delay
+func (b *retryBackoff) next() time.Duration {
+	delay := b.max
+	if b.attempt < 32 && b.initial<<b.attempt < b.max {
+		delay = b.initial << b.attempt
+		b.attempt++
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+func (b *retryBackoff) reset() {
+	b.attempt = 0
+}
+
+// processingStatus tracks the transient errors of the startup and block processing loops. The
+// loops are degraded from their first transient error until they succeed again, during which
+// queries are still served from the db.
+type processingStatus struct {
+	lastErr       error
+	degradedSince time.Time
+	failures      int
+	mutex         sync.Mutex
+}
+
+func (s *processingStatus) recordFailure(err error) (failures int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.failures == 0 {
+		s.degradedSince = time.Now()
+	}
+	s.failures++
+	s.lastErr = err
+	return s.failures
+}
+
+// recordSuccess clears the degraded state, and returns whether the loop was degraded
+func (s *processingStatus) recordSuccess() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	wasDegraded := s.failures > 0
+	s.failures = 0
+	s.lastErr = nil
+	return wasDegraded
+}
+
+// degradedErr returns an error describing the degraded state, nil if the loop is not degraded
+func (s *processingStatus) degradedErr() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.failures == 0 {
+		return nil
+	}
+	return &degradedError{fmt.Errorf("degraded for %s after %d consecutive failures, last error: %w",
+		time.Since(s.degradedSince).Truncate(time.Second), s.failures, s.lastErr)}
+}
+
+// degradedError marks a health check as degraded rather than failed
+type degradedError struct {
+	err error
+}
+
+func (e *degradedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *degradedError) Unwrap() error {
+	return e.err
+}
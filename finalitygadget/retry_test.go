@@ -0,0 +1,113 @@
+package finalitygadget
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/babylonlabs-io/finality-gadget/cwclient"
+	"github.com/babylonlabs-io/finality-gadget/ethl2client"
+	"github.com/babylonlabs-io/finality-gadget/types"
+	"github.com/ethereum/go-ethereum"
+	ethrpc "github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// jsonRpcError is a JSON-RPC error response returned by an L2 node
+type jsonRpcError struct{}
+
+func (e *jsonRpcError) Error() string  { return "limit exceeded" }
+func (e *jsonRpcError) ErrorCode() int { return -32005 }
+
+func TestClassifyError(t *testing.T) {
+	for _, tc := range []struct {
+		err      error
+		expected errorClass
+	}{
+		{&url.Error{Op: "Post", URL: "http://l2", Err: &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}}, errorClassTransient},
+		{fmt.Errorf("error fetching latest L2 block: %w", io.ErrUnexpectedEOF), errorClassTransient},
+		{fmt.Errorf("error fetching latest L2 block: %w", context.DeadlineExceeded), errorClassTransient},
+		{fmt.Errorf("error getting block at height 10: %w", ethereum.NotFound), errorClassTransient},
+		{fmt.Errorf("error getting block at height 10: %w", &jsonRpcError{}), errorClassTransient},
+		{ethrpc.HTTPError{StatusCode: 503, Status: "503 Service Unavailable"}, errorClassTransient},
+		{ethrpc.HTTPError{StatusCode: 429, Status: "429 Too Many Requests"}, errorClassTransient},
+		{ethrpc.HTTPError{StatusCode: 401, Status: "401 Unauthorized"}, errorClassFatal},
+		{status.Error(codes.Unavailable, "babylon node unavailable"), errorClassTransient},
+		{status.Error(codes.InvalidArgument, "invalid request"), errorClassFatal},
+		{fmt.Errorf("%w: block 10 has parent hash 0x1", types.ErrChainDiscontinuity), errorClassTransient},
+		{fmt.Errorf("%w: BTC header 10 was rolled back", types.ErrBtcHeaderNotIndexed), errorClassTransient},
+		{fmt.Errorf("%w on block 0xa: at most 1 of 3 endpoints agree, 2 required", ethl2client.ErrNoQuorum), errorClassTransient},
+		{fmt.Errorf("%w at height 10: at most 1 of 3 endpoints agree, 2 required", cwclient.ErrNoVotersQuorum), errorClassTransient},
+		{errors.New("post failed: Post \"http://babylon\": dial tcp: connection refused"), errorClassTransient},
+		{errors.New("failed to batch insert blocks: database not open"), errorClassFatal},
+		{errors.New("BTC staking activated before the first finalized block"), errorClassFatal},
+	} {
+		require.Equal(t, tc.expected, classifyError(tc.err), tc.err.Error())
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	backoff := &retryBackoff{initial: time.Second, max: 10 * time.Second}
+
+	// delays double up to the max, with up to half of each delay as jitter
+	for _, expected := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second} {
+		delay := backoff.next()
+		require.GreaterOrEqual(t, delay, expected/2)
+		require.LessOrEqual(t, delay, expected)
+	}
+
+	backoff.reset()
+	require.LessOrEqual(t, backoff.next(), time.Second)
+}
+
+func TestRunWithRetry(t *testing.T) {
+	fg := &FinalityGadget{pollInterval: time.Millisecond, logger: zap.NewNop()}
+	transientErr := fmt.Errorf("error fetching latest L2 block: %w", io.ErrUnexpectedEOF)
+
+	// transient errors are retried, and the loop is degraded until a call succeeds
+	var calls int
+	var degraded []bool
+	err := fg.runWithRetry(context.Background(), "test", func(ctx context.Context) (bool, error) {
+		degraded = append(degraded, fg.processing.degradedErr() != nil)
+		calls++
+		if calls <= 2 {
+			return false, transientErr
+		}
+		return calls == 4, nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []bool{false, true, true, false}, degraded)
+	require.NotZero(t, fg.lastHeartbeat.Load())
+
+	// the degraded state is reported by the readiness check
+	fg.processing.recordFailure(transientErr)
+	check := runHealthCheck(context.Background(), HealthCheckBlockProcessing, fg.checkBlockProcessing)
+	require.True(t, check.Healthy)
+	require.True(t, check.Degraded)
+	require.Contains(t, check.Error, "after 1 consecutive failures, last error: error fetching latest L2 block: unexpected EOF")
+	fg.processing.recordSuccess()
+
+	// fatal errors are returned
+	fatalErr := errors.New("failed to batch insert blocks: database not open")
+	err = fg.runWithRetry(context.Background(), "test", func(ctx context.Context) (bool, error) {
+		return false, fatalErr
+	})
+	require.Equal(t, fatalErr, err)
+
+	// the loop exits once the context is cancelled, even if the call failed
+	ctx, cancel := context.WithCancel(context.Background())
+	err = fg.runWithRetry(ctx, "test", func(ctx context.Context) (bool, error) {
+		cancel()
+		return false, fatalErr
+	})
+	require.NoError(t, err)
+}
@@ -0,0 +1,335 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+const namespace = "finality_gadget"
+
+// FinalityGadgetMetrics holds the prometheus metrics of the finality gadget daemon.
+// All methods are safe to call on a nil *FinalityGadgetMetrics, in which case nothing is recorded.
+type FinalityGadgetMetrics struct {
+	registry *prometheus.Registry
+
+	latestL2BlockHeight           prometheus.Gauge
+	latestBtcFinalizedBlockHeight prometheus.Gauge
+	finalityLagBlocks             prometheus.Gauge
+	finalityLagSeconds            prometheus.Gauge
+	batchProcessingDuration       prometheus.Histogram
+	rpcRequestDuration            *prometheus.HistogramVec
+	rpcErrors                     *prometheus.CounterVec
+	grpcRequests                  *prometheus.CounterVec
+	blockProcessingDegraded       prometheus.Gauge
+	blockProcessingErrors         *prometheus.CounterVec
+	l2EndpointRequestDuration     *prometheus.HistogramVec
+	l2EndpointErrors              *prometheus.CounterVec
+	l2EndpointHealthy             *prometheus.GaugeVec
+	l2QuorumFailures              prometheus.Counter
+	earliestBlockHeight           prometheus.Gauge
+	prunedBlocks                  prometheus.Counter
+
+	// latest L2 block and latest BTC finalized block, used to compute the finality lag
+	latestBlock    blockInfo
+	finalizedBlock blockInfo
+	mutex          sync.Mutex
+}
+
+type blockInfo struct {
+	height    uint64
+	timestamp uint64
+	set       bool
+}
+
+//////////////////////////////
+// CONSTRUCTOR
+//////////////////////////////
+
+func NewFinalityGadgetMetrics() *FinalityGadgetMetrics {
+	m := &FinalityGadgetMetrics{
+		registry: prometheus.NewRegistry(),
+		latestL2BlockHeight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "latest_l2_block_height",
+			Help:      "Height of the latest L2 block",
+		}),
+		latestBtcFinalizedBlockHeight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "latest_btc_finalized_block_height",
+			Help:      "Height of the latest BTC finalized L2 block",
+		}),
+		finalityLagBlocks: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "finality_lag_blocks",
+			Help:      "Number of L2 blocks between the latest L2 block and the latest BTC finalized block",
+		}),
+		finalityLagSeconds: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "finality_lag_seconds",
+			Help:      "Seconds between the timestamps of the latest L2 block and the latest BTC finalized block",
+		}),
+		batchProcessingDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "batch_processing_duration_seconds",
+			Help:      "Time taken to check the finality of a batch of L2 blocks",
+			Buckets:   prometheus.ExponentialBuckets(0.05, 2, 12),
+		}),
+		rpcRequestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "rpc_request_duration_seconds",
+			Help:      "Latency of RPC requests to the BTC, Babylon, CosmWasm and L2 nodes",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"client", "method"}),
+		rpcErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "rpc_errors_total",
+			Help:      "Number of failed RPC requests to the BTC, Babylon, CosmWasm and L2 nodes",
+		}, []string{"client", "method"}),
+		grpcRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "grpc_requests_total",
+			Help:      "Number of gRPC requests handled by the finality gadget server",
+		}, []string{"method", "code"}),
+		blockProcessingDegraded: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "block_processing_degraded",
+			Help:      "1 while block processing retries transient upstream errors, 0 otherwise",
+		}),
+		blockProcessingErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "block_processing_errors_total",
+			Help:      "Number of block processing errors by class (transient or fatal)",
+		}, []string{"class"}),
+		l2EndpointRequestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "l2_endpoint_request_duration_seconds",
+			Help:      "Latency of RPC requests to each L2 node",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"endpoint", "method"}),
+		l2EndpointErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "l2_endpoint_errors_total",
+			Help:      "Number of failed RPC requests to each L2 node",
+		}, []string{"endpoint", "method"}),
+		l2EndpointHealthy: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "l2_endpoint_healthy",
+			Help:      "1 if the L2 node is healthy, 0 while it is skipped after failing",
+		}, []string{"endpoint"}),
+		l2QuorumFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "l2_quorum_failures_total",
+			Help:      "Number of L2 header reads for which not enough L2 nodes agreed on the block hash",
+		}),
+		earliestBlockHeight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "earliest_block_height",
+			Help:      "Height of the earliest BTC finalized L2 block kept in the db",
+		}),
+		prunedBlocks: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "pruned_blocks_total",
+			Help:      "Number of BTC finalized L2 blocks pruned from the db by the retention policy",
+		}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.latestL2BlockHeight,
+		m.latestBtcFinalizedBlockHeight,
+		m.finalityLagBlocks,
+		m.finalityLagSeconds,
+		m.batchProcessingDuration,
+		m.rpcRequestDuration,
+		m.rpcErrors,
+		m.grpcRequests,
+		m.blockProcessingDegraded,
+		m.blockProcessingErrors,
+		m.l2EndpointRequestDuration,
+		m.l2EndpointErrors,
+		m.l2EndpointHealthy,
+		m.l2QuorumFailures,
+		m.earliestBlockHeight,
+		m.prunedBlocks,
+	)
+
+	return m
+}
+
+//////////////////////////////
+// METHODS
+//////////////////////////////
+
+// Handler returns the http handler serving the metrics in the prometheus exposition format
+func (m *FinalityGadgetMetrics) Handler() http.Handler {
+	if m == nil {
+		return http.NotFoundHandler()
+	}
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// SetLatestL2Block records the latest L2 block and updates the finality lag
+func (m *FinalityGadgetMetrics) SetLatestL2Block(height, timestamp uint64) {
+	if m == nil {
+		return
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.latestBlock = blockInfo{height: height, timestamp: timestamp, set: true}
+	m.latestL2BlockHeight.Set(float64(height))
+	m.updateFinalityLag()
+}
+
+// SetLatestBtcFinalizedBlock records the latest BTC finalized block and updates the finality lag
+func (m *FinalityGadgetMetrics) SetLatestBtcFinalizedBlock(height, timestamp uint64) {
+	if m == nil {
+		return
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.finalizedBlock = blockInfo{height: height, timestamp: timestamp, set: true}
+	m.latestBtcFinalizedBlockHeight.Set(float64(height))
+	m.updateFinalityLag()
+}
+
+// ObserveBatchProcessingDuration records the time taken to check the finality of a batch of blocks
+func (m *FinalityGadgetMetrics) ObserveBatchProcessingDuration(duration time.Duration) {
+	if m == nil {
+		return
+	}
+	m.batchProcessingDuration.Observe(duration.Seconds())
+}
+
+// SetBlockProcessingDegraded records whether block processing is retrying transient errors
+func (m *FinalityGadgetMetrics) SetBlockProcessingDegraded(degraded bool) {
+	if m == nil {
+		return
+	}
+	if degraded {
+		m.blockProcessingDegraded.Set(1)
+	} else {
+		m.blockProcessingDegraded.Set(0)
+	}
+}
+
+// IncBlockProcessingErrors counts a block processing error of the given class
+func (m *FinalityGadgetMetrics) IncBlockProcessingErrors(class string) {
+	if m == nil {
+		return
+	}
+	m.blockProcessingErrors.WithLabelValues(class).Inc()
+}
+
+// ObserveRPCRequest records the latency of an RPC request started at `start`, and counts it as
+// failed if err is not nil
+func (m *FinalityGadgetMetrics) ObserveRPCRequest(client, method string, start time.Time, err error) {
+	if m == nil {
+		return
+	}
+	m.rpcRequestDuration.WithLabelValues(client, method).Observe(time.Since(start).Seconds())
+	if err != nil {
+		m.rpcErrors.WithLabelValues(client, method).Inc()
+	}
+}
+
+// ObserveL2EndpointRequest records the latency of an RPC request to the given L2 node started at `start`, and counts
+// it as failed if err is not nil
+func (m *FinalityGadgetMetrics) ObserveL2EndpointRequest(endpoint, method string, start time.Time, err error) {
+	if m == nil {
+		return
+	}
+	m.l2EndpointRequestDuration.WithLabelValues(endpoint, method).Observe(time.Since(start).Seconds())
+	if err != nil {
+		m.l2EndpointErrors.WithLabelValues(endpoint, method).Inc()
+	}
+}
+
+// SetL2EndpointHealthy records whether the given L2 node is healthy
+func (m *FinalityGadgetMetrics) SetL2EndpointHealthy(endpoint string, healthy bool) {
+	if m == nil {
+		return
+	}
+	if healthy {
+		m.l2EndpointHealthy.WithLabelValues(endpoint).Set(1)
+	} else {
+		m.l2EndpointHealthy.WithLabelValues(endpoint).Set(0)
+	}
+}
+
+// IncL2QuorumFailures counts an L2 header read for which not enough L2 nodes agreed on the block hash
+func (m *FinalityGadgetMetrics) IncL2QuorumFailures() {
+	if m == nil {
+		return
+	}
+	m.l2QuorumFailures.Inc()
+}
+
+// AddPrunedBlocks counts the blocks pruned from the db
+func (m *FinalityGadgetMetrics) AddPrunedBlocks(pruned uint64) {
+	if m == nil {
+		return
+	}
+	m.prunedBlocks.Add(float64(pruned))
+}
+
+// SetEarliestBlock records the height of the earliest block kept in the db
+func (m *FinalityGadgetMetrics) SetEarliestBlock(height uint64) {
+	if m == nil {
+		return
+	}
+	m.earliestBlockHeight.Set(float64(height))
+}
+
+// UnaryServerInterceptor returns a gRPC interceptor counting requests by method and status code
+func (m *FinalityGadgetMetrics) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		if m != nil {
+			m.grpcRequests.WithLabelValues(info.FullMethod, status.Code(err).String()).Inc()
+		}
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor returns a gRPC interceptor counting streams by method and status code
+func (m *FinalityGadgetMetrics) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		err := handler(srv, ss)
+		if m != nil {
+			m.grpcRequests.WithLabelValues(info.FullMethod, status.Code(err).String()).Inc()
+		}
+		return err
+	}
+}
+
+//////////////////////////////
+// INTERNAL
+//////////////////////////////
+
+// updateFinalityLag sets the finality lag gauges once both the latest and finalized blocks are known
+func (m *FinalityGadgetMetrics) updateFinalityLag() {
+	if !m.latestBlock.set || !m.finalizedBlock.set {
+		return
+	}
+	m.finalityLagBlocks.Set(lag(m.latestBlock.height, m.finalizedBlock.height))
+	m.finalityLagSeconds.Set(lag(m.latestBlock.timestamp, m.finalizedBlock.timestamp))
+}
+
+// lag returns latest - finalized, or 0 if the finalized value is ahead (e.g. after an L2 reorg)
+func lag(latest, finalized uint64) float64 {
+	if finalized >= latest {
+		return 0
+	}
+	return float64(latest - finalized)
+}
@@ -0,0 +1,154 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestFinalityLag(t *testing.T) {
+	m := NewFinalityGadgetMetrics()
+
+	// lag is only set once both blocks are known
+	m.SetLatestL2Block(100, 2000)
+	require.Equal(t, float64(100), testutil.ToFloat64(m.latestL2BlockHeight))
+	require.Equal(t, float64(0), testutil.ToFloat64(m.finalityLagBlocks))
+
+	m.SetLatestBtcFinalizedBlock(90, 1980)
+	require.Equal(t, float64(90), testutil.ToFloat64(m.latestBtcFinalizedBlockHeight))
+	require.Equal(t, float64(10), testutil.ToFloat64(m.finalityLagBlocks))
+	require.Equal(t, float64(20), testutil.ToFloat64(m.finalityLagSeconds))
+
+	m.SetLatestL2Block(105, 2010)
+	require.Equal(t, float64(15), testutil.ToFloat64(m.finalityLagBlocks))
+	require.Equal(t, float64(30), testutil.ToFloat64(m.finalityLagSeconds))
+
+	// lag doesn't underflow if the finalized block is ahead of the latest block
+	m.SetLatestBtcFinalizedBlock(106, 2012)
+	require.Equal(t, float64(0), testutil.ToFloat64(m.finalityLagBlocks))
+	require.Equal(t, float64(0), testutil.ToFloat64(m.finalityLagSeconds))
+}
+
+func TestObserveRPCRequest(t *testing.T) {
+	m := NewFinalityGadgetMetrics()
+
+	m.ObserveRPCRequest("btcclient", "GetBlockCount", time.Now(), nil)
+	m.ObserveRPCRequest("btcclient", "GetBlockCount", time.Now(), errors.New("rpc error"))
+	m.ObserveRPCRequest("bbnclient", "QueryFpPower", time.Now(), nil)
+
+	require.Equal(t, 2, testutil.CollectAndCount(m.rpcRequestDuration))
+	require.Equal(t, float64(1), testutil.ToFloat64(m.rpcErrors.WithLabelValues("btcclient", "GetBlockCount")))
+	require.Equal(t, float64(0), testutil.ToFloat64(m.rpcErrors.WithLabelValues("bbnclient", "QueryFpPower")))
+}
+
+func TestL2EndpointMetrics(t *testing.T) {
+	m := NewFinalityGadgetMetrics()
+
+	m.ObserveL2EndpointRequest("https://a", "HeaderByNumber", time.Now(), nil)
+	m.ObserveL2EndpointRequest("https://b", "HeaderByNumber", time.Now(), errors.New("rpc error"))
+	m.SetL2EndpointHealthy("https://a", true)
+	m.SetL2EndpointHealthy("https://b", false)
+	m.IncL2QuorumFailures()
+
+	require.Equal(t, 2, testutil.CollectAndCount(m.l2EndpointRequestDuration))
+	require.Equal(t, float64(1), testutil.ToFloat64(m.l2EndpointErrors.WithLabelValues("https://b", "HeaderByNumber")))
+	require.Equal(t, float64(1), testutil.ToFloat64(m.l2EndpointHealthy.WithLabelValues("https://a")))
+	require.Equal(t, float64(0), testutil.ToFloat64(m.l2EndpointHealthy.WithLabelValues("https://b")))
+	require.Equal(t, float64(1), testutil.ToFloat64(m.l2QuorumFailures))
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	m := NewFinalityGadgetMetrics()
+	interceptor := m.UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/proto.FinalityGadget/QueryBlockByHeight"}
+
+	_, err := interceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	})
+	require.NoError(t, err)
+	_, err = interceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.NotFound, "block not found")
+	})
+	require.Error(t, err)
+
+	require.Equal(t, float64(1), testutil.ToFloat64(m.grpcRequests.WithLabelValues(info.FullMethod, "OK")))
+	require.Equal(t, float64(1), testutil.ToFloat64(m.grpcRequests.WithLabelValues(info.FullMethod, "NotFound")))
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	m := NewFinalityGadgetMetrics()
+	interceptor := m.StreamServerInterceptor()
+	info := &grpc.StreamServerInfo{FullMethod: "/proto.FinalityGadget/SubscribeFinalizedBlocks"}
+
+	err := interceptor(nil, nil, info, func(srv interface{}, stream grpc.ServerStream) error {
+		return status.Error(codes.ResourceExhausted, "subscriber fell behind")
+	})
+	require.Error(t, err)
+
+	require.Equal(t, float64(1), testutil.ToFloat64(m.grpcRequests.WithLabelValues(info.FullMethod, "ResourceExhausted")))
+}
+
+func TestHandler(t *testing.T) {
+	m := NewFinalityGadgetMetrics()
+	m.SetLatestL2Block(100, 2000)
+	m.ObserveBatchProcessingDuration(time.Second)
+
+	recorder := httptest.NewRecorder()
+	m.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	require.Equal(t, 200, recorder.Code)
+
+	body := recorder.Body.String()
+	require.Contains(t, body, "finality_gadget_latest_l2_block_height 100")
+	require.Contains(t, body, "finality_gadget_batch_processing_duration_seconds_count 1")
+	require.Contains(t, body, "go_goroutines")
+}
+
+func TestNilMetrics(t *testing.T) {
+	var m *FinalityGadgetMetrics
+
+	// recording on nil metrics is a no-op
+	m.SetLatestL2Block(100, 2000)
+	m.SetLatestBtcFinalizedBlock(90, 1980)
+	m.ObserveBatchProcessingDuration(time.Second)
+	m.ObserveRPCRequest("btcclient", "GetBlockCount", time.Now(), nil)
+
+	resp, err := m.UnaryServerInterceptor()(context.Background(), nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, "ok", resp)
+}
+
+func TestBlockProcessingErrors(t *testing.T) {
+	m := NewFinalityGadgetMetrics()
+
+	m.IncBlockProcessingErrors("transient")
+	m.IncBlockProcessingErrors("transient")
+	m.IncBlockProcessingErrors("fatal")
+	require.Equal(t, float64(2), testutil.ToFloat64(m.blockProcessingErrors.WithLabelValues("transient")))
+	require.Equal(t, float64(1), testutil.ToFloat64(m.blockProcessingErrors.WithLabelValues("fatal")))
+
+	m.SetBlockProcessingDegraded(true)
+	require.Equal(t, float64(1), testutil.ToFloat64(m.blockProcessingDegraded))
+	m.SetBlockProcessingDegraded(false)
+	require.Equal(t, float64(0), testutil.ToFloat64(m.blockProcessingDegraded))
+}
+
+func TestPrunedBlocks(t *testing.T) {
+	m := NewFinalityGadgetMetrics()
+
+	m.AddPrunedBlocks(10)
+	m.SetEarliestBlock(110)
+	m.AddPrunedBlocks(5)
+	m.SetEarliestBlock(115)
+	require.Equal(t, float64(15), testutil.ToFloat64(m.prunedBlocks))
+	require.Equal(t, float64(115), testutil.ToFloat64(m.earliestBlockHeight))
+}
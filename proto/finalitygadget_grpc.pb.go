@@ -25,6 +25,13 @@ const (
 	FinalityGadget_QueryIsBlockFinalizedByHeight_FullMethodName     = "/proto.FinalityGadget/QueryIsBlockFinalizedByHeight"
 	FinalityGadget_QueryIsBlockFinalizedByHash_FullMethodName       = "/proto.FinalityGadget/QueryIsBlockFinalizedByHash"
 	FinalityGadget_QueryLatestFinalizedBlock_FullMethodName         = "/proto.FinalityGadget/QueryLatestFinalizedBlock"
+	FinalityGadget_QueryBlockByHeight_FullMethodName                = "/proto.FinalityGadget/QueryBlockByHeight"
+	FinalityGadget_QueryBlockFinalityEvidence_FullMethodName        = "/proto.FinalityGadget/QueryBlockFinalityEvidence"
+	FinalityGadget_SubscribeFinalizedBlocks_FullMethodName          = "/proto.FinalityGadget/SubscribeFinalizedBlocks"
+	FinalityGadget_WatchTransaction_FullMethodName                  = "/proto.FinalityGadget/WatchTransaction"
+	FinalityGadget_QueryTransactionsStatus_FullMethodName           = "/proto.FinalityGadget/QueryTransactionsStatus"
+	FinalityGadget_QueryTransactionStatus_FullMethodName            = "/proto.FinalityGadget/QueryTransactionStatus"
+	FinalityGadget_QueryChainSyncStatus_FullMethodName              = "/proto.FinalityGadget/QueryChainSyncStatus"
 )
 
 // FinalityGadgetClient is the client API for FinalityGadget service.
@@ -49,6 +56,32 @@ type FinalityGadgetClient interface {
 	// QueryLatestFinalizedBlock returns the latest consecutively finalized block
 	// by querying the local db
 	QueryLatestFinalizedBlock(ctx context.Context, in *QueryLatestFinalizedBlockRequest, opts ...grpc.CallOption) (*QueryBlockResponse, error)
+	// QueryBlockByHeight returns the finalized block at given height by
+	// querying the local db
+	QueryBlockByHeight(ctx context.Context, in *QueryBlockByHeightRequest, opts ...grpc.CallOption) (*QueryBlockResponse, error)
+	// QueryBlockFinalityEvidence returns the voters, voting power and BTC
+	// height that made the block at given height BTC finalized by querying the
+	// local db
+	QueryBlockFinalityEvidence(ctx context.Context, in *QueryBlockFinalityEvidenceRequest, opts ...grpc.CallOption) (*QueryBlockFinalityEvidenceResponse, error)
+	// SubscribeFinalizedBlocks streams blocks as they are BTC finalized and
+	// stored in the local db, optionally starting with the stored blocks from
+	// a given height. The stream is closed with RESOURCE_EXHAUSTED if the
+	// client falls behind, in which case it should resubscribe from the height
+	// after the last received block
+	SubscribeFinalizedBlocks(ctx context.Context, in *SubscribeFinalizedBlocksRequest, opts ...grpc.CallOption) (FinalityGadget_SubscribeFinalizedBlocksClient, error)
+	// WatchTransaction registers a callback url that is POSTed a signed JSON
+	// notification whenever the finality status of a transaction changes, until
+	// the transaction is finalized. The transaction doesn't need to be mined yet
+	WatchTransaction(ctx context.Context, in *WatchTransactionRequest, opts ...grpc.CallOption) (*WatchTransactionResponse, error)
+	// QueryTransactionsStatus returns the finality status of multiple
+	// transactions, with batched L2 RPC calls. Transactions that are not found
+	// or have an invalid hash are returned with found set to false
+	QueryTransactionsStatus(ctx context.Context, in *QueryTransactionsStatusRequest, opts ...grpc.CallOption) (*QueryTransactionsStatusResponse, error)
+	// QueryTransactionStatus returns the finality status of a transaction
+	QueryTransactionStatus(ctx context.Context, in *QueryTransactionStatusRequest, opts ...grpc.CallOption) (*QueryTransactionStatusResponse, error)
+	// QueryChainSyncStatus returns the latest L2 block and the range of BTC
+	// finalized blocks stored in the local db
+	QueryChainSyncStatus(ctx context.Context, in *QueryChainSyncStatusRequest, opts ...grpc.CallOption) (*QueryChainSyncStatusResponse, error)
 }
 
 type finalityGadgetClient struct {
@@ -113,6 +146,92 @@ func (c *finalityGadgetClient) QueryLatestFinalizedBlock(ctx context.Context, in
 	return out, nil
 }
 
+func (c *finalityGadgetClient) QueryBlockByHeight(ctx context.Context, in *QueryBlockByHeightRequest, opts ...grpc.CallOption) (*QueryBlockResponse, error) {
+	out := new(QueryBlockResponse)
+	err := c.cc.Invoke(ctx, FinalityGadget_QueryBlockByHeight_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *finalityGadgetClient) QueryBlockFinalityEvidence(ctx context.Context, in *QueryBlockFinalityEvidenceRequest, opts ...grpc.CallOption) (*QueryBlockFinalityEvidenceResponse, error) {
+	out := new(QueryBlockFinalityEvidenceResponse)
+	err := c.cc.Invoke(ctx, FinalityGadget_QueryBlockFinalityEvidence_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *finalityGadgetClient) SubscribeFinalizedBlocks(ctx context.Context, in *SubscribeFinalizedBlocksRequest, opts ...grpc.CallOption) (FinalityGadget_SubscribeFinalizedBlocksClient, error) {
+	stream, err := c.cc.NewStream(ctx, &FinalityGadget_ServiceDesc.Streams[0], FinalityGadget_SubscribeFinalizedBlocks_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &finalityGadgetSubscribeFinalizedBlocksClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type FinalityGadget_SubscribeFinalizedBlocksClient interface {
+	Recv() (*SubscribeFinalizedBlocksResponse, error)
+	grpc.ClientStream
+}
+
+type finalityGadgetSubscribeFinalizedBlocksClient struct {
+	grpc.ClientStream
+}
+
+func (x *finalityGadgetSubscribeFinalizedBlocksClient) Recv() (*SubscribeFinalizedBlocksResponse, error) {
+	m := new(SubscribeFinalizedBlocksResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *finalityGadgetClient) WatchTransaction(ctx context.Context, in *WatchTransactionRequest, opts ...grpc.CallOption) (*WatchTransactionResponse, error) {
+	out := new(WatchTransactionResponse)
+	err := c.cc.Invoke(ctx, FinalityGadget_WatchTransaction_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *finalityGadgetClient) QueryTransactionsStatus(ctx context.Context, in *QueryTransactionsStatusRequest, opts ...grpc.CallOption) (*QueryTransactionsStatusResponse, error) {
+	out := new(QueryTransactionsStatusResponse)
+	err := c.cc.Invoke(ctx, FinalityGadget_QueryTransactionsStatus_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *finalityGadgetClient) QueryTransactionStatus(ctx context.Context, in *QueryTransactionStatusRequest, opts ...grpc.CallOption) (*QueryTransactionStatusResponse, error) {
+	out := new(QueryTransactionStatusResponse)
+	err := c.cc.Invoke(ctx, FinalityGadget_QueryTransactionStatus_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *finalityGadgetClient) QueryChainSyncStatus(ctx context.Context, in *QueryChainSyncStatusRequest, opts ...grpc.CallOption) (*QueryChainSyncStatusResponse, error) {
+	out := new(QueryChainSyncStatusResponse)
+	err := c.cc.Invoke(ctx, FinalityGadget_QueryChainSyncStatus_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // FinalityGadgetServer is the server API for FinalityGadget service.
 // All implementations must embed UnimplementedFinalityGadgetServer
 // for forward compatibility
@@ -135,6 +254,32 @@ type FinalityGadgetServer interface {
 	// QueryLatestFinalizedBlock returns the latest consecutively finalized block
 	// by querying the local db
 	QueryLatestFinalizedBlock(context.Context, *QueryLatestFinalizedBlockRequest) (*QueryBlockResponse, error)
+	// QueryBlockByHeight returns the finalized block at given height by
+	// querying the local db
+	QueryBlockByHeight(context.Context, *QueryBlockByHeightRequest) (*QueryBlockResponse, error)
+	// QueryBlockFinalityEvidence returns the voters, voting power and BTC
+	// height that made the block at given height BTC finalized by querying the
+	// local db
+	QueryBlockFinalityEvidence(context.Context, *QueryBlockFinalityEvidenceRequest) (*QueryBlockFinalityEvidenceResponse, error)
+	// SubscribeFinalizedBlocks streams blocks as they are BTC finalized and
+	// stored in the local db, optionally starting with the stored blocks from
+	// a given height. The stream is closed with RESOURCE_EXHAUSTED if the
+	// client falls behind, in which case it should resubscribe from the height
+	// after the last received block
+	SubscribeFinalizedBlocks(*SubscribeFinalizedBlocksRequest, FinalityGadget_SubscribeFinalizedBlocksServer) error
+	// WatchTransaction registers a callback url that is POSTed a signed JSON
+	// notification whenever the finality status of a transaction changes, until
+	// the transaction is finalized. The transaction doesn't need to be mined yet
+	WatchTransaction(context.Context, *WatchTransactionRequest) (*WatchTransactionResponse, error)
+	// QueryTransactionsStatus returns the finality status of multiple
+	// transactions, with batched L2 RPC calls. Transactions that are not found
+	// or have an invalid hash are returned with found set to false
+	QueryTransactionsStatus(context.Context, *QueryTransactionsStatusRequest) (*QueryTransactionsStatusResponse, error)
+	// QueryTransactionStatus returns the finality status of a transaction
+	QueryTransactionStatus(context.Context, *QueryTransactionStatusRequest) (*QueryTransactionStatusResponse, error)
+	// QueryChainSyncStatus returns the latest L2 block and the range of BTC
+	// finalized blocks stored in the local db
+	QueryChainSyncStatus(context.Context, *QueryChainSyncStatusRequest) (*QueryChainSyncStatusResponse, error)
 	mustEmbedUnimplementedFinalityGadgetServer()
 }
 
@@ -160,6 +305,27 @@ func (UnimplementedFinalityGadgetServer) QueryIsBlockFinalizedByHash(context.Con
 func (UnimplementedFinalityGadgetServer) QueryLatestFinalizedBlock(context.Context, *QueryLatestFinalizedBlockRequest) (*QueryBlockResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method QueryLatestFinalizedBlock not implemented")
 }
+func (UnimplementedFinalityGadgetServer) QueryBlockByHeight(context.Context, *QueryBlockByHeightRequest) (*QueryBlockResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method QueryBlockByHeight not implemented")
+}
+func (UnimplementedFinalityGadgetServer) QueryBlockFinalityEvidence(context.Context, *QueryBlockFinalityEvidenceRequest) (*QueryBlockFinalityEvidenceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method QueryBlockFinalityEvidence not implemented")
+}
+func (UnimplementedFinalityGadgetServer) SubscribeFinalizedBlocks(*SubscribeFinalizedBlocksRequest, FinalityGadget_SubscribeFinalizedBlocksServer) error {
+	return status.Errorf(codes.Unimplemented, "method SubscribeFinalizedBlocks not implemented")
+}
+func (UnimplementedFinalityGadgetServer) WatchTransaction(context.Context, *WatchTransactionRequest) (*WatchTransactionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method WatchTransaction not implemented")
+}
+func (UnimplementedFinalityGadgetServer) QueryTransactionsStatus(context.Context, *QueryTransactionsStatusRequest) (*QueryTransactionsStatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method QueryTransactionsStatus not implemented")
+}
+func (UnimplementedFinalityGadgetServer) QueryTransactionStatus(context.Context, *QueryTransactionStatusRequest) (*QueryTransactionStatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method QueryTransactionStatus not implemented")
+}
+func (UnimplementedFinalityGadgetServer) QueryChainSyncStatus(context.Context, *QueryChainSyncStatusRequest) (*QueryChainSyncStatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method QueryChainSyncStatus not implemented")
+}
 func (UnimplementedFinalityGadgetServer) mustEmbedUnimplementedFinalityGadgetServer() {}
 
 // UnsafeFinalityGadgetServer may be embedded to opt out of forward compatibility for this service.
@@ -281,6 +447,135 @@ func _FinalityGadget_QueryLatestFinalizedBlock_Handler(srv interface{}, ctx cont
 	return interceptor(ctx, in, info, handler)
 }
 
+func _FinalityGadget_QueryBlockByHeight_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QueryBlockByHeightRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FinalityGadgetServer).QueryBlockByHeight(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FinalityGadget_QueryBlockByHeight_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FinalityGadgetServer).QueryBlockByHeight(ctx, req.(*QueryBlockByHeightRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FinalityGadget_QueryBlockFinalityEvidence_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QueryBlockFinalityEvidenceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FinalityGadgetServer).QueryBlockFinalityEvidence(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FinalityGadget_QueryBlockFinalityEvidence_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FinalityGadgetServer).QueryBlockFinalityEvidence(ctx, req.(*QueryBlockFinalityEvidenceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FinalityGadget_SubscribeFinalizedBlocks_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeFinalizedBlocksRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(FinalityGadgetServer).SubscribeFinalizedBlocks(m, &finalityGadgetSubscribeFinalizedBlocksServer{stream})
+}
+
+type FinalityGadget_SubscribeFinalizedBlocksServer interface {
+	Send(*SubscribeFinalizedBlocksResponse) error
+	grpc.ServerStream
+}
+
+type finalityGadgetSubscribeFinalizedBlocksServer struct {
+	grpc.ServerStream
+}
+
+func (x *finalityGadgetSubscribeFinalizedBlocksServer) Send(m *SubscribeFinalizedBlocksResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _FinalityGadget_WatchTransaction_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WatchTransactionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FinalityGadgetServer).WatchTransaction(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FinalityGadget_WatchTransaction_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FinalityGadgetServer).WatchTransaction(ctx, req.(*WatchTransactionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FinalityGadget_QueryTransactionsStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QueryTransactionsStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FinalityGadgetServer).QueryTransactionsStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FinalityGadget_QueryTransactionsStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FinalityGadgetServer).QueryTransactionsStatus(ctx, req.(*QueryTransactionsStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FinalityGadget_QueryTransactionStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QueryTransactionStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FinalityGadgetServer).QueryTransactionStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FinalityGadget_QueryTransactionStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FinalityGadgetServer).QueryTransactionStatus(ctx, req.(*QueryTransactionStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FinalityGadget_QueryChainSyncStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QueryChainSyncStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FinalityGadgetServer).QueryChainSyncStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FinalityGadget_QueryChainSyncStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FinalityGadgetServer).QueryChainSyncStatus(ctx, req.(*QueryChainSyncStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // FinalityGadget_ServiceDesc is the grpc.ServiceDesc for FinalityGadget service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -312,7 +607,37 @@ var FinalityGadget_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "QueryLatestFinalizedBlock",
 			Handler:    _FinalityGadget_QueryLatestFinalizedBlock_Handler,
 		},
+		{
+			MethodName: "QueryBlockByHeight",
+			Handler:    _FinalityGadget_QueryBlockByHeight_Handler,
+		},
+		{
+			MethodName: "QueryBlockFinalityEvidence",
+			Handler:    _FinalityGadget_QueryBlockFinalityEvidence_Handler,
+		},
+		{
+			MethodName: "WatchTransaction",
+			Handler:    _FinalityGadget_WatchTransaction_Handler,
+		},
+		{
+			MethodName: "QueryTransactionsStatus",
+			Handler:    _FinalityGadget_QueryTransactionsStatus_Handler,
+		},
+		{
+			MethodName: "QueryTransactionStatus",
+			Handler:    _FinalityGadget_QueryTransactionStatus_Handler,
+		},
+		{
+			MethodName: "QueryChainSyncStatus",
+			Handler:    _FinalityGadget_QueryChainSyncStatus_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "SubscribeFinalizedBlocks",
+			Handler:       _FinalityGadget_SubscribeFinalizedBlocks_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/finalitygadget.proto",
 }
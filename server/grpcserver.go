@@ -2,13 +2,21 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/babylonlabs-io/finality-gadget/proto"
 	"github.com/babylonlabs-io/finality-gadget/types"
+	"github.com/babylonlabs-io/finality-gadget/webhook"
+	"github.com/ethereum/go-ethereum"
 	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
+// subscriptionBufferSize is the number of events buffered for a subscriber before it is dropped
+const subscriptionBufferSize = 1024
+
 // QueryIsBlockBabylonFinalized is an RPC method that returns the finality status of a block by querying the internal db.
 func (s *Server) QueryIsBlockBabylonFinalized(ctx context.Context, req *proto.QueryIsBlockBabylonFinalizedRequest) (*proto.QueryIsBlockFinalizedResponse, error) {
 	s.logger.Debug(
@@ -141,11 +149,303 @@ func (s *Server) QueryLatestFinalizedBlock(ctx context.Context, req *proto.Query
 		return nil, err
 	}
 
-	return &proto.QueryBlockResponse{
-		Block: &proto.BlockInfo{
-			BlockHash:      block.BlockHash,
-			BlockHeight:    block.BlockHeight,
-			BlockTimestamp: block.BlockTimestamp,
+	return &proto.QueryBlockResponse{Block: toBlockInfo(block)}, nil
+}
+
+// QueryBlockByHeight is an RPC method that returns the finalized block at a given height.
+func (s *Server) QueryBlockByHeight(ctx context.Context, req *proto.QueryBlockByHeightRequest) (*proto.QueryBlockResponse, error) {
+	s.logger.Debug(
+		"QueryBlockByHeight request",
+		zap.Uint64("blockHeight", req.BlockHeight),
+	)
+	block, err := s.fg.GetBlockByHeight(req.BlockHeight)
+	if err != nil {
+		if errors.Is(err, types.ErrBlockNotFound) {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		return nil, err
+	}
+
+	return &proto.QueryBlockResponse{Block: toBlockInfo(block)}, nil
+}
+
+// QueryBlockFinalityEvidence is an RPC method that returns the evidence of why the block at a given height was finalized.
+func (s *Server) QueryBlockFinalityEvidence(ctx context.Context, req *proto.QueryBlockFinalityEvidenceRequest) (*proto.QueryBlockFinalityEvidenceResponse, error) {
+	s.logger.Debug(
+		"QueryBlockFinalityEvidence request",
+		zap.Uint64("blockHeight", req.BlockHeight),
+	)
+	evidence, err := s.fg.QueryBlockFinalityEvidence(req.BlockHeight)
+	if err != nil {
+		if errors.Is(err, types.ErrFinalityEvidenceNotFound) {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		return nil, err
+	}
+
+	voters := make([]*proto.VoterPower, 0, len(evidence.Voters))
+	for _, voter := range evidence.Voters {
+		voters = append(voters, &proto.VoterPower{
+			FpBtcPkHex: voter.FpBtcPkHex,
+			Power:      voter.Power,
+		})
+	}
+
+	return &proto.QueryBlockFinalityEvidenceResponse{
+		Evidence: &proto.FinalityEvidence{
+			BlockHash:         evidence.BlockHash,
+			BlockHeight:       evidence.BlockHeight,
+			BtcHeight:         evidence.BtcHeight,
+			BabylonHeight:     evidence.BabylonHeight,
+			TotalPower:        evidence.TotalPower,
+			VotedPower:        evidence.VotedPower,
+			Voters:            voters,
+			QuorumNumerator:   evidence.QuorumThreshold.Numerator,
+			QuorumDenominator: evidence.QuorumThreshold.Denominator,
 		},
 	}, nil
 }
+
+/* SubscribeFinalizedBlocks is an RPC method that streams blocks as they are finalized.
+ *
+ * - subscribe to the finality gadget events first, so no block is missed while replaying
+ * - if a start height is given, stream the stored blocks from that height
+ * - stream the finalized blocks above the last block sent. After a reorg, stream the blocks finalized
+ *   again above the fork height
+ * - if the client falls behind and the subscription is dropped, end the stream with RESOURCE_EXHAUSTED
+ * - if the server shuts down, end the stream with UNAVAILABLE so the client resubscribes to another server
+ */
+func (s *Server) SubscribeFinalizedBlocks(req *proto.SubscribeFinalizedBlocksRequest, stream proto.FinalityGadget_SubscribeFinalizedBlocksServer) error {
+	s.logger.Debug(
+		"SubscribeFinalizedBlocks request",
+		zap.Uint64("fromHeight", req.FromHeight),
+	)
+	events, unsubscribe := s.fg.SubscribeEvents(subscriptionBufferSize)
+	defer unsubscribe()
+
+	var lastSentHeight uint64
+	if req.FromHeight > 0 {
+		var err error
+		if lastSentHeight, err = s.replayFinalizedBlocks(stream, req.FromHeight); err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-s.shutdown:
+			return status.Error(codes.Unavailable, "server shutting down, resubscribe from the last received height")
+		case event, ok := <-events:
+			if !ok {
+				return status.Error(codes.ResourceExhausted, "subscriber fell behind, resubscribe from the last received height")
+			}
+			switch event.Type {
+			case types.EventTypeReorg:
+				if event.Reorg.ForkHeight < lastSentHeight {
+					lastSentHeight = event.Reorg.ForkHeight
+				}
+			case types.EventTypeBlockFinalized:
+				// skip blocks already sent while replaying
+				if event.Block.BlockHeight <= lastSentHeight {
+					continue
+				}
+				if err := stream.Send(&proto.SubscribeFinalizedBlocksResponse{Block: toBlockInfo(event.Block)}); err != nil {
+					return err
+				}
+				lastSentHeight = event.Block.BlockHeight
+			}
+		}
+	}
+}
+
+// WatchTransaction is an RPC method that registers a callback notified when the finality status of a transaction changes.
+func (s *Server) WatchTransaction(ctx context.Context, req *proto.WatchTransactionRequest) (*proto.WatchTransactionResponse, error) {
+	s.logger.Debug(
+		"WatchTransaction request",
+		zap.String("txHash", req.TxHash),
+		zap.String("callbackUrl", req.CallbackUrl),
+	)
+	watch, err := s.watcher.Watch(req.TxHash, req.CallbackUrl)
+	if err != nil {
+		if errors.Is(err, webhook.ErrWebhooksDisabled) {
+			return nil, status.Error(codes.FailedPrecondition, err.Error())
+		}
+		if errors.Is(err, webhook.ErrInvalidWatch) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		if errors.Is(err, webhook.ErrTooManyWatches) {
+			return nil, status.Error(codes.ResourceExhausted, err.Error())
+		}
+		return nil, err
+	}
+
+	return &proto.WatchTransactionResponse{WatchId: watch.Id}, nil
+}
+
+// QueryTransactionsStatus is an RPC method that returns the finality status of multiple transactions.
+func (s *Server) QueryTransactionsStatus(ctx context.Context, req *proto.QueryTransactionsStatusRequest) (*proto.QueryTransactionsStatusResponse, error) {
+	s.logger.Debug(
+		"QueryTransactionsStatus request",
+		zap.Int("count", len(req.TxHashes)),
+	)
+	results, err := s.fg.QueryTransactionsStatus(req.TxHashes)
+	if err != nil {
+		if errors.Is(err, types.ErrTooManyTransactions) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		return nil, err
+	}
+
+	response := &proto.QueryTransactionsStatusResponse{
+		Results: make([]*proto.TransactionStatusResult, 0, len(results)),
+	}
+	for _, result := range results {
+		protoResult := &proto.TransactionStatusResult{
+			TxHash: result.TxHash,
+			Found:  result.Found,
+			Error:  result.Error,
+		}
+		if result.Transaction != nil {
+			protoResult.Transaction = toTransactionInfo(result.Transaction)
+		}
+		response.Results = append(response.Results, protoResult)
+	}
+	return response, nil
+}
+
+// QueryTransactionStatus is an RPC method that returns the finality status of a transaction.
+func (s *Server) QueryTransactionStatus(ctx context.Context, req *proto.QueryTransactionStatusRequest) (*proto.QueryTransactionStatusResponse, error) {
+	s.logger.Debug(
+		"QueryTransactionStatus request",
+		zap.String("txHash", req.TxHash),
+	)
+	txInfo, err := s.fg.QueryTransactionStatus(req.TxHash)
+	if err != nil {
+		if errors.Is(err, types.ErrInvalidTxHash) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		if errors.Is(err, ethereum.NotFound) {
+			return nil, status.Error(codes.NotFound, "transaction not found")
+		}
+		return nil, err
+	}
+	if txInfo == nil {
+		return nil, status.Error(codes.NotFound, "transaction not found")
+	}
+
+	return &proto.QueryTransactionStatusResponse{Transaction: toTransactionInfo(txInfo)}, nil
+}
+
+// QueryChainSyncStatus is an RPC method that returns the latest L2 block and the range of BTC finalized blocks.
+func (s *Server) QueryChainSyncStatus(ctx context.Context, req *proto.QueryChainSyncStatusRequest) (*proto.QueryChainSyncStatusResponse, error) {
+	s.logger.Debug("QueryChainSyncStatus request")
+	chainSyncStatus, err := s.fg.QueryChainSyncStatus()
+	if err != nil {
+		if errors.Is(err, types.ErrBlockNotFound) {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		return nil, err
+	}
+
+	return &proto.QueryChainSyncStatusResponse{
+		LatestBlockHeight:               chainSyncStatus.LatestBlockHeight,
+		LatestBtcFinalizedBlockHeight:   chainSyncStatus.LatestBtcFinalizedBlockHeight,
+		EarliestBtcFinalizedBlockHeight: chainSyncStatus.EarliestBtcFinalizedBlockHeight,
+		LatestEthFinalizedBlockHeight:   chainSyncStatus.LatestEthFinalizedBlockHeight,
+	}, nil
+}
+
+// replayFinalizedBlocks streams the stored blocks from the given height up to the latest finalized
+// block, and returns the height of the last block sent (0 if none). Blocks pruned from the db are skipped.
+func (s *Server) replayFinalizedBlocks(stream proto.FinalityGadget_SubscribeFinalizedBlocksServer, fromHeight uint64) (uint64, error) {
+	latestBlock, err := s.fg.QueryLatestFinalizedBlock()
+	if err != nil {
+		if errors.Is(err, types.ErrBlockNotFound) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	// no block is finalized yet
+	if latestBlock == nil {
+		return 0, nil
+	}
+	earliestBlock, err := s.db.QueryEarliestFinalizedBlock()
+	if err != nil {
+		return 0, err
+	}
+	if fromHeight < earliestBlock.BlockHeight {
+		fromHeight = earliestBlock.BlockHeight
+	}
+
+	var lastSentHeight uint64
+	for height := fromHeight; height <= latestBlock.BlockHeight; height++ {
+		if err := stream.Context().Err(); err != nil {
+			return 0, err
+		}
+		block, err := s.fg.GetBlockByHeight(height)
+		if errors.Is(err, types.ErrBlockNotFound) {
+			// the block was pruned while replaying, resume from the earliest block left
+			earliestBlock, err = s.db.QueryEarliestFinalizedBlock()
+			if err == nil && earliestBlock.BlockHeight > height {
+				height = earliestBlock.BlockHeight
+				block, err = s.fg.GetBlockByHeight(height)
+			}
+		}
+		if err != nil {
+			// the block was rolled back by a reorg while replaying, its replacement is streamed live
+			if errors.Is(err, types.ErrBlockNotFound) {
+				break
+			}
+			return 0, err
+		}
+		if err := stream.Send(&proto.SubscribeFinalizedBlocksResponse{Block: toBlockInfo(block)}); err != nil {
+			return 0, err
+		}
+		lastSentHeight = height
+	}
+	return lastSentHeight, nil
+}
+
+// toBlockInfo converts a stored block to its proto representation
+func toBlockInfo(block *types.Block) *proto.BlockInfo {
+	return &proto.BlockInfo{
+		BlockHash:      block.BlockHash,
+		BlockHeight:    block.BlockHeight,
+		BlockTimestamp: block.BlockTimestamp,
+		ParentHash:     block.ParentHash,
+		StateRoot:      block.StateRoot,
+		L1OriginNumber: block.L1OriginNumber,
+		L1OriginHash:   block.L1OriginHash,
+	}
+}
+
+// toTransactionInfo converts a transaction finality status to its proto representation
+func toTransactionInfo(txInfo *types.TransactionInfo) *proto.TransactionInfo {
+	return &proto.TransactionInfo{
+		TxHash:           txInfo.TxHash,
+		BlockHash:        txInfo.BlockHash,
+		BlockHeight:      txInfo.BlockHeight,
+		BlockTimestamp:   txInfo.BlockTimestamp,
+		Status:           toFinalityStatus(txInfo.Status),
+		BabylonFinalized: txInfo.BabylonFinalized,
+	}
+}
+
+func toFinalityStatus(status types.FinalityStatus) proto.FinalityStatus {
+	switch status {
+	case types.FinalityStatusPending:
+		return proto.FinalityStatus_FINALITY_STATUS_PENDING
+	case types.FinalityStatusUnsafe:
+		return proto.FinalityStatus_FINALITY_STATUS_UNSAFE
+	case types.FinalityStatusSafe:
+		return proto.FinalityStatus_FINALITY_STATUS_SAFE
+	case types.FinalityStatusBitcoinFinalized:
+		return proto.FinalityStatus_FINALITY_STATUS_BTC_FINALIZED
+	case types.FinalityStatusFinalized:
+		return proto.FinalityStatus_FINALITY_STATUS_FINALIZED
+	default:
+		return proto.FinalityStatus_FINALITY_STATUS_UNSPECIFIED
+	}
+}
@@ -0,0 +1,287 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/babylonlabs-io/finality-gadget/proto"
+	"github.com/babylonlabs-io/finality-gadget/testutil/mocks"
+	"github.com/babylonlabs-io/finality-gadget/types"
+	"github.com/babylonlabs-io/finality-gadget/webhook"
+	"github.com/ethereum/go-ethereum"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestSubscribeFinalizedBlocks(t *testing.T) {
+	ctl := gomock.NewController(t)
+	mockFg := mocks.NewMockIFinalityGadget(ctl)
+	mockDb := mocks.NewMockIDatabaseHandler(ctl)
+	s := &Server{fg: mockFg, db: mockDb, logger: zap.NewNop()}
+
+	blocks := map[uint64]*types.Block{
+		1: {BlockHeight: 1, BlockHash: "0x1"},
+		2: {BlockHeight: 2, BlockHash: "0x2"},
+		3: {BlockHeight: 3, BlockHash: "0x3"},
+	}
+	events := make(chan *types.Event, 10)
+	mockFg.EXPECT().SubscribeEvents(subscriptionBufferSize).Return(events, func() {}).Times(1)
+	mockFg.EXPECT().QueryLatestFinalizedBlock().Return(blocks[3], nil).Times(1)
+	mockDb.EXPECT().QueryEarliestFinalizedBlock().Return(blocks[1], nil).Times(1)
+	for height := uint64(2); height <= 3; height++ {
+		mockFg.EXPECT().GetBlockByHeight(height).Return(blocks[height], nil).Times(1)
+	}
+
+	// blocks finalized while replaying are only sent once, and blocks finalized again after a reorg are resent
+	events <- &types.Event{Type: types.EventTypeBlockFinalized, Block: blocks[3]}
+	events <- &types.Event{Type: types.EventTypeBlockFinalized, Block: &types.Block{BlockHeight: 4, BlockHash: "0x4"}}
+	events <- &types.Event{Type: types.EventTypeReorg, Reorg: &types.ReorgEvent{ForkHeight: 3, OldTipHeight: 4}}
+	events <- &types.Event{Type: types.EventTypeBlockFinalized, Block: &types.Block{BlockHeight: 4, BlockHash: "0x4b"}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := newFakeBlockStream(ctx)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- s.SubscribeFinalizedBlocks(&proto.SubscribeFinalizedBlocksRequest{FromHeight: 2}, stream)
+	}()
+
+	for _, expectedHash := range []string{"0x2", "0x3", "0x4", "0x4b"} {
+		select {
+		case res := <-stream.sent:
+			require.Equal(t, expectedHash, res.Block.BlockHash)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for block %s", expectedHash)
+		}
+	}
+
+	// the stream ends once the client cancels
+	cancel()
+	require.NoError(t, <-errCh)
+	require.Empty(t, stream.sent)
+}
+
+func TestSubscribeFinalizedBlocksSkipsPrunedBlocks(t *testing.T) {
+	ctl := gomock.NewController(t)
+	mockFg := mocks.NewMockIFinalityGadget(ctl)
+	mockDb := mocks.NewMockIDatabaseHandler(ctl)
+	s := &Server{fg: mockFg, db: mockDb, logger: zap.NewNop()}
+
+	blocks := map[uint64]*types.Block{
+		1: {BlockHeight: 1, BlockHash: "0x1"},
+		3: {BlockHeight: 3, BlockHash: "0x3"},
+		4: {BlockHeight: 4, BlockHash: "0x4"},
+	}
+	events := make(chan *types.Event, 1)
+	mockFg.EXPECT().SubscribeEvents(subscriptionBufferSize).Return(events, func() {}).Times(1)
+	mockFg.EXPECT().QueryLatestFinalizedBlock().Return(blocks[4], nil).Times(1)
+
+	// blocks 1 and 2 are pruned once the replay started
+	gomock.InOrder(
+		mockDb.EXPECT().QueryEarliestFinalizedBlock().Return(blocks[1], nil),
+		mockDb.EXPECT().QueryEarliestFinalizedBlock().Return(blocks[3], nil),
+	)
+	mockFg.EXPECT().GetBlockByHeight(uint64(1)).Return(nil, types.ErrBlockNotFound).Times(1)
+	for height := uint64(3); height <= 4; height++ {
+		mockFg.EXPECT().GetBlockByHeight(height).Return(blocks[height], nil).Times(1)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := newFakeBlockStream(ctx)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- s.SubscribeFinalizedBlocks(&proto.SubscribeFinalizedBlocksRequest{FromHeight: 1}, stream)
+	}()
+
+	for _, expectedHash := range []string{"0x3", "0x4"} {
+		select {
+		case res := <-stream.sent:
+			require.Equal(t, expectedHash, res.Block.BlockHash)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for block %s", expectedHash)
+		}
+	}
+
+	cancel()
+	require.NoError(t, <-errCh)
+}
+
+func TestSubscribeFinalizedBlocksDropped(t *testing.T) {
+	ctl := gomock.NewController(t)
+	mockFg := mocks.NewMockIFinalityGadget(ctl)
+	s := &Server{fg: mockFg, logger: zap.NewNop()}
+
+	// the finality gadget closes the subscription if the client falls behind
+	events := make(chan *types.Event, 1)
+	close(events)
+	mockFg.EXPECT().SubscribeEvents(subscriptionBufferSize).Return(events, func() {}).Times(1)
+
+	err := s.SubscribeFinalizedBlocks(&proto.SubscribeFinalizedBlocksRequest{}, newFakeBlockStream(context.Background()))
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
+
+func TestSubscribeFinalizedBlocksEmptyDb(t *testing.T) {
+	ctl := gomock.NewController(t)
+	mockFg := mocks.NewMockIFinalityGadget(ctl)
+	s := &Server{fg: mockFg, logger: zap.NewNop()}
+
+	events := make(chan *types.Event, 1)
+	mockFg.EXPECT().SubscribeEvents(subscriptionBufferSize).Return(events, func() {}).Times(1)
+	mockFg.EXPECT().QueryLatestFinalizedBlock().Return(nil, nil).Times(1)
+	events <- &types.Event{Type: types.EventTypeBlockFinalized, Block: &types.Block{BlockHeight: 1, BlockHash: "0x1"}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := newFakeBlockStream(ctx)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- s.SubscribeFinalizedBlocks(&proto.SubscribeFinalizedBlocksRequest{FromHeight: 1}, stream)
+	}()
+
+	res := <-stream.sent
+	require.Equal(t, "0x1", res.Block.BlockHash)
+	cancel()
+	require.NoError(t, <-errCh)
+}
+
+func TestWatchTransaction(t *testing.T) {
+	// webhooks are disabled without a webhook secret
+	s := &Server{logger: zap.NewNop()}
+	_, err := s.WatchTransaction(context.Background(), &proto.WatchTransactionRequest{TxHash: "0x1", CallbackUrl: "https://example.com"})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	s.watcher = webhook.NewWatcher(&webhook.Config{Secret: "secret", PollInterval: time.Second}, nil, nil, zap.NewNop())
+	_, err = s.WatchTransaction(context.Background(), &proto.WatchTransactionRequest{TxHash: "0x1", CallbackUrl: "https://example.com"})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestQueryTransactionsStatus(t *testing.T) {
+	ctl := gomock.NewController(t)
+	mockFg := mocks.NewMockIFinalityGadget(ctl)
+	s := &Server{fg: mockFg, logger: zap.NewNop()}
+
+	txHashes := []string{"0x1", "0x2"}
+	mockFg.EXPECT().QueryTransactionsStatus(txHashes).Return([]*types.TransactionStatusResult{
+		{TxHash: "0x1", Found: true, Transaction: &types.TransactionInfo{TxHash: "0x1", BlockHeight: 10, Status: types.FinalityStatusBitcoinFinalized, BabylonFinalized: true}},
+		{TxHash: "0x2"},
+	}, nil).Times(1)
+
+	res, err := s.QueryTransactionsStatus(context.Background(), &proto.QueryTransactionsStatusRequest{TxHashes: txHashes})
+	require.NoError(t, err)
+	require.Len(t, res.Results, 2)
+	require.True(t, res.Results[0].Found)
+	require.Equal(t, proto.FinalityStatus_FINALITY_STATUS_BTC_FINALIZED, res.Results[0].Transaction.Status)
+	require.Equal(t, uint64(10), res.Results[0].Transaction.BlockHeight)
+	require.False(t, res.Results[1].Found)
+	require.Nil(t, res.Results[1].Transaction)
+
+	mockFg.EXPECT().QueryTransactionsStatus(gomock.Any()).Return(nil, types.ErrTooManyTransactions).Times(1)
+	_, err = s.QueryTransactionsStatus(context.Background(), &proto.QueryTransactionsStatusRequest{})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestQueryTransactionStatus(t *testing.T) {
+	ctl := gomock.NewController(t)
+	mockFg := mocks.NewMockIFinalityGadget(ctl)
+	s := &Server{fg: mockFg, logger: zap.NewNop()}
+
+	mockFg.EXPECT().QueryTransactionStatus("0x1").Return(&types.TransactionInfo{
+		TxHash:         "0x1",
+		BlockHash:      "abc",
+		BlockHeight:    10,
+		BlockTimestamp: 1000,
+		Status:         types.FinalityStatusSafe,
+	}, nil).Times(1)
+	res, err := s.QueryTransactionStatus(context.Background(), &proto.QueryTransactionStatusRequest{TxHash: "0x1"})
+	require.NoError(t, err)
+	require.Equal(t, &proto.TransactionInfo{
+		TxHash:         "0x1",
+		BlockHash:      "abc",
+		BlockHeight:    10,
+		BlockTimestamp: 1000,
+		Status:         proto.FinalityStatus_FINALITY_STATUS_SAFE,
+	}, res.Transaction)
+
+	mockFg.EXPECT().QueryTransactionStatus("0x2").Return(nil, ethereum.NotFound).Times(1)
+	_, err = s.QueryTransactionStatus(context.Background(), &proto.QueryTransactionStatusRequest{TxHash: "0x2"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	mockFg.EXPECT().QueryTransactionStatus("0x3").Return(nil, types.ErrInvalidTxHash).Times(1)
+	_, err = s.QueryTransactionStatus(context.Background(), &proto.QueryTransactionStatusRequest{TxHash: "0x3"})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestQueryBlockByHeight(t *testing.T) {
+	ctl := gomock.NewController(t)
+	mockFg := mocks.NewMockIFinalityGadget(ctl)
+	s := &Server{fg: mockFg, logger: zap.NewNop()}
+
+	mockFg.EXPECT().GetBlockByHeight(uint64(10)).Return(&types.Block{BlockHeight: 10, BlockHash: "0x10", BlockTimestamp: 1000}, nil).Times(1)
+	res, err := s.QueryBlockByHeight(context.Background(), &proto.QueryBlockByHeightRequest{BlockHeight: 10})
+	require.NoError(t, err)
+	require.Equal(t, uint64(10), res.Block.BlockHeight)
+	require.Equal(t, "0x10", res.Block.BlockHash)
+
+	mockFg.EXPECT().GetBlockByHeight(uint64(11)).Return(nil, types.ErrBlockNotFound).Times(1)
+	_, err = s.QueryBlockByHeight(context.Background(), &proto.QueryBlockByHeightRequest{BlockHeight: 11})
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestQueryBlockFinalityEvidence(t *testing.T) {
+	ctl := gomock.NewController(t)
+	mockFg := mocks.NewMockIFinalityGadget(ctl)
+	s := &Server{fg: mockFg, logger: zap.NewNop()}
+
+	mockFg.EXPECT().QueryBlockFinalityEvidence(uint64(10)).Return(nil, types.ErrFinalityEvidenceNotFound).Times(1)
+	_, err := s.QueryBlockFinalityEvidence(context.Background(), &proto.QueryBlockFinalityEvidenceRequest{BlockHeight: 10})
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestQueryChainSyncStatus(t *testing.T) {
+	ctl := gomock.NewController(t)
+	mockFg := mocks.NewMockIFinalityGadget(ctl)
+	s := &Server{fg: mockFg, logger: zap.NewNop()}
+
+	mockFg.EXPECT().QueryChainSyncStatus().Return(&types.ChainSyncStatus{
+		LatestBlockHeight:               100,
+		LatestBtcFinalizedBlockHeight:   90,
+		EarliestBtcFinalizedBlockHeight: 1,
+		LatestEthFinalizedBlockHeight:   80,
+	}, nil).Times(1)
+	res, err := s.QueryChainSyncStatus(context.Background(), &proto.QueryChainSyncStatusRequest{})
+	require.NoError(t, err)
+	require.Equal(t, uint64(100), res.LatestBlockHeight)
+	require.Equal(t, uint64(90), res.LatestBtcFinalizedBlockHeight)
+	require.Equal(t, uint64(1), res.EarliestBtcFinalizedBlockHeight)
+	require.Equal(t, uint64(80), res.LatestEthFinalizedBlockHeight)
+
+	mockFg.EXPECT().QueryChainSyncStatus().Return(nil, types.ErrBlockNotFound).Times(1)
+	_, err = s.QueryChainSyncStatus(context.Background(), &proto.QueryChainSyncStatusRequest{})
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
+
+// fakeBlockStream is a server stream recording the sent blocks
+type fakeBlockStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	sent chan *proto.SubscribeFinalizedBlocksResponse
+}
+
+func newFakeBlockStream(ctx context.Context) *fakeBlockStream {
+	return &fakeBlockStream{
+		ctx:  ctx,
+		sent: make(chan *proto.SubscribeFinalizedBlocksResponse, 10),
+	}
+}
+
+func (s *fakeBlockStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *fakeBlockStream) Send(res *proto.SubscribeFinalizedBlocksResponse) error {
+	s.sent <- res
+	return nil
+}
@@ -0,0 +1,87 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/babylonlabs-io/finality-gadget/proto"
+	"github.com/babylonlabs-io/finality-gadget/types"
+	"go.uber.org/zap"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// readinessCheckInterval is how often the gRPC health status is refreshed from the readiness checks
+const readinessCheckInterval = 10 * time.Second
+
+// monitorReadiness keeps the gRPC health status of the server and of the FinalityGadget service, and
+// the report served by /health/ready, in sync with the readiness of the finality gadget, until the
+// context is cancelled
+func (s *Server) monitorReadiness(ctx context.Context) {
+	ticker := time.NewTicker(readinessCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		s.updateHealthStatus(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Server) updateHealthStatus(ctx context.Context) {
+	report := s.fg.CheckReadiness(ctx)
+	s.readiness.Store(report)
+	status := healthpb.HealthCheckResponse_SERVING
+	if !report.Healthy {
+		status = healthpb.HealthCheckResponse_NOT_SERVING
+		for _, check := range report.Checks {
+			if !check.Healthy {
+				s.logger.Warn("Readiness check failed", zap.String("check", check.Name), zap.String("error", check.Error))
+			}
+		}
+	}
+	s.health.SetServingStatus("", status)
+	s.health.SetServingStatus(proto.FinalityGadget_ServiceDesc.ServiceName, status)
+}
+
+// livenessHandler reports whether the block processing loop is making progress, with a 503 status
+// if it stalled so that the pod is restarted
+func (s *Server) livenessHandler(w http.ResponseWriter, r *http.Request) {
+	s.logger.Debug("liveness request", zap.String("path", r.URL.Path))
+	s.writeHealthReport(w, s.fg.CheckLiveness())
+}
+
+// readinessHandler reports whether the finality gadget is able to serve up to date finality data,
+// with a 503 status if not so that traffic is routed to other pods. The report of the last
+// readiness monitor run is served, so that probes don't query the upstream nodes.
+func (s *Server) readinessHandler(w http.ResponseWriter, r *http.Request) {
+	s.logger.Debug("readiness request", zap.String("path", r.URL.Path))
+	report := s.readiness.Load()
+	if report == nil {
+		report = s.fg.CheckReadiness(r.Context())
+	}
+	s.writeHealthReport(w, report)
+}
+
+func (s *Server) writeHealthReport(w http.ResponseWriter, report *types.HealthReport) {
+	jsonResponse, err := json.Marshal(report)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if report.Healthy {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if _, err := w.Write(jsonResponse); err != nil {
+		s.logger.Error("Failed to write response", zap.Error(err))
+	}
+}
@@ -0,0 +1,139 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/babylonlabs-io/finality-gadget/config"
+	"github.com/babylonlabs-io/finality-gadget/proto"
+	"github.com/babylonlabs-io/finality-gadget/testutil/mocks"
+	"github.com/babylonlabs-io/finality-gadget/types"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+)
+
+func TestUpdateHealthStatus(t *testing.T) {
+	ctl := gomock.NewController(t)
+	mockFg := mocks.NewMockIFinalityGadget(ctl)
+	s := &Server{fg: mockFg, health: health.NewServer(), logger: zap.NewNop()}
+
+	// the status of the server and of the service follows the readiness of the finality gadget
+	for _, healthy := range []bool{true, false, true} {
+		mockFg.EXPECT().CheckReadiness(gomock.Any()).Return(&types.HealthReport{
+			Checks:  []*types.HealthCheck{{Name: "db", Healthy: healthy}},
+			Healthy: healthy,
+		}).Times(1)
+		s.updateHealthStatus(context.Background())
+
+		expected := healthpb.HealthCheckResponse_NOT_SERVING
+		if healthy {
+			expected = healthpb.HealthCheckResponse_SERVING
+		}
+		for _, service := range []string{"", proto.FinalityGadget_ServiceDesc.ServiceName} {
+			res, err := s.health.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+			require.NoError(t, err)
+			require.Equal(t, expected, res.Status)
+		}
+	}
+}
+
+func TestHealthProbes(t *testing.T) {
+	ctl := gomock.NewController(t)
+	mockFg := mocks.NewMockIFinalityGadget(ctl)
+	s := &Server{fg: mockFg, health: health.NewServer(), logger: zap.NewNop()}
+
+	notReady := &types.HealthReport{
+		Checks: []*types.HealthCheck{
+			{Name: "db", Healthy: true},
+			{Name: "l2", Error: "connection refused"},
+		},
+	}
+	mockFg.EXPECT().CheckLiveness().Return(&types.HealthReport{Checks: []*types.HealthCheck{{Name: "block_processing", Healthy: true}}, Healthy: true}).Times(1)
+	mockFg.EXPECT().CheckReadiness(gomock.Any()).Return(notReady).Times(1)
+
+	// the liveness is checked on every request
+	code, report := getHealthReport(t, s.livenessHandler)
+	require.Equal(t, http.StatusOK, code)
+	require.True(t, report.Healthy)
+
+	// the readiness is checked on request until the readiness monitor ran, with the breakdown of the checks
+	code, report = getHealthReport(t, s.readinessHandler)
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	require.Equal(t, notReady, report)
+
+	// then the last report of the readiness monitor is served
+	mockFg.EXPECT().CheckReadiness(gomock.Any()).Return(&types.HealthReport{Healthy: true}).Times(1)
+	s.updateHealthStatus(context.Background())
+	code, report = getHealthReport(t, s.readinessHandler)
+	require.Equal(t, http.StatusOK, code)
+	require.True(t, report.Healthy)
+}
+
+func TestGrpcHealthAndReflection(t *testing.T) {
+	ctl := gomock.NewController(t)
+	mockFg := mocks.NewMockIFinalityGadget(ctl)
+	mockFg.EXPECT().CheckReadiness(gomock.Any()).Return(&types.HealthReport{Healthy: true}).AnyTimes()
+	_, conn := startTestGrpcServer(t, mockFg)
+
+	// the health status is served once the readiness checks ran
+	healthClient := healthpb.NewHealthClient(conn)
+	require.Eventually(t, func() bool {
+		res, err := healthClient.Check(context.Background(), &healthpb.HealthCheckRequest{Service: proto.FinalityGadget_ServiceDesc.ServiceName})
+		return err == nil && res.Status == healthpb.HealthCheckResponse_SERVING
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// the services are listed by reflection
+	stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+	}))
+	res, err := stream.Recv()
+	require.NoError(t, err)
+	var services []string
+	for _, service := range res.GetListServicesResponse().Service {
+		services = append(services, service.Name)
+	}
+	require.Contains(t, services, proto.FinalityGadget_ServiceDesc.ServiceName)
+	require.Contains(t, services, healthpb.Health_ServiceDesc.ServiceName)
+	require.NoError(t, stream.CloseSend())
+}
+
+func getHealthReport(t *testing.T, handler http.HandlerFunc) (int, *types.HealthReport) {
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	var report types.HealthReport
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	return rec.Code, &report
+}
+
+// startTestGrpcServer starts the gRPC server on a random port and returns a connection to it
+func startTestGrpcServer(t *testing.T, fg *mocks.MockIFinalityGadget) (*Server, *grpc.ClientConn) {
+	s := &Server{
+		fg:     fg,
+		cfg:    &config.Config{GRPCListener: "127.0.0.1:0"},
+		logger: zap.NewNop(),
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	require.NoError(t, s.startGrpcServer(ctx))
+	t.Cleanup(func() {
+		cancel()
+		s.grpcServer.Stop()
+	})
+
+	conn, err := grpc.NewClient(s.grpcAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return s, conn
+}
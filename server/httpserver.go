@@ -1,18 +1,59 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 
+	"github.com/babylonlabs-io/finality-gadget/proto"
+	"github.com/babylonlabs-io/finality-gadget/types"
+	"github.com/babylonlabs-io/finality-gadget/webhook"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/encoding/protojson"
 )
 
-func (s *Server) newHttpHandler() http.Handler {
+func (s *Server) newHttpHandler(ctx context.Context) (http.Handler, error) {
+	gateway, err := s.newGatewayHandler(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	mux := http.NewServeMux()
 	mux.HandleFunc("/v1/transaction", s.txStatusHandler)
+	mux.HandleFunc("/v1/transactions", s.txsStatusHandler)
 	mux.HandleFunc("/v1/chainSyncStatus", s.chainSyncStatusHandler)
-	mux.HandleFunc("/health", s.healthHandler)
-	return mux
+	mux.HandleFunc("/v1/blockFinalityEvidence", s.blockFinalityEvidenceHandler)
+	mux.HandleFunc("/v1/stream", s.streamHandler)
+	mux.HandleFunc("/v1/watch", s.watchHandler)
+	mux.HandleFunc("/health/live", s.livenessHandler)
+	mux.HandleFunc("/health/ready", s.readinessHandler)
+	// kept for compatibility, use /health/ready instead
+	mux.HandleFunc("/health", s.readinessHandler)
+	mux.Handle("/metrics", s.metrics.Handler())
+	mux.Handle("/api/", gateway)
+	return mux, nil
+}
+
+// newGatewayHandler serves the REST mapping of every gRPC method under /api/v1, generated by
+// grpc-gateway from the google.api.http annotations in finalitygadget.proto. Requests are
+// forwarded to the gRPC server, so they go through the same interceptors.
+func (s *Server) newGatewayHandler(ctx context.Context) (http.Handler, error) {
+	mux := runtime.NewServeMux(
+		runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.JSONPb{
+			MarshalOptions: protojson.MarshalOptions{EmitUnpopulated: true},
+		}),
+	)
+	opts := []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+	if err := proto.RegisterFinalityGadgetHandlerFromEndpoint(ctx, mux, s.grpcAddr, opts); err != nil {
+		return nil, fmt.Errorf("failed to register REST gateway: %w", err)
+	}
+	return mux, nil
 }
 
 func (s *Server) txStatusHandler(w http.ResponseWriter, r *http.Request) {
@@ -46,6 +87,53 @@ func (s *Server) txStatusHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// txsStatusRequest is the body of a /v1/transactions request
+type txsStatusRequest struct {
+	Hashes []string `json:"hashes"`
+}
+
+func (s *Server) txsStatusHandler(w http.ResponseWriter, r *http.Request) {
+	s.logger.Debug("transactions status request",
+		zap.String("path", "/v1/transactions"),
+		zap.String("method", r.Method),
+		zap.String("remoteAddr", r.RemoteAddr),
+	)
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req txsStatusRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Get statuses from rpc.
+	results, err := s.fg.QueryTransactionsStatus(req.Hashes)
+	if err != nil {
+		if errors.Is(err, types.ErrTooManyTransactions) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	jsonResponse, err := json.Marshal(results)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(jsonResponse)
+	if err != nil {
+		s.logger.Error("Failed to write response", zap.Error(err))
+	}
+}
+
 func (s *Server) chainSyncStatusHandler(w http.ResponseWriter, r *http.Request) {
 	s.logger.Debug(
 		"chainSyncStatus request",
@@ -72,13 +160,95 @@ func (s *Server) chainSyncStatusHandler(w http.ResponseWriter, r *http.Request)
 	}
 }
 
-func (s *Server) healthHandler(w http.ResponseWriter, r *http.Request) {
-	s.logger.Debug(
-		"health request",
-		zap.String("path", "/health"),
+func (s *Server) blockFinalityEvidenceHandler(w http.ResponseWriter, r *http.Request) {
+	// Extract query parameters
+	heightStr := r.URL.Query().Get("height")
+	s.logger.Debug("block finality evidence request",
+		zap.String("path", "/v1/blockFinalityEvidence"),
+		zap.String("method", r.Method),
+		zap.String("height", heightStr),
+		zap.String("remoteAddr", r.RemoteAddr),
+	)
+
+	height, err := strconv.ParseUint(heightStr, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid block height", http.StatusBadRequest)
+		return
+	}
+
+	// Get evidence from db
+	evidence, err := s.fg.QueryBlockFinalityEvidence(height)
+	if err != nil {
+		if errors.Is(err, types.ErrFinalityEvidenceNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	jsonResponse, err := json.Marshal(evidence)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(jsonResponse)
+	if err != nil {
+		s.logger.Error("Failed to write response", zap.Error(err))
+	}
+}
+
+// watchRequest is the body of a /v1/watch request
+type watchRequest struct {
+	TxHash      string `json:"txHash"`
+	CallbackUrl string `json:"callbackUrl"`
+}
+
+func (s *Server) watchHandler(w http.ResponseWriter, r *http.Request) {
+	s.logger.Debug("watch request",
+		zap.String("path", "/v1/watch"),
+		zap.String("method", r.Method),
+		zap.String("remoteAddr", r.RemoteAddr),
 	)
-	response := "Finality gadget is healthy"
-	_, err := w.Write([]byte(response))
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req watchRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Register the watch
+	watch, err := s.watcher.Watch(req.TxHash, req.CallbackUrl)
+	if err != nil {
+		switch {
+		case errors.Is(err, webhook.ErrWebhooksDisabled):
+			http.Error(w, err.Error(), http.StatusNotImplemented)
+		case errors.Is(err, webhook.ErrInvalidWatch):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, webhook.ErrTooManyWatches):
+			http.Error(w, err.Error(), http.StatusTooManyRequests)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	jsonResponse, err := json.Marshal(watch)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(jsonResponse)
 	if err != nil {
 		s.logger.Error("Failed to write response", zap.Error(err))
 	}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/babylonlabs-io/finality-gadget/testutil/mocks"
+	"github.com/babylonlabs-io/finality-gadget/types"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestGatewayHandler(t *testing.T) {
+	ctl := gomock.NewController(t)
+	mockFg := mocks.NewMockIFinalityGadget(ctl)
+	mockFg.EXPECT().CheckReadiness(gomock.Any()).Return(&types.HealthReport{Healthy: true}).AnyTimes()
+	s, _ := startTestGrpcServer(t, mockFg)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	gateway, err := s.newGatewayHandler(ctx)
+	require.NoError(t, err)
+	srv := httptest.NewServer(gateway)
+	defer srv.Close()
+
+	// path parameters are mapped to the request
+	mockFg.EXPECT().GetBlockByHeight(uint64(10)).Return(&types.Block{BlockHeight: 10, BlockHash: "0xa", BlockTimestamp: 1000}, nil).Times(1)
+	res, err := http.Get(srv.URL + "/api/v1/blocks/10")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	var block struct {
+		Block struct {
+			BlockHash   string `json:"blockHash"`
+			BlockHeight string `json:"blockHeight"`
+		} `json:"block"`
+	}
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&block))
+	res.Body.Close()
+	require.Equal(t, "0xa", block.Block.BlockHash)
+	require.Equal(t, "10", block.Block.BlockHeight)
+
+	// request bodies are mapped to the request, and unpopulated fields are returned
+	mockFg.EXPECT().QueryIsBlockBabylonFinalized(&types.Block{BlockHash: "0xb", BlockHeight: 11}).Return(false, nil).Times(1)
+	res, err = http.Post(srv.URL+"/api/v1/isBlockBabylonFinalized", "application/json", strings.NewReader(`{"block":{"blockHash":"0xb","blockHeight":"11"}}`))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	res.Body.Close()
+	require.JSONEq(t, `{"isFinalized":false}`, string(body))
+
+	// gRPC status codes are mapped to HTTP status codes
+	mockFg.EXPECT().QueryChainSyncStatus().Return(nil, types.ErrBlockNotFound).Times(1)
+	res, err = http.Get(srv.URL + "/api/v1/chainSyncStatus")
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusNotFound, res.StatusCode)
+}
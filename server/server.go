@@ -11,97 +11,159 @@ import (
 	"github.com/babylonlabs-io/finality-gadget/config"
 	"github.com/babylonlabs-io/finality-gadget/db"
 	"github.com/babylonlabs-io/finality-gadget/finalitygadget"
+	"github.com/babylonlabs-io/finality-gadget/metrics"
 	"github.com/babylonlabs-io/finality-gadget/proto"
-	"github.com/lightningnetwork/lnd/signal"
+	"github.com/babylonlabs-io/finality-gadget/supervisor"
+	"github.com/babylonlabs-io/finality-gadget/types"
+	"github.com/babylonlabs-io/finality-gadget/webhook"
 	"github.com/rs/cors"
 	"go.uber.org/zap"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
 )
 
+// shutdownTimeout bounds how long the gRPC and HTTP servers wait for in-flight requests to complete on shutdown
+const shutdownTimeout = 10 * time.Second
+
+var _ supervisor.Service = &Server{}
+
 // Server is the main daemon construct for the finality gadget server. It
-// handles spinning up both the gRPC and HTTP servers, the database, and any
-// other components that the the finality gadget server needs to run.
+// handles spinning up both the gRPC and HTTP servers, and runs as a service of
+// the daemon supervisor.
 type Server struct {
 	proto.UnimplementedFinalityGadgetServer
 
-	grpcServer  *grpc.Server
-	httpServer  *http.Server
-	fg          finalitygadget.IFinalityGadget
-	cfg         *config.Config
-	db          db.IDatabaseHandler
-	logger      *zap.Logger
-	interceptor signal.Interceptor
-
-	started int32
+	grpcServer *grpc.Server
+	httpServer *http.Server
+	health     *health.Server
+	fg         finalitygadget.IFinalityGadget
+	cfg        *config.Config
+	db         db.IDatabaseHandler
+	metrics    *metrics.FinalityGadgetMetrics
+	watcher    *webhook.Watcher
+	logger     *zap.Logger
+	// shutdown is closed once the server shuts down, to close the open event streams
+	shutdown chan struct{}
+	// serveErrs receives the errors of the gRPC and HTTP servers failing to serve
+	serveErrs chan error
+	// readiness is the last readiness report of the finality gadget, nil until it is first checked
+	readiness atomic.Pointer[types.HealthReport]
+	// grpcAddr is the address the gRPC server listens on, dialed by the REST gateway
+	grpcAddr string
 }
 
 // NewFinalityGadgetServer creates a new server with the given config.
-func NewFinalityGadgetServer(cfg *config.Config, db db.IDatabaseHandler, fg finalitygadget.IFinalityGadget, sig signal.Interceptor, logger *zap.Logger) *Server {
+func NewFinalityGadgetServer(cfg *config.Config, db db.IDatabaseHandler, fg finalitygadget.IFinalityGadget, watcher *webhook.Watcher, metrics *metrics.FinalityGadgetMetrics, logger *zap.Logger) *Server {
 	return &Server{
-		fg:          fg,
-		cfg:         cfg,
-		db:          db,
-		logger:      logger,
-		interceptor: sig,
+		fg:        fg,
+		cfg:       cfg,
+		db:        db,
+		metrics:   metrics,
+		watcher:   watcher,
+		logger:    logger,
+		shutdown:  make(chan struct{}),
+		serveErrs: make(chan error, 2),
 	}
 }
 
-// RunUntilShutdown runs the main finality gadget server loop until a signal is
-// received to shut down the process.
-func (s *Server) RunUntilShutdown() error {
-	if atomic.AddInt32(&s.started, 1) != 1 {
-		return nil
-	}
-
-	defer func() {
-		s.logger.Info("Closing database...")
-		if err := s.db.Close(); err != nil {
-			s.logger.Error("Failed to close database", zap.Error(err))
-		} else {
-			s.logger.Info("Database closed")
-		}
-	}()
+// Name implements supervisor.Service
+func (s *Server) Name() string {
+	return "server"
+}
 
-	if err := s.startGrpcServer(); err != nil {
-		return fmt.Errorf("failed to start gRPC listener: %v", err)
+// Start binds the gRPC and HTTP listeners and starts serving. The context stops the readiness monitor and the REST
+// gateway once it is cancelled.
+func (s *Server) Start(ctx context.Context) error {
+	if err := s.startGrpcServer(ctx); err != nil {
+		return fmt.Errorf("failed to start gRPC listener: %w", err)
 	}
 
-	if err := s.startHttpServer(); err != nil {
-		return fmt.Errorf("failed to start HTTP server: %v", err)
+	if err := s.startHttpServer(ctx); err != nil {
+		s.grpcServer.Stop()
+		return fmt.Errorf("failed to start HTTP server: %w", err)
 	}
 
 	s.logger.Info("Finality gadget is active")
+	return nil
+}
 
-	// Wait for shutdown signal from either a graceful server stop or from
-	// the interrupt handler.
-	<-s.interceptor.ShutdownChannel()
+/* Run serves until the context is cancelled or a server fails, and then shuts down the servers
+ *
+ * - gRPC health checking clients are told the server is NOT_SERVING first
+ * - open event streams are closed
+ * - in-flight gRPC and HTTP requests are drained, for up to shutdownTimeout each. the gRPC server is then
+ *   stopped forcefully
+ */
+func (s *Server) Run(ctx context.Context) error {
+	var serveErr error
+	select {
+	case <-ctx.Done():
+	case serveErr = <-s.serveErrs:
+		s.logger.Error("Server failed, shutting down", zap.Error(serveErr))
+	}
 
-	// shutdown servers
-	s.grpcServer.GracefulStop()
-	if err := s.httpServer.Shutdown(context.Background()); err != nil {
+	s.logger.Info("Shutting down servers...")
+	s.health.Shutdown()
+	close(s.shutdown)
+
+	stopped := make(chan struct{})
+	go func() {
+		s.grpcServer.GracefulStop()
+		close(stopped)
+	}()
+	timer := time.NewTimer(shutdownTimeout)
+	defer timer.Stop()
+	select {
+	case <-stopped:
+	case <-timer.C:
+		s.logger.Warn("Timed out draining gRPC requests, stopping", zap.Duration("timeout", shutdownTimeout))
+		s.grpcServer.Stop()
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := s.httpServer.Shutdown(shutdownCtx); err != nil {
 		s.logger.Error("Error shutting down HTTP server", zap.Error(err))
 	}
 
+	s.logger.Info("Servers shut down")
+	return serveErr
+}
+
+// Stop implements supervisor.Service. The servers are shut down by Run, and the db is closed by its owner.
+func (s *Server) Stop() error {
 	return nil
 }
 
-func (s *Server) startGrpcServer() error {
+func (s *Server) startGrpcServer(ctx context.Context) error {
 	listener, err := net.Listen("tcp", s.cfg.GRPCListener)
 	if err != nil {
 		return fmt.Errorf("failed to listen on %s: %w", s.cfg.GRPCListener, err)
 	}
 
-	grpcServer := grpc.NewServer()
+	grpcServer := grpc.NewServer(
+		grpc.UnaryInterceptor(s.metrics.UnaryServerInterceptor()),
+		grpc.StreamInterceptor(s.metrics.StreamServerInterceptor()),
+	)
 	proto.RegisterFinalityGadgetServer(grpcServer, s)
+	// serve the standard health checking protocol, and reflection for tools like grpcurl
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+	reflection.Register(grpcServer)
+	s.health = healthServer
+	s.grpcAddr = listener.Addr().String()
+	go s.monitorReadiness(ctx)
 
 	listenerReady := make(chan struct{})
-	// TODO: handle errors if grpcServer.Serve fails in the goroutine
 	go func() {
 		s.logger.Info("gRPC server listening", zap.String("address", s.cfg.GRPCListener))
 		close(listenerReady)
 		if err := grpcServer.Serve(listener); err != nil {
-			s.logger.Error("gRPC server failed", zap.Error(err))
+			s.serveErrs <- fmt.Errorf("gRPC server failed: %w", err)
 		}
 	}()
 	<-listenerReady
@@ -109,7 +171,12 @@ func (s *Server) startGrpcServer() error {
 	return nil
 }
 
-func (s *Server) startHttpServer() error {
+func (s *Server) startHttpServer(ctx context.Context) error {
+	handler, err := s.newHttpHandler(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create HTTP handler: %w", err)
+	}
+
 	corsOpts := cors.Options{
 		AllowOriginFunc:  func(origin string) bool { return true },
 		AllowCredentials: true,
@@ -119,7 +186,7 @@ func (s *Server) startHttpServer() error {
 
 	httpServer := &http.Server{
 		Addr:              s.cfg.HTTPListener,
-		Handler:           cors.New(corsOpts).Handler(s.newHttpHandler()),
+		Handler:           cors.New(corsOpts).Handler(handler),
 		ReadHeaderTimeout: 30 * time.Second,
 	}
 
@@ -129,12 +196,11 @@ func (s *Server) startHttpServer() error {
 	}
 
 	listenerReady := make(chan struct{})
-	// TODO: handle errors if httpServer.Serve fails in the goroutine
 	go func() {
 		s.logger.Info("Starting standalone HTTP server", zap.String("address", s.cfg.HTTPListener))
 		close(listenerReady)
 		if err := httpServer.Serve(listener); err != nil && err != http.ErrServerClosed {
-			s.logger.Error("HTTP server failed", zap.Error(err))
+			s.serveErrs <- fmt.Errorf("HTTP server failed: %w", err)
 		}
 	}()
 	<-listenerReady
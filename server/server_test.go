@@ -0,0 +1,59 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/babylonlabs-io/finality-gadget/config"
+	"github.com/babylonlabs-io/finality-gadget/metrics"
+	"github.com/babylonlabs-io/finality-gadget/proto"
+	"github.com/babylonlabs-io/finality-gadget/testutil/mocks"
+	"github.com/babylonlabs-io/finality-gadget/types"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+func TestServerShutdown(t *testing.T) {
+	ctl := gomock.NewController(t)
+	mockFg := mocks.NewMockIFinalityGadget(ctl)
+	mockFg.EXPECT().CheckReadiness(gomock.Any()).Return(&types.HealthReport{Healthy: true}).AnyTimes()
+	subscribed := make(chan struct{})
+	mockFg.EXPECT().SubscribeEvents(subscriptionBufferSize).DoAndReturn(func(int) (<-chan *types.Event, func()) {
+		close(subscribed)
+		return make(chan *types.Event), func() {}
+	}).Times(1)
+
+	cfg := &config.Config{GRPCListener: "127.0.0.1:0", HTTPListener: "127.0.0.1:0"}
+	s := NewFinalityGadgetServer(cfg, nil, mockFg, nil, metrics.NewFinalityGadgetMetrics(), zap.NewNop())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, s.Start(ctx))
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- s.Run(ctx)
+	}()
+
+	conn, err := grpc.NewClient(s.grpcAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+	stream, err := proto.NewFinalityGadgetClient(conn).SubscribeFinalizedBlocks(context.Background(), &proto.SubscribeFinalizedBlocksRequest{})
+	require.NoError(t, err)
+	<-subscribed
+
+	// open streams are ended so that in-flight requests are drained without waiting for the shutdown timeout
+	cancel()
+	select {
+	case err := <-runErr:
+		require.NoError(t, err)
+	case <-time.After(shutdownTimeout / 2):
+		t.Fatal("server did not shut down")
+	}
+	_, err = stream.Recv()
+	require.Equal(t, codes.Unavailable, status.Code(err))
+}
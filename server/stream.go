@@ -0,0 +1,191 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/babylonlabs-io/finality-gadget/types"
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+)
+
+const (
+	// streamKeepAliveInterval is the interval of SSE keep-alive comments and WebSocket pings
+	streamKeepAliveInterval = 15 * time.Second
+	// wsWriteTimeout is the timeout of a WebSocket write
+	wsWriteTimeout = 10 * time.Second
+)
+
+// wsUpgrader accepts WebSocket connections from any origin, like the CORS options of the HTTP server
+var wsUpgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// streamHandler pushes the finality gadget events as JSON, over a WebSocket if the request is a
+// WebSocket upgrade and as Server-Sent Events otherwise. The current chain sync status is sent first.
+func (s *Server) streamHandler(w http.ResponseWriter, r *http.Request) {
+	isWebSocket := websocket.IsWebSocketUpgrade(r)
+	s.logger.Debug(
+		"stream request",
+		zap.String("path", "/v1/stream"),
+		zap.Bool("websocket", isWebSocket),
+	)
+
+	if isWebSocket {
+		s.serveWebSocketStream(w, r)
+		return
+	}
+	s.serveSSEStream(w, r)
+}
+
+/* serveSSEStream streams the events as Server-Sent Events until the client disconnects
+ *
+ * - each event is sent with the event type as the SSE event name and the JSON event as data
+ * - a keep-alive comment is sent periodically so that proxies don't close the idle connection
+ * - if the client falls behind, the stream ends and the client is expected to reconnect
+ */
+func (s *Server) serveSSEStream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	events, unsubscribe := s.fg.SubscribeEvents(subscriptionBufferSize)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	for _, event := range s.initialStreamEvents() {
+		if err := writeSSEEvent(w, event); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	ticker := time.NewTicker(streamKeepAliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.shutdown:
+			return
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case event, ok := <-events:
+			if !ok {
+				s.logger.Debug("SSE subscriber fell behind, closing stream")
+				return
+			}
+			if err := writeSSEEvent(w, event); err != nil {
+				s.logger.Debug("Failed to write SSE event", zap.Error(err))
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+/* serveWebSocketStream streams the events as JSON text messages over a WebSocket until either side closes it
+ *
+ * - messages from the client are read and discarded, to process close frames and pongs
+ * - a ping is sent periodically so that proxies don't close the idle connection
+ * - if the client falls behind, the connection is closed with code 1013 (try again later)
+ */
+func (s *Server) serveWebSocketStream(w http.ResponseWriter, r *http.Request) {
+	conn, err := wsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// the upgrader already replied with an HTTP error
+		s.logger.Debug("Failed to upgrade to WebSocket", zap.Error(err))
+		return
+	}
+	defer conn.Close()
+
+	events, unsubscribe := s.fg.SubscribeEvents(subscriptionBufferSize)
+	defer unsubscribe()
+
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for _, event := range s.initialStreamEvents() {
+		if err := writeWebSocketEvent(conn, event); err != nil {
+			return
+		}
+	}
+
+	ticker := time.NewTicker(streamKeepAliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case <-s.shutdown:
+			closeWebSocket(conn, websocket.CloseGoingAway, "server shutting down")
+			return
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteTimeout)); err != nil {
+				return
+			}
+		case event, ok := <-events:
+			if !ok {
+				s.logger.Debug("WebSocket subscriber fell behind, closing connection")
+				closeWebSocket(conn, websocket.CloseTryAgainLater, "subscriber fell behind")
+				return
+			}
+			if err := writeWebSocketEvent(conn, event); err != nil {
+				s.logger.Debug("Failed to write WebSocket event", zap.Error(err))
+				return
+			}
+		}
+	}
+}
+
+// initialStreamEvents returns the events sent when a stream starts, so that clients don't have to
+// wait for the next change to know the current state
+func (s *Server) initialStreamEvents() []*types.Event {
+	status, err := s.fg.QueryChainSyncStatus()
+	if err != nil {
+		if !errors.Is(err, types.ErrBlockNotFound) {
+			s.logger.Warn("Failed to query chain sync status for stream", zap.Error(err))
+		}
+		return nil
+	}
+	return []*types.Event{{Type: types.EventTypeChainSyncStatus, ChainSyncStatus: status}}
+}
+
+func writeSSEEvent(w http.ResponseWriter, event *types.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+	return err
+}
+
+func writeWebSocketEvent(conn *websocket.Conn, event *types.Event) error {
+	if err := conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout)); err != nil {
+		return err
+	}
+	return conn.WriteJSON(event)
+}
+
+func closeWebSocket(conn *websocket.Conn, code int, reason string) {
+	_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(wsWriteTimeout))
+}
@@ -0,0 +1,106 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/babylonlabs-io/finality-gadget/testutil/mocks"
+	"github.com/babylonlabs-io/finality-gadget/types"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+)
+
+func TestStreamSSE(t *testing.T) {
+	ctl := gomock.NewController(t)
+	mockFg := mocks.NewMockIFinalityGadget(ctl)
+	s := &Server{fg: mockFg, logger: zap.NewNop()}
+
+	status := &types.ChainSyncStatus{LatestBlockHeight: 10, LatestBtcFinalizedBlockHeight: 8}
+	events := make(chan *types.Event, 10)
+	mockFg.EXPECT().SubscribeEvents(subscriptionBufferSize).Return(events, func() {}).Times(1)
+	mockFg.EXPECT().QueryChainSyncStatus().Return(status, nil).Times(1)
+	events <- &types.Event{Type: types.EventTypeBlockFinalized, Block: &types.Block{BlockHeight: 9, BlockHash: "0x9"}}
+	events <- &types.Event{Type: types.EventTypeBtcStakingActivated, BtcStakingActivatedTimestamp: 1234}
+
+	srv := httptest.NewServer(http.HandlerFunc(s.streamHandler))
+	defer srv.Close()
+
+	res, err := http.Get(srv.URL)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+
+	// the current chain sync status is sent first
+	reader := bufio.NewReader(res.Body)
+	event := readSSEEvent(t, reader, types.EventTypeChainSyncStatus)
+	require.Equal(t, status, event.ChainSyncStatus)
+	event = readSSEEvent(t, reader, types.EventTypeBlockFinalized)
+	require.Equal(t, "0x9", event.Block.BlockHash)
+	event = readSSEEvent(t, reader, types.EventTypeBtcStakingActivated)
+	require.Equal(t, uint64(1234), event.BtcStakingActivatedTimestamp)
+
+	// the stream ends if the client falls behind
+	close(events)
+	_, err = reader.ReadString('\n')
+	require.Error(t, err)
+}
+
+func TestStreamWebSocket(t *testing.T) {
+	ctl := gomock.NewController(t)
+	mockFg := mocks.NewMockIFinalityGadget(ctl)
+	s := &Server{fg: mockFg, logger: zap.NewNop()}
+
+	// no finalized block is stored yet, so no chain sync status is sent
+	events := make(chan *types.Event, 10)
+	mockFg.EXPECT().SubscribeEvents(subscriptionBufferSize).Return(events, func() {}).Times(1)
+	mockFg.EXPECT().QueryChainSyncStatus().Return(nil, types.ErrBlockNotFound).Times(1)
+	events <- &types.Event{Type: types.EventTypeBlockFinalized, Block: &types.Block{BlockHeight: 1, BlockHash: "0x1"}}
+	events <- &types.Event{Type: types.EventTypeReorg, Reorg: &types.ReorgEvent{ForkHeight: 0, OldTipHeight: 1}}
+
+	srv := httptest.NewServer(http.HandlerFunc(s.streamHandler))
+	defer srv.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	var event types.Event
+	require.NoError(t, conn.ReadJSON(&event))
+	require.Equal(t, types.EventTypeBlockFinalized, event.Type)
+	require.Equal(t, "0x1", event.Block.BlockHash)
+	event = types.Event{}
+	require.NoError(t, conn.ReadJSON(&event))
+	require.Equal(t, types.EventTypeReorg, event.Type)
+	require.Equal(t, uint64(1), event.Reorg.OldTipHeight)
+
+	// the connection is closed with "try again later" if the client falls behind
+	close(events)
+	_, _, err = conn.ReadMessage()
+	require.True(t, websocket.IsCloseError(err, websocket.CloseTryAgainLater))
+}
+
+// readSSEEvent reads the next SSE event and checks its type
+func readSSEEvent(t *testing.T, reader *bufio.Reader, eventType types.EventType) *types.Event {
+	line, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	require.Equal(t, "event: "+string(eventType)+"\n", line)
+
+	line, err = reader.ReadString('\n')
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(line, "data: "))
+	var event types.Event
+	require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event))
+	require.Equal(t, eventType, event.Type)
+
+	line, err = reader.ReadString('\n')
+	require.NoError(t, err)
+	require.Equal(t, "\n", line)
+	return &event
+}
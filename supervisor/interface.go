@@ -0,0 +1,27 @@
+package supervisor
+
+import "context"
+
+// Service is a long running component of the daemon, managed by the Supervisor
+type Service interface {
+	// Name identifies the service in logs and errors
+	Name() string
+
+	// Start prepares the service to run, e.g. binds its listeners. Services are started one after the other, in the
+	// order they were added to the supervisor, and a start error aborts the startup.
+	Start(ctx context.Context) error
+
+	/* Run runs the service until the context is cancelled
+	 *
+	 * - it is called in its own goroutine once the service started, before the next service is started
+	 * - once the context is cancelled, it must drain its in-flight work and return
+	 * - returning an error cancels the context of all the services
+	 * - returning nil before the context is cancelled means the service completed, and doesn't affect the other
+	 *   services
+	 */
+	Run(ctx context.Context) error
+
+	// Stop releases the resources of the service, e.g. flushes and closes the db. It is called once, after all the
+	// services returned from Run, in the reverse order they were started.
+	Stop() error
+}
@@ -0,0 +1,43 @@
+package supervisor
+
+import "context"
+
+// FuncService is a Service calling the given functions. The functions are optional, and a nil function does nothing.
+type FuncService struct {
+	StartFn     func(ctx context.Context) error
+	RunFn       func(ctx context.Context) error
+	StopFn      func() error
+	ServiceName string
+}
+
+var _ Service = &FuncService{}
+
+// NewFuncService returns a service running the given function
+func NewFuncService(name string, run func(ctx context.Context) error) *FuncService {
+	return &FuncService{RunFn: run, ServiceName: name}
+}
+
+func (s *FuncService) Name() string {
+	return s.ServiceName
+}
+
+func (s *FuncService) Start(ctx context.Context) error {
+	if s.StartFn == nil {
+		return nil
+	}
+	return s.StartFn(ctx)
+}
+
+func (s *FuncService) Run(ctx context.Context) error {
+	if s.RunFn == nil {
+		return nil
+	}
+	return s.RunFn(ctx)
+}
+
+func (s *FuncService) Stop() error {
+	if s.StopFn == nil {
+		return nil
+	}
+	return s.StopFn()
+}
@@ -0,0 +1,162 @@
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ErrDrainTimeout is returned if services don't return from Run within the drain timeout once they are stopped
+var ErrDrainTimeout = errors.New("services did not stop within the drain timeout")
+
+// Phase is the phase of the lifecycle of a service
+type Phase string
+
+const (
+	PhaseStart Phase = "start"
+	PhaseRun   Phase = "run"
+	PhaseStop  Phase = "stop"
+)
+
+// ServiceError is the error of a service, returned by the supervisor
+type ServiceError struct {
+	Err     error
+	Service string
+	Phase   Phase
+}
+
+func (e *ServiceError) Error() string {
+	return fmt.Sprintf("service %s failed to %s: %v", e.Service, e.Phase, e.Err)
+}
+
+func (e *ServiceError) Unwrap() error {
+	return e.Err
+}
+
+// Supervisor manages the lifecycle of the services of the daemon. It starts them in order, stops all of them on the
+// first error or once its context is cancelled, and then releases their resources in reverse order.
+type Supervisor struct {
+	logger   *zap.Logger
+	services []Service
+	// drainTimeout bounds how long the services have to return from Run once they are stopped
+	drainTimeout time.Duration
+}
+
+//////////////////////////////
+// CONSTRUCTOR
+//////////////////////////////
+
+func NewSupervisor(drainTimeout time.Duration, logger *zap.Logger) *Supervisor {
+	return &Supervisor{
+		logger:       logger,
+		drainTimeout: drainTimeout,
+	}
+}
+
+//////////////////////////////
+// METHODS
+//////////////////////////////
+
+// Add adds services, to be started after the services already added
+func (s *Supervisor) Add(services ...Service) {
+	s.services = append(s.services, services...)
+}
+
+/* Run runs the services until the context is cancelled or a service fails
+ *
+ * - the services are started in order, and each service runs once it started
+ * - if a service fails to start, the services after it are not started
+ * - the first start or run error cancels the context of all the services
+ * - once all services returned from Run, the started services are stopped in reverse order. if they didn't return
+ *   within the drain timeout, they are not stopped, as their resources may still be in use
+ * - the first error is returned, nil if the services were stopped cleanly
+ */
+func (s *Supervisor) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		firstErr error
+		errOnce  sync.Once
+		wg       sync.WaitGroup
+	)
+	fail := func(err error) {
+		errOnce.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	started := make([]Service, 0, len(s.services))
+	for _, svc := range s.services {
+		if ctx.Err() != nil {
+			break
+		}
+		s.logger.Info("Starting service", zap.String("service", svc.Name()))
+		if err := svc.Start(ctx); err != nil {
+			fail(&ServiceError{Err: err, Service: svc.Name(), Phase: PhaseStart})
+			break
+		}
+		started = append(started, svc)
+
+		wg.Add(1)
+		go func(svc Service) {
+			defer wg.Done()
+			if err := svc.Run(ctx); err != nil {
+				s.logger.Error("Service failed", zap.String("service", svc.Name()), zap.Error(err))
+				fail(&ServiceError{Err: err, Service: svc.Name(), Phase: PhaseRun})
+				return
+			}
+			s.logger.Debug("Service returned", zap.String("service", svc.Name()))
+		}(svc)
+	}
+
+	if err := s.wait(ctx, &wg); err != nil {
+		s.logger.Error("Not stopping services", zap.Error(err))
+		fail(err)
+		return firstErr
+	}
+
+	for i := len(started) - 1; i >= 0; i-- {
+		svc := started[i]
+		s.logger.Info("Stopping service", zap.String("service", svc.Name()))
+		if err := svc.Stop(); err != nil {
+			s.logger.Error("Error stopping service", zap.String("service", svc.Name()), zap.Error(err))
+			fail(&ServiceError{Err: err, Service: svc.Name(), Phase: PhaseStop})
+		}
+	}
+	return firstErr
+}
+
+//////////////////////////////
+// INTERNAL
+//////////////////////////////
+
+// wait waits for all services to return from Run, within the drain timeout once the context is cancelled
+func (s *Supervisor) wait(ctx context.Context, wg *sync.WaitGroup) error {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	s.logger.Info("Waiting for services to stop...", zap.Duration("timeout", s.drainTimeout))
+	timer := time.NewTimer(s.drainTimeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return nil
+	case <-timer.C:
+		return ErrDrainTimeout
+	}
+}
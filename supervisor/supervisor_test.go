@@ -0,0 +1,149 @@
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/babylonlabs-io/finality-gadget/testutil/mocks"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+)
+
+func TestRunStartsAndStopsInOrder(t *testing.T) {
+	ctl := gomock.NewController(t)
+	db := newMockService(ctl, "db")
+	server := newMockService(ctl, "server")
+	processing := newMockService(ctl, "processing")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	gomock.InOrder(
+		db.EXPECT().Start(gomock.Any()).Return(nil),
+		server.EXPECT().Start(gomock.Any()).Return(nil),
+		processing.EXPECT().Start(gomock.Any()).DoAndReturn(func(context.Context) error {
+			// shut down once all services started
+			cancel()
+			return nil
+		}),
+		processing.EXPECT().Stop().Return(nil),
+		server.EXPECT().Stop().Return(nil),
+		db.EXPECT().Stop().Return(nil),
+	)
+	// the db has nothing to run
+	db.EXPECT().Run(gomock.Any()).Return(nil)
+	server.EXPECT().Run(gomock.Any()).DoAndReturn(runUntilDone)
+	processing.EXPECT().Run(gomock.Any()).DoAndReturn(runUntilDone)
+
+	sv := NewSupervisor(time.Second, zap.NewNop())
+	sv.Add(db, server, processing)
+	require.NoError(t, sv.Run(ctx))
+}
+
+func TestRunStopsOnFirstError(t *testing.T) {
+	ctl := gomock.NewController(t)
+	server := newMockService(ctl, "server")
+	processing := newMockService(ctl, "processing")
+
+	server.EXPECT().Start(gomock.Any()).Return(nil)
+	processing.EXPECT().Start(gomock.Any()).Return(nil)
+	server.EXPECT().Run(gomock.Any()).DoAndReturn(runUntilDone)
+	processing.EXPECT().Run(gomock.Any()).Return(errors.New("db corrupted"))
+	// all started services are stopped, even if they failed
+	server.EXPECT().Stop().Return(nil)
+	processing.EXPECT().Stop().Return(nil)
+
+	sv := NewSupervisor(time.Second, zap.NewNop())
+	sv.Add(server, processing)
+	err := sv.Run(context.Background())
+	require.EqualError(t, err, "service processing failed to run: db corrupted")
+	var serviceErr *ServiceError
+	require.ErrorAs(t, err, &serviceErr)
+	require.Equal(t, PhaseRun, serviceErr.Phase)
+}
+
+func TestRunAbortsStartup(t *testing.T) {
+	ctl := gomock.NewController(t)
+	db := newMockService(ctl, "db")
+	server := newMockService(ctl, "server")
+	processing := newMockService(ctl, "processing")
+
+	db.EXPECT().Start(gomock.Any()).Return(nil)
+	db.EXPECT().Run(gomock.Any()).DoAndReturn(runUntilDone)
+	server.EXPECT().Start(gomock.Any()).Return(errors.New("address already in use"))
+	// the services after the failed one are not started, and only the started services are stopped
+	db.EXPECT().Stop().Return(nil)
+
+	sv := NewSupervisor(time.Second, zap.NewNop())
+	sv.Add(db, server, processing)
+	err := sv.Run(context.Background())
+	var serviceErr *ServiceError
+	require.ErrorAs(t, err, &serviceErr)
+	require.Equal(t, &ServiceError{Err: errors.New("address already in use"), Service: "server", Phase: PhaseStart}, serviceErr)
+}
+
+func TestRunReportsStopError(t *testing.T) {
+	ctl := gomock.NewController(t)
+	db := newMockService(ctl, "db")
+
+	db.EXPECT().Start(gomock.Any()).Return(nil)
+	db.EXPECT().Run(gomock.Any()).Return(nil)
+	db.EXPECT().Stop().Return(errors.New("disk full"))
+
+	sv := NewSupervisor(time.Second, zap.NewNop())
+	sv.Add(db)
+	require.EqualError(t, sv.Run(context.Background()), "service db failed to stop: disk full")
+}
+
+func TestRunDrainTimeout(t *testing.T) {
+	ctl := gomock.NewController(t)
+	db := newMockService(ctl, "db")
+	stuck := newMockService(ctl, "stuck")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	unblock := make(chan struct{})
+	defer close(unblock)
+	db.EXPECT().Start(gomock.Any()).Return(nil)
+	db.EXPECT().Run(gomock.Any()).Return(nil)
+	stuck.EXPECT().Start(gomock.Any()).DoAndReturn(func(context.Context) error {
+		cancel()
+		return nil
+	})
+	stuck.EXPECT().Run(gomock.Any()).DoAndReturn(func(context.Context) error {
+		<-unblock
+		return nil
+	})
+	// services are not stopped while the stuck service may still use them, e.g. the db is not closed
+
+	sv := NewSupervisor(10*time.Millisecond, zap.NewNop())
+	sv.Add(db, stuck)
+	require.ErrorIs(t, sv.Run(ctx), ErrDrainTimeout)
+}
+
+func TestFuncService(t *testing.T) {
+	// nil functions do nothing
+	svc := &FuncService{ServiceName: "noop"}
+	require.Equal(t, "noop", svc.Name())
+	require.NoError(t, svc.Start(context.Background()))
+	require.NoError(t, svc.Run(context.Background()))
+	require.NoError(t, svc.Stop())
+
+	svc = NewFuncService("failing", func(ctx context.Context) error {
+		return errors.New("failed")
+	})
+	require.EqualError(t, svc.Run(context.Background()), "failed")
+}
+
+func newMockService(ctl *gomock.Controller, name string) *mocks.MockService {
+	svc := mocks.NewMockService(ctl)
+	svc.EXPECT().Name().Return(name).AnyTimes()
+	return svc
+}
+
+func runUntilDone(ctx context.Context) error {
+	<-ctx.Done()
+	return nil
+}
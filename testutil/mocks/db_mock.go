@@ -40,6 +40,20 @@ func (m *MockIDatabaseHandler) EXPECT() *MockIDatabaseHandlerMockRecorder {
 	return m.recorder
 }
 
+// Backup mocks base method.
+func (m *MockIDatabaseHandler) Backup(path string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Backup", path)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Backup indicates an expected call of Backup.
+func (mr *MockIDatabaseHandlerMockRecorder) Backup(path any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Backup", reflect.TypeOf((*MockIDatabaseHandler)(nil).Backup), path)
+}
+
 // Close mocks base method.
 func (m *MockIDatabaseHandler) Close() error {
 	m.ctrl.T.Helper()
@@ -68,6 +82,20 @@ func (mr *MockIDatabaseHandlerMockRecorder) CreateInitialSchema() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateInitialSchema", reflect.TypeOf((*MockIDatabaseHandler)(nil).CreateInitialSchema))
 }
 
+// DeleteTxWatch mocks base method.
+func (m *MockIDatabaseHandler) DeleteTxWatch(id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteTxWatch", id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteTxWatch indicates an expected call of DeleteTxWatch.
+func (mr *MockIDatabaseHandlerMockRecorder) DeleteTxWatch(id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteTxWatch", reflect.TypeOf((*MockIDatabaseHandler)(nil).DeleteTxWatch), id)
+}
+
 // GetActivatedTimestamp mocks base method.
 func (m *MockIDatabaseHandler) GetActivatedTimestamp() (uint64, error) {
 	m.ctrl.T.Helper()
@@ -113,6 +141,66 @@ func (mr *MockIDatabaseHandlerMockRecorder) GetBlockByHeight(height any) *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBlockByHeight", reflect.TypeOf((*MockIDatabaseHandler)(nil).GetBlockByHeight), height)
 }
 
+// GetBtcHeaderByHeight mocks base method.
+func (m *MockIDatabaseHandler) GetBtcHeaderByHeight(height uint64) (*types.BtcHeader, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBtcHeaderByHeight", height)
+	ret0, _ := ret[0].(*types.BtcHeader)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBtcHeaderByHeight indicates an expected call of GetBtcHeaderByHeight.
+func (mr *MockIDatabaseHandlerMockRecorder) GetBtcHeaderByHeight(height any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBtcHeaderByHeight", reflect.TypeOf((*MockIDatabaseHandler)(nil).GetBtcHeaderByHeight), height)
+}
+
+// GetFinalityEvidenceByHeight mocks base method.
+func (m *MockIDatabaseHandler) GetFinalityEvidenceByHeight(height uint64) (*types.FinalityEvidence, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFinalityEvidenceByHeight", height)
+	ret0, _ := ret[0].(*types.FinalityEvidence)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFinalityEvidenceByHeight indicates an expected call of GetFinalityEvidenceByHeight.
+func (mr *MockIDatabaseHandlerMockRecorder) GetFinalityEvidenceByHeight(height any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFinalityEvidenceByHeight", reflect.TypeOf((*MockIDatabaseHandler)(nil).GetFinalityEvidenceByHeight), height)
+}
+
+// GetSchemaVersion mocks base method.
+func (m *MockIDatabaseHandler) GetSchemaVersion() (uint64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSchemaVersion")
+	ret0, _ := ret[0].(uint64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSchemaVersion indicates an expected call of GetSchemaVersion.
+func (mr *MockIDatabaseHandlerMockRecorder) GetSchemaVersion() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSchemaVersion", reflect.TypeOf((*MockIDatabaseHandler)(nil).GetSchemaVersion))
+}
+
+// GetTxWatch mocks base method.
+func (m *MockIDatabaseHandler) GetTxWatch(id string) (*types.TxWatch, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTxWatch", id)
+	ret0, _ := ret[0].(*types.TxWatch)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTxWatch indicates an expected call of GetTxWatch.
+func (mr *MockIDatabaseHandlerMockRecorder) GetTxWatch(id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTxWatch", reflect.TypeOf((*MockIDatabaseHandler)(nil).GetTxWatch), id)
+}
+
 // InsertBlocks mocks base method.
 func (m *MockIDatabaseHandler) InsertBlocks(block []*types.Block) error {
 	m.ctrl.T.Helper()
@@ -127,6 +215,78 @@ func (mr *MockIDatabaseHandlerMockRecorder) InsertBlocks(block any) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertBlocks", reflect.TypeOf((*MockIDatabaseHandler)(nil).InsertBlocks), block)
 }
 
+// InsertBtcHeaders mocks base method.
+func (m *MockIDatabaseHandler) InsertBtcHeaders(headers []*types.BtcHeader) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertBtcHeaders", headers)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InsertBtcHeaders indicates an expected call of InsertBtcHeaders.
+func (mr *MockIDatabaseHandlerMockRecorder) InsertBtcHeaders(headers any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertBtcHeaders", reflect.TypeOf((*MockIDatabaseHandler)(nil).InsertBtcHeaders), headers)
+}
+
+// InsertFinalityEvidence mocks base method.
+func (m *MockIDatabaseHandler) InsertFinalityEvidence(evidence []*types.FinalityEvidence) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertFinalityEvidence", evidence)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InsertFinalityEvidence indicates an expected call of InsertFinalityEvidence.
+func (mr *MockIDatabaseHandlerMockRecorder) InsertFinalityEvidence(evidence any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertFinalityEvidence", reflect.TypeOf((*MockIDatabaseHandler)(nil).InsertFinalityEvidence), evidence)
+}
+
+// Ping mocks base method.
+func (m *MockIDatabaseHandler) Ping() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Ping")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Ping indicates an expected call of Ping.
+func (mr *MockIDatabaseHandlerMockRecorder) Ping() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockIDatabaseHandler)(nil).Ping))
+}
+
+// PruneBlocks mocks base method.
+func (m *MockIDatabaseHandler) PruneBlocks(height uint64) (uint64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PruneBlocks", height)
+	ret0, _ := ret[0].(uint64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PruneBlocks indicates an expected call of PruneBlocks.
+func (mr *MockIDatabaseHandlerMockRecorder) PruneBlocks(height any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PruneBlocks", reflect.TypeOf((*MockIDatabaseHandler)(nil).PruneBlocks), height)
+}
+
+// QueryEarliestBtcHeader mocks base method.
+func (m *MockIDatabaseHandler) QueryEarliestBtcHeader() (*types.BtcHeader, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryEarliestBtcHeader")
+	ret0, _ := ret[0].(*types.BtcHeader)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryEarliestBtcHeader indicates an expected call of QueryEarliestBtcHeader.
+func (mr *MockIDatabaseHandlerMockRecorder) QueryEarliestBtcHeader() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryEarliestBtcHeader", reflect.TypeOf((*MockIDatabaseHandler)(nil).QueryEarliestBtcHeader))
+}
+
 // QueryEarliestFinalizedBlock mocks base method.
 func (m *MockIDatabaseHandler) QueryEarliestFinalizedBlock() (*types.Block, error) {
 	m.ctrl.T.Helper()
@@ -142,6 +302,21 @@ func (mr *MockIDatabaseHandlerMockRecorder) QueryEarliestFinalizedBlock() *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryEarliestFinalizedBlock", reflect.TypeOf((*MockIDatabaseHandler)(nil).QueryEarliestFinalizedBlock))
 }
 
+// QueryEarliestFinalizedHeight mocks base method.
+func (m *MockIDatabaseHandler) QueryEarliestFinalizedHeight() (uint64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryEarliestFinalizedHeight")
+	ret0, _ := ret[0].(uint64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryEarliestFinalizedHeight indicates an expected call of QueryEarliestFinalizedHeight.
+func (mr *MockIDatabaseHandlerMockRecorder) QueryEarliestFinalizedHeight() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryEarliestFinalizedHeight", reflect.TypeOf((*MockIDatabaseHandler)(nil).QueryEarliestFinalizedHeight))
+}
+
 // QueryIsBlockFinalizedByHash mocks base method.
 func (m *MockIDatabaseHandler) QueryIsBlockFinalizedByHash(hash string) (bool, error) {
 	m.ctrl.T.Helper()
@@ -172,6 +347,21 @@ func (mr *MockIDatabaseHandlerMockRecorder) QueryIsBlockFinalizedByHeight(height
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryIsBlockFinalizedByHeight", reflect.TypeOf((*MockIDatabaseHandler)(nil).QueryIsBlockFinalizedByHeight), height)
 }
 
+// QueryLatestBtcHeader mocks base method.
+func (m *MockIDatabaseHandler) QueryLatestBtcHeader() (*types.BtcHeader, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryLatestBtcHeader")
+	ret0, _ := ret[0].(*types.BtcHeader)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryLatestBtcHeader indicates an expected call of QueryLatestBtcHeader.
+func (mr *MockIDatabaseHandlerMockRecorder) QueryLatestBtcHeader() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryLatestBtcHeader", reflect.TypeOf((*MockIDatabaseHandler)(nil).QueryLatestBtcHeader))
+}
+
 // QueryLatestFinalizedBlock mocks base method.
 func (m *MockIDatabaseHandler) QueryLatestFinalizedBlock() (*types.Block, error) {
 	m.ctrl.T.Helper()
@@ -187,6 +377,49 @@ func (mr *MockIDatabaseHandlerMockRecorder) QueryLatestFinalizedBlock() *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryLatestFinalizedBlock", reflect.TypeOf((*MockIDatabaseHandler)(nil).QueryLatestFinalizedBlock))
 }
 
+// QueryTxWatches mocks base method.
+func (m *MockIDatabaseHandler) QueryTxWatches() ([]*types.TxWatch, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryTxWatches")
+	ret0, _ := ret[0].([]*types.TxWatch)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryTxWatches indicates an expected call of QueryTxWatches.
+func (mr *MockIDatabaseHandlerMockRecorder) QueryTxWatches() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryTxWatches", reflect.TypeOf((*MockIDatabaseHandler)(nil).QueryTxWatches))
+}
+
+// RollbackBtcHeadersToHeight mocks base method.
+func (m *MockIDatabaseHandler) RollbackBtcHeadersToHeight(height uint64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RollbackBtcHeadersToHeight", height)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RollbackBtcHeadersToHeight indicates an expected call of RollbackBtcHeadersToHeight.
+func (mr *MockIDatabaseHandlerMockRecorder) RollbackBtcHeadersToHeight(height any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RollbackBtcHeadersToHeight", reflect.TypeOf((*MockIDatabaseHandler)(nil).RollbackBtcHeadersToHeight), height)
+}
+
+// RollbackToHeight mocks base method.
+func (m *MockIDatabaseHandler) RollbackToHeight(height uint64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RollbackToHeight", height)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RollbackToHeight indicates an expected call of RollbackToHeight.
+func (mr *MockIDatabaseHandlerMockRecorder) RollbackToHeight(height any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RollbackToHeight", reflect.TypeOf((*MockIDatabaseHandler)(nil).RollbackToHeight), height)
+}
+
 // SaveActivatedTimestamp mocks base method.
 func (m *MockIDatabaseHandler) SaveActivatedTimestamp(timestamp uint64) error {
 	m.ctrl.T.Helper()
@@ -200,3 +433,31 @@ func (mr *MockIDatabaseHandlerMockRecorder) SaveActivatedTimestamp(timestamp any
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveActivatedTimestamp", reflect.TypeOf((*MockIDatabaseHandler)(nil).SaveActivatedTimestamp), timestamp)
 }
+
+// SaveSchemaVersion mocks base method.
+func (m *MockIDatabaseHandler) SaveSchemaVersion(version uint64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveSchemaVersion", version)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveSchemaVersion indicates an expected call of SaveSchemaVersion.
+func (mr *MockIDatabaseHandlerMockRecorder) SaveSchemaVersion(version any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveSchemaVersion", reflect.TypeOf((*MockIDatabaseHandler)(nil).SaveSchemaVersion), version)
+}
+
+// SaveTxWatch mocks base method.
+func (m *MockIDatabaseHandler) SaveTxWatch(watch *types.TxWatch) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveTxWatch", watch)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveTxWatch indicates an expected call of SaveTxWatch.
+func (mr *MockIDatabaseHandlerMockRecorder) SaveTxWatch(watch any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveTxWatch", reflect.TypeOf((*MockIDatabaseHandler)(nil).SaveTxWatch), watch)
+}
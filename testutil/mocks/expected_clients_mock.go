@@ -14,6 +14,7 @@ import (
 	big "math/big"
 	reflect "reflect"
 
+	ethl2client "github.com/babylonlabs-io/finality-gadget/ethl2client"
 	types "github.com/babylonlabs-io/finality-gadget/types"
 	chainhash "github.com/btcsuite/btcd/chaincfg/chainhash"
 	wire "github.com/btcsuite/btcd/wire"
@@ -145,63 +146,78 @@ func (m *MockIBabylonClient) EXPECT() *MockIBabylonClientMockRecorder {
 }
 
 // QueryAllFpBtcPubKeys mocks base method.
-func (m *MockIBabylonClient) QueryAllFpBtcPubKeys(consumerId string) ([]string, error) {
+func (m *MockIBabylonClient) QueryAllFpBtcPubKeys(consumerId string, babylonHeight int64) ([]string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "QueryAllFpBtcPubKeys", consumerId)
+	ret := m.ctrl.Call(m, "QueryAllFpBtcPubKeys", consumerId, babylonHeight)
 	ret0, _ := ret[0].([]string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // QueryAllFpBtcPubKeys indicates an expected call of QueryAllFpBtcPubKeys.
-func (mr *MockIBabylonClientMockRecorder) QueryAllFpBtcPubKeys(consumerId any) *gomock.Call {
+func (mr *MockIBabylonClientMockRecorder) QueryAllFpBtcPubKeys(consumerId, babylonHeight any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryAllFpBtcPubKeys", reflect.TypeOf((*MockIBabylonClient)(nil).QueryAllFpBtcPubKeys), consumerId)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryAllFpBtcPubKeys", reflect.TypeOf((*MockIBabylonClient)(nil).QueryAllFpBtcPubKeys), consumerId, babylonHeight)
 }
 
 // QueryEarliestActiveDelBtcHeight mocks base method.
-func (m *MockIBabylonClient) QueryEarliestActiveDelBtcHeight(fpPubkeyHexList []string) (uint64, error) {
+func (m *MockIBabylonClient) QueryEarliestActiveDelBtcHeight(fpPubkeyHexList []string, babylonHeight int64) (uint64, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "QueryEarliestActiveDelBtcHeight", fpPubkeyHexList)
+	ret := m.ctrl.Call(m, "QueryEarliestActiveDelBtcHeight", fpPubkeyHexList, babylonHeight)
 	ret0, _ := ret[0].(uint64)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // QueryEarliestActiveDelBtcHeight indicates an expected call of QueryEarliestActiveDelBtcHeight.
-func (mr *MockIBabylonClientMockRecorder) QueryEarliestActiveDelBtcHeight(fpPubkeyHexList any) *gomock.Call {
+func (mr *MockIBabylonClientMockRecorder) QueryEarliestActiveDelBtcHeight(fpPubkeyHexList, babylonHeight any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryEarliestActiveDelBtcHeight", reflect.TypeOf((*MockIBabylonClient)(nil).QueryEarliestActiveDelBtcHeight), fpPubkeyHexList)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryEarliestActiveDelBtcHeight", reflect.TypeOf((*MockIBabylonClient)(nil).QueryEarliestActiveDelBtcHeight), fpPubkeyHexList, babylonHeight)
 }
 
 // QueryFpPower mocks base method.
-func (m *MockIBabylonClient) QueryFpPower(fpPubkeyHex string, btcHeight uint64) (uint64, error) {
+func (m *MockIBabylonClient) QueryFpPower(fpPubkeyHex string, btcHeight uint64, babylonHeight int64) (uint64, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "QueryFpPower", fpPubkeyHex, btcHeight)
+	ret := m.ctrl.Call(m, "QueryFpPower", fpPubkeyHex, btcHeight, babylonHeight)
 	ret0, _ := ret[0].(uint64)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // QueryFpPower indicates an expected call of QueryFpPower.
-func (mr *MockIBabylonClientMockRecorder) QueryFpPower(fpPubkeyHex, btcHeight any) *gomock.Call {
+func (mr *MockIBabylonClientMockRecorder) QueryFpPower(fpPubkeyHex, btcHeight, babylonHeight any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryFpPower", reflect.TypeOf((*MockIBabylonClient)(nil).QueryFpPower), fpPubkeyHex, btcHeight)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryFpPower", reflect.TypeOf((*MockIBabylonClient)(nil).QueryFpPower), fpPubkeyHex, btcHeight, babylonHeight)
+}
+
+// QueryLatestHeight mocks base method.
+func (m *MockIBabylonClient) QueryLatestHeight() (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryLatestHeight")
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryLatestHeight indicates an expected call of QueryLatestHeight.
+func (mr *MockIBabylonClientMockRecorder) QueryLatestHeight() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryLatestHeight", reflect.TypeOf((*MockIBabylonClient)(nil).QueryLatestHeight))
 }
 
 // QueryMultiFpPower mocks base method.
-func (m *MockIBabylonClient) QueryMultiFpPower(fpPubkeyHexList []string, btcHeight uint64) (map[string]uint64, error) {
+func (m *MockIBabylonClient) QueryMultiFpPower(fpPubkeyHexList []string, btcHeight uint64, babylonHeight int64) (map[string]uint64, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "QueryMultiFpPower", fpPubkeyHexList, btcHeight)
+	ret := m.ctrl.Call(m, "QueryMultiFpPower", fpPubkeyHexList, btcHeight, babylonHeight)
 	ret0, _ := ret[0].(map[string]uint64)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // QueryMultiFpPower indicates an expected call of QueryMultiFpPower.
-func (mr *MockIBabylonClientMockRecorder) QueryMultiFpPower(fpPubkeyHexList, btcHeight any) *gomock.Call {
+func (mr *MockIBabylonClientMockRecorder) QueryMultiFpPower(fpPubkeyHexList, btcHeight, babylonHeight any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryMultiFpPower", reflect.TypeOf((*MockIBabylonClient)(nil).QueryMultiFpPower), fpPubkeyHexList, btcHeight)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryMultiFpPower", reflect.TypeOf((*MockIBabylonClient)(nil).QueryMultiFpPower), fpPubkeyHexList, btcHeight, babylonHeight)
 }
 
 // MockICosmWasmClient is a mock of ICosmWasmClient interface.
@@ -229,48 +245,63 @@ func (m *MockICosmWasmClient) EXPECT() *MockICosmWasmClientMockRecorder {
 }
 
 // QueryConsumerId mocks base method.
-func (m *MockICosmWasmClient) QueryConsumerId() (string, error) {
+func (m *MockICosmWasmClient) QueryConsumerId(babylonHeight int64) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "QueryConsumerId")
+	ret := m.ctrl.Call(m, "QueryConsumerId", babylonHeight)
 	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // QueryConsumerId indicates an expected call of QueryConsumerId.
-func (mr *MockICosmWasmClientMockRecorder) QueryConsumerId() *gomock.Call {
+func (mr *MockICosmWasmClientMockRecorder) QueryConsumerId(babylonHeight any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryConsumerId", reflect.TypeOf((*MockICosmWasmClient)(nil).QueryConsumerId))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryConsumerId", reflect.TypeOf((*MockICosmWasmClient)(nil).QueryConsumerId), babylonHeight)
 }
 
 // QueryIsEnabled mocks base method.
-func (m *MockICosmWasmClient) QueryIsEnabled() (bool, error) {
+func (m *MockICosmWasmClient) QueryIsEnabled(babylonHeight int64) (bool, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "QueryIsEnabled")
+	ret := m.ctrl.Call(m, "QueryIsEnabled", babylonHeight)
 	ret0, _ := ret[0].(bool)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // QueryIsEnabled indicates an expected call of QueryIsEnabled.
-func (mr *MockICosmWasmClientMockRecorder) QueryIsEnabled() *gomock.Call {
+func (mr *MockICosmWasmClientMockRecorder) QueryIsEnabled(babylonHeight any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryIsEnabled", reflect.TypeOf((*MockICosmWasmClient)(nil).QueryIsEnabled))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryIsEnabled", reflect.TypeOf((*MockICosmWasmClient)(nil).QueryIsEnabled), babylonHeight)
 }
 
 // QueryListOfVotedFinalityProviders mocks base method.
-func (m *MockICosmWasmClient) QueryListOfVotedFinalityProviders(queryParams *types.Block) ([]string, error) {
+func (m *MockICosmWasmClient) QueryListOfVotedFinalityProviders(queryParams *types.Block, babylonHeight int64) ([]string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "QueryListOfVotedFinalityProviders", queryParams)
+	ret := m.ctrl.Call(m, "QueryListOfVotedFinalityProviders", queryParams, babylonHeight)
 	ret0, _ := ret[0].([]string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // QueryListOfVotedFinalityProviders indicates an expected call of QueryListOfVotedFinalityProviders.
-func (mr *MockICosmWasmClientMockRecorder) QueryListOfVotedFinalityProviders(queryParams any) *gomock.Call {
+func (mr *MockICosmWasmClientMockRecorder) QueryListOfVotedFinalityProviders(queryParams, babylonHeight any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryListOfVotedFinalityProviders", reflect.TypeOf((*MockICosmWasmClient)(nil).QueryListOfVotedFinalityProviders), queryParams, babylonHeight)
+}
+
+// QueryQuorumThreshold mocks base method.
+func (m *MockICosmWasmClient) QueryQuorumThreshold(babylonHeight int64) (*types.QuorumThreshold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryQuorumThreshold", babylonHeight)
+	ret0, _ := ret[0].(*types.QuorumThreshold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryQuorumThreshold indicates an expected call of QueryQuorumThreshold.
+func (mr *MockICosmWasmClientMockRecorder) QueryQuorumThreshold(babylonHeight any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryListOfVotedFinalityProviders", reflect.TypeOf((*MockICosmWasmClient)(nil).QueryListOfVotedFinalityProviders), queryParams)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryQuorumThreshold", reflect.TypeOf((*MockICosmWasmClient)(nil).QueryQuorumThreshold), babylonHeight)
 }
 
 // MockIEthL2Client is a mock of IEthL2Client interface.
@@ -324,6 +355,36 @@ func (mr *MockIEthL2ClientMockRecorder) HeaderByNumber(ctx, number any) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HeaderByNumber", reflect.TypeOf((*MockIEthL2Client)(nil).HeaderByNumber), ctx, number)
 }
 
+// HeadersByNumbers mocks base method.
+func (m *MockIEthL2Client) HeadersByNumbers(ctx context.Context, numbers []*big.Int) ([]*types0.Header, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HeadersByNumbers", ctx, numbers)
+	ret0, _ := ret[0].([]*types0.Header)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// HeadersByNumbers indicates an expected call of HeadersByNumbers.
+func (mr *MockIEthL2ClientMockRecorder) HeadersByNumbers(ctx, numbers any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HeadersByNumbers", reflect.TypeOf((*MockIEthL2Client)(nil).HeadersByNumbers), ctx, numbers)
+}
+
+// L1OriginByNumber mocks base method.
+func (m *MockIEthL2Client) L1OriginByNumber(ctx context.Context, number *big.Int) (*ethl2client.L1Origin, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "L1OriginByNumber", ctx, number)
+	ret0, _ := ret[0].(*ethl2client.L1Origin)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// L1OriginByNumber indicates an expected call of L1OriginByNumber.
+func (mr *MockIEthL2ClientMockRecorder) L1OriginByNumber(ctx, number any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "L1OriginByNumber", reflect.TypeOf((*MockIEthL2Client)(nil).L1OriginByNumber), ctx, number)
+}
+
 // TransactionReceipt mocks base method.
 func (m *MockIEthL2Client) TransactionReceipt(ctx context.Context, txHash string) (*types0.Receipt, error) {
 	m.ctrl.T.Helper()
@@ -338,3 +399,18 @@ func (mr *MockIEthL2ClientMockRecorder) TransactionReceipt(ctx, txHash any) *gom
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TransactionReceipt", reflect.TypeOf((*MockIEthL2Client)(nil).TransactionReceipt), ctx, txHash)
 }
+
+// TransactionReceipts mocks base method.
+func (m *MockIEthL2Client) TransactionReceipts(ctx context.Context, txHashes []string) ([]*types0.Receipt, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TransactionReceipts", ctx, txHashes)
+	ret0, _ := ret[0].([]*types0.Receipt)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TransactionReceipts indicates an expected call of TransactionReceipts.
+func (mr *MockIEthL2ClientMockRecorder) TransactionReceipts(ctx, txHashes any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TransactionReceipts", reflect.TypeOf((*MockIEthL2Client)(nil).TransactionReceipts), ctx, txHashes)
+}
@@ -10,6 +10,7 @@
 package mocks
 
 import (
+	context "context"
 	reflect "reflect"
 
 	types "github.com/babylonlabs-io/finality-gadget/types"
@@ -40,6 +41,34 @@ func (m *MockIFinalityGadget) EXPECT() *MockIFinalityGadgetMockRecorder {
 	return m.recorder
 }
 
+// CheckLiveness mocks base method.
+func (m *MockIFinalityGadget) CheckLiveness() *types.HealthReport {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckLiveness")
+	ret0, _ := ret[0].(*types.HealthReport)
+	return ret0
+}
+
+// CheckLiveness indicates an expected call of CheckLiveness.
+func (mr *MockIFinalityGadgetMockRecorder) CheckLiveness() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckLiveness", reflect.TypeOf((*MockIFinalityGadget)(nil).CheckLiveness))
+}
+
+// CheckReadiness mocks base method.
+func (m *MockIFinalityGadget) CheckReadiness(ctx context.Context) *types.HealthReport {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckReadiness", ctx)
+	ret0, _ := ret[0].(*types.HealthReport)
+	return ret0
+}
+
+// CheckReadiness indicates an expected call of CheckReadiness.
+func (mr *MockIFinalityGadgetMockRecorder) CheckReadiness(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckReadiness", reflect.TypeOf((*MockIFinalityGadget)(nil).CheckReadiness), ctx)
+}
+
 // GetBlockByHash mocks base method.
 func (m *MockIFinalityGadget) GetBlockByHash(hash string) (*types.Block, error) {
 	m.ctrl.T.Helper()
@@ -70,6 +99,21 @@ func (mr *MockIFinalityGadgetMockRecorder) GetBlockByHeight(height any) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBlockByHeight", reflect.TypeOf((*MockIFinalityGadget)(nil).GetBlockByHeight), height)
 }
 
+// QueryBlockFinalityEvidence mocks base method.
+func (m *MockIFinalityGadget) QueryBlockFinalityEvidence(height uint64) (*types.FinalityEvidence, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryBlockFinalityEvidence", height)
+	ret0, _ := ret[0].(*types.FinalityEvidence)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryBlockFinalityEvidence indicates an expected call of QueryBlockFinalityEvidence.
+func (mr *MockIFinalityGadgetMockRecorder) QueryBlockFinalityEvidence(height any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryBlockFinalityEvidence", reflect.TypeOf((*MockIFinalityGadget)(nil).QueryBlockFinalityEvidence), height)
+}
+
 // QueryBlockRangeBabylonFinalized mocks base method.
 func (m *MockIFinalityGadget) QueryBlockRangeBabylonFinalized(queryBlocks []*types.Block) (*uint64, error) {
 	m.ctrl.T.Helper()
@@ -204,3 +248,33 @@ func (mr *MockIFinalityGadgetMockRecorder) QueryTransactionStatus(txHash any) *g
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryTransactionStatus", reflect.TypeOf((*MockIFinalityGadget)(nil).QueryTransactionStatus), txHash)
 }
+
+// QueryTransactionsStatus mocks base method.
+func (m *MockIFinalityGadget) QueryTransactionsStatus(txHashes []string) ([]*types.TransactionStatusResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryTransactionsStatus", txHashes)
+	ret0, _ := ret[0].([]*types.TransactionStatusResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryTransactionsStatus indicates an expected call of QueryTransactionsStatus.
+func (mr *MockIFinalityGadgetMockRecorder) QueryTransactionsStatus(txHashes any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryTransactionsStatus", reflect.TypeOf((*MockIFinalityGadget)(nil).QueryTransactionsStatus), txHashes)
+}
+
+// SubscribeEvents mocks base method.
+func (m *MockIFinalityGadget) SubscribeEvents(bufferSize int) (<-chan *types.Event, func()) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SubscribeEvents", bufferSize)
+	ret0, _ := ret[0].(<-chan *types.Event)
+	ret1, _ := ret[1].(func())
+	return ret0, ret1
+}
+
+// SubscribeEvents indicates an expected call of SubscribeEvents.
+func (mr *MockIFinalityGadgetMockRecorder) SubscribeEvents(bufferSize any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SubscribeEvents", reflect.TypeOf((*MockIFinalityGadget)(nil).SubscribeEvents), bufferSize)
+}
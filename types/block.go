@@ -2,8 +2,12 @@ package types
 
 type Block struct {
 	BlockHash      string `json:"block_hash" description:"block hash"`
+	ParentHash     string `json:"parent_hash,omitempty" description:"parent block hash"`
+	StateRoot      string `json:"state_root,omitempty" description:"block state root"`
+	L1OriginHash   string `json:"l1_origin_hash,omitempty" description:"hash of the L1 block the block was derived from"`
 	BlockHeight    uint64 `json:"block_height" description:"block height"`
 	BlockTimestamp uint64 `json:"block_timestamp" description:"block timestamp"`
+	L1OriginNumber uint64 `json:"l1_origin_number,omitempty" description:"number of the L1 block the block was derived from"`
 }
 
 type ChainSyncStatus struct {
@@ -12,3 +16,38 @@ type ChainSyncStatus struct {
 	EarliestBtcFinalizedBlockHeight uint64 `json:"earliest_btc_finalized_block"`
 	LatestEthFinalizedBlockHeight   uint64 `json:"latest_eth_finalized_block"`
 }
+
+// ReorgEvent describes a divergence between the blocks stored in the local db and the
+// canonical L2 chain, and the rollback performed to recover from it
+type ReorgEvent struct {
+	OldTipHash string `json:"old_tip_hash"`
+	// ForkHeight is the last height at which the stored chain matches the L2 chain.
+	// All stored blocks above it are rolled back.
+	ForkHeight    uint64 `json:"fork_height"`
+	OldTipHeight  uint64 `json:"old_tip_height"`
+	RolledBackNum uint64 `json:"rolled_back_num"`
+}
+
+// BtcHeader is a BTC block header stored in the local BTC header index
+type BtcHeader struct {
+	Hash     string `json:"hash" description:"block hash"`
+	PrevHash string `json:"prev_hash" description:"previous block hash"`
+	Height   uint64 `json:"height" description:"block height"`
+	// Timestamp is the timestamp set by the miner, which is not monotonic across blocks
+	Timestamp uint64 `json:"timestamp" description:"block timestamp"`
+	// MedianTimePast is the median timestamp of the block and the 10 blocks before it, which
+	// is monotonic across blocks as enforced by consensus
+	MedianTimePast uint64 `json:"median_time_past" description:"median time past of the block"`
+}
+
+// BtcTimestampMapping is the way L2 block timestamps are mapped to BTC heights
+type BtcTimestampMapping string
+
+const (
+	// BtcTimestampMappingBlockTime maps a timestamp to the last BTC block with a block timestamp at
+	// or before it. BTC block timestamps are not monotonic, so neither is the mapping.
+	BtcTimestampMappingBlockTime BtcTimestampMapping = "timestamp"
+	// BtcTimestampMappingMedianTimePast maps a timestamp to the last BTC block with a median time
+	// past at or before it. The median time past is monotonic, so the mapping is too.
+	BtcTimestampMappingMedianTimePast BtcTimestampMapping = "mtp"
+)
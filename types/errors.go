@@ -8,4 +8,12 @@ var (
 	ErrNoFpHasVotingPower         = errors.New("no FP has voting power for the consumer chain")
 	ErrBtcStakingNotActivated     = errors.New("BTC staking is not activated for the consumer chain")
 	ErrActivatedTimestampNotFound = errors.New("BTC staking activated timestamp not found")
+	ErrChainDiscontinuity         = errors.New("block does not extend the stored chain")
+	ErrFinalityEvidenceNotFound   = errors.New("finality evidence not found")
+	ErrBtcHeaderNotFound          = errors.New("BTC header not found")
+	ErrBtcHeaderNotIndexed        = errors.New("BTC header not indexed yet")
+	ErrBtcHeightNotFinal          = errors.New("BTC height is not final yet")
+	ErrTxWatchNotFound            = errors.New("transaction watch not found")
+	ErrTooManyTransactions        = errors.New("too many transactions")
+	ErrInvalidTxHash              = errors.New("invalid EVM transaction hash")
 )
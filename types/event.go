@@ -0,0 +1,25 @@
+package types
+
+type EventType string
+
+const (
+	// EventTypeBlockFinalized is published when a BTC finalized block is stored in the local db
+	EventTypeBlockFinalized EventType = "block_finalized"
+	// EventTypeReorg is published when BTC finalized blocks are rolled back after an L2 reorg
+	EventTypeReorg EventType = "reorg"
+	// EventTypeChainSyncStatus is published when the chain sync status changes
+	EventTypeChainSyncStatus EventType = "chain_sync_status"
+	// EventTypeBtcStakingActivated is published when BTC staking activation is detected
+	EventTypeBtcStakingActivated EventType = "btc_staking_activated"
+)
+
+// Event is published by the finality gadget to its subscribers. Only the field matching the
+// event type is set.
+type Event struct {
+	Block           *Block           `json:"block,omitempty"`
+	Reorg           *ReorgEvent      `json:"reorg,omitempty"`
+	ChainSyncStatus *ChainSyncStatus `json:"chain_sync_status,omitempty"`
+	Type            EventType        `json:"type"`
+	// BtcStakingActivatedTimestamp is the timestamp BTC staking was activated at
+	BtcStakingActivatedTimestamp uint64 `json:"btc_staking_activated_timestamp,omitempty"`
+}
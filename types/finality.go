@@ -0,0 +1,64 @@
+package types
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// DefaultQuorumThreshold is the share of voting power required to finalize a block if neither
+// the contract nor the local config set a threshold
+var DefaultQuorumThreshold = QuorumThreshold{Numerator: 2, Denominator: 3}
+
+// QuorumThreshold is the share of the total voting power, expressed as a rational
+// numerator/denominator, that must vote for a block for it to be finalized
+type QuorumThreshold struct {
+	Numerator   uint64 `json:"numerator" description:"numerator of the quorum threshold"`
+	Denominator uint64 `json:"denominator" description:"denominator of the quorum threshold"`
+}
+
+func (q QuorumThreshold) Validate() error {
+	if q.Denominator == 0 {
+		return fmt.Errorf("quorum threshold denominator must be greater than 0")
+	}
+	if q.Numerator == 0 {
+		return fmt.Errorf("quorum threshold numerator must be greater than 0")
+	}
+	if q.Numerator > q.Denominator {
+		return fmt.Errorf("quorum threshold %d/%d must not exceed 1", q.Numerator, q.Denominator)
+	}
+	return nil
+}
+
+// IsMet returns whether votedPower/totalPower >= Numerator/Denominator. The products are computed
+// with big ints as they can overflow uint64.
+func (q QuorumThreshold) IsMet(votedPower, totalPower uint64) bool {
+	lhs := new(big.Int).Mul(new(big.Int).SetUint64(votedPower), new(big.Int).SetUint64(q.Denominator))
+	rhs := new(big.Int).Mul(new(big.Int).SetUint64(totalPower), new(big.Int).SetUint64(q.Numerator))
+	return lhs.Cmp(rhs) >= 0
+}
+
+func (q QuorumThreshold) String() string {
+	return fmt.Sprintf("%d/%d", q.Numerator, q.Denominator)
+}
+
+// FinalityEvidence records the quorum that made a block BTC-finalized, so the finality
+// decision can be audited after the fact
+type FinalityEvidence struct {
+	BlockHash string `json:"block_hash" description:"block hash"`
+	// Voters are the FPs that voted for the block, along with their voting power
+	Voters      []*VoterPower `json:"voters" description:"FPs that voted for the block"`
+	BlockHeight uint64        `json:"block_height" description:"block height"`
+	// BtcHeight is the BTC height the block timestamp was mapped to, at which voting power is taken
+	BtcHeight uint64 `json:"btc_height" description:"BTC height used to query voting power"`
+	// BabylonHeight is the Babylon height all Babylon queries were pinned to, 0 if they were made at the latest height
+	BabylonHeight uint64 `json:"babylon_height,omitempty" description:"Babylon height the finality was evaluated at"`
+	TotalPower    uint64 `json:"total_power" description:"total voting power of all FPs"`
+	VotedPower    uint64 `json:"voted_power" description:"voting power of the FPs that voted for the block"`
+	// QuorumThreshold is the threshold the voted power was checked against
+	QuorumThreshold QuorumThreshold `json:"quorum_threshold" description:"quorum threshold used to finalize the block"`
+}
+
+type VoterPower struct {
+	FpBtcPkHex string `json:"fp_btc_pk_hex" description:"BTC public key of the FP"`
+	Power      uint64 `json:"power" description:"voting power of the FP"`
+}
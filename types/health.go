@@ -0,0 +1,20 @@
+package types
+
+// HealthCheck is the result of checking a single dependency of the finality gadget
+type HealthCheck struct {
+	Name string `json:"name"`
+	// Error is set if the check failed or is degraded
+	Error   string `json:"error,omitempty"`
+	Healthy bool   `json:"healthy"`
+	// Degraded is set if the check passes but the component is retrying errors, e.g. block
+	// processing retrying an unreachable upstream node while queries are served from the db
+	Degraded bool `json:"degraded,omitempty"`
+}
+
+// HealthReport is the breakdown of the checks run to determine whether the finality gadget is healthy
+type HealthReport struct {
+	Checks  []*HealthCheck `json:"checks"`
+	Healthy bool           `json:"healthy"`
+	// Degraded is set if any check is degraded
+	Degraded bool `json:"degraded"`
+}
@@ -0,0 +1,55 @@
+package types
+
+import (
+	"fmt"
+	"time"
+)
+
+// RetentionMode is the policy deciding which finalized blocks are kept in the db, older blocks are pruned
+type RetentionMode string
+
+const (
+	// RetentionModeArchive keeps all blocks
+	RetentionModeArchive RetentionMode = "archive"
+	// RetentionModeBlocks keeps the last RetentionPolicy.Blocks blocks
+	RetentionModeBlocks RetentionMode = "blocks"
+	// RetentionModeAge keeps the blocks with a timestamp within RetentionPolicy.Age of the current time
+	RetentionModeAge RetentionMode = "age"
+	// RetentionModeEthFinalized keeps the blocks above the latest ETH finalized block minus
+	// RetentionPolicy.EthFinalizedMargin blocks
+	RetentionModeEthFinalized RetentionMode = "eth-finalized"
+)
+
+// RetentionPolicy decides which finalized blocks are kept in the db. The latest finalized block is always kept.
+type RetentionPolicy struct {
+	Mode RetentionMode
+	// Blocks is the number of blocks kept by RetentionModeBlocks
+	Blocks uint64
+	// Age is how old the blocks kept by RetentionModeAge can be
+	Age time.Duration
+	// EthFinalizedMargin is the number of blocks below the latest ETH finalized block kept by
+	// RetentionModeEthFinalized
+	EthFinalizedMargin uint64
+}
+
+func (p RetentionPolicy) Validate() error {
+	switch p.Mode {
+	case RetentionModeArchive, RetentionModeEthFinalized:
+	case RetentionModeBlocks:
+		if p.Blocks == 0 {
+			return fmt.Errorf("retention blocks must be greater than 0")
+		}
+	case RetentionModeAge:
+		if p.Age <= 0 {
+			return fmt.Errorf("retention age must be positive")
+		}
+	default:
+		return fmt.Errorf("invalid retention mode: %s", p.Mode)
+	}
+	return nil
+}
+
+// IsArchive returns whether the policy keeps all blocks
+func (p RetentionPolicy) IsArchive() bool {
+	return p.Mode == RetentionModeArchive
+}
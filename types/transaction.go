@@ -9,6 +9,16 @@ type TransactionInfo struct {
 	BabylonFinalized bool           `json:"babylonFinalized"`
 }
 
+// TransactionStatusResult is the result of a transaction in a batch transaction status query
+type TransactionStatusResult struct {
+	// Transaction is the finality status of the transaction, nil if it is not found
+	Transaction *TransactionInfo `json:"transaction,omitempty"`
+	TxHash      string           `json:"txHash"`
+	// Error is set if the transaction hash is invalid, or its receipt is not in the canonical block
+	Error string `json:"error,omitempty"`
+	Found bool   `json:"found"`
+}
+
 type FinalityStatus string
 
 const (
@@ -0,0 +1,22 @@
+package types
+
+// TxWatch is a registered callback notified when the finality status of a transaction changes
+type TxWatch struct {
+	Id          string `json:"id"`
+	TxHash      string `json:"txHash"`
+	CallbackUrl string `json:"callbackUrl"`
+	// LastStatus is the last status delivered to the callback, empty until the first delivery
+	LastStatus FinalityStatus `json:"lastStatus,omitempty"`
+	// CreatedAt is the unix timestamp the watch was registered at
+	CreatedAt uint64 `json:"createdAt"`
+}
+
+// TxStatusNotification is the JSON payload POSTed to the callback of a TxWatch
+type TxStatusNotification struct {
+	Transaction *TransactionInfo `json:"transaction"`
+	WatchId     string           `json:"watchId"`
+	// PreviousStatus is the status delivered in the previous notification, empty for the first one
+	PreviousStatus FinalityStatus `json:"previousStatus,omitempty"`
+	// Timestamp is the unix timestamp the notification was sent at, it is also part of the signature
+	Timestamp int64 `json:"timestamp"`
+}
@@ -0,0 +1,57 @@
+package webhook
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// callbackTimeout bounds the delivery of a notification to a callback
+const callbackTimeout = 10 * time.Second
+
+// newCallbackClient returns the HTTP client notifying the callbacks. Unless private callbacks are allowed, it
+// refuses to connect to loopback, link-local and private addresses. The check is made on the resolved address
+// being connected to, so it also applies to redirects and to host names resolving to internal addresses.
+func newCallbackClient(allowPrivateCallbacks bool) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   callbackTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+	if !allowPrivateCallbacks {
+		dialer.Control = func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil {
+				return fmt.Errorf("invalid callback address %s", address)
+			}
+			return checkCallbackIP(ip)
+		}
+	}
+	return &http.Client{
+		Timeout: callbackTimeout,
+		Transport: &http.Transport{
+			// callbacks are not sent through a proxy, whose address would be checked instead of the callback's
+			Proxy:                 nil,
+			DialContext:           dialer.DialContext,
+			ForceAttemptHTTP2:     true,
+			MaxIdleConns:          100,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: time.Second,
+		},
+	}
+}
+
+// checkCallbackIP rejects the addresses of the finality gadget host and its private networks
+func checkCallbackIP(ip net.IP) error {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() {
+		return fmt.Errorf("callback address %s is not public", ip)
+	}
+	return nil
+}
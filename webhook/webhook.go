@@ -0,0 +1,498 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/babylonlabs-io/finality-gadget/db"
+	"github.com/babylonlabs-io/finality-gadget/finalitygadget"
+	"github.com/babylonlabs-io/finality-gadget/types"
+	"go.uber.org/zap"
+)
+
+const (
+	// SignatureHeader is the header carrying the hex encoded HMAC-SHA256 signature of a notification
+	SignatureHeader = "X-Finality-Gadget-Signature"
+	// TimestampHeader is the header carrying the unix timestamp a notification was signed at
+	TimestampHeader = "X-Finality-Gadget-Timestamp"
+
+	// eventsBufferSize is the number of finality gadget events buffered by the watcher
+	eventsBufferSize = 16
+	// unminedWatchExpiry is how long a watch is kept while its transaction is not found on L2
+	unminedWatchExpiry = 24 * time.Hour
+
+	// DefaultMaxWatches is the max number of registered watches if not configured
+	DefaultMaxWatches = 10000
+	// DefaultWorkers is the number of watches evaluated concurrently if not configured
+	DefaultWorkers = 16
+)
+
+var (
+	ErrWebhooksDisabled = errors.New("transaction finality webhooks are disabled, set webhook-secret to enable them")
+	ErrInvalidWatch     = errors.New("invalid transaction watch")
+	ErrTooManyWatches   = errors.New("too many transaction watches")
+)
+
+// Config configures the transaction finality webhooks
+type Config struct {
+	// Secret is the HMAC-SHA256 key notifications are signed with, webhooks are disabled if empty
+	Secret string
+	// AllowedHosts restricts the callback urls to these hosts if not empty
+	AllowedHosts []string
+	// PollInterval is the interval watches are evaluated at
+	PollInterval time.Duration
+	// MaxWatches is the max number of registered watches, DefaultMaxWatches if 0
+	MaxWatches int
+	// Workers is the number of watches evaluated concurrently, DefaultWorkers if 0
+	Workers int
+	// AllowPrivateCallbacks allows callbacks to loopback, link-local and private addresses, which are
+	// rejected by default so that callers can't reach internal services through the watcher
+	AllowPrivateCallbacks bool
+}
+
+// Watcher persists transaction watches and POSTs signed notifications to their callbacks as the
+// transactions move through the pending, safe, btc finalized and finalized statuses. A watch is
+// removed once its transaction is finalized.
+type Watcher struct {
+	// ctx is cancelled when Run returns, to abort the in-flight deliveries
+	ctx    context.Context
+	cancel context.CancelFunc
+	fg     finalitygadget.IFinalityGadget
+	db     db.IDatabaseHandler
+	client *http.Client
+	logger *zap.Logger
+	// inFlight holds the ids of the watches being evaluated, so that a slow callback doesn't
+	// receive concurrent notifications
+	inFlight map[string]struct{}
+	// allowedHosts restricts the callback hosts if not empty
+	allowedHosts map[string]struct{}
+	// workers holds a slot per watch being evaluated, bounding the number of concurrent evaluations
+	workers chan struct{}
+	secret  []byte
+	wg      sync.WaitGroup
+	mutex   sync.Mutex
+	// watchMutex serializes the registration of watches, so that the max number of watches is enforced
+	watchMutex sync.Mutex
+
+	pollInterval time.Duration
+	// maxAttempts and retryInterval bound the delivery of a notification, retries back off
+	// exponentially. A notification that is not delivered is retried on the next evaluation.
+	maxAttempts   int
+	retryInterval time.Duration
+	maxWatches    int
+	// allowPrivateCallbacks allows callbacks to loopback, link-local and private addresses
+	allowPrivateCallbacks bool
+}
+
+//////////////////////////////
+// CONSTRUCTOR
+//////////////////////////////
+
+func NewWatcher(cfg *Config, fg finalitygadget.IFinalityGadget, db db.IDatabaseHandler, logger *zap.Logger) *Watcher {
+	ctx, cancel := context.WithCancel(context.Background())
+	allowedHosts := make(map[string]struct{}, len(cfg.AllowedHosts))
+	for _, host := range cfg.AllowedHosts {
+		allowedHosts[strings.ToLower(host)] = struct{}{}
+	}
+	maxWatches := cfg.MaxWatches
+	if maxWatches <= 0 {
+		maxWatches = DefaultMaxWatches
+	}
+	workers := cfg.Workers
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+	return &Watcher{
+		ctx:                   ctx,
+		cancel:                cancel,
+		fg:                    fg,
+		db:                    db,
+		client:                newCallbackClient(cfg.AllowPrivateCallbacks),
+		logger:                logger,
+		inFlight:              make(map[string]struct{}),
+		allowedHosts:          allowedHosts,
+		workers:               make(chan struct{}, workers),
+		secret:                []byte(cfg.Secret),
+		pollInterval:          cfg.PollInterval,
+		maxAttempts:           5,
+		retryInterval:         time.Second,
+		maxWatches:            maxWatches,
+		allowPrivateCallbacks: cfg.AllowPrivateCallbacks,
+	}
+}
+
+//////////////////////////////
+// METHODS
+//////////////////////////////
+
+// Enabled returns whether webhooks are enabled, i.e. a webhook secret is configured
+func (w *Watcher) Enabled() bool {
+	return w != nil && len(w.secret) > 0
+}
+
+// Watch registers a callback notified when the finality status of the transaction changes. The
+// transaction doesn't need to be mined yet.
+func (w *Watcher) Watch(txHash string, callbackUrl string) (*types.TxWatch, error) {
+	if !w.Enabled() {
+		return nil, ErrWebhooksDisabled
+	}
+	if err := validateTxHash(txHash); err != nil {
+		return nil, err
+	}
+	if err := w.validateCallbackUrl(callbackUrl); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidWatch, err)
+	}
+
+	w.watchMutex.Lock()
+	defer w.watchMutex.Unlock()
+	watches, err := w.db.QueryTxWatches()
+	if err != nil {
+		return nil, fmt.Errorf("error querying transaction watches: %w", err)
+	}
+	if len(watches) >= w.maxWatches {
+		return nil, fmt.Errorf("%w: at most %d watches can be registered", ErrTooManyWatches, w.maxWatches)
+	}
+
+	id, err := newWatchId()
+	if err != nil {
+		return nil, fmt.Errorf("error generating watch id: %w", err)
+	}
+	watch := &types.TxWatch{
+		Id:          id,
+		TxHash:      txHash,
+		CallbackUrl: callbackUrl,
+		CreatedAt:   uint64(time.Now().Unix()),
+	}
+	if err := w.db.SaveTxWatch(watch); err != nil {
+		return nil, fmt.Errorf("error saving transaction watch: %w", err)
+	}
+	w.logger.Info("Registered transaction watch", zap.String("watch_id", id), zap.String("tx_hash", txHash))
+
+	// deliver the current status right away rather than on the next evaluation, unless all workers are busy
+	w.tryEvaluate(watch, nil, false)
+	return watch, nil
+}
+
+/* Run evaluates the watches until the context is cancelled
+ *
+ * - watches are evaluated on every poll interval, as the safe and finalized L2 heads move
+ *   independently of the finality gadget
+ * - and whenever the finality gadget finalizes blocks or rolls them back
+ * - watches are evaluated by a fixed number of workers, an evaluation waits for a free worker
+ * - on return, aborts the in-flight deliveries and waits for them to complete
+ */
+func (w *Watcher) Run(ctx context.Context) {
+	defer w.wg.Wait()
+	// abort the in-flight deliveries, and the evaluations waiting for a worker, once the context is cancelled
+	stop := context.AfterFunc(ctx, w.cancel)
+	defer stop()
+	defer w.cancel()
+
+	ticker := time.NewTicker(w.pollInterval)
+	defer ticker.Stop()
+
+	events, unsubscribe := w.fg.SubscribeEvents(eventsBufferSize)
+	defer func() { unsubscribe() }()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// resubscribe if the previous subscription was dropped
+			if events == nil {
+				events, unsubscribe = w.fg.SubscribeEvents(eventsBufferSize)
+			}
+			w.evaluateAll()
+		case event, ok := <-events:
+			if !ok {
+				events = nil
+				continue
+			}
+			if event.Type == types.EventTypeBlockFinalized || event.Type == types.EventTypeReorg {
+				w.evaluateAll()
+			}
+		}
+	}
+}
+
+// Sign returns the hex encoded HMAC-SHA256 signature of a notification body sent at the given
+// timestamp, i.e. HMAC-SHA256(secret, "<timestamp>.<body>"). Receivers should recompute it from
+// the TimestampHeader and the raw body, and reject stale timestamps to prevent replays.
+func Sign(secret []byte, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+//////////////////////////////
+// INTERNAL
+//////////////////////////////
+
+// evaluateAll evaluates all the watches, with the statuses of their transactions queried in batches
+func (w *Watcher) evaluateAll() {
+	watches, err := w.db.QueryTxWatches()
+	if err != nil {
+		w.logger.Error("Failed to query transaction watches", zap.Error(err))
+		return
+	}
+	statuses := w.queryStatuses(watches)
+	for _, watch := range watches {
+		w.tryEvaluate(watch, statuses[watch.TxHash], true)
+	}
+}
+
+// txStatus is the result of the finality status query of the transaction of a watch
+type txStatus struct {
+	// txInfo is nil if the transaction is not found
+	txInfo *types.TransactionInfo
+	err    error
+}
+
+// queryStatuses returns the finality statuses of the transactions of the watches by transaction hash. The
+// statuses are queried in batches, so that evaluating the watches costs two L2 RPC requests per batch rather
+// than four per watch.
+func (w *Watcher) queryStatuses(watches []*types.TxWatch) map[string]*txStatus {
+	statuses := make(map[string]*txStatus, len(watches))
+	txHashes := make([]string, 0, len(watches))
+	for _, watch := range watches {
+		if _, ok := statuses[watch.TxHash]; !ok {
+			statuses[watch.TxHash] = &txStatus{}
+			txHashes = append(txHashes, watch.TxHash)
+		}
+	}
+
+	for start := 0; start < len(txHashes); start += finalitygadget.MaxTransactionsStatusQuerySize {
+		end := min(start+finalitygadget.MaxTransactionsStatusQuerySize, len(txHashes))
+		results, err := w.fg.QueryTransactionsStatus(txHashes[start:end])
+		if err != nil {
+			for _, txHash := range txHashes[start:end] {
+				statuses[txHash].err = err
+			}
+			continue
+		}
+		for _, result := range results {
+			if result.Error != "" {
+				statuses[result.TxHash].err = errors.New(result.Error)
+				continue
+			}
+			statuses[result.TxHash].txInfo = result.Transaction
+		}
+	}
+	return statuses
+}
+
+// tryEvaluate evaluates the watch in the background on a free worker, unless it is already being evaluated.
+// If all workers are busy, it waits for one to free up, or leaves the watch to the next evaluation if wait
+// isn't set. The status of the transaction is queried by the worker if not given.
+func (w *Watcher) tryEvaluate(watch *types.TxWatch, status *txStatus, wait bool) {
+	w.mutex.Lock()
+	if _, ok := w.inFlight[watch.Id]; ok {
+		w.mutex.Unlock()
+		return
+	}
+	w.inFlight[watch.Id] = struct{}{}
+	w.mutex.Unlock()
+	release := func() {
+		w.mutex.Lock()
+		defer w.mutex.Unlock()
+		delete(w.inFlight, watch.Id)
+	}
+
+	if wait {
+		select {
+		case w.workers <- struct{}{}:
+		case <-w.ctx.Done():
+			release()
+			return
+		}
+	} else {
+		select {
+		case w.workers <- struct{}{}:
+		default:
+			release()
+			return
+		}
+	}
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		defer func() { <-w.workers }()
+		defer release()
+		if status == nil {
+			status = w.queryStatuses([]*types.TxWatch{watch})[watch.TxHash]
+		}
+		w.evaluate(w.ctx, watch, status)
+	}()
+}
+
+/* evaluate notifies the callback of a watch if the status of its transaction changed
+ *
+ * - if the transaction is not found, keep the watch until it expires
+ * - if the status didn't change since the last delivery, there is nothing to do
+ * - else, deliver the notification and save the delivered status, or remove the watch once the
+ *   transaction is finalized
+ * - if the delivery fails, the status is left unchanged so it is retried on the next evaluation
+ */
+func (w *Watcher) evaluate(ctx context.Context, watch *types.TxWatch, status *txStatus) {
+	txInfo, err := status.txInfo, status.err
+	if err != nil || txInfo == nil {
+		if watch.LastStatus == "" && time.Since(time.Unix(int64(watch.CreatedAt), 0)) > unminedWatchExpiry {
+			w.logger.Info("Transaction not found before watch expiry, removing watch", zap.String("watch_id", watch.Id), zap.String("tx_hash", watch.TxHash))
+			w.deleteWatch(watch)
+			return
+		}
+		w.logger.Debug("Failed to query transaction status", zap.String("tx_hash", watch.TxHash), zap.Error(err))
+		return
+	}
+	if txInfo.Status == watch.LastStatus {
+		return
+	}
+
+	notification := &types.TxStatusNotification{
+		Transaction:    txInfo,
+		WatchId:        watch.Id,
+		PreviousStatus: watch.LastStatus,
+	}
+	if err := w.deliver(ctx, watch.CallbackUrl, notification); err != nil {
+		w.logger.Warn("Failed to deliver transaction status notification",
+			zap.String("watch_id", watch.Id),
+			zap.String("tx_hash", watch.TxHash),
+			zap.String("status", string(txInfo.Status)),
+			zap.Error(err),
+		)
+		return
+	}
+	w.logger.Debug("Delivered transaction status notification",
+		zap.String("watch_id", watch.Id),
+		zap.String("tx_hash", watch.TxHash),
+		zap.String("status", string(txInfo.Status)),
+	)
+
+	if txInfo.Status == types.FinalityStatusFinalized {
+		w.deleteWatch(watch)
+		return
+	}
+	watch.LastStatus = txInfo.Status
+	if err := w.db.SaveTxWatch(watch); err != nil {
+		w.logger.Error("Failed to save transaction watch", zap.String("watch_id", watch.Id), zap.Error(err))
+	}
+}
+
+// deliver POSTs the signed notification to the callback, retrying with exponential backoff
+func (w *Watcher) deliver(ctx context.Context, callbackUrl string, notification *types.TxStatusNotification) error {
+	var err error
+	backoff := w.retryInterval
+	for attempt := 1; attempt <= w.maxAttempts; attempt++ {
+		if err = w.post(ctx, callbackUrl, notification); err == nil {
+			return nil
+		}
+		if attempt == w.maxAttempts {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+	return fmt.Errorf("failed after %d attempts: %w", w.maxAttempts, err)
+}
+
+func (w *Watcher) post(ctx context.Context, callbackUrl string, notification *types.TxStatusNotification) error {
+	// the allowed hosts may have changed since the watch was registered
+	if err := w.validateCallbackUrl(callbackUrl); err != nil {
+		return err
+	}
+
+	notification.Timestamp = time.Now().Unix()
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, callbackUrl, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(TimestampHeader, strconv.FormatInt(notification.Timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(w.secret, notification.Timestamp, body))
+
+	res, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, res.Body)
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("callback returned status %d", res.StatusCode)
+	}
+	return nil
+}
+
+func (w *Watcher) deleteWatch(watch *types.TxWatch) {
+	if err := w.db.DeleteTxWatch(watch.Id); err != nil {
+		w.logger.Error("Failed to delete transaction watch", zap.String("watch_id", watch.Id), zap.Error(err))
+	}
+}
+
+func newWatchId() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
+
+func validateTxHash(txHash string) error {
+	if len(txHash) != 66 || !strings.HasPrefix(txHash, "0x") {
+		return fmt.Errorf("%w: invalid EVM transaction hash", ErrInvalidWatch)
+	}
+	if _, err := hex.DecodeString(txHash[2:]); err != nil {
+		return fmt.Errorf("%w: invalid EVM transaction hash", ErrInvalidWatch)
+	}
+	return nil
+}
+
+/* validateCallbackUrl checks that the callback url can be notified
+ *
+ * - it must be an absolute http(s) url
+ * - its host must be allowed, if the allowed hosts are configured
+ * - it must not be a loopback, link-local or private address, unless allowed. Host names are resolved
+ *   and checked when connecting, see newCallbackClient
+ */
+func (w *Watcher) validateCallbackUrl(callbackUrl string) error {
+	u, err := url.Parse(callbackUrl)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("callback url must be an absolute http(s) url")
+	}
+	if len(w.allowedHosts) > 0 {
+		if _, ok := w.allowedHosts[strings.ToLower(u.Hostname())]; !ok {
+			return fmt.Errorf("callback host %s is not allowed", u.Hostname())
+		}
+	}
+	if ip := net.ParseIP(u.Hostname()); ip != nil && !w.allowPrivateCallbacks {
+		return checkCallbackIP(ip)
+	}
+	return nil
+}
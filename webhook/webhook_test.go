@@ -0,0 +1,407 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/babylonlabs-io/finality-gadget/db"
+	"github.com/babylonlabs-io/finality-gadget/finalitygadget"
+	"github.com/babylonlabs-io/finality-gadget/testutil/mocks"
+	"github.com/babylonlabs-io/finality-gadget/types"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+)
+
+const (
+	testSecret = "secret"
+	testTxHash = "0x1111111111111111111111111111111111111111111111111111111111111111"
+)
+
+func TestWatchValidation(t *testing.T) {
+	ctl := gomock.NewController(t)
+	mockFg := mocks.NewMockIFinalityGadget(ctl)
+	handler := newTestDb(t)
+
+	disabled := NewWatcher(&Config{PollInterval: time.Second}, mockFg, handler, zap.NewNop())
+	_, err := disabled.Watch(testTxHash, "https://example.com")
+	require.ErrorIs(t, err, ErrWebhooksDisabled)
+
+	watcher := NewWatcher(&Config{Secret: testSecret, PollInterval: time.Second}, mockFg, handler, zap.NewNop())
+	for _, tc := range []struct {
+		txHash      string
+		callbackUrl string
+	}{
+		{"0x1234", "https://example.com"},
+		{"0xzz11111111111111111111111111111111111111111111111111111111111111", "https://example.com"},
+		{testTxHash, ""},
+		{testTxHash, "ftp://example.com"},
+		{testTxHash, "/callback"},
+		{testTxHash, "http://127.0.0.1:8080/callback"},
+		{testTxHash, "http://[::1]/callback"},
+		{testTxHash, "http://169.254.169.254/latest/meta-data"},
+		{testTxHash, "http://10.0.0.1/callback"},
+		{testTxHash, "http://0.0.0.0/callback"},
+	} {
+		_, err := watcher.Watch(tc.txHash, tc.callbackUrl)
+		require.ErrorIs(t, err, ErrInvalidWatch)
+	}
+
+	watches, err := handler.QueryTxWatches()
+	require.NoError(t, err)
+	require.Empty(t, watches)
+}
+
+func TestWatchAllowedHosts(t *testing.T) {
+	ctl := gomock.NewController(t)
+	mockFg := mocks.NewMockIFinalityGadget(ctl)
+	mockFg.EXPECT().QueryTransactionsStatus([]string{testTxHash}).DoAndReturn(transactionsStatus(nil)).AnyTimes()
+	handler := newTestDb(t)
+
+	watcher := NewWatcher(&Config{
+		Secret:       testSecret,
+		AllowedHosts: []string{"hooks.example.com"},
+		PollInterval: time.Second,
+	}, mockFg, handler, zap.NewNop())
+	_, err := watcher.Watch(testTxHash, "https://example.com/callback")
+	require.ErrorIs(t, err, ErrInvalidWatch)
+	_, err = watcher.Watch(testTxHash, "https://Hooks.Example.com/callback")
+	require.NoError(t, err)
+	watcher.wg.Wait()
+}
+
+func TestWatchMaxWatches(t *testing.T) {
+	ctl := gomock.NewController(t)
+	mockFg := mocks.NewMockIFinalityGadget(ctl)
+	mockFg.EXPECT().QueryTransactionsStatus([]string{testTxHash}).DoAndReturn(transactionsStatus(nil)).AnyTimes()
+	handler := newTestDb(t)
+
+	watcher := NewWatcher(&Config{Secret: testSecret, PollInterval: time.Second, MaxWatches: 2}, mockFg, handler, zap.NewNop())
+	for range 2 {
+		_, err := watcher.Watch(testTxHash, "https://example.com/callback")
+		require.NoError(t, err)
+	}
+	_, err := watcher.Watch(testTxHash, "https://example.com/callback")
+	require.ErrorIs(t, err, ErrTooManyWatches)
+	watcher.wg.Wait()
+
+	watches, err := handler.QueryTxWatches()
+	require.NoError(t, err)
+	require.Len(t, watches, 2)
+}
+
+func TestWatcherRejectsPrivateCallbackAddresses(t *testing.T) {
+	ctl := gomock.NewController(t)
+	mockFg := mocks.NewMockIFinalityGadget(ctl)
+	mockFg.EXPECT().QueryTransactionsStatus([]string{testTxHash}).DoAndReturn(transactionsStatus(&types.TransactionInfo{Status: types.FinalityStatusSafe})).AnyTimes()
+	handler := newTestDb(t)
+	callback := newTestCallback(t)
+
+	// host names are accepted on registration, the address they resolve to is checked on delivery
+	watcher := NewWatcher(&Config{Secret: testSecret, PollInterval: time.Second}, mockFg, handler, zap.NewNop())
+	watcher.maxAttempts = 1
+	u, err := url.Parse(callback.url)
+	require.NoError(t, err)
+	u.Host = net.JoinHostPort("localhost", u.Port())
+	watch, err := watcher.Watch(testTxHash, u.String())
+	require.NoError(t, err)
+	watcher.wg.Wait()
+
+	require.Empty(t, callback.notifications)
+	stored, err := handler.GetTxWatch(watch.Id)
+	require.NoError(t, err)
+	require.Empty(t, stored.LastStatus)
+}
+
+func TestWatcherDeliversStatusChanges(t *testing.T) {
+	ctl := gomock.NewController(t)
+	mockFg := mocks.NewMockIFinalityGadget(ctl)
+	handler := newTestDb(t)
+	callback := newTestCallback(t)
+
+	var mutex sync.Mutex
+	status := types.FinalityStatusPending
+	setStatus := func(s types.FinalityStatus) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		status = s
+	}
+	mockFg.EXPECT().QueryTransactionsStatus([]string{testTxHash}).DoAndReturn(func(txHashes []string) ([]*types.TransactionStatusResult, error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return transactionsStatus(&types.TransactionInfo{BlockHeight: 10, Status: status})(txHashes)
+	}).AnyTimes()
+
+	watcher := newTestWatcher(time.Second, mockFg, handler)
+	watch, err := watcher.Watch(testTxHash, callback.url)
+	require.NoError(t, err)
+
+	// the current status is delivered on registration
+	notification := callback.next(t)
+	require.Equal(t, watch.Id, notification.WatchId)
+	require.Equal(t, types.FinalityStatusPending, notification.Transaction.Status)
+	require.Empty(t, notification.PreviousStatus)
+
+	// unchanged statuses are not delivered again
+	watcher.evaluateAll()
+	watcher.wg.Wait()
+	require.Empty(t, callback.notifications)
+
+	previousStatus := types.FinalityStatusPending
+	for _, nextStatus := range []types.FinalityStatus{
+		types.FinalityStatusSafe,
+		types.FinalityStatusBitcoinFinalized,
+		types.FinalityStatusFinalized,
+	} {
+		// wait for the previous delivery to be saved
+		watcher.wg.Wait()
+		setStatus(nextStatus)
+		watcher.evaluateAll()
+		notification := callback.next(t)
+		require.Equal(t, nextStatus, notification.Transaction.Status)
+		require.Equal(t, previousStatus, notification.PreviousStatus)
+		previousStatus = nextStatus
+	}
+
+	// the watch is removed once the transaction is finalized
+	watcher.wg.Wait()
+	_, err = handler.GetTxWatch(watch.Id)
+	require.ErrorIs(t, err, types.ErrTxWatchNotFound)
+}
+
+func TestWatcherRetriesDelivery(t *testing.T) {
+	ctl := gomock.NewController(t)
+	mockFg := mocks.NewMockIFinalityGadget(ctl)
+	handler := newTestDb(t)
+	callback := newTestCallback(t)
+	mockFg.EXPECT().QueryTransactionsStatus([]string{testTxHash}).DoAndReturn(transactionsStatus(&types.TransactionInfo{Status: types.FinalityStatusSafe})).AnyTimes()
+
+	watcher := newTestWatcher(time.Second, mockFg, handler)
+	watcher.maxAttempts = 3
+	watcher.retryInterval = time.Millisecond
+
+	// the delivery fails after all attempts fail, and the status is left unchanged
+	callback.setFailures(3)
+	watch, err := watcher.Watch(testTxHash, callback.url)
+	require.NoError(t, err)
+	watcher.wg.Wait()
+	require.Empty(t, callback.notifications)
+	stored, err := handler.GetTxWatch(watch.Id)
+	require.NoError(t, err)
+	require.Empty(t, stored.LastStatus)
+
+	// the delivery succeeds if an attempt succeeds
+	callback.setFailures(2)
+	watcher.evaluateAll()
+	require.Equal(t, types.FinalityStatusSafe, callback.next(t).Transaction.Status)
+	watcher.wg.Wait()
+	stored, err = handler.GetTxWatch(watch.Id)
+	require.NoError(t, err)
+	require.Equal(t, types.FinalityStatusSafe, stored.LastStatus)
+}
+
+func TestWatcherBoundsConcurrentEvaluations(t *testing.T) {
+	ctl := gomock.NewController(t)
+	mockFg := mocks.NewMockIFinalityGadget(ctl)
+	handler := newTestDb(t)
+
+	// the callback records the max number of concurrent notifications
+	var active, maxActive, delivered atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := active.Add(1)
+		defer active.Add(-1)
+		for {
+			prev := maxActive.Load()
+			if current <= prev || maxActive.CompareAndSwap(prev, current) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		delivered.Add(1)
+	}))
+	t.Cleanup(srv.Close)
+
+	// the status of the transaction watched by all the watches is queried once
+	mockFg.EXPECT().QueryTransactionsStatus([]string{testTxHash}).DoAndReturn(transactionsStatus(&types.TransactionInfo{Status: types.FinalityStatusSafe})).Times(1)
+	for i := range 6 {
+		require.NoError(t, handler.SaveTxWatch(&types.TxWatch{
+			Id:          strconv.Itoa(i),
+			TxHash:      testTxHash,
+			CallbackUrl: srv.URL,
+			CreatedAt:   uint64(time.Now().Unix()),
+		}))
+	}
+
+	watcher := NewWatcher(&Config{Secret: testSecret, PollInterval: time.Second, Workers: 2, AllowPrivateCallbacks: true}, mockFg, handler, zap.NewNop())
+	watcher.evaluateAll()
+	watcher.wg.Wait()
+	require.Equal(t, int32(6), delivered.Load())
+	require.LessOrEqual(t, maxActive.Load(), int32(2))
+}
+
+func TestWatcherRun(t *testing.T) {
+	ctl := gomock.NewController(t)
+	mockFg := mocks.NewMockIFinalityGadget(ctl)
+	handler := newTestDb(t)
+	callback := newTestCallback(t)
+
+	events := make(chan *types.Event, 1)
+	mockFg.EXPECT().SubscribeEvents(eventsBufferSize).Return(events, func() {}).Times(1)
+	mockFg.EXPECT().QueryTransactionsStatus([]string{testTxHash}).DoAndReturn(transactionsStatus(&types.TransactionInfo{Status: types.FinalityStatusBitcoinFinalized})).Times(1)
+
+	// a watch registered before a restart is evaluated when blocks are finalized
+	require.NoError(t, handler.SaveTxWatch(&types.TxWatch{
+		Id:          "watch",
+		TxHash:      testTxHash,
+		CallbackUrl: callback.url,
+		LastStatus:  types.FinalityStatusSafe,
+		CreatedAt:   uint64(time.Now().Unix()),
+	}))
+
+	watcher := newTestWatcher(time.Hour, mockFg, handler)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		watcher.Run(ctx)
+	}()
+
+	events <- &types.Event{Type: types.EventTypeBlockFinalized, Block: &types.Block{BlockHeight: 10}}
+	notification := callback.next(t)
+	require.Equal(t, "watch", notification.WatchId)
+	require.Equal(t, types.FinalityStatusSafe, notification.PreviousStatus)
+	require.Equal(t, types.FinalityStatusBitcoinFinalized, notification.Transaction.Status)
+
+	cancel()
+	<-done
+}
+
+func TestWatcherQueriesStatusesInBatches(t *testing.T) {
+	ctl := gomock.NewController(t)
+	mockFg := mocks.NewMockIFinalityGadget(ctl)
+	handler := newTestDb(t)
+
+	// the watches are evaluated with one query per batch of transactions, a failed batch leaves its watches
+	// to the next evaluation
+	numTxs := finalitygadget.MaxTransactionsStatusQuerySize + 1
+	for i := range numTxs {
+		require.NoError(t, handler.SaveTxWatch(&types.TxWatch{
+			Id:          strconv.Itoa(i),
+			TxHash:      fmt.Sprintf("0x%064x", i),
+			CallbackUrl: "https://example.com/callback",
+			LastStatus:  types.FinalityStatusSafe,
+			CreatedAt:   uint64(time.Now().Unix()),
+		}))
+	}
+	var queried []string
+	mockFg.EXPECT().QueryTransactionsStatus(gomock.Any()).DoAndReturn(func(txHashes []string) ([]*types.TransactionStatusResult, error) {
+		queried = append(queried, txHashes...)
+		if len(txHashes) == 1 {
+			return nil, errors.New("L2 node unavailable")
+		}
+		return transactionsStatus(&types.TransactionInfo{Status: types.FinalityStatusSafe})(txHashes)
+	}).Times(2)
+
+	watcher := newTestWatcher(time.Second, mockFg, handler)
+	watcher.evaluateAll()
+	watcher.wg.Wait()
+	require.Len(t, queried, numTxs)
+	watches, err := handler.QueryTxWatches()
+	require.NoError(t, err)
+	require.Len(t, watches, numTxs)
+}
+
+// transactionsStatus returns a QueryTransactionsStatus implementation returning a copy of the given transaction
+// for every tx hash, or not found results if nil
+func transactionsStatus(txInfo *types.TransactionInfo) func(txHashes []string) ([]*types.TransactionStatusResult, error) {
+	return func(txHashes []string) ([]*types.TransactionStatusResult, error) {
+		results := make([]*types.TransactionStatusResult, len(txHashes))
+		for i, txHash := range txHashes {
+			results[i] = &types.TransactionStatusResult{TxHash: txHash}
+			if txInfo != nil {
+				tx := *txInfo
+				tx.TxHash = txHash
+				results[i].Transaction = &tx
+				results[i].Found = true
+			}
+		}
+		return results, nil
+	}
+}
+
+// newTestWatcher returns a watcher allowed to notify the test callbacks, which listen on the loopback address
+func newTestWatcher(pollInterval time.Duration, fg *mocks.MockIFinalityGadget, handler *db.BBoltHandler) *Watcher {
+	return NewWatcher(&Config{Secret: testSecret, PollInterval: pollInterval, AllowPrivateCallbacks: true}, fg, handler, zap.NewNop())
+}
+
+func newTestDb(t *testing.T) *db.BBoltHandler {
+	handler, err := db.NewBBoltHandler(filepath.Join(t.TempDir(), "test.db"), zap.NewNop())
+	require.NoError(t, err)
+	require.NoError(t, handler.CreateInitialSchema())
+	t.Cleanup(func() { handler.Close() })
+	return handler
+}
+
+// testCallback is a webhook receiver verifying the signature of the notifications it receives
+type testCallback struct {
+	notifications chan *types.TxStatusNotification
+	url           string
+	failures      int
+	mutex         sync.Mutex
+}
+
+func newTestCallback(t *testing.T) *testCallback {
+	callback := &testCallback{notifications: make(chan *types.TxStatusNotification, 10)}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		timestamp, err := strconv.ParseInt(r.Header.Get(TimestampHeader), 10, 64)
+		require.NoError(t, err)
+		require.Equal(t, Sign([]byte(testSecret), timestamp, body), r.Header.Get(SignatureHeader))
+
+		callback.mutex.Lock()
+		defer callback.mutex.Unlock()
+		if callback.failures > 0 {
+			callback.failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		var notification types.TxStatusNotification
+		require.NoError(t, json.Unmarshal(body, &notification))
+		require.Equal(t, timestamp, notification.Timestamp)
+		callback.notifications <- &notification
+	}))
+	t.Cleanup(srv.Close)
+	callback.url = srv.URL
+	return callback
+}
+
+func (c *testCallback) setFailures(failures int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.failures = failures
+}
+
+func (c *testCallback) next(t *testing.T) *types.TxStatusNotification {
+	select {
+	case notification := <-c.notifications:
+		return notification
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for notification")
+		return nil
+	}
+}